@@ -0,0 +1,170 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchInitArgs returns the argv that starts shell without user config and
+// runs script; zsh initialises the completion system first so compdef works
+func benchInitArgs(shell, script string) ([]string, error) {
+	switch shell {
+	case "bash":
+		return []string{"bash", "--norc", "--noprofile", "-c", script}, nil
+	case "zsh":
+		return []string{"zsh", "-f", "-c", "autoload -Uz compinit && compinit -D; " + script}, nil
+	case "fish":
+		return []string{"fish", "--no-config", "-c", script}, nil
+	default:
+		return nil, fmt.Errorf("unsupported shell: %s", shell)
+	}
+}
+
+// medianDuration returns the median of samples
+func medianDuration(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// timeShell runs the shell command runs times and returns the median duration
+func timeShell(argv []string, runs int) (time.Duration, error) {
+	var samples []time.Duration
+	for i := 0; i < runs; i++ {
+		cmd := exec.Command(argv[0], argv[1:]...)
+		start := time.Now()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return 0, fmt.Errorf("%s failed: %v: %s", argv[0], err, strings.TrimSpace(string(out)))
+		}
+		samples = append(samples, time.Since(start))
+	}
+	return medianDuration(samples), nil
+}
+
+// benchCommand measures how much the generated shell snippet adds to shell
+// startup ('mark bench init [--shell <shell>] [--runs N]')
+func benchCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 || args[0] != "init" {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark bench init [--shell bash|zsh|fish] [--runs N]\n")
+		os.Exit(1)
+	}
+
+	shell := detectShell() // honours --shell
+	runs := 10
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--runs":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --runs requires a value\n")
+				os.Exit(1)
+			}
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil || value < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --runs must be a positive number\n")
+				os.Exit(1)
+			}
+			runs = value
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown bench option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	// Benchmark the features the user has installed, or everything if none
+	aliases, completions := getEnabledFeatures(shell)
+	if !aliases && !completions {
+		aliases, completions = true, true
+	}
+
+	var content string
+	switch shell {
+	case "bash":
+		content = generateBashRC(getMarkPath(), aliases, completions)
+	case "zsh":
+		content = generateZshRC(getMarkPath(), aliases, completions)
+	case "fish":
+		content = generateFishRC(getMarkPath(), aliases, completions)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unsupported shell '%s'. Use --shell bash, zsh or fish\n", shell)
+		os.Exit(1)
+	}
+
+	shellPath, err := exec.LookPath(shell)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s is not installed\n", shell)
+		os.Exit(1)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mark-bench")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating temporary directory: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snippet := filepath.Join(tmpDir, "mark."+shell)
+	if err := os.WriteFile(snippet, []byte(content), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing snippet: %v\n", err)
+		os.Exit(1)
+	}
+
+	baselineArgs, _ := benchInitArgs(shell, "true")
+	snippetArgs, _ := benchInitArgs(shell, fmt.Sprintf("source '%s'", snippet))
+
+	baseline, err := timeShell(baselineArgs, runs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	withMark, err := timeShell(snippetArgs, runs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	overhead := withMark - baseline
+	if overhead < 0 {
+		overhead = 0
+	}
+
+	var features []string
+	if aliases {
+		features = append(features, "aliases")
+	}
+	if completions {
+		features = append(features, "completions")
+	}
+
+	fmt.Printf("Shell:      %s (%s)\n", shell, shellPath)
+	fmt.Printf("Snippet:    %s\n", strings.Join(features, " "))
+	fmt.Printf("Baseline:   %s (median of %d runs)\n", baseline.Round(time.Microsecond), runs)
+	fmt.Printf("With mark:  %s\n", withMark.Round(time.Microsecond))
+	fmt.Printf("mark adds:  %s\n", overhead.Round(time.Microsecond))
+}
@@ -0,0 +1,306 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// onBrokenModes are the values of on_broken / --on-broken, deciding what
+// 'mark -j' does when a bookmark target is missing
+var onBrokenModes = []string{"fail", "ancestor", "repair"}
+
+// validOnBroken reports whether mode is a known on_broken value; empty
+// means the default, fail
+func validOnBroken(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, m := range onBrokenModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// brokenJumpTarget handles a jump to a bookmark whose target is missing,
+// returning the directory to jump to or exiting according to mode
+func brokenJumpTarget(config Config, bookmark Bookmark, target, mode string) string {
+	switch mode {
+	case "ancestor":
+		d := diagnoseTarget(target)
+		if d.Existing != "" {
+			fmt.Fprintf(os.Stderr, "Warning: Bookmark '%s' points to missing %s, jumping to %s\n", bookmark.Name, target, d.Existing)
+			return d.Existing
+		}
+	case "repair":
+		if dir, ok := repairBookmark(config, bookmark, target); ok {
+			return dir
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' points to non-existent directory\n", bookmark.Name)
+	fmt.Fprintf(os.Stderr, "Run 'mark why-broken %s' for details\n", bookmark.Name)
+	os.Exit(exitBroken)
+	return ""
+}
+
+// repairBookmark asks for a new target for a broken bookmark and stores it.
+// The prompt goes to stderr because the jump wrapper captures stdout.
+func repairBookmark(config Config, bookmark Bookmark, target string) (string, bool) {
+	if bookmark.Shared {
+		fmt.Fprintf(os.Stderr, "Bookmark '%s' comes from a shared source and cannot be repaired here\n", bookmark.Name)
+		return "", false
+	}
+
+	fmt.Fprintf(os.Stderr, "Bookmark '%s' points to missing %s\n", bookmark.Name, target)
+	fmt.Fprint(os.Stderr, "New target (empty to cancel): ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return "", false
+	}
+
+	dir := normalizeTarget(marks.ExpandPath(normalizeTargetArg(response)))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: %s is not a directory\n", dir)
+		return "", false
+	}
+
+	if err := repointBookmark(config, openStorage(config), bookmark.Name, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating bookmark: %v\n", err)
+		return "", false
+	}
+	fmt.Fprintf(os.Stderr, "✓ Bookmark '%s' now points to %s\n", bookmark.Name, dir)
+
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return dir, true
+}
+
+// repointBookmark replaces the target of a bookmark, keeping its metadata
+// and recording the new directory's identity
+func repointBookmark(config Config, storage Storage, name, dir string) error {
+	if err := marks.Retarget(storage, name, linkTarget(config, dir)); err != nil {
+		return err
+	}
+
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		return err
+	}
+	m := Metadata{}
+	if meta[name] != nil {
+		m = *meta[name]
+	}
+	m.DirID = dirID(dir)
+	_ = recordAudit(config, "repoint", name, dir)
+	return marks.UpdateMetadata(config.MarksDir, name, &m)
+}
+
+// repairSearchDepth limits how far below each root 'mark repair' looks
+const repairSearchDepth = 5
+
+// repairCandidate is a directory a broken bookmark's target may have become
+type repairCandidate struct {
+	Path   string
+	SameID bool // the very directory originally bookmarked, moved or renamed
+}
+
+// dirIndex lists the directories found below the repair roots by basename
+// and by identity
+type dirIndex struct {
+	byName map[string][]string
+	byID   map[string]string
+}
+
+// indexDirectories walks up to depth levels below each root, skipping hidden
+// and dependency directories like 'mark scan'
+func indexDirectories(roots []string, depth int) dirIndex {
+	idx := dirIndex{byName: make(map[string][]string), byID: make(map[string]string)}
+	var walk func(dir string, level int)
+	walk = func(dir string, level int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || scanSkipDirs[entry.Name()] {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			idx.byName[entry.Name()] = append(idx.byName[entry.Name()], path)
+			if info, err := entry.Info(); err == nil {
+				if id := fileInfoID(info); id != "" {
+					idx.byID[id] = path
+				}
+			}
+			if level < depth {
+				walk(path, level+1)
+			}
+		}
+	}
+	for _, root := range roots {
+		walk(root, 1)
+	}
+	return idx
+}
+
+// repairCandidates returns where target may have gone: the directory with
+// the recorded identity first, then any with the same basename
+func (idx dirIndex) repairCandidates(target, id string) []repairCandidate {
+	var candidates []repairCandidate
+	seen := make(map[string]bool)
+	if path, ok := idx.byID[id]; ok && id != "" {
+		candidates = append(candidates, repairCandidate{Path: path, SameID: true})
+		seen[path] = true
+	}
+	for _, path := range idx.byName[filepath.Base(filepath.Clean(target))] {
+		if !seen[path] {
+			candidates = append(candidates, repairCandidate{Path: path})
+			seen[path] = true
+		}
+	}
+	return candidates
+}
+
+// repairRoots returns the directories searched for moved targets:
+// repair_root entries from the config, or the home directory
+func repairRoots(config Config) []string {
+	if len(config.RepairRoots) > 0 {
+		return config.RepairRoots
+	}
+	homeDir, _ := os.UserHomeDir()
+	return []string{homeDir}
+}
+
+// chooseRepair asks which candidate a broken bookmark should point to,
+// returning "" to leave it alone
+func chooseRepair(reader *bufio.Reader, name, target string, candidates []repairCandidate) string {
+	fmt.Printf("Bookmark '%s' points to missing %s\n", name, target)
+	for i, c := range candidates {
+		note := ""
+		if c.SameID {
+			note = "  (same directory, moved or renamed)"
+		}
+		fmt.Printf("  %d) %s%s\n", i+1, c.Path, note)
+	}
+	fmt.Printf("Repoint to [1-%d, empty to skip]: ", len(candidates))
+
+	response, _ := reader.ReadString('\n')
+	choice, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return ""
+	}
+	return candidates[choice-1].Path
+}
+
+// repairCommand offers new targets for broken bookmarks, found below the
+// repair roots by directory identity or basename ('mark repair [name]')
+func repairCommand(config Config, flags *ParsedFlags, args []string) {
+	storage := openStorage(config)
+
+	var bookmarks []Bookmark
+	if len(args) > 0 {
+		bookmarks = []Bookmark{lookupBookmark(storage, args[0])}
+	} else {
+		var err error
+		if bookmarks, err = storage.List(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+			os.Exit(1)
+		}
+		sort.Slice(bookmarks, func(i, j int) bool { return bookmarks[i].Name < bookmarks[j].Name })
+	}
+
+	var broken []Bookmark
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) {
+			continue
+		}
+		if _, err := os.Stat(marks.TargetPath(config, bookmark.Target)); err != nil {
+			broken = append(broken, bookmark)
+		}
+	}
+	if len(broken) == 0 {
+		if len(args) > 0 {
+			fmt.Printf("Bookmark '%s' is not broken\n", args[0])
+		} else {
+			fmt.Println("No broken bookmarks.")
+		}
+		return
+	}
+
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	idx := indexDirectories(repairRoots(config), repairSearchDepth)
+	reader := bufio.NewReader(os.Stdin)
+
+	repaired := 0
+	for _, bookmark := range broken {
+		target := marks.TargetPath(config, bookmark.Target)
+		id := ""
+		if m := meta[bookmark.Name]; m != nil {
+			id = m.DirID
+		}
+
+		candidates := idx.repairCandidates(target, id)
+		var dir string
+		switch {
+		case len(candidates) == 0:
+			fmt.Printf("  %-20s no match found for %s\n", bookmark.Name, target)
+			continue
+		case flags.Yes && (candidates[0].SameID || len(candidates) == 1):
+			dir = candidates[0].Path
+		case flags.Yes:
+			fmt.Printf("  %-20s %d possible matches, skipped (run without --yes to choose)\n", bookmark.Name, len(candidates))
+			continue
+		default:
+			if dir = chooseRepair(reader, bookmark.Name, target, candidates); dir == "" {
+				continue
+			}
+		}
+
+		if flags.DryRun {
+			fmt.Printf("Would repoint bookmark '%s' -> %s\n", bookmark.Name, dir)
+			continue
+		}
+		if err := repointBookmark(config, storage, bookmark.Name, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating bookmark '%s': %v\n", bookmark.Name, err)
+			continue
+		}
+		fmt.Printf("✓ Bookmark '%s' now points to %s\n", bookmark.Name, dir)
+		repaired++
+	}
+
+	if !flags.DryRun {
+		fmt.Printf("Repaired %d of %d broken bookmark(s)\n", repaired, len(broken))
+	}
+}
@@ -0,0 +1,430 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// editEntry is one bookmark line in the 'mark edit' file
+type editEntry struct {
+	ID          int // position in the generated file, 0 for lines added by the user
+	Name        string
+	Target      string
+	Tags        []string
+	Description string
+}
+
+// editRename is a rename in an edit plan
+type editRename struct {
+	From, To string
+}
+
+// editPlan is what applying an edited file changes, in application order
+type editPlan struct {
+	Deletes  []editEntry
+	Renames  []editRename
+	Repoints []editEntry // by their new name
+	Metadata []editEntry // tags or description changed, by their new name
+	Creates  []editEntry
+}
+
+// empty reports whether the plan changes nothing
+func (p editPlan) empty() bool {
+	return len(p.Deletes)+len(p.Renames)+len(p.Repoints)+len(p.Metadata)+len(p.Creates) == 0
+}
+
+const editFileHeader = `# Edit the bookmarks below, then save and quit to apply the changes.
+# One bookmark per line: id, name, target, tags (comma-separated, - for
+# none) and description, separated by tabs or by two or more spaces.
+#
+#   change a name            rename the bookmark
+#   change a target          repoint the bookmark
+#   change tags/description  update them
+#   delete a line            delete the bookmark (to the trash)
+#   add a line with id +     create a bookmark
+#
+# Lines starting with # are ignored. Removing every line changes nothing.
+`
+
+// editFieldSeparator splits lines that lost their tabs, e.g. to expandtab
+var editFieldSeparator = regexp.MustCompile(` {2,}`)
+
+// formatEditFile renders entries as the file opened in the editor
+func formatEditFile(entries []editEntry) string {
+	var sb strings.Builder
+	sb.WriteString(editFileHeader)
+	sb.WriteString("\n")
+	for _, entry := range entries {
+		tags := strings.Join(entry.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		description := strings.Join(strings.Fields(entry.Description), " ")
+		sb.WriteString(strings.TrimRight(fmt.Sprintf("%d\t%s\t%s\t%s\t%s", entry.ID, entry.Name, entry.Target, tags, description), "\t"))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// parseEditFile reads the edited file back. Errors name the offending line.
+func parseEditFile(content string) ([]editEntry, error) {
+	var entries []editEntry
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		var fields []string
+		if strings.Contains(line, "\t") {
+			fields = strings.SplitN(line, "\t", 5)
+		} else {
+			fields = editFieldSeparator.Split(line, 5)
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected id, name and target: %s", i+1, line)
+		}
+		for len(fields) < 5 {
+			fields = append(fields, "")
+		}
+
+		entry := editEntry{
+			Name:        strings.TrimSpace(fields[1]),
+			Target:      strings.TrimSpace(fields[2]),
+			Description: strings.TrimSpace(fields[4]),
+		}
+		if tags := strings.TrimSpace(fields[3]); tags != "-" {
+			entry.Tags = parseTags(tags)
+		}
+		if id := strings.TrimSpace(fields[0]); id != "+" {
+			n, err := strconv.Atoi(id)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("line %d: id must be a number from the original file or + for a new bookmark: %s", i+1, id)
+			}
+			entry.ID = n
+		}
+		if entry.Name == "" || entry.Target == "" {
+			return nil, fmt.Errorf("line %d: name and target cannot be empty", i+1)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// planEdit compares the edited entries with the original ones. Renames are
+// ordered so no name is taken before it is freed; a cycle of renames
+// (a -> b, b -> a) is an error.
+func planEdit(original, edited []editEntry) (editPlan, error) {
+	var plan editPlan
+
+	byID := make(map[int]editEntry)
+	for _, entry := range original {
+		byID[entry.ID] = entry
+	}
+
+	seenID := make(map[int]bool)
+	seenName := make(map[string]bool)
+	var pending []editRename
+	for _, entry := range edited {
+		if seenName[entry.Name] {
+			return plan, fmt.Errorf("bookmark '%s' appears more than once", entry.Name)
+		}
+		seenName[entry.Name] = true
+
+		if entry.ID == 0 {
+			plan.Creates = append(plan.Creates, entry)
+			continue
+		}
+		old, ok := byID[entry.ID]
+		if !ok {
+			return plan, fmt.Errorf("unknown id %d (use + for a new bookmark)", entry.ID)
+		}
+		if seenID[entry.ID] {
+			return plan, fmt.Errorf("id %d appears more than once", entry.ID)
+		}
+		seenID[entry.ID] = true
+
+		if entry.Name != old.Name {
+			pending = append(pending, editRename{From: old.Name, To: entry.Name})
+		}
+		if entry.Target != old.Target {
+			plan.Repoints = append(plan.Repoints, entry)
+		}
+		if strings.Join(entry.Tags, ",") != strings.Join(old.Tags, ",") || entry.Description != strings.Join(strings.Fields(old.Description), " ") {
+			plan.Metadata = append(plan.Metadata, entry)
+		}
+	}
+
+	taken := make(map[string]bool)
+	for _, entry := range original {
+		if seenID[entry.ID] {
+			taken[entry.Name] = true
+		} else {
+			plan.Deletes = append(plan.Deletes, entry)
+		}
+	}
+
+	for len(pending) > 0 {
+		var blocked []editRename
+		for _, rename := range pending {
+			if taken[rename.To] {
+				blocked = append(blocked, rename)
+				continue
+			}
+			delete(taken, rename.From)
+			taken[rename.To] = true
+			plan.Renames = append(plan.Renames, rename)
+		}
+		if len(blocked) == len(pending) {
+			var names []string
+			for _, rename := range blocked {
+				names = append(names, rename.From+" -> "+rename.To)
+			}
+			sort.Strings(names)
+			return plan, fmt.Errorf("renames form a cycle (%s); rename through a temporary name", strings.Join(names, ", "))
+		}
+		pending = blocked
+	}
+	return plan, nil
+}
+
+// editEntries returns the personal bookmarks with their metadata, numbered
+// from 1 in name order
+func editEntries(config Config, storage Storage) ([]editEntry, error) {
+	bookmarks, err := storage.List()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(bookmarks, func(i, j int) bool { return bookmarks[i].Name < bookmarks[j].Name })
+
+	var entries []editEntry
+	for i, bookmark := range bookmarks {
+		entry := editEntry{ID: i + 1, Name: bookmark.Name, Target: marks.TargetPath(config, bookmark.Target)}
+		if m := meta[bookmark.Name]; m != nil {
+			entry.Tags = m.Tags
+			entry.Description = m.Description
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// validateEditPlan checks new names against the name policy and new
+// targets for existence before anything is changed, resolving the targets
+// in place
+func validateEditPlan(config Config, plan *editPlan) error {
+	policy := configNamePolicy(config)
+	for _, rename := range plan.Renames {
+		if err := policy.check(rename.To); err != nil {
+			return fmt.Errorf("'%s': %w", rename.To, err)
+		}
+	}
+	for i, entry := range plan.Creates {
+		if err := policy.check(entry.Name); err != nil {
+			return fmt.Errorf("'%s': %w", entry.Name, err)
+		}
+		target, err := apiTarget(entry.Target)
+		if err != nil {
+			return fmt.Errorf("'%s': %w", entry.Name, err)
+		}
+		plan.Creates[i].Target = target
+	}
+	for i, entry := range plan.Repoints {
+		target, err := apiTarget(entry.Target)
+		if err != nil {
+			return fmt.Errorf("'%s': %w", entry.Name, err)
+		}
+		plan.Repoints[i].Target = target
+	}
+	return nil
+}
+
+// applyEditPlan carries out a validated plan, stopping at the first error
+func applyEditPlan(config Config, storage Storage, plan editPlan) error {
+	for _, entry := range plan.Deletes {
+		bookmark, err := storage.Get(entry.Name)
+		if err != nil {
+			return fmt.Errorf("deleting '%s': %w", entry.Name, err)
+		}
+		if _, err := removeBookmark(config, storage, bookmark); err != nil {
+			return fmt.Errorf("deleting '%s': %w", entry.Name, err)
+		}
+		fmt.Printf("✓ Removed bookmark '%s'\n", entry.Name)
+	}
+
+	for _, rename := range plan.Renames {
+		if err := renameBookmark(config, storage, rename.From, rename.To); err != nil {
+			return fmt.Errorf("renaming '%s': %w", rename.From, err)
+		}
+		fmt.Printf("✓ Renamed bookmark '%s' -> '%s'\n", rename.From, rename.To)
+	}
+
+	for _, entry := range plan.Repoints {
+		if err := repointBookmark(config, storage, entry.Name, entry.Target); err != nil {
+			return fmt.Errorf("repointing '%s': %w", entry.Name, err)
+		}
+		fmt.Printf("✓ Repointed bookmark '%s' -> %s\n", entry.Name, entry.Target)
+	}
+
+	for _, entry := range plan.Metadata {
+		all, err := marks.LoadMetadata(config.MarksDir)
+		if err != nil {
+			return err
+		}
+		m := Metadata{}
+		if all[entry.Name] != nil {
+			m = *all[entry.Name]
+		}
+		m.Tags = entry.Tags
+		m.Description = entry.Description
+		if marks.IsEmptyMetadata(&m) {
+			err = marks.UpdateMetadata(config.MarksDir, entry.Name, nil)
+		} else {
+			err = marks.UpdateMetadata(config.MarksDir, entry.Name, &m)
+		}
+		if err != nil {
+			return fmt.Errorf("updating '%s': %w", entry.Name, err)
+		}
+		fmt.Printf("✓ Updated bookmark '%s'\n", entry.Name)
+	}
+
+	for _, entry := range plan.Creates {
+		meta := Metadata{Tags: entry.Tags, Description: entry.Description}
+		if err := storeBookmark(config, storage, entry.Name, entry.Target, meta); err != nil {
+			return fmt.Errorf("creating '%s': %w", entry.Name, err)
+		}
+		fmt.Printf("✓ Created bookmark '%s' -> %s\n", entry.Name, entry.Target)
+	}
+	return nil
+}
+
+// applyEditFile parses, plans, validates and applies an edited file. applied
+// reports whether any change was made before an error, after which editing
+// again would start from stale ids.
+func applyEditFile(config Config, storage Storage, original []editEntry, content string) (applied bool, err error) {
+	edited, err := parseEditFile(content)
+	if err != nil {
+		return false, err
+	}
+	if len(edited) == 0 && len(original) > 0 {
+		fmt.Println("No bookmarks left in the file; nothing changed.")
+		return false, nil
+	}
+	plan, err := planEdit(original, edited)
+	if err != nil {
+		return false, err
+	}
+	if plan.empty() {
+		fmt.Println("No changes.")
+		return false, nil
+	}
+	if err := validateEditPlan(config, &plan); err != nil {
+		return false, err
+	}
+	return true, applyEditPlan(config, storage, plan)
+}
+
+// editCommand opens every personal bookmark in $VISUAL/$EDITOR as one line
+// each and applies the differences on save ('mark edit'), like crontab -e.
+// A file that does not parse or apply cleanly can be edited again.
+func editCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark edit\n")
+		os.Exit(1)
+	}
+
+	storage := openStorage(config)
+	original, err := editEntries(config, storage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	file, err := os.CreateTemp("", "mark-edit-*.txt")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+	content := formatEditFile(original)
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	editor := editorCommandLine(os.Getenv)
+	if editor == nil {
+		editor = []string{"vi"}
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		cmd := exec.Command(editor[0], append(editor[1:], path)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: editor failed: %v; nothing changed\n", err)
+			os.Exit(1)
+		}
+
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if string(edited) == content {
+			fmt.Println("No changes.")
+			return
+		}
+
+		applied, err := applyEditFile(config, storage, original, string(edited))
+		if err == nil {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if applied || !stdinIsTerminal() {
+			os.Exit(1)
+		}
+		fmt.Print("Edit again? (Y/n): ")
+		response, _ := reader.ReadString('\n')
+		if answer := cleanResponse(response); answer != "" {
+			if yes, _ := parseYesNo(answer); !yes {
+				fmt.Println("Nothing changed.")
+				os.Exit(1)
+			}
+		}
+		content = string(edited)
+	}
+}
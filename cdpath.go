@@ -0,0 +1,142 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// cdpathLinks returns the bookmarks that plain 'cd name' can reach through
+// a directory of symlinks, as name -> target directory. URL, !command and
+// glob bookmarks and names that are not a single path component are left
+// out.
+func cdpathLinks(config Config, bookmarks []Bookmark) map[string]string {
+	links := make(map[string]string)
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) || marks.IsGlobTarget(bookmark.Target) || bookmark.Name != filepath.Base(bookmark.Name) || strings.HasPrefix(bookmark.Name, ".") {
+			continue
+		}
+		links[bookmark.Name] = marks.TargetPath(config, bookmark.Target)
+	}
+	return links
+}
+
+// cdpathParents returns the distinct parent directories of the bookmark
+// targets, sorted, for a CDPATH that reaches targets by their own base name
+func cdpathParents(config Config, bookmarks []Bookmark) []string {
+	seen := make(map[string]bool)
+	var parents []string
+	for _, target := range cdpathLinks(config, bookmarks) {
+		parent := filepath.Dir(target)
+		if !seen[parent] {
+			seen[parent] = true
+			parents = append(parents, parent)
+		}
+	}
+	sort.Strings(parents)
+	return parents
+}
+
+// marksDirUsable reports whether the marks directory itself can serve as a
+// CDPATH entry: the OS must be able to follow every bookmark symlink, which
+// rules out other storage backends, ~/ (link_style=home), $VAR, !command
+// and glob targets, and no bookmark may be restricted to other hosts
+func marksDirUsable(config Config, storage Storage, bookmarks []Bookmark) bool {
+	if storageName(config) != "symlink" {
+		return false
+	}
+	if _, filtered := storage.(*hostFilteredStorage); filtered {
+		return false
+	}
+	for _, bookmark := range bookmarks {
+		if marks.IsHomeTarget(bookmark.Target) || marks.IsEnvTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) || marks.IsGlobTarget(bookmark.Target) {
+			return false
+		}
+	}
+	return true
+}
+
+// cdpathLinkDir returns the generated symlink directory for the marks
+// directory in use, so profiles do not share one
+func cdpathLinkDir(config Config) string {
+	return filepath.Join(stateDir(), "cdpath", fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(config.MarksDir))))
+}
+
+// writeLinkDir replaces the contents of dir with one symlink per link
+func writeLinkDir(dir string, links map[string]string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cdpathCommand prints a CDPATH value that makes 'cd name' reach bookmarks
+// without the jump function ('mark cdpath [--parents]'). With symlink
+// storage that is the marks directory itself, so new bookmarks work at
+// once; otherwise a directory of symlinks is regenerated on every run.
+// --parents lists the target parent directories instead.
+func cdpathCommand(config Config, flags *ParsedFlags, args []string) {
+	parents := false
+	for _, arg := range args {
+		if arg != "--parents" {
+			fmt.Fprintf(os.Stderr, "Error: Unknown cdpath option: %s\n", arg)
+			os.Exit(1)
+		}
+		parents = true
+	}
+
+	storage := hostFiltered(config, openStorage(config))
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	var dirs []string
+	switch {
+	case parents:
+		dirs = cdpathParents(config, bookmarks)
+	case marksDirUsable(config, storage, bookmarks):
+		dirs = []string{config.MarksDir}
+	default:
+		dir := cdpathLinkDir(config)
+		if err := writeLinkDir(dir, cdpathLinks(config, bookmarks)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", dir, err)
+			os.Exit(1)
+		}
+		dirs = []string{dir}
+	}
+
+	// The current directory comes first so local names still win
+	fmt.Println(strings.Join(append([]string{"."}, dirs...), string(os.PathListSeparator)))
+}
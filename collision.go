@@ -0,0 +1,171 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// maxNameSuggestions is how many alternatives a name collision offers
+const maxNameSuggestions = 3
+
+// nameTaken reports whether storage already has a bookmark called name
+func nameTaken(storage Storage, name string) bool {
+	_, err := storage.Get(name)
+	return !errors.Is(err, marks.ErrNotFound)
+}
+
+// suffixedName returns name-2, name-3, ... whichever is free first. The
+// name is shortened as needed for the suffix to fit the policy's
+// max_length; an error means the policy rejects suffixed names outright.
+func suffixedName(policy namePolicy, taken func(string) bool, name string) (string, error) {
+	for n := 2; ; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		base := []rune(name)
+		if policy.MaxLength > 0 && len(base)+len(suffix) > policy.MaxLength {
+			base = base[:max(policy.MaxLength-len(suffix), 0)]
+		}
+		candidate, err := policy.normalize(string(base) + suffix)
+		if err != nil {
+			return "", err
+		}
+		if !taken(candidate) {
+			return candidate, nil
+		}
+	}
+}
+
+// nameSuggestions returns free alternatives to a taken name: the target's
+// parent directory joined to it (work-api for .../work/api), then numbered
+// names (api-2, api-3). Each one passes the naming policy.
+func nameSuggestions(policy namePolicy, taken func(string) bool, name, targetDir string) []string {
+	var suggestions []string
+	seen := map[string]bool{name: true}
+	add := func(candidate string) {
+		candidate, err := policy.normalize(candidate)
+		if err != nil || seen[candidate] || taken(candidate) {
+			return
+		}
+		seen[candidate] = true
+		suggestions = append(suggestions, candidate)
+	}
+
+	if !marks.IsURLTarget(targetDir) && !marks.IsCommandTarget(targetDir) {
+		parent := filepath.Base(filepath.Dir(targetDir))
+		if parent != "." && parent != string(filepath.Separator) && parent != "" {
+			add(parent + "-" + name)
+		}
+	}
+	for n := 2; len(suggestions) < maxNameSuggestions; n++ {
+		add(name + "-" + strconv.Itoa(n))
+	}
+	return suggestions
+}
+
+// nameConflictChoice is the answer to a name collision: a new name to use,
+// overwrite the existing bookmark, or neither (abort)
+type nameConflictChoice struct {
+	Name      string
+	Overwrite bool
+}
+
+// parseConflictAnswer interprets an answer to askNameConflict: o
+// overwrites, a number picks a suggestion, a or nothing aborts
+func parseConflictAnswer(answer string, suggestions []string) (nameConflictChoice, bool) {
+	switch strings.ToLower(answer) {
+	case "", "a", "abort":
+		return nameConflictChoice{}, true
+	case "o", "overwrite":
+		return nameConflictChoice{Overwrite: true}, true
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(suggestions) {
+		return nameConflictChoice{Name: suggestions[n-1]}, true
+	}
+	return nameConflictChoice{}, false
+}
+
+// askNameConflict offers the ways out of creating a bookmark whose name is
+// taken and reads the choice, asking again after an unknown answer
+func askNameConflict(reader *bufio.Reader, name, existing string, suggestions []string) nameConflictChoice {
+	fmt.Printf("Bookmark '%s' already exists -> %s\n", name, existing)
+	fmt.Println("  o) overwrite it")
+	for i, suggestion := range suggestions {
+		fmt.Printf("  %d) create '%s' instead\n", i+1, suggestion)
+	}
+	fmt.Println("  a) abort")
+	for {
+		fmt.Print("Choice [a]: ")
+		response, err := reader.ReadString('\n')
+		choice, ok := parseConflictAnswer(cleanResponse(response), suggestions)
+		if ok || err != nil {
+			return choice
+		}
+		fmt.Println("Please answer o, a or one of the numbers.")
+	}
+}
+
+// exitNameConflict reports a taken name and exits, pointing at the
+// alternatives a non-interactive caller can use
+func exitNameConflict(name string, suggestions []string) {
+	fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' already exists. Use 'mark -d %s' to remove it first", name, name)
+	if len(suggestions) > 0 {
+		fmt.Fprintf(os.Stderr, ", pick another name (e.g. '%s') or add --suffix", suggestions[0])
+	}
+	fmt.Fprintln(os.Stderr, ".")
+	os.Exit(exitConflict)
+}
+
+// resolveNameConflict decides what to do when name is taken: with --suffix
+// the first free name-N is used, on a terminal the user picks, otherwise it
+// exits with the suggestions. It returns the name to create and, when the
+// user chose to overwrite, the bookmark to replace; ok is false on abort.
+func resolveNameConflict(config Config, storage Storage, name, targetDir string, suffix, dryRun bool) (newName string, replace *Bookmark, ok bool) {
+	taken := func(candidate string) bool { return nameTaken(storage, candidate) }
+	policy := configNamePolicy(config)
+	if suffix {
+		suffixed, err := suffixedName(policy, taken, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return suffixed, nil, true
+	}
+
+	suggestions := nameSuggestions(policy, taken, name, targetDir)
+	existing, err := storage.Get(name)
+	if err != nil || dryRun || !stdinIsTerminal() {
+		exitNameConflict(name, suggestions)
+	}
+
+	choice := askNameConflict(bufio.NewReader(os.Stdin), name, existing.Target, suggestions)
+	switch {
+	case choice.Overwrite:
+		return name, &existing, true
+	case choice.Name != "":
+		return choice.Name, nil, true
+	}
+	return "", nil, false
+}
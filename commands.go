@@ -0,0 +1,307 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// subcommand handles a named command such as 'mark explain <name>'
+type subcommand func(config Config, flags *ParsedFlags, args []string)
+
+// subcommands maps command names to their handlers. A bookmark whose name
+// collides with a command can still be created with 'mark -- <name>'.
+// Filled in by init because some handlers generate shell scripts, which in
+// turn list the command names.
+var subcommands map[string]subcommand
+
+// configFreeCommands run without loading ~/.mark, so they work before
+// first-time setup: init is evaluated by shell startup files, completion is
+// run by package builds, the tutorial brings its own sandbox config, config
+// edits the file directly and uninstall must not recreate it
+var configFreeCommands = map[string]bool{
+	"completion": true,
+	"config":     true,
+	"init":       true,
+	"tutorial":   true,
+	"uninstall":  true,
+}
+
+func init() {
+	subcommands = map[string]subcommand{
+		"bench":        benchCommand,
+		"breadcrumb":   breadcrumbCommand,
+		"cdpath":       cdpathCommand,
+		"completion":   completionCommand,
+		"config":       configCommand,
+		"dedupe":       dedupeCommand,
+		"diff":         diffCommand,
+		"edit":         editCommand,
+		"edit-dir":     editDirCommand,
+		"exec":         execCommand,
+		"explain":      explainBookmark,
+		"export":       exportCommand,
+		"fallback":     fallbackCommand,
+		"history":      historyCommand,
+		"import":       importCommand,
+		"init":         initCommand,
+		"maintain":     maintainCommand,
+		"menu":         menuCommand,
+		"merge":        mergeCommand,
+		"only-on":      onlyOnCommand,
+		"open":         openCommand,
+		"os-target":    osTargetCommand,
+		"recent":       recentCommand,
+		"repair":       repairCommand,
+		"report":       reportCommand,
+		"restore":      restoreCommand,
+		"scan":         scanCommand,
+		"serve":        serveCommand,
+		"shell":        shellCommand,
+		"show":         showCommand,
+		"ssh":          sshCommand,
+		"stats":        statsCommand,
+		"suggest":      suggestCommand,
+		"tidy-names":   tidyNamesCommand,
+		"tidy-targets": tidyTargetsCommand,
+		"top":          topCommand,
+		"trash":        trashCommand,
+		"tui":          tuiCommand,
+		"tutorial":     tutorialCommand,
+		"uninstall":    uninstallCommand,
+		"which":        whichCommand,
+		"why-broken":   whyBrokenCommand,
+	}
+}
+
+// symlinkHop is one step while following a chain of symbolic links
+type symlinkHop struct {
+	from string
+	to   string
+}
+
+// traceSymlinks follows path through successive symlinks, returning each hop
+// until a non-symlink (or a missing path) is reached
+func traceSymlinks(path string) ([]symlinkHop, error) {
+	var hops []symlinkHop
+
+	for i := 0; i < 40; i++ {
+		fileInfo, err := os.Lstat(path)
+		if err != nil || fileInfo.Mode()&os.ModeSymlink == 0 {
+			return hops, nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return hops, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+
+		hops = append(hops, symlinkHop{from: path, to: target})
+		path = target
+	}
+
+	return hops, fmt.Errorf("too many levels of symbolic links")
+}
+
+// explainBookmark prints a step-by-step trace of how a bookmark name resolves
+func explainBookmark(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for explain\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	bookmark, source := findBookmark(config, name)
+	targetPath := marks.TargetPath(config, bookmark.Target)
+
+	fmt.Printf("Bookmark:   %s\n", name)
+	if marks.IsURLTarget(bookmark.Target) {
+		fmt.Printf("URL:        %s (opened with 'mark open %s')\n", bookmark.Target, name)
+		return
+	}
+	if marks.IsCommandTarget(bookmark.Target) {
+		fmt.Printf("Command:    %s (run by -j when dynamic_targets=on)\n", strings.TrimPrefix(bookmark.Target, "!"))
+		return
+	}
+	fmt.Printf("Source:     %s\n", source.Origin)
+	var hops []symlinkHop
+	if dir, ok := bookmarkLinkDir(config, source); ok {
+		symlinkPath := filepath.Join(dir, name)
+		fmt.Printf("Symlink:    %s\n", symlinkPath)
+		hops = append(hops, symlinkHop{from: symlinkPath, to: targetPath})
+	}
+	fmt.Printf("Raw target: %s\n", bookmark.Target)
+
+	targetHops, err := traceSymlinks(targetPath)
+	hops = append(hops, targetHops...)
+	fmt.Println("Hops:")
+	for i, hop := range hops {
+		fmt.Printf("  %d. %s -> %s\n", i+1, hop.from, hop.to)
+	}
+	if err != nil {
+		fmt.Printf("  (stopped: %v)\n", err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(targetPath)
+	if err != nil {
+		fmt.Printf("Resolved:   %s[broken]%s %v\n", colorRed, colorReset, err)
+		return
+	}
+	fmt.Printf("Resolved:   %s\n", resolved)
+
+	if targetInfo, err := os.Stat(resolved); err == nil && !targetInfo.IsDir() {
+		fmt.Println("Status:     not a directory")
+	} else {
+		fmt.Println("Status:     ok")
+	}
+}
+
+// bookmarkLinkDir returns the directory holding the source's bookmarks as
+// symlinks, if it keeps them that way
+func bookmarkLinkDir(config Config, source marks.Source) (string, bool) {
+	if shared, ok := source.Storage.(*marks.SharedDirStorage); ok {
+		return shared.Dir, true
+	}
+	if !source.Shared && storageName(config) == "symlink" {
+		return config.MarksDir, true
+	}
+	return "", false
+}
+
+// showCommand prints everything known about one bookmark: its target, how
+// it resolves, its metadata and recent usage ('mark show <name>')
+func showCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for show\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	bookmark := lookupBookmark(openLayeredStorage(config), name)
+	targetPath := marks.TargetPath(config, bookmark.Target)
+
+	fmt.Printf("Bookmark:    %s\n", name)
+	if bookmark.Shared {
+		fmt.Println("Source:      shared (read-only)")
+	}
+	fmt.Printf("Target:      %s\n", bookmark.Target)
+	if !bookmark.Shared {
+		if osPath, ok := marks.OSTarget(config, bookmarkMetadata(config, name)); ok {
+			fmt.Printf("On %-9s %s\n", runtime.GOOS+":", osPath)
+			targetPath = osPath
+		}
+	}
+
+	if !marks.IsURLTarget(bookmark.Target) && !marks.IsCommandTarget(bookmark.Target) {
+		if resolved, err := filepath.EvalSymlinks(targetPath); err != nil {
+			fmt.Printf("Resolved:    %s[broken]%s %v\n", colorRed, colorReset, err)
+		} else {
+			fmt.Printf("Resolved:    %s\n", resolved)
+		}
+	}
+
+	switch info, err := os.Stat(targetPath); {
+	case marks.IsURLTarget(bookmark.Target):
+		fmt.Printf("Status:      URL, opened with 'mark open %s'\n", name)
+	case marks.IsCommandTarget(targetPath):
+		fmt.Println("Status:      command, run by -j when dynamic_targets=on")
+	case err != nil:
+		fmt.Printf("Status:      %smissing%s (see 'mark why-broken %s')\n", colorRed, colorReset, name)
+	case !info.IsDir():
+		fmt.Println("Status:      not a directory")
+	default:
+		fmt.Println("Status:      ok, directory")
+	}
+
+	// Personal metadata belongs to the personal bookmark of the same name,
+	// not to a shared one
+	var meta map[string]*Metadata
+	if !bookmark.Shared {
+		var err error
+		if meta, err = marks.LoadMetadata(config.MarksDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if m := meta[name]; m != nil {
+		if len(m.Tags) > 0 {
+			fmt.Printf("Tags:        %s\n", strings.Join(m.Tags, ", "))
+		}
+		if m.Description != "" {
+			fmt.Printf("Description: %s\n", m.Description)
+		}
+		if m.Host != "" {
+			fmt.Printf("Host:        %s\n", m.Host)
+		}
+		if m.Pinned {
+			fmt.Println("Pinned:      yes")
+		}
+		for i, fallback := range m.Fallbacks {
+			label := ""
+			if i == 0 {
+				label = "Fallbacks:"
+			}
+			status := ""
+			if info, err := os.Stat(marks.TargetPath(config, fallback)); err != nil || !info.IsDir() {
+				status = " (missing)"
+			}
+			fmt.Printf("%-12s %s%s\n", label, fallback, status)
+		}
+	}
+
+	changes, _ := loadChanges()
+	if created := creationTime(config, name, meta[name], createdTimes(changes), bookmark.Shared); !created.IsZero() {
+		fmt.Printf("Created:     %s (%s)\n", formatLastUsed(created), formatAge(time.Since(created)))
+	}
+
+	events, err := loadUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	var jumps []usageEvent
+	for _, event := range events {
+		if event.Name == name {
+			jumps = append(jumps, event)
+		}
+	}
+	fmt.Printf("Uses:        %d\n", len(jumps))
+	if len(jumps) > 0 {
+		fmt.Println("Recent jumps:")
+		for i := len(jumps) - 1; i >= 0 && i >= len(jumps)-5; i-- {
+			fmt.Printf("  %s  %s\n", formatLastUsed(jumps[i].Time), jumps[i].Path)
+		}
+	}
+}
+
+// subcommandNames returns the command names in alphabetical order
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
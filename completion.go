@@ -24,6 +24,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
 )
 
 // RC file paths for unified shell configuration
@@ -50,81 +52,240 @@ func getMarkPath() string {
 	return markPath
 }
 
-// generateBashRC generates unified bash RC content with aliases and/or completions
-func generateBashRC(markPath string, includeAliases, includeCompletions bool) string {
-	var features []string
-	if includeAliases {
-		features = append(features, "aliases")
-	}
-	if includeCompletions {
-		features = append(features, "completions")
+// integrationConfig reads the config file for the settings built into the
+// generated shell integration (jump hooks, visit tracking). A missing or
+// unreadable config yields the defaults.
+func integrationConfig() Config {
+	configPath, err := marks.ConfigFilePath()
+	if err != nil {
+		return Config{}
 	}
+	config, _ := marks.ParseConfigFile(configPath)
+	return config
+}
 
-	var sb strings.Builder
-	sb.WriteString("#!/bin/bash\n")
-	sb.WriteString("# mark shell configuration\n")
-	sb.WriteString("# Generated by mark - do not edit manually\n")
-	sb.WriteString(fmt.Sprintf("# Features: %s\n", strings.Join(features, " ")))
-	sb.WriteString("\n")
+// visitHook returns the shell code that reports every change of directory
+// to 'mark --record-visit' when track_visits is on
+func visitHook(shell, markPath string) string {
+	if integrationConfig().TrackVisits != "on" {
+		return ""
+	}
 
-	if includeAliases {
-		sb.WriteString("# === ALIASES ===\n")
-		sb.WriteString(fmt.Sprintf("alias marks='%s -l'\n", markPath))
-		sb.WriteString(fmt.Sprintf("alias unmark='%s -d'\n", markPath))
-		sb.WriteString(fmt.Sprintf(`function jump() {
-    local target=$(%s -j "$@")
-    if [ $? -eq 0 ] && [ -n "$target" ]; then
-        cd "$target"
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(`# === VISIT TRACKING ===
+__mark_record_visit() {
+    if [ "$PWD" != "$__mark_last_pwd" ]; then
+        __mark_last_pwd="$PWD"
+        '%s' --record-visit >/dev/null 2>&1
     fi
 }
-`, markPath))
-		sb.WriteString("\n")
+if [[ "$PROMPT_COMMAND" != *__mark_record_visit* ]]; then
+    PROMPT_COMMAND="__mark_record_visit${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+
+`, markPath)
+	case "zsh":
+		return fmt.Sprintf(`# === VISIT TRACKING ===
+__mark_record_visit() {
+    '%s' --record-visit >/dev/null 2>&1
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd __mark_record_visit
+
+`, markPath)
+	case "fish":
+		return fmt.Sprintf(`# === VISIT TRACKING ===
+function __mark_record_visit --on-variable PWD
+    '%s' --record-visit >/dev/null 2>&1
+end
+
+`, markPath)
 	}
+	return ""
+}
 
-	if includeCompletions {
-		sb.WriteString("# === COMPLETIONS ===\n")
-		sb.WriteString(`# Helper function to get bookmarks with their paths for display
+// hookLine formats a jump hook as a line inside the jump function, with
+// $target holding the destination directory
+func hookLine(command string) string {
+	if command == "" {
+		return ""
+	}
+	return "        " + command + "\n"
+}
+
+// markFunction wraps mark for bash and zsh so that 'mark <name>' changes
+// directory when default_action=jump makes it a jump; mark itself can only
+// print the target. Everything else runs mark unchanged.
+func markFunction(markPath string, hooks Config) string {
+	return fmt.Sprintf(`function mark() {
+    if [ $# -ne 1 ] || [ "${1#-}" != "$1" ]; then
+        '%[1]s' "$@"
+        return
+    fi
+    local target
+    target=$('%[1]s' --default-jump "$1") || return
+    if [ -z "$target" ]; then
+        '%[1]s' "$@"
+    else
+%[2]s        cd "$target"
+%[3]s    fi
+}
+`, markPath, hookLine(hooks.PreJump), hookLine(hooks.PostJump))
+}
+
+// fishMarkFunction is markFunction for fish
+func fishMarkFunction(markPath string, hooks Config) string {
+	return fmt.Sprintf(`function mark
+    if test (count $argv) -ne 1; or string match -q -- '-*' $argv[1]
+        '%[1]s' $argv
+        return
+    end
+    set -l target ('%[1]s' --default-jump $argv[1]); or return
+    if test -z "$target"
+        '%[1]s' $argv
+    else
+%[2]s        cd "$target"
+%[3]s    end
+end
+`, markPath, hookLine(hooks.PreJump), hookLine(hooks.PostJump))
+}
+
+// bashCompletionScript completes mark, marks, unmark and jump in bash
+const bashCompletionScript = `# Helper function to get bookmarks with their paths for display
 _mark_list_with_paths() {
     mark -l 2>/dev/null || true
 }
 
+# Bookmark names in the order 'mark -l' lists them (see sort= in ~/.mark)
+_mark_names() {
+    mark -l "$@" 2>/dev/null | awk '$2 == "->" {print $1}'
+}
+
+# Optional full-screen chooser (completion_chooser=fzf or fzf-tmux in ~/.mark)
+# Returns 1 when no chooser is configured so the plain list is shown instead
+_mark_choose() {
+    local chooser choice
+    chooser=$(mark --complete-chooser 2>/dev/null) || return 1
+    choice=$(mark -l 2>/dev/null | "$chooser" --query "$1" --select-1 --exit-0 | awk '{print $1}')
+    if [[ -n "$choice" ]]; then
+        COMPREPLY=("$choice")
+    else
+        COMPREPLY=()
+    fi
+    return 0
+}
+
+# Helper function to find a --tag filter earlier on the command line
+_mark_tag_filter() {
+    local i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        if [[ "${COMP_WORDS[i]}" == "--tag" ]]; then
+            echo "${COMP_WORDS[i+1]}"
+            return
+        fi
+    done
+}
+
+# Bookmark names may contain ':' (import --prefix work:), which bash splits
+# words at; rebuild the words up to the cursor splitting on spaces alone
+_mark_split_words() {
+    if [[ -z "${COMP_LINE:-}" ]]; then
+        cword=$COMP_CWORD cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+        return
+    fi
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *[[:space:]] ]] && words+=("")
+    cword=$((${#words[@]} - 1))
+    cur="${words[cword]}"
+    prev="${words[cword-1]}"
+}
+
+# Bash only replaces the part of a name after its last ':', so drop what
+# comes before it from each reply
+_mark_trim_colons() {
+    [[ "$cur" == *:* && "$COMP_WORDBREAKS" == *:* ]] || return
+    local prefix="${cur%"${cur##*:}"}" i
+    for i in "${!COMPREPLY[@]}"; do
+        COMPREPLY[i]="${COMPREPLY[i]#"$prefix"}"
+    done
+}
+
 _mark_complete() {
-    local cur="${COMP_WORDS[COMP_CWORD]}"
-    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cur prev cword
+    _mark_split_words
+    _mark_complete_words
+    _mark_trim_colons
+}
+
+_mark_complete_words() {
     local cmd="${COMP_WORDS[0]}"
 
+    # Complete tag names after --tag
+    if [[ "$prev" == "--tag" ]]; then
+        COMPREPLY=($(compgen -W "$(mark --complete-tags 2>/dev/null)" -- "${cur}"))
+        return
+    fi
+
+    # Below a bookmark ('jump proj/<TAB>'), complete subdirectories of its target
+    if [[ "$cur" == */* ]] && { [[ "$cmd" == "jump" && ${cword} -eq 1 ]] || [[ "$prev" =~ ^(-j|exec)$ ]]; }; then
+        COMPREPLY=($(mark --complete-subpath "$cur" 2>/dev/null))
+        compopt -o nospace 2>/dev/null
+        return
+    fi
+
+    # With a --tag filter, only offer bookmarks carrying that tag
+    local tag=$(_mark_tag_filter)
+    if [[ -n "$tag" && "$cur" != -* ]]; then
+        local marks=$(_mark_names --tag "$tag")
+        compopt -o nosort 2>/dev/null
+        COMPREPLY=($(compgen -W "$marks" -- "${cur}"))
+        return
+    fi
+
     # If we're on the first argument
-    if [[ ${COMP_CWORD} -eq 1 ]]; then
+    if [[ ${cword} -eq 1 ]]; then
         # If user starts typing a dash, offer flags (only for 'mark' command)
         if [[ "$cur" == -* && "$cmd" == "mark" ]]; then
-            local flags="-l -d -j -v -h --config --configure --autocomplete --alias --help --version"
+            local flags="-l -d -j -y -v -h --config --configure --yes --dry-run --verbose --marksdir --no-completion --no-alias --autocomplete --alias --check-names --long --tree --screen-reader --tag --desc --host --only-on --in-container --handoff --raw --on-broken --link-style --profile --shell --sort --pin --git-root --suffix --help --version"
             COMPREPLY=($(compgen -W "$flags" -- "${cur}"))
         else
-            # For bookmark completion, show formatted list
-            if [[ -d ~/.marks ]]; then
-                # Get bookmark names for actual completion
-                local marks=$(ls ~/.marks 2>/dev/null | tr '\n' ' ')
+            local create_names
+            if [[ "$cmd" == "mark" ]] && create_names=$(mark --complete-create 2>/dev/null); then
+                # Opt-in (create_completion=dirs): suggest new names from the current path
+                COMPREPLY=($(compgen -W "$create_names" -- "${cur}"))
+            # For bookmark completion, ask mark itself so a custom marksdir works
+            else
+                # Get bookmark names in the configured sort order
+                local marks=$(_mark_names)
+                compopt -o nosort 2>/dev/null
                 COMPREPLY=($(compgen -W "$marks" -- "${cur}"))
 
-                # Only show formatted list on double-tab (COMP_TYPE = 63)
-                if [[ ${#COMPREPLY[@]} -gt 1 ]] && [[ ${COMP_TYPE:-} -eq 63 ]]; then
+                # On double-tab (COMP_TYPE = 63) open the chooser or show formatted list
+                if [[ ${#COMPREPLY[@]} -gt 1 ]] && [[ ${COMP_TYPE:-} -eq 63 ]] && ! _mark_choose "$cur"; then
                     echo >&2  # Newline before the list
                     _mark_list_with_paths >&2
                 fi
             fi
-        fi
-    # If previous was -d or -j, offer bookmark names with paths
-    elif [[ "$prev" == "-d" || "$prev" == "-j" ]]; then
-        if [[ -d ~/.marks ]]; then
-            local marks=$(ls ~/.marks 2>/dev/null | tr '\n' ' ')
-            COMPREPLY=($(compgen -W "$marks" -- "${cur}"))
-
-            # Only show formatted list on double-tab (COMP_TYPE = 63)
-            if [[ ${#COMPREPLY[@]} -gt 1 ]] && [[ ${COMP_TYPE:-} -eq 63 ]]; then
-                echo >&2  # Newline before the list
-                _mark_list_with_paths >&2
+
+            # Offer subcommands alongside names (only for 'mark' command)
+            if [[ "$cmd" == "mark" ]]; then
+                COMPREPLY+=($(compgen -W "@MARK_COMMANDS@" -- "${cur}"))
             fi
         fi
+    # If previous was -d, -j or a bookmark command, offer bookmark names with paths
+    elif [[ "$prev" =~ ^(-d|-j|edit-dir|exec|explain|open|repair|shell|show|ssh|why-broken)$ ]]; then
+        local marks=$(_mark_names)
+        compopt -o nosort 2>/dev/null
+        COMPREPLY=($(compgen -W "$marks" -- "${cur}"))
+
+        # On double-tab (COMP_TYPE = 63) open the chooser or show formatted list
+        if [[ ${#COMPREPLY[@]} -gt 1 ]] && [[ ${COMP_TYPE:-} -eq 63 ]] && ! _mark_choose "$cur"; then
+            echo >&2  # Newline before the list
+            _mark_list_with_paths >&2
+        fi
     fi
 }
 
@@ -132,14 +293,10 @@ complete -F _mark_complete mark
 complete -F _mark_complete marks
 complete -F _mark_complete unmark
 complete -F _mark_complete jump
-`)
-	}
-
-	return sb.String()
-}
+`
 
-// generateZshRC generates unified zsh RC content with aliases and/or completions
-func generateZshRC(markPath string, includeAliases, includeCompletions bool) string {
+// generateBashRC generates unified bash RC content with aliases and/or completions
+func generateBashRC(markPath string, includeAliases, includeCompletions bool) string {
 	var features []string
 	if includeAliases {
 		features = append(features, "aliases")
@@ -149,7 +306,7 @@ func generateZshRC(markPath string, includeAliases, includeCompletions bool) str
 	}
 
 	var sb strings.Builder
-	sb.WriteString("#!/bin/zsh\n")
+	sb.WriteString("#!/bin/bash\n")
 	sb.WriteString("# mark shell configuration\n")
 	sb.WriteString("# Generated by mark - do not edit manually\n")
 	sb.WriteString(fmt.Sprintf("# Features: %s\n", strings.Join(features, " ")))
@@ -159,33 +316,80 @@ func generateZshRC(markPath string, includeAliases, includeCompletions bool) str
 		sb.WriteString("# === ALIASES ===\n")
 		sb.WriteString(fmt.Sprintf("alias marks='%s -l'\n", markPath))
 		sb.WriteString(fmt.Sprintf("alias unmark='%s -d'\n", markPath))
+		hooks := integrationConfig()
 		sb.WriteString(fmt.Sprintf(`function jump() {
-    local target=$(%s -j "$@")
+    local target=$(%s --dir-fallback -j "$@")
     if [ $? -eq 0 ] && [ -n "$target" ]; then
-        cd "$target"
-    fi
+%s        cd "$target"
+%s    fi
 }
-`, markPath))
+`, markPath, hookLine(hooks.PreJump), hookLine(hooks.PostJump)))
+		sb.WriteString(markFunction(markPath, hooks))
 		sb.WriteString("\n")
 	}
 
+	sb.WriteString(visitHook("bash", markPath))
+
 	if includeCompletions {
 		sb.WriteString("# === COMPLETIONS ===\n")
-		sb.WriteString("autoload -U +X compinit && compinit\n\n")
-		sb.WriteString(`_mark_complete() {
+		sb.WriteString(bashCompletionScript)
+	}
+
+	return withCommandNames(sb.String())
+}
+
+// zshCompletionFunction defines _mark_complete for zsh; the rc file binds
+// it with compdef, the site-functions file calls it directly
+const zshCompletionFunction = `_mark_complete() {
     local cur="${words[CURRENT]}"
     local prev="${words[CURRENT-1]}"
     local cmd="${words[1]}"
 
+    # Complete tag names after --tag
+    if [[ "$prev" == "--tag" ]]; then
+        local -a tags
+        tags=(${(f)"$(mark --complete-tags 2>/dev/null)"})
+        compadd -a tags
+        return
+    fi
+
+    # Below a bookmark ('jump proj/<TAB>'), complete subdirectories of its target
+    if [[ "$cur" == */* ]] && { [[ "$cmd" == "jump" && $CURRENT -eq 2 ]] || [[ "$prev" == (-j|exec) ]]; }; then
+        local -a subdirs
+        subdirs=(${(f)"$(mark --complete-subpath "$cur" 2>/dev/null)"})
+        compadd -S '' -a subdirs
+        return
+    fi
+
+    # With a --tag filter, only offer bookmarks carrying that tag
+    local -a tagopt
+    local i
+    for ((i = 2; i < CURRENT; i++)); do
+        [[ "${words[i]}" == "--tag" ]] && tagopt=(--tag "${words[i+1]}")
+    done
+    if [[ ${#tagopt[@]} -gt 0 && "$cur" != -* ]]; then
+        local -a marks
+        marks=(${(f)"$(mark -l $tagopt 2>/dev/null | awk '{print $1}')"})
+        compadd -V bookmarks -a marks
+        return
+    fi
+
     # If we're on the first argument
     if [[ $CURRENT -eq 2 ]]; then
         # If user starts typing a dash, offer flags (only for 'mark' command)
         if [[ "$cur" == -* && "$cmd" == "mark" ]]; then
-            local flags=("-l" "-d" "-j" "-v" "-h" "--config" "--configure" "--autocomplete" "--alias" "--help" "--version")
+            local flags=("-l" "-d" "-j" "-y" "-v" "-h" "--config" "--configure" "--yes" "--dry-run" "--verbose" "--marksdir" "--no-completion" "--no-alias" "--autocomplete" "--alias" "--check-names" "--long" "--tree" "--screen-reader" "--tag" "--desc" "--host" "--only-on" "--in-container" "--handoff" "--raw" "--on-broken" "--link-style" "--profile" "--shell" "--sort" "--pin" "--git-root" "--suffix" "--help" "--version")
             compadd -a flags
         else
+            local create_names
+            if [[ "$cmd" == "mark" ]] && create_names=$(mark --complete-create 2>/dev/null); then
+                # Opt-in (create_completion=dirs): suggest new names from the current path
+                local -a names
+                names=(${(f)create_names})
+                compadd -X 'new bookmark name (existing bookmarks excluded)' -a names
             # For bookmark completion, parse 'mark -l' output to get names and descriptions
-            if [[ -d ~/.marks ]]; then
+            # (asking mark itself, so a custom marksdir works)
+            else
                 local -a marks descriptions
                 local name desc
 
@@ -204,48 +408,44 @@ func generateZshRC(markPath string, includeAliases, includeCompletions bool) str
 
                 # Use compadd with descriptions
                 if [[ ${#marks[@]} -gt 0 ]]; then
-                    compadd -d descriptions -a marks
+                    compadd -V bookmarks -d descriptions -a marks
                 fi
             fi
-        fi
 
-    # If previous was -d or -j, offer bookmark names with descriptions
-    elif [[ "$prev" == "-d" || "$prev" == "-j" ]]; then
-        if [[ -d ~/.marks ]]; then
-            local -a marks descriptions
-            local name desc
+            # Offer subcommands alongside names (only for 'mark' command)
+            if [[ "$cmd" == "mark" ]]; then
+                local -a commands
+                commands=(@MARK_COMMANDS@)
+                compadd -a commands
+            fi
+        fi
 
-            # Parse mark -l output
-            while IFS= read -r line; do
-                name=$(echo "$line" | sed -E 's/^[[:space:]]*([^[:space:]]+)[[:space:]]*->.*/\1/')
-                desc=$(echo "$line" | sed -E 's/^[[:space:]]*[^[:space:]]+[[:space:]]*(->.*)/\1/')
+    # If previous was -d, -j or a bookmark command, offer bookmark names with descriptions
+    elif [[ "$prev" =~ ^(-d|-j|edit-dir|exec|explain|open|repair|shell|show|ssh|why-broken)$ ]]; then
+        local -a marks descriptions
+        local name desc
 
-                if [[ -n "$name" && -n "$desc" ]]; then
-                    marks+=("$name")
-                    descriptions+=("$desc")
-                fi
-            done < <(mark -l 2>/dev/null)
+        # Parse mark -l output
+        while IFS= read -r line; do
+            name=$(echo "$line" | sed -E 's/^[[:space:]]*([^[:space:]]+)[[:space:]]*->.*/\1/')
+            desc=$(echo "$line" | sed -E 's/^[[:space:]]*[^[:space:]]+[[:space:]]*(->.*)/\1/')
 
-            # Use compadd with descriptions
-            if [[ ${#marks[@]} -gt 0 ]]; then
-                compadd -d descriptions -a marks
+            if [[ -n "$name" && -n "$desc" ]]; then
+                marks+=("$name")
+                descriptions+=("$desc")
             fi
+        done < <(mark -l 2>/dev/null)
+
+        # Use compadd with descriptions
+        if [[ ${#marks[@]} -gt 0 ]]; then
+            compadd -V bookmarks -d descriptions -a marks
         fi
     fi
 }
+`
 
-compdef _mark_complete mark
-compdef _mark_complete marks
-compdef _mark_complete unmark
-compdef _mark_complete jump
-`)
-	}
-
-	return sb.String()
-}
-
-// generateFishRC generates unified fish RC content with aliases and/or completions
-func generateFishRC(markPath string, includeAliases, includeCompletions bool) string {
+// generateZshRC generates unified zsh RC content with aliases and/or completions
+func generateZshRC(markPath string, includeAliases, includeCompletions bool) string {
 	var features []string
 	if includeAliases {
 		features = append(features, "aliases")
@@ -255,6 +455,7 @@ func generateFishRC(markPath string, includeAliases, includeCompletions bool) st
 	}
 
 	var sb strings.Builder
+	sb.WriteString("#!/bin/zsh\n")
 	sb.WriteString("# mark shell configuration\n")
 	sb.WriteString("# Generated by mark - do not edit manually\n")
 	sb.WriteString(fmt.Sprintf("# Features: %s\n", strings.Join(features, " ")))
@@ -262,56 +463,351 @@ func generateFishRC(markPath string, includeAliases, includeCompletions bool) st
 
 	if includeAliases {
 		sb.WriteString("# === ALIASES ===\n")
-		sb.WriteString(fmt.Sprintf("alias marks '%s -l'\n", markPath))
-		sb.WriteString(fmt.Sprintf("alias unmark '%s -d'\n", markPath))
-		sb.WriteString(fmt.Sprintf(`function jump
-    set -l target (%s -j $argv)
-    if test $status -eq 0 -a -n "$target"
-        cd "$target"
-    end
-end
-`, markPath))
+		sb.WriteString(fmt.Sprintf("alias marks='%s -l'\n", markPath))
+		sb.WriteString(fmt.Sprintf("alias unmark='%s -d'\n", markPath))
+		hooks := integrationConfig()
+		sb.WriteString(fmt.Sprintf(`function jump() {
+    local target=$(%s --dir-fallback -j "$@")
+    if [ $? -eq 0 ] && [ -n "$target" ]; then
+%s        cd "$target"
+%s    fi
+}
+`, markPath, hookLine(hooks.PreJump), hookLine(hooks.PostJump)))
+		sb.WriteString(markFunction(markPath, hooks))
 		sb.WriteString("\n")
 	}
 
+	sb.WriteString(visitHook("zsh", markPath))
+
 	if includeCompletions {
 		sb.WriteString("# === COMPLETIONS ===\n")
-		sb.WriteString(`# Helper function to list bookmarks with their paths
+		sb.WriteString("autoload -U +X compinit && compinit\n\n")
+		sb.WriteString(zshCompletionFunction)
+		sb.WriteString(`
+compdef _mark_complete mark
+compdef _mark_complete marks
+compdef _mark_complete unmark
+compdef _mark_complete jump
+`)
+	}
+
+	return withCommandNames(sb.String())
+}
+
+// fishCompletionScript completes mark, marks, unmark and jump in fish
+const fishCompletionScript = `# Helper function to list bookmarks with their paths
 function __fish_mark_list_bookmarks
-    mark -l 2>/dev/null | while read -l line
+    # Honor a --tag filter earlier on the command line
+    set -l tagopt
+    set -l tokens (commandline -opc)
+    for i in (seq (count $tokens))
+        if test "$tokens[$i]" = "--tag"; and test $i -lt (count $tokens)
+            set tagopt --tag $tokens[(math $i + 1)]
+        end
+    end
+
+    mark -l $tagopt 2>/dev/null | while read -l line
         # Parse "  name -> target" format into "name\t-> target" format
         echo "$line" | sed -E 's/^[[:space:]]*([^[:space:]]+)[[:space:]]*(->.*)/\1\t\2/'
     end
 end
 
+# Creation candidates for the main argument; falls back to existing bookmarks
+# unless create_completion=dirs is configured
+function __fish_mark_create_candidates
+    if set -l names (mark --complete-create 2>/dev/null)
+        for name in $names
+            printf '%s\tnew bookmark\n' $name
+        end
+    else
+        __fish_mark_list_bookmarks
+    end
+end
+
+# Subdirectories below a bookmark ('jump proj/<TAB>')
+function __fish_mark_subpaths
+    set -l token (commandline -ct)
+    string match -q -- '*/*' $token; and mark --complete-subpath $token 2>/dev/null
+end
+
 complete -c mark -f
 complete -c mark -s l -d "List bookmarks"
 complete -c mark -s d -d "Delete bookmark" -r
 complete -c mark -s j -d "Jump to bookmark" -r
 complete -c mark -l config -d "Run setup/reconfigure"
 complete -c mark -l configure -d "Run setup/reconfigure"
+complete -c mark -s y -l yes -d "Delete without confirmation; accept setup defaults"
+complete -c mark -l dry-run -d "Show what create, delete or import would change"
+complete -c mark -l verbose -d "Trace config, paths and written files to stderr"
+complete -c mark -l marksdir -d "With setup, store bookmarks here" -r
+complete -c mark -l no-completion -d "With setup, skip completion"
+complete -c mark -l no-alias -d "With setup, skip aliases"
 complete -c mark -l autocomplete -d "Setup/update command line autocompletion"
 complete -c mark -l alias -d "Setup shell aliases"
+complete -c mark -l check-names -d "Find names that break the shell integration"
+complete -c mark -l long -d "With -l, show usage statistics"
+complete -c mark -l tree -d "With -l, group bookmarks by common path prefix"
+complete -c mark -l screen-reader -d "With -l, one plain sentence per bookmark"
+complete -c mark -l tag -d "Tag a new bookmark or filter the list" -x -a '(mark --complete-tags 2>/dev/null)'
+complete -c mark -l desc -d "Describe a new bookmark" -x
+complete -c mark -l host -d "SSH host of the bookmark" -x
+complete -c mark -l only-on -d "Hostname patterns the bookmark is active on" -x
+complete -c mark -l in-container -d "Use the path inside a dev container" -x
+complete -c mark -l handoff -d "With -j, also write the target to the handoff file"
+complete -c mark -l raw -d "Keep symlinks in the target unresolved"
+complete -c mark -l on-broken -d "With -j, handle a missing target" -x -a 'fail ancestor repair'
+complete -c mark -l link-style -d "How new targets are stored" -x -a 'absolute relative home'
+complete -c mark -l profile -d "Use the marks directory of this profile" -x
+complete -c mark -l shell -d "Use this shell instead of detecting it" -x -a 'bash zsh fish'
+complete -c mark -l sort -d "With -l, order by these keys" -x -a 'pinned frecency uses recent name target'
+complete -c mark -l pin -d "Pin a new bookmark"
+complete -c mark -l git-root -d "Bookmark the git repository root"
+complete -c mark -l suffix -d "Use name-2, name-3, ... when the name is taken"
 complete -c mark -s v -l version -d "Show version"
 complete -c mark -s h -l help -d "Show help"
 
-# Complete with existing bookmark names with paths for main argument
-complete -c mark -n '__fish_is_first_token' -a '(__fish_mark_list_bookmarks)'
+# Complete the main argument with creation candidates
+complete -c mark -n '__fish_is_first_token' -k -a '(__fish_mark_create_candidates)'
 
 # Complete with bookmark names and paths for -d and -j flags
-complete -c mark -n '__fish_seen_subcommand_from -d' -a '(__fish_mark_list_bookmarks)'
-complete -c mark -n '__fish_seen_subcommand_from -j' -a '(__fish_mark_list_bookmarks)'
+complete -c mark -n '__fish_seen_subcommand_from -d' -k -a '(__fish_mark_list_bookmarks)'
+complete -c mark -n '__fish_seen_subcommand_from -j' -k -a '(__fish_mark_list_bookmarks)'
+
+# Subcommands, and bookmark names for subcommands that take one
+complete -c mark -n '__fish_is_first_token' -a '@MARK_COMMANDS@'
+complete -c mark -n '__fish_seen_subcommand_from edit-dir exec explain open repair shell show ssh why-broken' -k -a '(__fish_mark_list_bookmarks)'
 
 # Alias completions with descriptions
-complete -c marks -f -a '(__fish_mark_list_bookmarks)'
-complete -c unmark -f -a '(__fish_mark_list_bookmarks)'
-complete -c jump -f -a '(__fish_mark_list_bookmarks)'
+complete -c marks -f -k -a '(__fish_mark_list_bookmarks)'
+complete -c unmark -f -k -a '(__fish_mark_list_bookmarks)'
+complete -c jump -f -k -a '(__fish_mark_list_bookmarks)'
+complete -c jump -f -a '(__fish_mark_subpaths)'
+complete -c mark -n '__fish_seen_subcommand_from -j exec' -f -a '(__fish_mark_subpaths)'
+`
+
+// generateFishRC generates unified fish RC content with aliases and/or completions
+func generateFishRC(markPath string, includeAliases, includeCompletions bool) string {
+	var features []string
+	if includeAliases {
+		features = append(features, "aliases")
+	}
+	if includeCompletions {
+		features = append(features, "completions")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# mark shell configuration\n")
+	sb.WriteString("# Generated by mark - do not edit manually\n")
+	sb.WriteString(fmt.Sprintf("# Features: %s\n", strings.Join(features, " ")))
+	sb.WriteString("\n")
+
+	if includeAliases {
+		sb.WriteString("# === ALIASES ===\n")
+		sb.WriteString(fmt.Sprintf("alias marks '%s -l'\n", markPath))
+		sb.WriteString(fmt.Sprintf("alias unmark '%s -d'\n", markPath))
+		hooks := integrationConfig()
+		sb.WriteString(fmt.Sprintf(`function jump
+    set -l target (%s --dir-fallback -j $argv)
+    if test $status -eq 0 -a -n "$target"
+%s        cd "$target"
+%s    end
+end
+`, markPath, hookLine(hooks.PreJump), hookLine(hooks.PostJump)))
+		sb.WriteString(fishMarkFunction(markPath, hooks))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(visitHook("fish", markPath))
+
+	if includeCompletions {
+		sb.WriteString("# === COMPLETIONS ===\n")
+		sb.WriteString(fishCompletionScript)
+	}
+
+	return withCommandNames(sb.String())
+}
+
+// generateLazyRC generates bash or zsh stubs that load the full integration
+// ('mark init <shell>') the first time mark, marks, unmark or jump is used
+// or completed, keeping shell startup to a few function definitions
+func generateLazyRC(shell, markPath string) (string, error) {
+	var sb strings.Builder
+
+	switch shell {
+	case "bash":
+		sb.WriteString("#!/bin/bash\n")
+	case "zsh":
+		sb.WriteString("#!/bin/zsh\n")
+	default:
+		return "", fmt.Errorf("lazy loading is not supported for %s", shell)
+	}
+	sb.WriteString("# mark shell configuration (lazy)\n")
+	sb.WriteString("# Generated by mark - do not edit manually\n")
+	sb.WriteString("# Features: aliases completions lazy\n")
+	sb.WriteString("\n")
+
+	unset := "unset -f"
+	if shell == "zsh" {
+		unset = "unfunction"
+	}
+	sb.WriteString(fmt.Sprintf(`# Replace these stubs with the full integration on first use
+__mark_lazy_load() {
+    %s __mark_lazy_load __mark_lazy_complete mark jump marks unmark 2>/dev/null
+    eval "$('%s' init %s)"
+}
+
+mark() { __mark_lazy_load; mark "$@"; }
+jump() { __mark_lazy_load; jump "$@"; }
+marks() { __mark_lazy_load; '%s' -l "$@"; }
+unmark() { __mark_lazy_load; '%s' -d "$@"; }
+
+`, unset, markPath, shell, markPath, markPath))
+
+	if shell == "bash" {
+		// Status 124 makes bash retry with the compspec the full script installs
+		sb.WriteString(`__mark_lazy_complete() {
+    __mark_lazy_load
+    return 124
+}
+
+complete -F __mark_lazy_complete mark marks unmark jump
+`)
+	} else {
+		sb.WriteString(`__mark_lazy_complete() {
+    __mark_lazy_load
+    _mark_complete "$@"
+}
+
+compdef __mark_lazy_complete mark marks unmark jump
 `)
 	}
 
+	// Visits are recorded from the first prompt, before anything is loaded
+	if hook := visitHook(shell, markPath); hook != "" {
+		sb.WriteString("\n" + hook)
+	}
+
+	return sb.String(), nil
+}
+
+// zshHashDirs returns 'hash -d name=path' lines for the bookmarks, making
+// each one a zsh named directory (~name). URL, !command and glob bookmarks,
+// whose directory is only known at jump time, and names zsh cannot use
+// after ~ are skipped.
+func zshHashDirs(config Config, bookmarks []Bookmark) string {
+	var sb strings.Builder
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) || marks.IsGlobTarget(bookmark.Target) || !isNamedDirName(bookmark.Name) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("hash -d %s=%s\n", bookmark.Name, shellQuote(marks.TargetPath(config, bookmark.Target))))
+	}
 	return sb.String()
 }
 
+// isNamedDirName reports whether name can follow ~ in zsh
+func isNamedDirName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// initHashDirs returns the named directory lines for 'mark init zsh
+// --hash-dirs'. init runs without the usual config loading, so the config
+// is read here; without one there are simply no bookmarks to hash.
+func initHashDirs(flags *ParsedFlags) string {
+	config, err := marks.LoadConfig()
+	if err != nil {
+		debugf("init --hash-dirs: %v", err)
+		return ""
+	}
+	if config, err = applyProfile(config, profileName(config, flags.Profile)); err != nil {
+		debugf("init --hash-dirs: %v", err)
+		return ""
+	}
+	config = marks.ApplyMarksDirEnv(config)
+
+	storage, err := marks.Open(config)
+	if err != nil {
+		debugf("init --hash-dirs: %v", err)
+		return ""
+	}
+	bookmarks, err := hostFiltered(config, storage).List()
+	if err != nil {
+		debugf("init --hash-dirs: %v", err)
+		return ""
+	}
+	return zshHashDirs(config, bookmarks)
+}
+
+// initCommand prints the shell integration for eval in a shell startup file
+// ('mark init [<shell>] [--lazy] [--hash-dirs]'); the shell defaults to
+// --shell or $SHELL. --hash-dirs (zsh) adds every bookmark as a named
+// directory, so ~name expands, shortens the prompt and completes natively.
+func initCommand(config Config, flags *ParsedFlags, args []string) {
+	shell := detectShell()
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		shell = args[0]
+		args = args[1:]
+	}
+	if shell == "" {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark init bash|zsh|fish [--lazy] [--hash-dirs]\n")
+		os.Exit(1)
+	}
+
+	lazy, hashDirs := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--lazy":
+			lazy = true
+		case "--hash-dirs":
+			hashDirs = true
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown init option: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+	if hashDirs && shell != "zsh" {
+		fmt.Fprintf(os.Stderr, "Error: --hash-dirs is only supported for zsh\n")
+		os.Exit(1)
+	}
+
+	markPath := getMarkPath()
+	var content string
+	if lazy {
+		var err error
+		content, err = generateLazyRC(shell, markPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		switch shell {
+		case "bash":
+			content = generateBashRC(markPath, true, true)
+		case "zsh":
+			content = generateZshRC(markPath, true, true)
+		case "fish":
+			content = generateFishRC(markPath, true, true)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unsupported shell: %s\n", shell)
+			os.Exit(1)
+		}
+	}
+	if hashDirs {
+		if lines := initHashDirs(flags); lines != "" {
+			content += "\n# === NAMED DIRECTORIES ===\n" + lines
+		}
+	}
+	fmt.Print(content)
+}
+
 // writeShellRC writes the unified RC file for the specified shell
 func writeShellRC(shell string, includeAliases, includeCompletions bool) error {
 	homeDir, err := os.UserHomeDir()
@@ -341,7 +837,7 @@ func writeShellRC(shell string, includeAliases, includeCompletions bool) error {
 		return fmt.Errorf("unsupported shell: %s", shell)
 	}
 
-	if err := os.WriteFile(rcPath, []byte(content), 0644); err != nil {
+	if err := marks.WriteFileAtomic(rcPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("error writing RC file: %w", err)
 	}
 
@@ -463,18 +959,14 @@ func getRCFilePath(shell string) string {
 }
 
 // SetupCompletion handles the interactive completion setup prompt
-func SetupCompletion(reader *bufio.Reader) {
+func SetupCompletion(prompter *setupPrompter) {
 	// Check if completion is already set up
 	if IsCompletionAlreadySetup() {
 		return
 	}
 
 	fmt.Println()
-	fmt.Print("Would you like to set up command line completion for mark? (y/N): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	if response != "y" && response != "yes" {
+	if !prompter.askYesNo("completion", "Would you like to set up command line completion for mark?") {
 		fmt.Println("Skipping completion setup. You can run 'mark --config' later to set it up.")
 		return
 	}
@@ -482,6 +974,7 @@ func SetupCompletion(reader *bufio.Reader) {
 	shell := detectShell()
 	if shell == "" {
 		fmt.Println("Could not detect shell type. Skipping completion setup.")
+		fmt.Println("Use 'mark --autocomplete --shell bash|zsh|fish' to choose one.")
 		return
 	}
 
@@ -494,6 +987,7 @@ func SetupCompletion(reader *bufio.Reader) {
 		SetupFishCompletion()
 	default:
 		fmt.Printf("Shell '%s' not supported for completion. Supported shells: bash, zsh, fish\n", shell)
+		fmt.Println("Use 'mark --autocomplete --shell <name>' for the shell family you run.")
 	}
 }
 
@@ -655,7 +1149,7 @@ func SetupFishCompletion() {
 
 // RunAutocompleteSetup handles the main autocomplete setup flow
 func RunAutocompleteSetup() {
-	reader := bufio.NewReader(os.Stdin)
+	prompter := newSetupPrompter()
 
 	fmt.Println("mark - Command Line Autocompletion Setup")
 	fmt.Println()
@@ -664,12 +1158,7 @@ func RunAutocompleteSetup() {
 	fmt.Println("• Tab-complete command flags")
 	fmt.Println("• Get context-aware completions")
 	fmt.Println()
-	fmt.Print("Would you like to set up autocompletion? (y/N): ")
-
-	response, _ := reader.ReadString('\n')
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	if response != "y" && response != "yes" {
+	if !prompter.askYesNo("completion", "Would you like to set up autocompletion?") {
 		fmt.Println("Autocompletion setup cancelled.")
 		return
 	}
@@ -677,7 +1166,7 @@ func RunAutocompleteSetup() {
 	shell := detectShell()
 	if shell == "" {
 		fmt.Println("Could not detect shell type. Skipping completion setup.")
-		fmt.Println("Supported shells: bash, zsh, fish")
+		fmt.Println("Supported shells: bash, zsh, fish. Use --shell <name> to choose one.")
 		return
 	}
 
@@ -699,6 +1188,7 @@ func RunAutocompleteSetup() {
 		SetupFishCompletion()
 	default:
 		fmt.Printf("Shell '%s' not supported for completion. Supported shells: bash, zsh, fish\n", shell)
+		fmt.Println("Use 'mark --autocomplete --shell <name>' for the shell family you run.")
 		return
 	}
 
@@ -918,8 +1408,12 @@ func cleanupShellConfigSourceLine(configFile string) {
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		// Skip "# mark shell integration" and the following source line
+		// Skip "# mark shell integration" and the following source line,
+		// along with the blank line ensureSourceLine put before them
 		if strings.Contains(line, sourceLineMarker) {
+			if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) == "" {
+				lines = lines[:n-1]
+			}
 			skipNext = true
 			continue
 		}
@@ -945,3 +1439,143 @@ func cleanupShellConfigSourceLine(configFile string) {
 		fmt.Fprintln(outFile, line)
 	}
 }
+
+// createCompletionCandidates suggests bookmark names for a new bookmark at dir:
+// the directory names along the path, nearest first, as the naming policy
+// turns them into names. Names the policy rejects and names that already
+// exist are skipped, since creation must not reuse them.
+func createCompletionCandidates(policy namePolicy, dir string, existing map[string]bool) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+
+	for dir != "" {
+		base := filepath.Base(dir)
+		if base == string(os.PathSeparator) || base == "." {
+			break
+		}
+		name, err := policy.normalize(base)
+		if err == nil && !existing[name] && !seen[name] {
+			candidates = append(candidates, name)
+		}
+		seen[name] = true
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return candidates
+}
+
+// printCreateCompletions prints new bookmark name candidates for the current
+// directory, one per line. It returns false when create_completion=dirs is
+// not configured so the shell scripts fall back to existing bookmark names.
+func printCreateCompletions() bool {
+	config, err := readConfig()
+	if err != nil || config.CreateCompletion != "dirs" {
+		return false
+	}
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	policy, err := parseNamePolicy(config.NamePolicy)
+	if err != nil {
+		return false
+	}
+
+	existing := make(map[string]bool)
+	if storage, err := marks.Open(config); err == nil {
+		bookmarks, _ := storage.List()
+		for _, bookmark := range bookmarks {
+			existing[bookmark.Name] = true
+		}
+	}
+
+	for _, name := range createCompletionCandidates(policy, currentDir, existing) {
+		fmt.Println(name)
+	}
+	return true
+}
+
+// subpathCompletions returns the subdirectories that complete word, written
+// as 'name/sub/': the part before the first slash is a bookmark and the rest
+// a partial path below its target. Hidden directories are only offered once
+// the typed component starts with a dot.
+func subpathCompletions(config Config, storage Storage, word string) []string {
+	name, rest, ok := strings.Cut(word, "/")
+	if !ok || !insideSubpath(rest) {
+		return nil
+	}
+	bookmark, err := storage.Get(name)
+	if err != nil {
+		return nil
+	}
+
+	dirPart, prefix := "", rest
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		dirPart, prefix = rest[:i+1], rest[i+1:]
+	}
+	dir := filepath.Join(marks.TargetPath(config, bookmark.Target), dirPart)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if !strings.HasPrefix(entryName, prefix) || (strings.HasPrefix(entryName, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		// Stat follows symlinks, so linked directories are offered too
+		if info, err := os.Stat(filepath.Join(dir, entryName)); err != nil || !info.IsDir() {
+			continue
+		}
+		candidates = append(candidates, name+"/"+dirPart+entryName+"/")
+	}
+	return candidates
+}
+
+// printSubpathCompletions prints the subdirectory candidates for word
+// ('jump proj/<TAB>'); errors simply yield no candidates
+func printSubpathCompletions(word string) {
+	config, err := readConfig()
+	if err != nil {
+		return
+	}
+	for _, candidate := range subpathCompletions(config, openLayeredStorage(config), word) {
+		fmt.Println(candidate)
+	}
+}
+
+// printCompletionChooser prints the configured full-screen chooser command.
+// It returns false when completion_chooser is unset or the command is not
+// installed, so the bash script keeps its plain double-Tab list.
+func printCompletionChooser() bool {
+	config, err := readConfig()
+	if err != nil {
+		return false
+	}
+
+	switch config.Chooser {
+	case "fzf", "fzf-tmux":
+	default:
+		return false
+	}
+	if _, err := exec.LookPath(config.Chooser); err != nil {
+		return false
+	}
+
+	fmt.Println(config.Chooser)
+	return true
+}
+
+// withCommandNames fills the subcommand placeholder in generated shell scripts
+func withCommandNames(script string) string {
+	return strings.ReplaceAll(script, "@MARK_COMMANDS@", strings.Join(subcommandNames(), " "))
+}
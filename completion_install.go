@@ -0,0 +1,165 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// completionShells are the shells 'mark completion install' writes files for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionFile returns the completion script for shell in the form the
+// shell loads on demand from its completion directories. Unlike the rc
+// file it holds no aliases or hooks, so it can be shared by all users.
+func completionFile(shell string) (string, error) {
+	header := "# Generated by mark - do not edit manually\n\n"
+	switch shell {
+	case "bash":
+		return withCommandNames("# bash completion for mark\n" + header + bashCompletionScript), nil
+	case "zsh":
+		// An autoloaded _mark file is the body of the _mark function
+		return withCommandNames("#compdef mark marks unmark jump\n" + header +
+			zshCompletionFunction + "\n_mark_complete \"$@\"\n"), nil
+	case "fish":
+		return withCommandNames("# fish completion for mark\n" + header + fishCompletionScript), nil
+	}
+	return "", fmt.Errorf("unsupported shell: %s", shell)
+}
+
+// completionInstallPath returns where the completion file for shell goes:
+// the vendor directories below prefix for a system install, or the
+// per-user directories each shell searches
+func completionInstallPath(shell, prefix string, system bool) string {
+	if system {
+		switch shell {
+		case "bash":
+			return filepath.Join(prefix, "share", "bash-completion", "completions", "mark")
+		case "zsh":
+			return filepath.Join(prefix, "share", "zsh", "site-functions", "_mark")
+		case "fish":
+			return filepath.Join(prefix, "share", "fish", "vendor_completions.d", "mark.fish")
+		}
+		return ""
+	}
+
+	switch shell {
+	case "bash":
+		return filepath.Join(xdgDataHome(), "bash-completion", "completions", "mark")
+	case "zsh":
+		return filepath.Join(xdgDataHome(), "zsh", "site-functions", "_mark")
+	case "fish":
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			homeDir, _ := os.UserHomeDir()
+			configHome = filepath.Join(homeDir, ".config")
+		}
+		return filepath.Join(configHome, "fish", "completions", "mark.fish")
+	}
+	return ""
+}
+
+// completionCommand prints a completion script ('mark completion <shell>')
+// or writes them to the standard completion directories
+// ('mark completion install --system|--user [--prefix <dir>] [<shell>...]').
+// $DESTDIR is prepended to system paths for staged package builds.
+func completionCommand(config Config, flags *ParsedFlags, args []string) {
+	usage := "Usage: mark completion bash|zsh|fish, or mark completion install --system|--user [--prefix <dir>] [<shell>...]"
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", usage)
+		os.Exit(1)
+	}
+
+	if args[0] != "install" {
+		content, err := completionFile(args[0])
+		if err != nil || len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", usage)
+			os.Exit(1)
+		}
+		fmt.Print(content)
+		return
+	}
+
+	system, user := false, false
+	prefix := "/usr"
+	var shells []string
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--system":
+			system = true
+		case "--user":
+			user = true
+		case "--prefix":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --prefix requires a directory\n")
+				os.Exit(1)
+			}
+			prefix = args[i+1]
+			i++
+		case "bash", "zsh", "fish":
+			shells = append(shells, args[i])
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown completion option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+	if system == user {
+		fmt.Fprintf(os.Stderr, "Error: Choose one of --system or --user\n")
+		os.Exit(1)
+	}
+	if len(shells) == 0 {
+		shells = completionShells
+	}
+	if system {
+		prefix = filepath.Join(os.Getenv("DESTDIR"), prefix)
+	}
+
+	failed := false
+	for _, shell := range shells {
+		path := completionInstallPath(shell, prefix, system)
+		if flags.DryRun {
+			fmt.Printf("Would install %s completion: %s\n", shell, path)
+			continue
+		}
+
+		content, _ := completionFile(shell)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = marks.WriteFileAtomic(path, []byte(content), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error installing %s completion: %v\n", shell, err)
+			if system && os.IsPermission(err) {
+				fmt.Fprintf(os.Stderr, "  System directories usually need root (sudo), or pass --prefix/DESTDIR\n")
+			}
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Installed %s completion: %s\n", shell, path)
+		if shell == "zsh" && user {
+			fmt.Printf("  Add %s to fpath before compinit in ~/.zshrc\n", filepath.Dir(path))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// debugEnabled turns on tracing, set by --verbose or MARK_DEBUG=1
+var debugEnabled bool
+
+func init() {
+	// The library traces path expansion, file writes and storage commands
+	marks.Tracef = debugf
+}
+
+// debugFromEnv reports whether $MARK_DEBUG asks for tracing
+func debugFromEnv(getenv func(string) string) bool {
+	on, _ := parseYesNo(getenv("MARK_DEBUG"))
+	return on
+}
+
+// debugf writes one trace line to stderr when tracing is on: how the config
+// was resolved, how paths were expanded and evaluated, and which files were
+// written. Traces never go to stdout, which the jump function and
+// completion capture.
+func debugf(format string, args ...any) {
+	if debugEnabled {
+		fmt.Fprintf(os.Stderr, "mark: "+format+"\n", args...)
+	}
+}
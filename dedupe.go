@@ -0,0 +1,147 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// duplicateGroup is a set of bookmarks whose targets resolve to one directory
+type duplicateGroup struct {
+	Path  string
+	Names []string
+}
+
+// isAliasTarget reports whether a stored target is the name of another
+// bookmark in the marks directory, as left by 'mark dedupe' for aliases
+func isAliasTarget(target string, names map[string]bool) bool {
+	return !strings.ContainsAny(target, `/\`) && names[target]
+}
+
+// findDuplicates groups bookmarks by the real path their target resolves to,
+// returning the groups of two or more sorted by path. URL, broken and alias
+// bookmarks are left out.
+func findDuplicates(config Config, bookmarks []Bookmark) []duplicateGroup {
+	names := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		names[bookmark.Name] = true
+	}
+
+	byPath := make(map[string][]string)
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) || marks.IsGlobTarget(bookmark.Target) || isAliasTarget(bookmark.Target, names) {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(marks.TargetPath(config, bookmark.Target))
+		if err != nil {
+			continue
+		}
+		byPath[resolved] = append(byPath[resolved], bookmark.Name)
+	}
+
+	var groups []duplicateGroup
+	for path, group := range byPath {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Strings(group)
+		groups = append(groups, duplicateGroup{Path: path, Names: group})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Path < groups[j].Path })
+	return groups
+}
+
+// dedupeCommand finds bookmarks pointing at the same directory and, for each
+// group, keeps the chosen name and removes the others or turns them into
+// aliases of it ('mark dedupe'). With --dry-run the groups are only listed.
+func dedupeCommand(config Config, flags *ParsedFlags, args []string) {
+	storage := hostFiltered(config, openStorage(config))
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	groups := findDuplicates(config, bookmarks)
+	if len(groups) == 0 {
+		fmt.Println("No duplicate bookmarks found.")
+		return
+	}
+
+	events, err := loadUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	usage := summarizeUsage(events)
+
+	// Aliases are bookmarks linking to another bookmark, which only the
+	// symlink storage can express
+	canAlias := config.Storage == "" || config.Storage == "symlink"
+
+	reader := bufio.NewReader(os.Stdin)
+	for _, group := range groups {
+		fmt.Printf("%s is bookmarked as:\n", group.Path)
+		for i, name := range group.Names {
+			fmt.Printf("  %d) %-20s %d uses\n", i+1, name, usage[name].Count)
+		}
+		if flags.DryRun {
+			continue
+		}
+
+		fmt.Printf("Keep which name [1-%d, empty to skip]: ", len(group.Names))
+		response, _ := reader.ReadString('\n')
+		choice, err := strconv.Atoi(strings.TrimSpace(response))
+		if err != nil || choice < 1 || choice > len(group.Names) {
+			fmt.Println("Skipped.")
+			continue
+		}
+		keep := group.Names[choice-1]
+
+		alias := false
+		if canAlias {
+			fmt.Printf("Keep the other names as aliases of '%s'? (y/N): ", keep)
+			response, _ = reader.ReadString('\n')
+			alias, _ = parseYesNo(cleanResponse(response))
+		}
+		for _, name := range group.Names {
+			if name == keep {
+				continue
+			}
+			if !alias {
+				deleteBookmark(config, name, true, false)
+				continue
+			}
+			if err := storage.Delete(name); err != nil {
+				exitBookmarkError(name, err)
+			}
+			if err := storage.Create(name, keep); err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating alias '%s': %v\n", name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Bookmark '%s' is now an alias of '%s'\n", name, keep)
+		}
+	}
+}
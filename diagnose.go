@@ -0,0 +1,210 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// Mount tables consulted when a target lives on a missing filesystem
+var (
+	fstabPath  = "/etc/fstab"
+	mountsPath = "/proc/mounts"
+)
+
+// targetDiagnosis explains why a bookmark target cannot be reached
+type targetDiagnosis struct {
+	Existing   string // deepest ancestor that exists
+	Missing    string // first path component that does not exist, if any
+	Blocked    string // first component that could not be inspected, if any
+	Dangling   bool   // Missing is a symlink whose own target is gone
+	NotDir     string // a component that exists but is not a directory
+	Err        error  // error returned for Blocked
+	Unmounted  string // fstab mount point covering the target that is not mounted
+	Reachable  bool   // the target exists and is a directory
+	TargetFile bool   // the target exists but is not a directory
+}
+
+// diagnoseTarget walks path one component at a time to find where it breaks
+func diagnoseTarget(path string) targetDiagnosis {
+	var d targetDiagnosis
+	path = filepath.Clean(path)
+
+	if info, err := os.Stat(path); err == nil {
+		d.Reachable = info.IsDir()
+		d.TargetFile = !info.IsDir()
+		d.Existing = path
+		return d
+	}
+
+	current := filepath.VolumeName(path) + string(os.PathSeparator)
+	d.Existing = current
+	rest := strings.TrimPrefix(path, current)
+	for _, part := range strings.Split(rest, string(os.PathSeparator)) {
+		if part == "" {
+			continue
+		}
+		next := filepath.Join(current, part)
+
+		linfo, err := os.Lstat(next)
+		if err != nil {
+			if os.IsNotExist(err) {
+				d.Missing = next
+			} else {
+				d.Blocked = next
+				d.Err = err
+			}
+			break
+		}
+
+		info, err := os.Stat(next)
+		if err != nil {
+			if os.IsNotExist(err) && linfo.Mode()&os.ModeSymlink != 0 {
+				d.Missing = next
+				d.Dangling = true
+			} else {
+				d.Blocked = next
+				d.Err = err
+			}
+			break
+		}
+		if !info.IsDir() {
+			d.NotDir = next
+			break
+		}
+
+		current = next
+		d.Existing = current
+	}
+
+	d.Unmounted = unmountedMountPoint(path, readMountPoints(fstabPath), readMountPoints(mountsPath))
+	return d
+}
+
+// readMountPoints returns the mount point column of an fstab-style file.
+// Unreadable files yield no entries.
+func readMountPoints(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		points = append(points, fields[1])
+	}
+	return points
+}
+
+// unmountedMountPoint returns the most specific configured mount point that
+// contains path but is not currently mounted, or "" if there is none
+func unmountedMountPoint(path string, configured, mounted []string) string {
+	isMounted := make(map[string]bool)
+	for _, point := range mounted {
+		isMounted[point] = true
+	}
+
+	best := ""
+	for _, point := range configured {
+		if point == "/" || point == "none" || isMounted[point] {
+			continue
+		}
+		if path != point && !strings.HasPrefix(path, point+string(os.PathSeparator)) {
+			continue
+		}
+		if len(point) > len(best) {
+			best = point
+		}
+	}
+	return best
+}
+
+// lastKnownGood returns the last time a jump to the bookmark succeeded
+func lastKnownGood(name string) time.Time {
+	events, err := loadUsage()
+	if err != nil {
+		return time.Time{}
+	}
+	return summarizeUsage(events)[name].LastUsed
+}
+
+// whyBrokenCommand reports exactly why a bookmark target cannot be reached
+// ('mark why-broken <name>')
+func whyBrokenCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for why-broken\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	bookmark, source := findBookmark(config, name)
+	targetPath := marks.TargetPath(config, bookmark.Target)
+	if marks.IsURLTarget(targetPath) {
+		fmt.Printf("Bookmark '%s' is a URL (%s); mark does not check web addresses\n", name, targetPath)
+		return
+	}
+	if marks.IsCommandTarget(targetPath) {
+		fmt.Printf("Bookmark '%s' runs a command (%s); jump to it with MARK_DEBUG=1 to see what it prints\n", name, strings.TrimPrefix(targetPath, "!"))
+		return
+	}
+
+	d := diagnoseTarget(targetPath)
+	if d.Reachable {
+		fmt.Printf("Bookmark '%s' is not broken: %s exists\n", name, targetPath)
+		return
+	}
+
+	fmt.Printf("Bookmark:   %s\n", name)
+	fmt.Printf("Source:     %s\n", source.Origin)
+	fmt.Printf("Target:     %s\n", targetPath)
+
+	switch {
+	case d.TargetFile:
+		fmt.Println("Problem:    target exists but is not a directory")
+	case d.NotDir != "":
+		fmt.Printf("Problem:    %s is a file, not a directory\n", d.NotDir)
+	case d.Blocked != "" && os.IsPermission(d.Err):
+		fmt.Printf("Problem:    permission denied while checking %s\n", d.Blocked)
+		fmt.Printf("            (the path may exist; %s is not accessible)\n", d.Existing)
+	case d.Blocked != "":
+		fmt.Printf("Problem:    cannot check %s: %v\n", d.Blocked, d.Err)
+	case d.Dangling:
+		fmt.Printf("Problem:    %s is a symlink to a path that does not exist\n", d.Missing)
+	default:
+		fmt.Printf("Problem:    %s does not exist\n", d.Missing)
+		fmt.Printf("Exists:     %s\n", d.Existing)
+	}
+
+	if d.Unmounted != "" {
+		fmt.Printf("Mount:      %s is listed in %s but not mounted\n", d.Unmounted, fstabPath)
+	}
+
+	fmt.Printf("Last good:  %s\n", formatLastUsed(lastKnownGood(name)))
+}
@@ -0,0 +1,246 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// manifestDiff is the difference between the local bookmarks and a manifest
+type manifestDiff struct {
+	Added   []exportRecord    // only in the manifest
+	Removed []exportRecord    // only in the local set
+	Drifted [][2]exportRecord // same name, different target (local, manifest)
+	Changed [][2]exportRecord // same name and target, different tags, description, host, fallbacks, OS targets or hosts it is only on
+}
+
+// empty reports whether both sets have the same names, targets and details
+func (d manifestDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Drifted) == 0 && len(d.Changed) == 0
+}
+
+// detailChanges describes how the tags, description, host, fallback and
+// per-OS targets and only_on patterns of two records of one bookmark differ,
+// local value first; tag and pattern order is ignored, fallback order is not
+func detailChanges(ours, theirs exportRecord) []string {
+	var changes []string
+	ourTags, theirTags := slices.Sorted(slices.Values(ours.Tags)), slices.Sorted(slices.Values(theirs.Tags))
+	if !slices.Equal(ourTags, theirTags) {
+		changes = append(changes, fmt.Sprintf("tags %s -> %s", orDash(strings.Join(ourTags, ",")), orDash(strings.Join(theirTags, ","))))
+	}
+	if ours.Description != theirs.Description {
+		changes = append(changes, fmt.Sprintf("description %q -> %q", ours.Description, theirs.Description))
+	}
+	if ours.Host != theirs.Host {
+		changes = append(changes, fmt.Sprintf("host %s -> %s", orDash(ours.Host), orDash(theirs.Host)))
+	}
+	if !slices.Equal(ours.Fallbacks, theirs.Fallbacks) {
+		changes = append(changes, fmt.Sprintf("fallbacks %s -> %s", orDash(strings.Join(ours.Fallbacks, ",")), orDash(strings.Join(theirs.Fallbacks, ","))))
+	}
+	if ourOS, theirOS := osTargetPairs(ours.OSTargets), osTargetPairs(theirs.OSTargets); !slices.Equal(ourOS, theirOS) {
+		changes = append(changes, fmt.Sprintf("os targets %s -> %s", orDash(strings.Join(ourOS, ",")), orDash(strings.Join(theirOS, ","))))
+	}
+	ourHosts, theirHosts := slices.Sorted(slices.Values(ours.OnlyOn)), slices.Sorted(slices.Values(theirs.OnlyOn))
+	if !slices.Equal(ourHosts, theirHosts) {
+		changes = append(changes, fmt.Sprintf("only on %s -> %s", orDash(strings.Join(ourHosts, ",")), orDash(strings.Join(theirHosts, ","))))
+	}
+	return changes
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// loadDiffSource reads the bookmarks to compare with: a marks directory
+// (a synced copy, a backup, a checkout of a remote) or a 'mark export' file
+func loadDiffSource(path string) ([]exportRecord, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return collectExportRecords(Config{MarksDir: path}, &marks.SymlinkStorage{Dir: path})
+	}
+	return loadManifest(path)
+}
+
+// loadManifest reads a file written by 'mark export'. CSV is recognised by
+// its extension; anything else is read as JSON.
+func loadManifest(path string) ([]exportRecord, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return decodeManifestCSV(content)
+	}
+	var records []exportRecord
+	if err := json.Unmarshal(content, &records); err != nil {
+		return nil, fmt.Errorf("error parsing manifest %s (expected JSON or .csv from 'mark export'): %w", path, err)
+	}
+	return records, nil
+}
+
+// decodeManifestCSV reads the columns written by encodeExportCSV, using the
+// header row to locate them
+func decodeManifestCSV(content []byte) ([]exportRecord, error) {
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CSV manifest: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	column := map[string]int{}
+	for i, heading := range rows[0] {
+		column[heading] = i
+	}
+	if _, ok := column["name"]; !ok {
+		return nil, fmt.Errorf("error parsing CSV manifest: missing name column")
+	}
+	if _, ok := column["target"]; !ok {
+		return nil, fmt.Errorf("error parsing CSV manifest: missing target column")
+	}
+	field := func(row []string, heading string) string {
+		if i, ok := column[heading]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var records []exportRecord
+	for _, row := range rows[1:] {
+		record := exportRecord{
+			Name:        field(row, "name"),
+			Target:      field(row, "target"),
+			Description: field(row, "description"),
+			Host:        field(row, "host"),
+		}
+		if tags := field(row, "tags"); tags != "" {
+			record.Tags = strings.Split(tags, ",")
+		}
+		if fallbacks := field(row, "fallbacks"); fallbacks != "" {
+			record.Fallbacks = strings.Split(fallbacks, ",")
+		}
+		if pairs := field(row, "os_targets"); pairs != "" {
+			record.OSTargets = make(map[string]string)
+			for _, pair := range strings.Split(pairs, ",") {
+				goos, target, _ := strings.Cut(pair, "=")
+				record.OSTargets[goos] = target
+			}
+		}
+		if patterns := field(row, "only_on"); patterns != "" {
+			record.OnlyOn = strings.Split(patterns, ",")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// diffRecords compares local bookmarks against a manifest by name; each list
+// in the result is sorted by name
+func diffRecords(local, manifest []exportRecord) manifestDiff {
+	localByName := map[string]exportRecord{}
+	for _, r := range local {
+		localByName[r.Name] = r
+	}
+	manifestByName := map[string]exportRecord{}
+	for _, r := range manifest {
+		manifestByName[r.Name] = r
+	}
+
+	var diff manifestDiff
+	for name, theirs := range manifestByName {
+		ours, ok := localByName[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, theirs)
+		case normalizeTarget(ours.Target) != normalizeTarget(theirs.Target):
+			diff.Drifted = append(diff.Drifted, [2]exportRecord{ours, theirs})
+		case len(detailChanges(ours, theirs)) > 0:
+			diff.Changed = append(diff.Changed, [2]exportRecord{ours, theirs})
+		}
+	}
+	for name, ours := range localByName {
+		if _, ok := manifestByName[name]; !ok {
+			diff.Removed = append(diff.Removed, ours)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Name < diff.Added[j].Name })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Name < diff.Removed[j].Name })
+	sort.Slice(diff.Drifted, func(i, j int) bool { return diff.Drifted[i][0].Name < diff.Drifted[j][0].Name })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i][0].Name < diff.Changed[j][0].Name })
+	return diff
+}
+
+// diffCommand compares the local bookmarks with an exported manifest or
+// another marks directory ('mark diff <manifest|marks-dir>'). Like diff(1)
+// it exits 1 when the sets differ; failing to compare them exits with
+// exitTrouble.
+func diffCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark diff <manifest|marks-dir>\n")
+		os.Exit(exitTrouble)
+	}
+
+	manifest, err := loadDiffSource(marks.ExpandPath(args[0]))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitTrouble)
+	}
+	local, err := collectExportRecords(config, openStorage(config))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitTrouble)
+	}
+
+	diff := diffRecords(local, manifest)
+	for _, r := range diff.Added {
+		fmt.Printf("+ %-20s %s\n", r.Name, r.Target)
+	}
+	for _, r := range diff.Removed {
+		fmt.Printf("- %-20s %s\n", r.Name, r.Target)
+	}
+	for _, pair := range diff.Drifted {
+		fmt.Printf("~ %-20s %s -> %s\n", pair[0].Name, pair[0].Target, pair[1].Target)
+	}
+	for _, pair := range diff.Changed {
+		fmt.Printf("* %-20s %s\n", pair[0].Name, strings.Join(detailChanges(pair[0], pair[1]), "; "))
+	}
+
+	if diff.empty() {
+		fmt.Println("✓ Bookmarks match the manifest")
+		return
+	}
+	fmt.Printf("%d only in manifest, %d only here, %d with different targets, %d with different details\n",
+		len(diff.Added), len(diff.Removed), len(diff.Drifted), len(diff.Changed))
+	os.Exit(1)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// defaultDynamicTimeout is how long a !command target may run unless
+// dynamic_timeout says otherwise
+const defaultDynamicTimeout = 5 * time.Second
+
+// parseDynamicTimeout validates a dynamic_timeout value in seconds; empty
+// means the default
+func parseDynamicTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultDynamicTimeout, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid dynamic_timeout '%s' (expected a number of seconds)", value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// targetCommandLine returns the shell invocation that runs command
+func targetCommandLine(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+	return []string{"sh", "-c", command}
+}
+
+// commandOutputDir returns the directory a target command printed: its
+// first non-empty line, with ~ expanded. It must be absolute, since the
+// command's working directory is not the user's.
+func commandOutputDir(output []byte) (string, error) {
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		dir := marks.ExpandHome(line)
+		if !filepath.IsAbs(dir) {
+			return "", fmt.Errorf("command printed a relative path: %s", line)
+		}
+		return filepath.Clean(dir), nil
+	}
+	return "", errors.New("command printed nothing")
+}
+
+// runTargetCommand runs the command of a !command target with a timeout and
+// returns the directory it printed
+func runTargetCommand(command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	line := targetCommandLine(command)
+	cmd := exec.CommandContext(ctx, line[0], line[1:]...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	return commandOutputDir(stdout.Bytes())
+}
+
+// commandJumpTarget evaluates a !command bookmark for -j, exiting with an
+// error unless dynamic_targets=on allows running it and it prints a path
+func commandJumpTarget(config Config, name, target string) string {
+	command := strings.TrimSpace(strings.TrimPrefix(target, "!"))
+	if config.DynamicTargets != "on" {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' runs a command (%s); set dynamic_targets=on in ~/.mark to allow it\n", name, command)
+		os.Exit(exitConfig)
+	}
+	timeout, err := parseDynamicTimeout(config.DynamicTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	debugf("bookmark %s: running %q (timeout %s)", name, command, timeout)
+	dir, err := runTargetCommand(command, timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s': %v\n", name, err)
+		os.Exit(exitBroken)
+	}
+	debugf("bookmark %s: command printed %s", name, dir)
+	return dir
+}
@@ -0,0 +1,62 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editorCommandLine returns the editor to launch from $VISUAL or $EDITOR,
+// split into program and arguments (e.g. "code --new-window")
+func editorCommandLine(getenv func(string) string) []string {
+	for _, variable := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(getenv(variable)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return nil
+}
+
+// editDirCommand opens a bookmark's directory as the editor's workspace
+// ('mark edit-dir <name>')
+func editDirCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for edit-dir\n")
+		os.Exit(1)
+	}
+	name := args[0]
+
+	editor := editorCommandLine(os.Getenv)
+	if editor == nil {
+		fmt.Fprintf(os.Stderr, "Error: Set $VISUAL or $EDITOR to the editor to open (e.g. export VISUAL=code)\n")
+		os.Exit(1)
+	}
+
+	targetPath := resolveJumpTarget(config, name, flags.Raw)
+	if strings.Contains(name, "/") {
+		name, _ = splitSubpath(openLayeredStorage(config), name)
+	}
+	_ = recordUsage(name, targetPath)
+
+	cmd := exec.Command(editor[0], append(editor[1:], targetPath)...)
+	cmd.Dir = targetPath
+	runInteractive(cmd)
+}
@@ -0,0 +1,296 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// exportRecord is one bookmark with its metadata as written by 'mark export'
+type exportRecord struct {
+	Name        string            `json:"name"`
+	Target      string            `json:"target"`
+	Tags        []string          `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Host        string            `json:"host,omitempty"`
+	Fallbacks   []string          `json:"fallbacks,omitempty"`
+	OSTargets   map[string]string `json:"os_targets,omitempty"`
+	OnlyOn      []string          `json:"only_on,omitempty"`
+}
+
+// exportFormats maps format names to their encoders
+var exportFormats = map[string]func([]exportRecord) ([]byte, error){
+	"csv":    encodeExportCSV,
+	"json":   encodeExportJSON,
+	"script": encodeExportScript,
+	"toml":   encodeExportTOML,
+}
+
+// collectExportRecords gathers every bookmark with its metadata, sorted by
+// name. Targets are exported as stored, so $VAR and glob bookmarks stay
+// portable; only targets relative to the marks directory are made absolute.
+// Bookmarks restricted to other hosts are included, so one exported set can
+// carry every host's bookmarks.
+func collectExportRecords(config Config, storage Storage) ([]exportRecord, error) {
+	bookmarks, err := storage.List()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []exportRecord{}
+	for _, bookmark := range bookmarks {
+		record := exportRecord{Name: bookmark.Name, Target: portableTarget(config.MarksDir, bookmark.Target)}
+		if m := meta[bookmark.Name]; m != nil {
+			record.Tags = m.Tags
+			record.Description = m.Description
+			record.Host = m.Host
+			record.Fallbacks = m.Fallbacks
+			record.OSTargets = m.OSTargets
+			record.OnlyOn = m.OnlyOn
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+	return records, nil
+}
+
+func encodeExportJSON(records []exportRecord) ([]byte, error) {
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// encodeExportCSV writes a header row and one row per bookmark; tags,
+// fallback targets, os=path targets and host patterns are joined with commas
+// inside their field
+func encodeExportCSV(records []exportRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"name", "target", "tags", "description", "host", "fallbacks", "os_targets", "only_on"})
+	for _, r := range records {
+		w.Write([]string{r.Name, r.Target, strings.Join(r.Tags, ","), r.Description, r.Host, strings.Join(r.Fallbacks, ","), strings.Join(osTargetPairs(r.OSTargets), ","), strings.Join(r.OnlyOn, ",")})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// encodeExportTOML writes an array of [[bookmark]] tables
+func encodeExportTOML(records []exportRecord) ([]byte, error) {
+	var sb strings.Builder
+	for i, r := range records {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[[bookmark]]\n")
+		fmt.Fprintf(&sb, "name = %s\n", tomlString(r.Name))
+		fmt.Fprintf(&sb, "target = %s\n", tomlString(r.Target))
+		if len(r.Tags) > 0 {
+			fmt.Fprintf(&sb, "tags = %s\n", tomlStrings(r.Tags))
+		}
+		if r.Description != "" {
+			fmt.Fprintf(&sb, "description = %s\n", tomlString(r.Description))
+		}
+		if r.Host != "" {
+			fmt.Fprintf(&sb, "host = %s\n", tomlString(r.Host))
+		}
+		if len(r.Fallbacks) > 0 {
+			fmt.Fprintf(&sb, "fallbacks = %s\n", tomlStrings(r.Fallbacks))
+		}
+		if len(r.OSTargets) > 0 {
+			var pairs []string
+			for _, goos := range slices.Sorted(maps.Keys(r.OSTargets)) {
+				pairs = append(pairs, goos+" = "+tomlString(r.OSTargets[goos]))
+			}
+			fmt.Fprintf(&sb, "os_targets = { %s }\n", strings.Join(pairs, ", "))
+		}
+		if len(r.OnlyOn) > 0 {
+			fmt.Fprintf(&sb, "only_on = %s\n", tomlStrings(r.OnlyOn))
+		}
+	}
+	return []byte(sb.String()), nil
+}
+
+// encodeExportScript writes a POSIX shell script of 'mark <name> <path>'
+// commands that re-creates the bookmarks, metadata included, when run
+func encodeExportScript(records []exportRecord) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString("# Bookmarks exported by 'mark export --format script'\n")
+	for _, r := range records {
+		sb.WriteString("mark")
+		if len(r.Tags) > 0 {
+			sb.WriteString(" --tag " + shellQuote(strings.Join(r.Tags, ",")))
+		}
+		if r.Description != "" {
+			sb.WriteString(" --desc " + shellQuote(r.Description))
+		}
+		if r.Host != "" {
+			sb.WriteString(" --host " + shellQuote(r.Host))
+		}
+		if len(r.OnlyOn) > 0 {
+			sb.WriteString(" --only-on " + shellQuote(strings.Join(r.OnlyOn, ",")))
+		}
+		// Names that look like commands or flags need the end-of-flags marker
+		if _, ok := subcommands[r.Name]; ok || strings.HasPrefix(r.Name, "-") {
+			sb.WriteString(" --")
+		}
+		fmt.Fprintf(&sb, " %s %s\n", shellQuote(r.Name), shellQuote(r.Target))
+		if len(r.Fallbacks) > 0 {
+			sb.WriteString("mark fallback " + shellQuote(r.Name))
+			for _, fallback := range r.Fallbacks {
+				sb.WriteString(" " + shellQuote(fallback))
+			}
+			sb.WriteString("\n")
+		}
+		if len(r.OSTargets) > 0 {
+			sb.WriteString("mark os-target " + shellQuote(r.Name))
+			for _, pair := range osTargetPairs(r.OSTargets) {
+				sb.WriteString(" " + shellQuote(pair))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return []byte(sb.String()), nil
+}
+
+// osTargetPairs returns per-OS targets as 'os=path' arguments, sorted by OS
+func osTargetPairs(targets map[string]string) []string {
+	var pairs []string
+	for _, goos := range slices.Sorted(maps.Keys(targets)) {
+		pairs = append(pairs, goos+"="+targets[goos])
+	}
+	return pairs
+}
+
+// tomlStrings formats values as a TOML array of strings
+func tomlStrings(values []string) string {
+	var quoted []string
+	for _, value := range values {
+		quoted = append(quoted, tomlString(value))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// tomlString quotes s as a TOML basic string
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// exportCommand writes all bookmarks with their metadata to stdout or a file
+// ('mark export --format json|csv|toml|script [file]')
+func exportCommand(config Config, flags *ParsedFlags, args []string) {
+	format := "json"
+	file := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --format requires a value\n")
+				os.Exit(1)
+			}
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--"):
+			fmt.Fprintf(os.Stderr, "Error: Unknown export option: %s\n", args[i])
+			os.Exit(1)
+		case file == "":
+			file = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Only one output file can be given\n")
+			os.Exit(1)
+		}
+	}
+
+	encode, ok := exportFormats[format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: Unknown export format '%s' (supported: %s)\n", format, strings.Join(exportFormatNames(), ", "))
+		os.Exit(1)
+	}
+
+	records, err := collectExportRecords(config, openStorage(config))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	content, err := encode(records)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	if file == "" {
+		os.Stdout.Write(content)
+		return
+	}
+	perm := os.FileMode(0644)
+	if format == "script" {
+		perm = 0755
+	}
+	if err := marks.WriteFileAtomic(marks.ExpandPath(file), content, perm); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Exported %d bookmark(s) to %s\n", len(records), file)
+}
+
+// exportFormatNames returns the supported export formats in alphabetical order
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,135 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// normalizeFallback returns the form a fallback target is stored in: ~/
+// paths stay relative to each machine's home directory, anything else
+// becomes absolute. Fallbacks need not exist yet; symlinks are not
+// resolved, since the mount they point into may be missing.
+func normalizeFallback(arg string) (string, error) {
+	path := normalizeTargetArg(arg)
+	if marks.IsURLTarget(path) {
+		return "", fmt.Errorf("fallback target must be a directory, not a URL: %s", arg)
+	}
+	if marks.IsHomeTarget(path) {
+		return filepath.ToSlash(filepath.Clean(path)), nil
+	}
+	abs, err := filepath.Abs(marks.ExpandHome(path))
+	if err != nil {
+		return "", err
+	}
+	return normalizeTarget(abs), nil
+}
+
+// parseFallbacks normalizes the fallback targets given on the command line
+func parseFallbacks(args []string) ([]string, error) {
+	var fallbacks []string
+	for _, arg := range args {
+		fallback, err := normalizeFallback(arg)
+		if err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+	return fallbacks, nil
+}
+
+// bookmarkMetadata returns the metadata stored for a personal bookmark, or
+// nil when there is none or it cannot be read
+func bookmarkMetadata(config Config, name string) *Metadata {
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		debugf("metadata: %v", err)
+		return nil
+	}
+	return meta[name]
+}
+
+// fallbackCommand shows or replaces the fallback targets -j tries in order
+// when a bookmark's own target is missing ('mark fallback <name>
+// [<path>...|--clear]')
+func fallbackCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark fallback <name> [<path>...|--clear]\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	bookmark := lookupBookmark(openStorage(config), name)
+	if marks.IsURLTarget(bookmark.Target) {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' is a URL; fallback targets need a directory bookmark\n", name)
+		os.Exit(1)
+	}
+
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m := Metadata{}
+	if meta[name] != nil {
+		m = *meta[name]
+	}
+
+	if len(args) == 1 {
+		if len(m.Fallbacks) == 0 {
+			fmt.Printf("Bookmark '%s' has no fallback targets\n", name)
+			return
+		}
+		fmt.Printf("  1. %s\n", bookmark.Target)
+		for i, fallback := range m.Fallbacks {
+			status := ""
+			if info, err := os.Stat(marks.TargetPath(config, fallback)); err != nil || !info.IsDir() {
+				status = " (missing)"
+			}
+			fmt.Printf("  %d. %s%s\n", i+2, fallback, status)
+		}
+		return
+	}
+
+	if len(args) == 2 && args[1] == "--clear" {
+		m.Fallbacks = nil
+	} else {
+		if m.Fallbacks, err = parseFallbacks(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if marks.IsEmptyMetadata(&m) {
+		err = marks.UpdateMetadata(config.MarksDir, name, nil)
+	} else {
+		err = marks.UpdateMetadata(config.MarksDir, name, &m)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(m.Fallbacks) == 0 {
+		fmt.Printf("✓ Cleared fallback targets of '%s'\n", name)
+		return
+	}
+	fmt.Printf("✓ Bookmark '%s' falls back to %d target(s) when %s is missing\n", name, len(m.Fallbacks), bookmark.Target)
+}
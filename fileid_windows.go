@@ -0,0 +1,28 @@
+//go:build windows
+
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import "os"
+
+// fileInfoID would identify a directory across renames; Windows file IDs
+// are not exposed by os.FileInfo, so repairs fall back to matching names
+func fileInfoID(info os.FileInfo) string {
+	return ""
+}
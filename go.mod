@@ -1,3 +1,3 @@
-module mark
+module github.com/brockers/mark
 
 go 1.24.11
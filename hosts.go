@@ -0,0 +1,178 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// currentHostname returns the name bookmarks' only_on patterns are matched
+// against: $MARK_HOSTNAME when set, otherwise the system hostname
+func currentHostname() string {
+	if name := os.Getenv("MARK_HOSTNAME"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// hostMatches reports whether hostname matches one of the glob patterns,
+// ignoring case. A pattern without a dot also matches the short name of a
+// fully qualified hostname.
+func hostMatches(patterns []string, hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	short, _, _ := strings.Cut(hostname, ".")
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return true
+		}
+		if !strings.Contains(pattern, ".") {
+			if ok, _ := path.Match(pattern, short); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inactiveHere returns the bookmarks whose only_on patterns exclude
+// hostname
+func inactiveHere(meta map[string]*Metadata, hostname string) map[string]bool {
+	hidden := make(map[string]bool)
+	for name, m := range meta {
+		if m != nil && len(m.OnlyOn) > 0 && !hostMatches(m.OnlyOn, hostname) {
+			hidden[name] = true
+		}
+	}
+	return hidden
+}
+
+// hostFilteredStorage hides the bookmarks that are not active on this host
+// from reads; it is only used as a read source, so such bookmarks can still
+// be managed by name through the plain storage
+type hostFilteredStorage struct {
+	Storage
+	hidden map[string]bool
+}
+
+func (s *hostFilteredStorage) List() ([]Bookmark, error) {
+	bookmarks, err := s.Storage.List()
+	if err != nil {
+		return nil, err
+	}
+	var active []Bookmark
+	for _, bookmark := range bookmarks {
+		if !s.hidden[bookmark.Name] {
+			active = append(active, bookmark)
+		}
+	}
+	return active, nil
+}
+
+func (s *hostFilteredStorage) Get(name string) (Bookmark, error) {
+	if s.hidden[name] {
+		return Bookmark{}, marks.ErrNotFound
+	}
+	return s.Storage.Get(name)
+}
+
+// hostFiltered wraps the personal storage so bookmarks restricted to other
+// hosts are left out; it returns storage itself when nothing is restricted
+func hostFiltered(config Config, storage Storage) Storage {
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		debugf("metadata: %v", err)
+		return storage
+	}
+	hidden := inactiveHere(meta, currentHostname())
+	if len(hidden) == 0 {
+		return storage
+	}
+	debugf("hiding %d bookmark(s) restricted to other hosts", len(hidden))
+	return &hostFilteredStorage{Storage: storage, hidden: hidden}
+}
+
+// onlyOnCommand shows or replaces the hostname patterns a bookmark is
+// restricted to ('mark only-on <name> [<pattern>...|--clear]')
+func onlyOnCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark only-on <name> [<pattern>...|--clear]\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	lookupBookmark(openStorage(config), name)
+
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m := Metadata{}
+	if meta[name] != nil {
+		m = *meta[name]
+	}
+
+	if len(args) == 1 {
+		if len(m.OnlyOn) == 0 {
+			fmt.Printf("Bookmark '%s' is active on every host\n", name)
+			return
+		}
+		state := "inactive"
+		if hostMatches(m.OnlyOn, currentHostname()) {
+			state = "active"
+		}
+		fmt.Printf("Bookmark '%s' is only on %s (%s on %s)\n", name, strings.Join(m.OnlyOn, ", "), state, currentHostname())
+		return
+	}
+
+	if len(args) == 2 && args[1] == "--clear" {
+		m.OnlyOn = nil
+	} else {
+		m.OnlyOn = nil
+		for _, pattern := range args[1:] {
+			m.OnlyOn = append(m.OnlyOn, parseTags(pattern)...)
+		}
+		for _, pattern := range m.OnlyOn {
+			if _, err := path.Match(pattern, ""); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Invalid host pattern '%s'\n", pattern)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if marks.IsEmptyMetadata(&m) {
+		err = marks.UpdateMetadata(config.MarksDir, name, nil)
+	} else {
+		err = marks.UpdateMetadata(config.MarksDir, name, &m)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(m.OnlyOn) == 0 {
+		fmt.Printf("✓ Bookmark '%s' is active on every host\n", name)
+		return
+	}
+	fmt.Printf("✓ Bookmark '%s' is only active on %s\n", name, strings.Join(m.OnlyOn, ", "))
+}
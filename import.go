@@ -0,0 +1,471 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// importEntry is one bookmark candidate read from another tool's data
+type importEntry struct {
+	Name string
+	Path string
+	Tags []string
+}
+
+// importSource reads bookmark candidates from another tool's data file
+type importSource struct {
+	parse       func(path string) ([]importEntry, error)
+	defaultFile func() string
+	promptNames bool // names are terse (e.g. single letters); offer to rename
+}
+
+// importSources maps 'mark import <tool>' names to their readers
+var importSources = map[string]importSource{
+	"lf":     {parse: parseLfMarks, defaultFile: lfMarksFile, promptNames: true},
+	"mc":     {parse: parseMcHotlist, defaultFile: mcHotlistFile},
+	"nnn":    {parse: parseNnnBookmarks, defaultFile: nnnBookmarksSource, promptNames: true},
+	"ranger": {parse: parseRangerBookmarks, defaultFile: rangerBookmarksFile, promptNames: true},
+	"z":      {parse: parseZData, defaultFile: zDataFile},
+}
+
+// xdgDataHome returns $XDG_DATA_HOME or ~/.local/share
+func xdgDataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".local", "share")
+}
+
+// rangerBookmarksFile returns ranger's bookmark file
+func rangerBookmarksFile() string {
+	return filepath.Join(xdgDataHome(), "ranger", "bookmarks")
+}
+
+// parseRangerBookmarks reads ranger's bookmark file
+func parseRangerBookmarks(path string) ([]importEntry, error) {
+	return parseKeyPathFile(path, "ranger bookmarks")
+}
+
+// lfMarksFile returns lf's marks file
+func lfMarksFile() string {
+	return filepath.Join(xdgDataHome(), "lf", "marks")
+}
+
+// parseLfMarks reads lf's marks file, which uses the same format as ranger
+func parseLfMarks(path string) ([]importEntry, error) {
+	return parseKeyPathFile(path, "lf marks")
+}
+
+// parseKeyPathFile reads "key:path" lines as written by ranger and lf. The
+// "'" and "`" keys hold the last visited directory, not a bookmark, and are
+// skipped.
+func parseKeyPathFile(path, what string) ([]importEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", what, err)
+	}
+	defer file.Close()
+
+	var entries []importEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		if parts[0] == "'" || parts[0] == "`" {
+			continue
+		}
+		entries = append(entries, importEntry{Name: parts[0], Path: parts[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", what, err)
+	}
+	return entries, nil
+}
+
+// nnnBookmarksSource returns nnn's bookmarks directory of symlinks.
+// NNN_BMS is read in addition to it.
+func nnnBookmarksSource() string {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "nnn", "bookmarks")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "nnn", "bookmarks")
+}
+
+// parseNnnBookmarks reads nnn bookmarks from the NNN_BMS environment
+// variable ("d:~/Documents;D:~/Downloads") and from path, which is either
+// nnn's bookmarks directory of symlinks or a file holding an NNN_BMS value
+func parseNnnBookmarks(path string) ([]importEntry, error) {
+	entries := parseNnnBMS(os.Getenv("NNN_BMS"))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) && len(entries) > 0 {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("error reading nnn bookmarks: %w", err)
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading nnn bookmarks: %w", err)
+		}
+		value := strings.TrimSpace(string(content))
+		value = strings.TrimPrefix(value, "export ")
+		value = strings.TrimPrefix(value, "NNN_BMS=")
+		return append(entries, parseNnnBMS(strings.Trim(value, `'"`))...), nil
+	}
+
+	links, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading nnn bookmarks: %w", err)
+	}
+	for _, link := range links {
+		target, err := os.Readlink(filepath.Join(path, link.Name()))
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(path, target)
+		}
+		entries = append(entries, importEntry{Name: link.Name(), Path: target})
+	}
+	return entries, nil
+}
+
+// parseNnnBMS splits an NNN_BMS value into entries, expanding a leading ~
+func parseNnnBMS(value string) []importEntry {
+	var entries []importEntry
+	for _, item := range strings.Split(value, ";") {
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		path := parts[1]
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			homeDir, _ := os.UserHomeDir()
+			path = filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+		}
+		entries = append(entries, importEntry{Name: parts[0], Path: path})
+	}
+	return entries
+}
+
+// mcHotlistFile returns Midnight Commander's directory hotlist
+func mcHotlistFile() string {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "mc", "hotlist")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "mc", "hotlist")
+}
+
+// parseMcHotlist reads a Midnight Commander hotlist:
+//
+//	GROUP "Work"
+//	  ENTRY "api" URL "/home/me/src/api"
+//	ENDGROUP
+//
+// The names of enclosing groups become tags. Entries pointing at virtual
+// filesystems (ftp://, /#sh:...) are skipped.
+func parseMcHotlist(path string) ([]importEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading mc hotlist: %w", err)
+	}
+
+	var entries []importEntry
+	var groups []string
+	tokens := mcTokens(string(content))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "GROUP":
+			if i+1 < len(tokens) {
+				groups = append(groups, strings.ReplaceAll(strings.TrimSpace(tokens[i+1]), " ", "-"))
+				i++
+			}
+		case "ENDGROUP":
+			if len(groups) > 0 {
+				groups = groups[:len(groups)-1]
+			}
+		case "ENTRY":
+			if i+3 >= len(tokens) || tokens[i+2] != "URL" {
+				continue
+			}
+			name, url := tokens[i+1], tokens[i+3]
+			i += 3
+			if !filepath.IsAbs(url) || strings.Contains(url, "#") {
+				continue
+			}
+			entries = append(entries, importEntry{
+				Name: name,
+				Path: url,
+				Tags: append([]string(nil), groups...),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// mcTokens splits hotlist content into words and quoted strings, honouring
+// backslash escapes inside quotes
+func mcTokens(content string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, escaped, inToken := false, false, false
+
+	for _, r := range content {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case inQuotes && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			inToken = true
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// promptImportNames asks for a descriptive name for each single-letter
+// entry, defaulting to the directory name; entering "-" keeps the letter
+func promptImportNames(entries []importEntry, reader *bufio.Reader) []importEntry {
+	for i, entry := range entries {
+		if len([]rune(entry.Name)) > 1 {
+			continue
+		}
+
+		suggestion := filepath.Base(entry.Path)
+		fmt.Printf("Name for '%s' -> %s [%s] (- keeps '%s'): ", entry.Name, entry.Path, suggestion, entry.Name)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(response)
+
+		switch response {
+		case "":
+			entries[i].Name = suggestion
+		case "-":
+		default:
+			entries[i].Name = response
+		}
+	}
+	return entries
+}
+
+// zDataFile returns the z.sh data file ($_Z_DATA or ~/.z)
+func zDataFile() string {
+	if file := os.Getenv("_Z_DATA"); file != "" {
+		return file
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".z")
+}
+
+// parseZData reads z.sh records, highest rank first so the most used
+// directory wins when two share a name. z.sh writes "path|rank|time"; the
+// "rank|time|path" order used by some ports is accepted too.
+func parseZData(path string) ([]importEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading z data: %w", err)
+	}
+	defer file.Close()
+
+	type zRecord struct {
+		path string
+		rank float64
+	}
+	var records []zRecord
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "|")
+		if len(parts) != 3 {
+			continue
+		}
+		dir, rankField := parts[0], parts[1]
+		if !filepath.IsAbs(dir) && filepath.IsAbs(parts[2]) {
+			dir, rankField = parts[2], parts[0]
+		}
+		rank, err := strconv.ParseFloat(rankField, 64)
+		if err != nil || dir == "" {
+			continue
+		}
+		records = append(records, zRecord{path: dir, rank: rank})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading z data: %w", err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].rank > records[j].rank
+	})
+
+	var entries []importEntry
+	for _, r := range records {
+		entries = append(entries, importEntry{Name: filepath.Base(r.path), Path: r.path})
+	}
+	return entries, nil
+}
+
+// importCommand converts another tool's bookmarks into marks
+// ('mark import <tool> [--file <path>] [--prefix <prefix>]')
+func importCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Import source required: mark import <%s> [--file <path>] [--prefix <prefix>]\n", strings.Join(importSourceNames(), "|"))
+		os.Exit(1)
+	}
+
+	source, ok := importSources[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: Unknown import source '%s' (supported: %s)\n", args[0], strings.Join(importSourceNames(), ", "))
+		os.Exit(1)
+	}
+
+	file := source.defaultFile()
+	prefix := ""
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--prefix":
+			if i+1 >= len(args) || args[i+1] == "" {
+				fmt.Fprintf(os.Stderr, "Error: --prefix requires a value (e.g. work:)\n")
+				os.Exit(1)
+			}
+			prefix = args[i+1]
+			i++
+		case "--file":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --file requires a path\n")
+				os.Exit(1)
+			}
+			file = marks.ExpandPath(args[i+1])
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown import option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	entries, err := source.parse(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if source.promptNames {
+		entries = promptImportNames(entries, bufio.NewReader(os.Stdin))
+	}
+
+	imported, skipped := importEntries(config, openStorage(config), entries, prefix, flags.DryRun)
+	if flags.DryRun {
+		fmt.Printf("Would import %d bookmark(s), skip %d\n", imported, skipped)
+		return
+	}
+	fmt.Printf("Imported %d bookmark(s), skipped %d\n", imported, skipped)
+}
+
+// importEntries creates a bookmark for each entry whose directory still
+// exists and whose name is free, reporting what was skipped and why. The
+// prefix (e.g. "work:") is put in front of each name after the naming
+// policy is applied, so it is kept as given. With dryRun nothing is
+// written; the report shows what would happen.
+func importEntries(config Config, storage Storage, entries []importEntry, prefix string, dryRun bool) (imported, skipped int) {
+	policy := configNamePolicy(config)
+	planned := make(map[string]bool) // names a dry run would already have taken
+	for _, entry := range entries {
+		name, err := policy.normalize(entry.Name)
+		if err == nil && prefix != "" {
+			name = prefix + name
+			err = policy.check(name)
+		}
+		if err != nil {
+			fmt.Printf("  skip %-20s (no usable name for %s: %v)\n", entry.Name, entry.Path, err)
+			skipped++
+			continue
+		}
+
+		if info, err := os.Stat(entry.Path); err != nil || !info.IsDir() {
+			fmt.Printf("  skip %-20s (%s no longer exists)\n", name, entry.Path)
+			skipped++
+			continue
+		}
+
+		if dryRun {
+			if _, err := storage.Get(name); planned[name] || !errors.Is(err, marks.ErrNotFound) {
+				fmt.Printf("  skip %-20s (bookmark already exists)\n", name)
+				skipped++
+				continue
+			}
+			planned[name] = true
+			fmt.Printf("Would import bookmark '%s' -> %s\n", name, entry.Path)
+			imported++
+			continue
+		}
+
+		if err := storeBookmark(config, storage, name, normalizeTarget(entry.Path), Metadata{Tags: entry.Tags}); err != nil {
+			if errors.Is(err, marks.ErrExists) {
+				fmt.Printf("  skip %-20s (bookmark already exists)\n", name)
+			} else {
+				fmt.Printf("  skip %-20s (%v)\n", name, err)
+			}
+			skipped++
+			continue
+		}
+
+		fmt.Printf("✓ Imported bookmark '%s' -> %s\n", name, entry.Path)
+		imported++
+	}
+	return imported, skipped
+}
+
+// importSourceNames returns the supported import sources in alphabetical order
+func importSourceNames() []string {
+	names := make([]string, 0, len(importSources))
+	for name := range importSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -19,16 +19,21 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
 )
 
-type Config struct {
-	MarksDir string
-}
+// Config holds the settings of the config file; see pkg/marks
+type Config = marks.Config
 
 var (
 	Version   = "dev"
@@ -42,10 +47,39 @@ const (
 	colorReset = "\033[0m"
 )
 
+// Exit statuses scripts can branch on; every other failure exits with 1.
+// They are documented in the README, so never renumber them.
+const (
+	exitNotFound = 2 // no bookmark by that name
+	exitBroken   = 3 // the bookmark target is missing or not a directory
+	exitConflict = 4 // a bookmark by that name already exists
+	exitConfig   = 5 // the config file or a setting is invalid
+	exitTrouble  = 6 // mark diff could not compare (exit 1 there means the sets differ)
+)
+
 func main() {
 	// Parse custom flags with Unix-like behavior first
 	flags, args := parseFlags(os.Args[1:])
 
+	// Trace to stderr with --verbose or MARK_DEBUG=1
+	debugEnabled = flags.Verbose || debugFromEnv(os.Getenv)
+
+	// Setup questions are answered from a file with --defaults and from
+	// flags such as --yes for unattended runs
+	setupDefaultsFile = flags.Defaults
+	setupFlagAnswers = setupAnswersFromFlags(flags)
+
+	// Apply --shell before anything generates or installs shell code
+	if flags.Shell != "" {
+		switch flags.Shell {
+		case "bash", "zsh", "fish":
+			shellOverride = flags.Shell
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unsupported shell '%s'. Supported shells: bash, zsh, fish\n", flags.Shell)
+			os.Exit(1)
+		}
+	}
+
 	// Handle version number (before config load)
 	if flags.Version {
 		printVersion()
@@ -58,14 +92,94 @@ func main() {
 		return
 	}
 
+	// Handle creation completion candidates (before config load, never prompts)
+	if flags.CompleteCreate {
+		if !printCreateCompletions() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle completion chooser lookup (before config load, never prompts)
+	if flags.CompleteChooser {
+		if !printCompletionChooser() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle subdirectory candidates below a bookmark (before config load, never prompts)
+	if flags.CompleteSubpath {
+		if len(args) > 0 {
+			printSubpathCompletions(args[0])
+		}
+		return
+	}
+
+	// Record a directory visit from the track_visits hook (before config
+	// load, never prompts or fails loudly)
+	if flags.RecordVisit {
+		if dir, err := os.Getwd(); err == nil {
+			_ = recordVisit(dir)
+		}
+		return
+	}
+
+	// Answer the mark shell function: a jump target when default_action=jump
+	// turns 'mark <name>' into a jump, else nothing (before config load,
+	// never prompts)
+	if flags.DefaultJump {
+		printDefaultJump(flags, args)
+		return
+	}
+
+	// Handle tag completion candidates (before config load, never prompts)
+	if flags.CompleteTags {
+		printTagCompletions()
+		return
+	}
+
+	// Commands that never need the config run before setup can be triggered,
+	// so 'eval "$(mark init bash)"' on a fresh machine never prompts
+	if len(args) > 0 && !flags.Literal && configFreeCommands[args[0]] {
+		subcommands[args[0]](Config{}, flags, args[1:])
+		return
+	}
+
 	// Load config after checking version/help
 	config, firstTimeSetup := loadOrCreateConfig()
 
 	// If first-time setup was just completed, exit gracefully
 	if firstTimeSetup {
+		fmt.Println("New to mark? Run 'mark tutorial' for a guided tour.")
 		return
 	}
 
+	// Switch to the marks directory of the selected profile
+	config, err := applyProfile(config, profileName(config, flags.Profile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	config = marks.ApplyMarksDirEnv(config)
+
+	// --link-style overrides link_style from the config for this run
+	if flags.LinkStyle != "" {
+		config.LinkStyle = flags.LinkStyle
+	}
+	if !validLinkStyle(config.LinkStyle) {
+		fmt.Fprintf(os.Stderr, "Error: Unknown link_style '%s' (supported: %s)\n", config.LinkStyle, strings.Join(linkStyles, ", "))
+		os.Exit(exitConfig)
+	}
+
+	// Anything that is not a builtin may be a plugin (mark-<name> on PATH),
+	// which gets the rest of the command line, flags included
+	if len(args) > 0 && !flags.Literal && subcommands[args[0]] == nil {
+		if path := findPlugin(args[0]); path != "" {
+			runPlugin(path, config, flags)
+		}
+	}
+
 	// Handle config
 	if flags.Config {
 		runSetup()
@@ -84,109 +198,235 @@ func main() {
 		return
 	}
 
+	// Handle the shell-safety audit of bookmark names
+	if flags.CheckNames {
+		checkNames(config)
+		return
+	}
+
 	// Handle listing
 	if flags.List {
-		listBookmarks(config)
+		listBookmarks(config, flags)
 		return
 	}
 
 	// Handle delete
 	if flags.Delete != "" {
-		deleteBookmark(config, flags.Delete)
+		deleteBookmark(config, flags.Delete, flags.Yes, flags.DryRun)
 		return
 	}
 
 	// Handle jump
 	if flags.Jump != "" {
-		jumpBookmark(config, flags.Jump)
+		jumpBookmark(config, flags)
+		return
+	}
+
+	// Handle subcommands (unless '--' asked for a literal bookmark name)
+	if len(args) > 0 && !flags.Literal {
+		if command, ok := subcommands[args[0]]; ok {
+			command(config, flags, args[1:])
+			return
+		}
+	}
+
+	// With default_action=jump, a lone existing name jumps instead of creating
+	if len(args) == 1 && defaultsToJump(config, args[0]) {
+		flags.Jump = args[0]
+		jumpBookmark(config, flags)
 		return
 	}
 
 	// Handle bookmark creation
 	bookmarkName := ""
 	targetPath := ""
+	var fallbacks []string
 
 	if len(args) == 1 {
 		// Single argument: bookmark name, use current directory as target
 		bookmarkName = args[0]
 	} else if len(args) >= 2 {
-		// Two arguments: bookmark name and custom path
+		// Two arguments: bookmark name and custom path; any further paths
+		// are fallback targets for when that one is missing
 		bookmarkName = args[0]
 		targetPath = args[1]
+		fallbacks, err = parseFallbacks(args[2:])
+		if err == nil && len(fallbacks) > 0 && (marks.IsURLTarget(targetPath) || marks.IsCommandTarget(targetPath)) {
+			err = fmt.Errorf("fallback targets need a directory bookmark, not a URL or command")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 	// else: no arguments, createBookmark will use current directory name
 
-	createBookmark(config, bookmarkName, targetPath)
+	// --git-root bookmarks the repository root instead of the current
+	// directory; without a name the root's directory name is used
+	if flags.GitRoot {
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --git-root takes only a bookmark name\n")
+			os.Exit(1)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
+			os.Exit(1)
+		}
+		targetPath, err = gitRoot(cwd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --git-root: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	createBookmark(config, bookmarkName, targetPath, Metadata{
+		Tags:        parseTags(flags.Tag),
+		Description: strings.TrimSpace(flags.Desc),
+		Host:        strings.TrimSpace(flags.Host),
+		Pinned:      flags.Pin,
+		Fallbacks:   fallbacks,
+		OnlyOn:      parseTags(flags.OnlyOn),
+	}, flags.Raw, flags.Suffix, flags.DryRun)
 }
 
 func loadOrCreateConfig() (Config, bool) {
-	homeDir, err := os.UserHomeDir()
+	configPath, err := marks.ConfigFilePath()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	configPath := filepath.Join(homeDir, ".mark")
-
 	// Check if config exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		// $MARK_DIR alone is enough to work without a config, e.g. in containers
+		if os.Getenv("MARK_DIR") != "" {
+			return marks.ApplyMarksDirEnv(Config{}), false
+		}
 		// First run, create config
 		return runSetup(), true
 	}
 
 	// Load existing config
-	file, err := os.Open(configPath)
+	debugf("config file %s", configPath)
+	config, err := marks.ParseConfigFile(configPath)
+	problem := err
+	if err == nil && config.MarksDir == "" {
+		problem = fmt.Errorf("no marksdir set")
+	}
+	if problem != nil && offerConfigRestore(configPath, problem) {
+		config, err = marks.ParseConfigFile(configPath)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening config: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error opening config: %v%s\n", err, marks.BackupHint(configPath))
+		os.Exit(exitConfig)
 	}
-	defer file.Close()
 
-	config := Config{}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
+	if config.MarksDir == "" {
+		// Keep the damaged file so setup never silently discards it
+		if content, err := os.ReadFile(configPath); err == nil && len(content) > 0 {
+			if err := marks.WriteFileAtomic(configPath+".broken", content, 0600); err == nil {
+				fmt.Printf("The previous config was kept in %s.broken\n", configPath)
+			}
 		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+		fmt.Println("Invalid config file. Running setup...")
+		return runSetup(), false
+	}
+
+	debugf("config: marksdir=%s storage=%s", config.MarksDir, storageName(config))
+	return config, false
+}
+
+// offerConfigRestore asks whether to replace a damaged config file with its
+// backup, when the backup is usable and someone is at the terminal
+func offerConfigRestore(configPath string, problem error) bool {
+	backup, err := marks.ParseConfigFile(configPath + marks.BackupSuffix)
+	if err != nil || backup.MarksDir == "" || !stdinIsTerminal() {
+		return false
+	}
 
-		switch key {
-		case "marksdir":
-			config.MarksDir = expandPath(value)
+	fmt.Printf("Config file %s is damaged: %v\n", configPath, problem)
+	fmt.Printf("Restore the previous version from %s%s? (Y/n): ", configPath, marks.BackupSuffix)
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer := cleanResponse(response); answer != "" {
+		if yes, _ := parseYesNo(answer); !yes {
+			return false
 		}
 	}
 
-	if config.MarksDir == "" {
-		fmt.Println("Invalid config file. Running setup...")
-		return runSetup(), false
+	if err := marks.RestoreBackup(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error restoring config: %v\n", err)
+		return false
 	}
+	fmt.Printf("✓ Restored config (the damaged file is kept in %s.broken)\n", configPath)
+	return true
+}
 
-	return config, false
+// readConfig loads the config file without ever triggering interactive
+// setup, honoring $MARK_PROFILE and $MARK_DIR
+func readConfig() (Config, error) {
+	configPath, err := marks.ConfigFilePath()
+	if err != nil {
+		return Config{}, err
+	}
+	config, err := marks.ParseConfigFile(configPath)
+	if err != nil && !(errors.Is(err, os.ErrNotExist) && os.Getenv("MARK_DIR") != "") {
+		return config, err
+	}
+	config, err = applyProfile(config, profileName(config, ""))
+	return marks.ApplyMarksDirEnv(config), err
+}
+
+// profileName picks the active profile: the explicit --profile name, then
+// $MARK_PROFILE, then the workspace rule matching the current directory
+func profileName(config Config, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if env := os.Getenv("MARK_PROFILE"); env != "" {
+		return env
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	if best := bestMapping(marks.ExpandPath(cwd), config.WorkspaceMap); best >= 0 {
+		return config.WorkspaceMap[best].To
+	}
+	return ""
+}
+
+// applyProfile points config at the marks directory of the named profile;
+// an empty name keeps the default marksdir
+func applyProfile(config Config, name string) (Config, error) {
+	if name == "" {
+		return config, nil
+	}
+	dir, ok := config.Profiles[name]
+	if !ok {
+		var names []string
+		for profile := range config.Profiles {
+			names = append(names, profile)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return config, fmt.Errorf("unknown profile '%s' (no profiles configured; add profile=%s=<marksdir> to ~/.mark)", name, name)
+		}
+		return config, fmt.Errorf("unknown profile '%s' (configured: %s)", name, strings.Join(names, ", "))
+	}
+	config.MarksDir = dir
+	debugf("profile %s: marksdir=%s", name, dir)
+	return config, nil
 }
 
 func runSetup() Config {
-	reader := bufio.NewReader(os.Stdin)
+	prompter := newSetupPrompter()
 	config := Config{}
 
 	// Get current values if they exist
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".mark")
-	if file, err := os.Open(configPath); err == nil {
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				switch strings.TrimSpace(parts[0]) {
-				case "marksdir":
-					config.MarksDir = expandPath(strings.TrimSpace(parts[1]))
-				}
-			}
-		}
-		file.Close()
+	configPath, _ := marks.ConfigFilePath()
+	if existing, err := marks.ParseConfigFile(configPath); err == nil {
+		config = existing
 	}
 
 	// Ask for marks directory
@@ -195,14 +435,7 @@ func runSetup() Config {
 		defaultDir = "~/.marks"
 	}
 
-	fmt.Printf("Where should bookmarks be stored (%s): ", defaultDir)
-	marksDir, _ := reader.ReadString('\n')
-	marksDir = strings.TrimSpace(marksDir)
-	if marksDir == "" {
-		marksDir = defaultDir
-	}
-
-	marksDir = expandPath(marksDir)
+	marksDir := marks.ExpandPath(prompter.askText("marksdir", "Where should bookmarks be stored", defaultDir))
 	fmt.Printf("Setting your bookmarks location to %s ...\n", marksDir)
 	config.MarksDir = marksDir
 
@@ -213,10 +446,10 @@ func runSetup() Config {
 	}
 
 	// Ask about command line completion
-	SetupCompletion(reader)
+	SetupCompletion(prompter)
 
 	// Ask about shell aliases
-	setupAliases(reader)
+	setupAliases(prompter)
 
 	// Save config
 	saveConfig(config)
@@ -230,35 +463,128 @@ func saveConfig(config Config) {
 		os.Exit(1)
 	}
 
-	configPath := filepath.Join(homeDir, ".mark")
-	file, err := os.Create(configPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating config file: %v\n", err)
-		os.Exit(1)
+	configPath, _ := marks.ConfigFilePath()
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "marksdir=%s\n", tildePath(config.MarksDir, homeDir))
+	if config.CreateCompletion != "" {
+		fmt.Fprintf(&content, "create_completion=%s\n", config.CreateCompletion)
+	}
+	if config.Chooser != "" {
+		fmt.Fprintf(&content, "completion_chooser=%s\n", config.Chooser)
+	}
+	if config.Storage != "" {
+		fmt.Fprintf(&content, "storage=%s\n", config.Storage)
+	}
+	if config.StorageFile != "" {
+		fmt.Fprintf(&content, "storage_file=%s\n", config.StorageFile)
+	}
+	if config.StorageCommand != "" {
+		fmt.Fprintf(&content, "storage_command=%s\n", config.StorageCommand)
+	}
+	if config.NamePolicy != "" {
+		fmt.Fprintf(&content, "name_policy=%s\n", config.NamePolicy)
+	}
+	for _, m := range config.SSHPathMap {
+		fmt.Fprintf(&content, "ssh_path_map=%s=%s\n", m.From, m.To)
+	}
+	for _, m := range config.ContainerPathMap {
+		fmt.Fprintf(&content, "container_path_map=%s=%s\n", m.From, m.To)
+	}
+	if config.ContainerRuntime != "" {
+		fmt.Fprintf(&content, "container_runtime=%s\n", config.ContainerRuntime)
+	}
+	if config.JumpDirFallback != "" {
+		fmt.Fprintf(&content, "jump_dir_fallback=%s\n", config.JumpDirFallback)
+	}
+	if config.DefaultAction != "" {
+		fmt.Fprintf(&content, "default_action=%s\n", config.DefaultAction)
+	}
+	if config.OnBroken != "" {
+		fmt.Fprintf(&content, "on_broken=%s\n", config.OnBroken)
+	}
+	if config.LinkStyle != "" {
+		fmt.Fprintf(&content, "link_style=%s\n", config.LinkStyle)
+	}
+	if config.TrashDays != "" {
+		fmt.Fprintf(&content, "trash_days=%s\n", config.TrashDays)
+	}
+	if config.MaxAge != "" {
+		fmt.Fprintf(&content, "max_age=%s\n", config.MaxAge)
+	}
+	if config.DynamicTargets != "" {
+		fmt.Fprintf(&content, "dynamic_targets=%s\n", config.DynamicTargets)
+	}
+	if config.DynamicTimeout != "" {
+		fmt.Fprintf(&content, "dynamic_timeout=%s\n", config.DynamicTimeout)
+	}
+	if config.PreJump != "" {
+		fmt.Fprintf(&content, "pre_jump=%s\n", config.PreJump)
+	}
+	if config.PostJump != "" {
+		fmt.Fprintf(&content, "post_jump=%s\n", config.PostJump)
+	}
+	if config.TrackVisits != "" {
+		fmt.Fprintf(&content, "track_visits=%s\n", config.TrackVisits)
+	}
+	if config.AuditLog != "" {
+		fmt.Fprintf(&content, "audit_log=%s\n", config.AuditLog)
+	}
+	if config.Sort != "" {
+		fmt.Fprintf(&content, "sort=%s\n", config.Sort)
+	}
+	if config.ProjectMarks != "" {
+		fmt.Fprintf(&content, "project_marks=%s\n", config.ProjectMarks)
+	}
+	if config.SystemMarks != "" {
+		fmt.Fprintf(&content, "system_marks=%s\n", tildePath(config.SystemMarks, homeDir))
+	}
+	for _, dir := range config.SharedMarks {
+		fmt.Fprintf(&content, "shared_marks=%s\n", tildePath(dir, homeDir))
+	}
+	for _, dir := range config.RepairRoots {
+		fmt.Fprintf(&content, "repair_root=%s\n", tildePath(dir, homeDir))
+	}
+	if config.SourceOrder != "" {
+		fmt.Fprintf(&content, "source_order=%s\n", config.SourceOrder)
+	}
+	var profiles []string
+	for name := range config.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	for _, name := range profiles {
+		fmt.Fprintf(&content, "profile=%s=%s\n", name, tildePath(config.Profiles[name], homeDir))
+	}
+	for _, m := range config.WorkspaceMap {
+		fmt.Fprintf(&content, "workspace=%s=%s\n", tildePath(m.From, homeDir), m.To)
 	}
-	defer file.Close()
 
-	// Convert absolute path back to ~ notation for config file
-	marksDir := config.MarksDir
-	if strings.HasPrefix(marksDir, homeDir) {
-		marksDir = "~" + strings.TrimPrefix(marksDir, homeDir)
+	// Write atomically so a crash never leaves a truncated config behind,
+	// keeping the previous version in ~/.mark.bak
+	if err := marks.WriteFileBackup(configPath, []byte(content.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing config file: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	fmt.Fprintf(file, "marksdir=%s\n", marksDir)
+// tildePath converts an absolute path under homeDir back to ~ notation for
+// the config file
+func tildePath(path, homeDir string) string {
+	if strings.HasPrefix(path, homeDir) {
+		return "~" + strings.TrimPrefix(path, homeDir)
+	}
+	return path
 }
 
-func setupAliases(reader *bufio.Reader) {
+func setupAliases(prompter *setupPrompter) {
 	// Check if aliases are already set up
 	if areAliasesAlreadySetup() {
 		return
 	}
 
 	fmt.Println()
-	fmt.Print("Would you like to set up shell aliases (marks, unmark, jump)? (y/N): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	if response != "y" && response != "yes" {
+	if !prompter.askYesNo("aliases", "Would you like to set up shell aliases (marks, unmark, jump)?") {
 		fmt.Println("Skipping alias setup. You can run 'mark --config' later to set them up.")
 		return
 	}
@@ -266,6 +592,7 @@ func setupAliases(reader *bufio.Reader) {
 	shell := detectShell()
 	if shell == "" {
 		fmt.Println("Could not detect shell type. Skipping alias setup.")
+		fmt.Println("Use 'mark --alias --shell bash|zsh|fish' to choose one.")
 		return
 	}
 
@@ -278,6 +605,7 @@ func setupAliases(reader *bufio.Reader) {
 		setupFishAliases()
 	default:
 		fmt.Printf("Shell '%s' not supported for aliases. Supported shells: bash, zsh, fish\n", shell)
+		fmt.Println("Use 'mark --alias --shell <name>' for the shell family you run.")
 	}
 }
 
@@ -400,31 +728,119 @@ func setupFishAliases() {
 	fmt.Printf("  Fish auto-sources files in conf.d, restart your shell to activate\n")
 }
 
-func expandPath(path string) string {
-	// Handle tilde expansion first
-	if strings.HasPrefix(path, "~/") {
-		homeDir, _ := os.UserHomeDir()
-		path = filepath.Join(homeDir, path[2:])
+// isDriveRelative reports whether path names a drive without a root, such
+// as "C:projects", which Windows resolves against that drive's current
+// directory rather than the drive root
+func isDriveRelative(path string) bool {
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	letter := path[0] | 0x20
+	if letter < 'a' || letter > 'z' {
+		return false
 	}
+	return len(path) == 2 || (path[2] != '\\' && path[2] != '/')
+}
 
-	// Resolve symbolic links to get the actual path
-	resolvedPath, err := filepath.EvalSymlinks(path)
-	if err != nil {
-		// If we can't resolve symlinks, return the original path
-		// This handles cases where the path doesn't exist yet or other errors
-		return path
+// normalizeTargetArg turns a target as pasted or dropped from a GUI file
+// manager into a plain path: file:// URIs are decoded, surrounding quotes
+// and whitespace are removed, "\ " escapes are undone when the literal path
+// does not exist, and trailing slashes are dropped
+func normalizeTargetArg(arg string) string {
+	path := strings.TrimSpace(arg)
+	if len(path) >= 2 && (path[0] == '\'' || path[0] == '"') && path[len(path)-1] == path[0] {
+		path = path[1 : len(path)-1]
+	}
+
+	if strings.HasPrefix(strings.ToLower(path), "file://") {
+		if u, err := url.Parse(path); err == nil && (u.Host == "" || u.Host == "localhost") {
+			path = u.Path
+			// file:///C:/dir decodes to /C:/dir on Windows
+			if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+				path = path[1:]
+			}
+			path = filepath.FromSlash(path)
+		}
+	} else if strings.Contains(path, "\\ ") {
+		if _, err := os.Stat(path); err != nil {
+			path = strings.ReplaceAll(path, "\\ ", " ")
+		}
+	}
+
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/"+string(os.PathSeparator))
 	}
+	return path
+}
 
-	return resolvedPath
+// nameSeparators returns the characters a bookmark name may not contain
+// because the platform treats them as path separators
+func nameSeparators() string {
+	if runtime.GOOS == "windows" {
+		return `\/:`
+	}
+	return string(os.PathSeparator)
 }
 
-func createBookmark(config Config, name string, targetPath string) {
+func createBookmark(config Config, name string, targetPath string, meta Metadata, raw, suffix, dryRun bool) {
 	var targetDir string
 
 	// Determine target directory
-	if targetPath != "" {
-		// Custom path provided - expand and validate it
-		targetDir = expandPath(targetPath)
+	if marks.IsURLTarget(targetPath) {
+		// Web addresses are stored as given and opened with 'mark open'
+		if err := validateURLTarget(targetPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		targetDir = targetPath
+	} else if marks.IsCommandTarget(targetPath) {
+		// !command targets are stored as given and run at jump time; what
+		// they print is only known then
+		command := strings.TrimSpace(strings.TrimPrefix(targetPath, "!"))
+		if command == "" {
+			fmt.Fprintf(os.Stderr, "Error: Command target is empty (expected !command)\n")
+			os.Exit(1)
+		}
+		if config.DynamicTargets != "on" {
+			fmt.Fprintf(os.Stderr, "Note: -j runs this command only with dynamic_targets=on in ~/.mark\n")
+		}
+		targetDir = "!" + command
+	} else if envTarget := normalizeTargetArg(targetPath); marks.IsEnvTarget(envTarget) {
+		// $VAR targets are stored literally and expanded whenever they are
+		// resolved; the directory must exist under the current value
+		expanded := marks.TargetPath(config, envTarget)
+		if info, err := os.Stat(expanded); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: Target directory does not exist: %s (expands to %s)\n", envTarget, expanded)
+			os.Exit(1)
+		}
+		targetDir = filepath.Clean(envTarget)
+	} else if pattern := normalizeTargetArg(targetPath); marks.IsGlobTarget(pattern) {
+		// Glob targets are stored as patterns and resolve to the newest
+		// matching directory at each jump; something must match now
+		pattern = normalizeTarget(marks.ExpandHome(pattern))
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Invalid target pattern %s: %v\n", pattern, err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(marks.GlobTargetPath(pattern)); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: No directory matches %s\n", pattern)
+			os.Exit(1)
+		}
+		targetDir = pattern
+	} else if targetPath != "" {
+		// Custom path provided - normalize, expand and validate it. With
+		// --raw a symlinked directory is bookmarked as itself.
+		if raw {
+			targetDir = marks.ExpandHome(normalizeTargetArg(targetPath))
+		} else {
+			targetDir = marks.ExpandPath(normalizeTargetArg(targetPath))
+		}
+
+		if runtime.GOOS == "windows" && isDriveRelative(targetDir) {
+			fmt.Fprintf(os.Stderr, "Error: Target path must include a drive root (e.g. C:\\dir): %s\n", targetPath)
+			os.Exit(1)
+		}
+		targetDir = normalizeTarget(targetDir)
 
 		// Verify the target directory exists
 		fileInfo, err := os.Stat(targetDir)
@@ -457,34 +873,38 @@ func createBookmark(config Config, name string, targetPath string) {
 		name = filepath.Base(targetDir)
 	}
 
-	// Sanitize bookmark name
-	// Replace spaces with underscores and remove path separators
-	name = strings.ReplaceAll(name, " ", "_")
-	if strings.Contains(name, string(os.PathSeparator)) {
-		fmt.Fprintf(os.Stderr, "Error: Bookmark name cannot contain path separators\n")
+	// Normalize and validate the name according to name_policy (by
+	// default spaces become underscores)
+	name, err := configNamePolicy(config).normalize(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if name == "" {
-		fmt.Fprintf(os.Stderr, "Error: Bookmark name cannot be empty\n")
-		os.Exit(1)
+	// Create the bookmark in the configured storage
+	storage := openStorage(config)
+	var replace *Bookmark
+	if nameTaken(storage, name) {
+		var ok bool
+		if name, replace, ok = resolveNameConflict(config, storage, name, targetDir, suffix, dryRun); !ok {
+			fmt.Println("No bookmark created.")
+			return
+		}
 	}
-
-	// Ensure marks directory exists
-	if err := os.MkdirAll(config.MarksDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating marks directory: %v\n", err)
-		os.Exit(1)
+	if dryRun {
+		fmt.Printf("Would create bookmark '%s' -> %s\n", name, targetDir)
+		return
 	}
-
-	// Check if bookmark already exists
-	symlinkPath := filepath.Join(config.MarksDir, name)
-	if _, err := os.Lstat(symlinkPath); err == nil {
-		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' already exists. Use 'mark -d %s' to remove it first.\n", name, name)
-		os.Exit(1)
+	if replace != nil {
+		if _, err := removeBookmark(config, storage, *replace); err != nil {
+			fmt.Fprintf(os.Stderr, "Error replacing bookmark: %v\n", err)
+			os.Exit(1)
+		}
 	}
-
-	// Create the symlink
-	if err := os.Symlink(targetDir, symlinkPath); err != nil {
+	if err := storeBookmark(config, storage, name, targetDir, meta); err != nil {
+		if errors.Is(err, marks.ErrExists) {
+			exitNameConflict(name, nil)
+		}
 		fmt.Fprintf(os.Stderr, "Error creating bookmark: %v\n", err)
 		os.Exit(1)
 	}
@@ -492,15 +912,42 @@ func createBookmark(config Config, name string, targetPath string) {
 	fmt.Printf("✓ Created bookmark '%s' -> %s\n", name, targetDir)
 }
 
-func listBookmarks(config Config) {
-	// Read directory entries
-	entries, err := os.ReadDir(config.MarksDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("No bookmarks found. Create one with 'mark <name>'")
-			return
+// storeBookmark creates a bookmark whose name and target were already
+// validated, then records it in the change and audit logs and stores its
+// metadata. Only the storage error is returned; a metadata failure is a
+// warning.
+func storeBookmark(config Config, storage Storage, name, targetDir string, meta Metadata) error {
+	if err := storage.Create(name, linkTarget(config, targetDir)); err != nil {
+		return err
+	}
+
+	// Record the creation for 'mark report' and the audit log; failures
+	// never block creating
+	_ = recordChange("create", name)
+	_ = recordAudit(config, "create", name, targetDir)
+
+	// Record metadata (tags, description) for the new bookmark, along with
+	// when it was created and the target's identity so 'mark repair' can
+	// find it after a move
+	if meta.Created.IsZero() {
+		meta.Created = time.Now().UTC().Truncate(time.Second)
+	}
+	if !marks.IsURLTarget(targetDir) && !marks.IsCommandTarget(targetDir) && !marks.IsGlobTarget(targetDir) {
+		meta.DirID = dirID(marks.TargetPath(config, targetDir))
+	}
+	if !marks.IsEmptyMetadata(&meta) {
+		if err := marks.UpdateMetadata(config.MarksDir, name, &meta); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-		fmt.Fprintf(os.Stderr, "Error reading bookmarks directory: %v\n", err)
+	}
+	return nil
+}
+
+func listBookmarks(config Config, flags *ParsedFlags) {
+	// Read bookmarks from storage
+	entries, err := openLayeredStorage(config).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -509,164 +956,659 @@ func listBookmarks(config Config) {
 		return
 	}
 
+	// Load metadata for tag filtering and descriptions
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Sort keys: --sort overrides the configured default
+	sortValue := config.Sort
+	if flags.Sort != "" {
+		sortValue = flags.Sort
+	}
+	keys, err := parseSortKeys(sortValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	// Usage history feeds --long, --screen-reader --long and usage sort keys
+	var usage map[string]usageSummary
+	var frecency map[string]float64
+	if flags.Long || sortNeedsUsage(keys) {
+		events, err := loadUsage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		usage = summarizeUsage(events)
+		frecency = frecencyScores(events, time.Now())
+	}
+
 	// Collect bookmark information
 	type bookmarkInfo struct {
-		name   string
-		target string
-		broken bool
+		sortEntry
+		broken      bool
+		shared      bool
+		description string
 	}
 
 	var bookmarks []bookmarkInfo
 
 	for _, entry := range entries {
-		symlinkPath := filepath.Join(config.MarksDir, entry.Name())
-
-		// Check if it's a symlink
-		fileInfo, err := os.Lstat(symlinkPath)
-		if err != nil {
+		// Apply tag filter
+		if flags.Tag != "" && !hasTag(meta[entry.Name], flags.Tag) {
 			continue
 		}
 
-		if fileInfo.Mode()&os.ModeSymlink == 0 {
-			// Not a symlink, skip
-			continue
+		// Check if target exists (this OS's own target when there is one);
+		// a bookmark reached through a fallback target is not broken
+		target := entry.Target
+		if osPath, ok := marks.OSTarget(config, meta[entry.Name]); ok && !entry.Shared {
+			target = osPath
 		}
-
-		// Read symlink target
-		target, err := os.Readlink(symlinkPath)
-		if err != nil {
-			continue
+		_, err := os.Stat(marks.TargetPath(config, target))
+		broken := err != nil && !marks.IsURLTarget(target) && !marks.IsCommandTarget(target)
+		via := ""
+		if broken && !entry.Shared {
+			if fallback, ok := marks.ActiveFallback(config, meta[entry.Name]); ok {
+				broken, via = false, fallback
+			}
 		}
 
-		// Check if target exists
-		_, err = os.Stat(symlinkPath)
-		broken := err != nil
+		description := ""
+		pinned := false
+		if m := meta[entry.Name]; m != nil {
+			description = m.Description
+			pinned = m.Pinned
+		}
+		if via != "" {
+			description = strings.TrimSpace("via " + via + "  " + description)
+		}
 
 		bookmarks = append(bookmarks, bookmarkInfo{
-			name:   entry.Name(),
-			target: target,
-			broken: broken,
+			sortEntry: sortEntry{
+				name:     entry.Name,
+				target:   target,
+				pinned:   pinned,
+				usage:    usage[entry.Name],
+				frecency: frecency[entry.Name],
+			},
+			broken:      broken,
+			shared:      entry.Shared,
+			description: description,
 		})
 	}
 
-	// Sort alphabetically by name
-	sort.Slice(bookmarks, func(i, j int) bool {
-		return bookmarks[i].name < bookmarks[j].name
+	// Apply the sort keys in order; name breaks any remaining ties
+	sort.SliceStable(bookmarks, func(i, j int) bool {
+		return compareBookmarks(bookmarks[i].sortEntry, bookmarks[j].sortEntry, keys) < 0
 	})
 
+	// Screen reader format: one plain sentence per bookmark
+	if flags.ScreenReader {
+		for _, bm := range bookmarks {
+			sentence := screenReaderSentence(bm.name, bm.target, bm.broken, bm.description)
+			if flags.Long {
+				sentence = strings.TrimSuffix(sentence, ".") + ", " + spokenUsage(bm.usage) + "."
+			}
+			fmt.Println(sentence)
+		}
+		return
+	}
+
+	// Tree format: bookmarks grouped under their shared path prefixes
+	if flags.Tree {
+		home, _ := os.UserHomeDir()
+		var placed []treeBookmark
+		for _, bm := range bookmarks {
+			label := bm.name
+			if bm.broken {
+				label = colorRed + bm.name + colorReset
+			}
+			placed = append(placed, treeBookmark{label: label, path: marks.TargetPath(config, bm.target)})
+		}
+		for _, line := range pathTreeLines(buildPathTree(placed, home)) {
+			fmt.Println(line)
+		}
+		return
+	}
+
+	// Long format: usage, creation date, status and tags in aligned columns
+	if flags.Long {
+		changes, err := loadChanges()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		created := createdTimes(changes)
+
+		tagsWidth := len("TAGS")
+		tags := make(map[string]string)
+		for _, bm := range bookmarks {
+			tags[bm.name] = "-"
+			if m := meta[bm.name]; m != nil && len(m.Tags) > 0 {
+				tags[bm.name] = strings.Join(m.Tags, ",")
+			}
+			tagsWidth = max(tagsWidth, len(tags[bm.name]))
+		}
+
+		fmt.Printf("  %-20s %5s  %-16s  %-10s  %-6s  %-*s  %s\n", "NAME", "USES", "LAST USED", "CREATED", "STATUS", tagsWidth, "TAGS", "TARGET")
+		for _, bm := range bookmarks {
+			status, target := "ok", bm.target
+			if marks.IsURLTarget(bm.target) {
+				status = "url"
+			} else if marks.IsCommandTarget(bm.target) {
+				status = "cmd"
+			}
+			if bm.broken {
+				status = colorRed + "broken" + colorReset
+				target = colorRed + bm.target + colorReset
+			}
+			if bm.shared {
+				target += " (shared)"
+			}
+
+			createdAt := "-"
+			if t := creationTime(config, bm.name, meta[bm.name], created, bm.shared); !t.IsZero() {
+				createdAt = t.Local().Format("2006-01-02")
+			}
+
+			u := bm.usage
+			fmt.Printf("  %-20s %5d  %-16s  %-10s  %-6s  %-*s  %s\n", bm.name, u.Count, formatLastUsed(u.LastUsed), createdAt, status, tagsWidth, tags[bm.name], target)
+		}
+		return
+	}
+
 	// Print bookmarks with aligned arrows
 	for _, bm := range bookmarks {
+		// Bookmarks from read-only sources are annotated after the target
+		description := ""
+		if bm.shared {
+			description = " (shared)"
+		}
+		if bm.description != "" {
+			description += "  # " + bm.description
+		}
+
 		if bm.broken {
-			fmt.Printf("  %-20s -> [%sbroken%s] %s%s%s\n", bm.name, colorRed, colorReset, colorRed, bm.target, colorReset)
+			fmt.Printf("  %-20s -> [%sbroken%s] %s%s%s%s\n", bm.name, colorRed, colorReset, colorRed, bm.target, colorReset, description)
+		} else if marks.IsURLTarget(bm.target) {
+			fmt.Printf("  %-20s -> [url] %s%s\n", bm.name, bm.target, description)
+		} else if marks.IsCommandTarget(bm.target) {
+			fmt.Printf("  %-20s -> [cmd] %s%s\n", bm.name, bm.target, description)
 		} else {
-			fmt.Printf("  %-20s -> %s\n", bm.name, bm.target)
+			fmt.Printf("  %-20s -> %s%s\n", bm.name, bm.target, description)
 		}
 	}
 }
 
-func deleteBookmark(config Config, name string) {
+// spokenPath spells out path separators so screen readers announce them
+// ("/home/me" -> "slash home slash me")
+func spokenPath(path string) string {
+	var words []string
+	for i, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if i > 0 {
+			words = append(words, "slash")
+		}
+		if part != "" {
+			words = append(words, part)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// screenReaderSentence describes one bookmark as a complete sentence without
+// columns, arrows or color
+func screenReaderSentence(name, target string, broken bool, description string) string {
+	status := "ok"
+	if broken {
+		status = "broken, the target does not exist"
+	}
+
+	sentence := fmt.Sprintf("bookmark %s points to %s, status %s", name, spokenPath(target), status)
+	if marks.IsURLTarget(target) {
+		sentence = fmt.Sprintf("bookmark %s opens the web address %s", name, target)
+	}
+	if description != "" {
+		sentence += ", description: " + description
+	}
+	return sentence + "."
+}
+
+// spokenUsage describes usage statistics in words
+func spokenUsage(u usageSummary) string {
+	switch u.Count {
+	case 0:
+		return "never used"
+	case 1:
+		return "used once, last on " + formatLastUsed(u.LastUsed)
+	default:
+		return fmt.Sprintf("used %d times, last on %s", u.Count, formatLastUsed(u.LastUsed))
+	}
+}
+
+// deleteBookmark removes a bookmark. At a terminal it first shows the target
+// and asks for confirmation, unless assumeYes (-y/--yes) is set.
+func deleteBookmark(config Config, name string, assumeYes, dryRun bool) {
 	if name == "" {
 		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for -d flag\n")
 		os.Exit(1)
 	}
 
-	symlinkPath := filepath.Join(config.MarksDir, name)
+	storage := openStorage(config)
+	bookmark := lookupBookmark(storage, name)
+	if dryRun {
+		fmt.Printf("Would remove bookmark '%s' -> %s\n", name, marks.TargetPath(config, bookmark.Target))
+		return
+	}
+	if !assumeYes && stdinIsTerminal() {
+		if !confirmDeletion(bufio.NewReader(os.Stdin), name, marks.TargetPath(config, bookmark.Target)) {
+			fmt.Printf("Kept bookmark '%s'\n", name)
+			return
+		}
+	}
 
-	// Check if bookmark exists
-	fileInfo, err := os.Lstat(symlinkPath)
+	trashed, err := removeBookmark(config, storage, bookmark)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' does not exist\n", name)
-			os.Exit(1)
-		}
-		fmt.Fprintf(os.Stderr, "Error accessing bookmark: %v\n", err)
-		os.Exit(1)
+		exitBookmarkError(name, err)
 	}
 
-	// Verify it's a symlink
-	if fileInfo.Mode()&os.ModeSymlink == 0 {
-		fmt.Fprintf(os.Stderr, "Error: '%s' is not a bookmark (not a symlink)\n", name)
-		os.Exit(1)
+	if trashed {
+		fmt.Printf("✓ Removed bookmark '%s' (restore with 'mark restore %s')\n", name, name)
+		return
 	}
+	fmt.Printf("✓ Removed bookmark '%s'\n", name)
+}
 
-	// Remove the symlink
-	if err := os.Remove(symlinkPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error removing bookmark: %v\n", err)
-		os.Exit(1)
+// removeBookmark deletes a bookmark from storage along with its metadata,
+// keeping both in the trash for 'mark restore' when trash_days allows. It
+// reports whether the bookmark went to the trash.
+func removeBookmark(config Config, storage Storage, bookmark Bookmark) (bool, error) {
+	name := bookmark.Name
+
+	// Keep the bookmark and its metadata in the trash for 'mark restore'
+	trashed := false
+	if trashDays(config) > 0 {
+		var meta *Metadata
+		if all, err := marks.LoadMetadata(config.MarksDir); err == nil {
+			meta = all[name]
+		}
+		if err := moveToTrash(config, bookmark, meta); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			trashed = true
+		}
 	}
 
-	fmt.Printf("✓ Removed bookmark '%s'\n", name)
+	// Remove the bookmark from storage
+	if err := storage.Delete(name); err != nil {
+		return false, err
+	}
+
+	// Drop any metadata recorded for the bookmark
+	if err := marks.UpdateMetadata(config.MarksDir, name, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	_ = recordChange("delete", name)
+	_ = recordAudit(config, "delete", name, marks.TargetPath(config, bookmark.Target))
+	return trashed, nil
 }
 
-func jumpBookmark(config Config, name string) {
+func jumpBookmark(config Config, flags *ParsedFlags) {
+	name := flags.Jump
 	if name == "" {
 		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for -j flag\n")
 		os.Exit(1)
 	}
 
-	symlinkPath := filepath.Join(config.MarksDir, name)
-
-	// Check if bookmark exists
-	fileInfo, err := os.Lstat(symlinkPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' does not exist\n", name)
+	// 'mark -j -' returns to where the last jump started, like 'cd -'
+	if name == "-" {
+		events, err := loadJumpHistory()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Fprintf(os.Stderr, "Error accessing bookmark: %v\n", err)
+		dir, ok := previousLocation(events)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: No previous location in the jump history\n")
+			os.Exit(1)
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: Previous location no longer exists: %s\n", dir)
+			os.Exit(1)
+		}
+		_ = recordJump(dir)
+		printJumpTarget(dir, flags)
+		return
+	}
+
+	// The jump wrapper passes --dir-fallback so ordinary paths work too
+	if flags.DirFallback && config.JumpDirFallback != "off" {
+		if dir, ok := directoryFallback(openLayeredStorage(config), name); ok {
+			_ = recordJump(dir)
+			printJumpTarget(dir, flags)
+			return
+		}
+	}
+
+	// --on-broken overrides on_broken from the config for this jump
+	if flags.OnBroken != "" {
+		config.OnBroken = flags.OnBroken
+	}
+
+	targetPath := resolveJumpTarget(config, name, flags.Raw)
+
+	// Record the jump for usage tracking; failures must never block the jump.
+	// A subpath jump ('proj/src') counts towards its bookmark.
+	if strings.Contains(name, "/") {
+		name, _ = splitSubpath(openLayeredStorage(config), name)
+	}
+	_ = recordUsage(name, targetPath)
+	_ = recordAudit(config, "jump", name, targetPath)
+
+	// Inside a container the same directory is bind-mounted elsewhere; only
+	// local jumps go into the history for 'mark -j -'
+	if flags.InContainer != "" {
+		targetPath = remapPath(targetPath, config.ContainerPathMap)
+	} else {
+		_ = recordJump(targetPath)
+	}
+
+	printJumpTarget(targetPath, flags)
+}
+
+// printJumpTarget prints the jump target to stdout for the shell function to
+// capture and, with --handoff, also leaves it in the handoff file
+func printJumpTarget(path string, flags *ParsedFlags) {
+	if flags.Handoff {
+		if err := writeHandoff(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	fmt.Println(path)
+}
+
+// directoryFallback returns the absolute path of arg when it is not a
+// bookmark but names an existing directory. Only plain names are looked up
+// in storage: .. or ../work can never be bookmarks, and a marks directory
+// would resolve them to paths outside itself.
+func directoryFallback(storage Storage, arg string) (string, bool) {
+	if isPlainName(arg) {
+		if _, err := storage.Get(arg); !errors.Is(err, marks.ErrNotFound) && !errors.Is(err, marks.ErrNotBookmark) {
+			return "", false
+		}
+	} else if _, subpath := splitSubpath(storage, arg); subpath != "" {
+		return "", false
+	}
+	dir := marks.ExpandPath(normalizeTargetArg(arg))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	return dir, true
+}
+
+// defaultsToJump reports whether 'mark <name>' should jump to name rather
+// than create it: default_action=jump is set and the bookmark exists
+func defaultsToJump(config Config, name string) bool {
+	switch config.DefaultAction {
+	case "", "create":
+		return false
+	case "jump":
+		_, err := openLayeredStorage(config).Get(name)
+		return err == nil
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown default_action '%s' (supported: create, jump)\n", config.DefaultAction)
+		os.Exit(exitConfig)
+		return false
+	}
+}
+
+// printDefaultJump prints where 'mark <name>' jumps when default_action=jump
+// makes it a jump, so the shell function can cd there; it prints nothing
+// when mark would do something else, and the function then runs mark
+// itself. Without a config there is nothing to jump to.
+func printDefaultJump(flags *ParsedFlags, args []string) {
+	if len(args) != 1 || (!flags.Literal && subcommands[args[0]] != nil) {
+		return
+	}
+	config, err := marks.LoadConfig()
+	if err != nil {
+		debugf("--default-jump: %v", err)
+		return
+	}
+	if config, err = applyProfile(config, profileName(config, flags.Profile)); err != nil {
+		debugf("--default-jump: %v", err)
+		return
+	}
+	config = marks.ApplyMarksDirEnv(config)
+	if config.DefaultAction != "jump" || (!flags.Literal && findPlugin(args[0]) != "") || !defaultsToJump(config, args[0]) {
+		return
+	}
+
+	flags.Jump = args[0]
+	jumpBookmark(config, flags)
+}
+
+// resolveJumpTarget returns the directory a bookmark leads to. A missing
+// target is handled according to on_broken; a file is always an error. With
+// raw, symlinks in the target are kept instead of resolved.
+func resolveJumpTarget(config Config, name string, raw bool) string {
+	storage := openLayeredStorage(config)
+	name, subpath := splitSubpath(storage, name)
+	bookmark := lookupBookmark(storage, name)
+	if !insideSubpath(subpath) {
+		fmt.Fprintf(os.Stderr, "Error: '%s' leaves bookmark '%s'\n", subpath, name)
 		os.Exit(1)
 	}
 
-	// Verify it's a symlink
-	if fileInfo.Mode()&os.ModeSymlink == 0 {
-		fmt.Fprintf(os.Stderr, "Error: '%s' is not a bookmark (not a symlink)\n", name)
+	if marks.IsURLTarget(bookmark.Target) {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' is a URL (%s), not a directory; use 'mark open %s'\n", name, bookmark.Target, name)
 		os.Exit(1)
 	}
 
-	// Resolve the symlink to get the actual target
-	targetPath, err := filepath.EvalSymlinks(symlinkPath)
+	if !validOnBroken(config.OnBroken) {
+		fmt.Fprintf(os.Stderr, "Error: Unknown on_broken mode '%s' (supported: %s)\n", config.OnBroken, strings.Join(onBrokenModes, ", "))
+		os.Exit(exitConfig)
+	}
+
+	// Resolve the target to get the actual directory: the one for this OS
+	// when the bookmark has one, then the fallback targets in order when it
+	// is missing
+	var m *Metadata
+	if !bookmark.Shared {
+		m = bookmarkMetadata(config, name)
+	}
+	target := marks.TargetPath(config, bookmark.Target)
+	if osPath, ok := marks.OSTarget(config, m); ok {
+		debugf("bookmark %s: using the %s target %s", name, runtime.GOOS, osPath)
+		target = osPath
+	} else if marks.IsCommandTarget(bookmark.Target) {
+		target = commandJumpTarget(config, name, bookmark.Target)
+	}
+	targetPath, err := filepath.EvalSymlinks(target)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' points to non-existent directory\n", name)
-		os.Exit(1)
+		if fallback, ok := marks.ActiveFallback(config, m); ok {
+			debugf("bookmark %s: %s is missing, using fallback %s", name, target, fallback)
+			target = fallback
+			targetPath, err = filepath.EvalSymlinks(fallback)
+		}
+	}
+	if err != nil {
+		debugf("bookmark %s: stored target %s does not evaluate: %v", name, bookmark.Target, err)
+		return brokenJumpTarget(config, bookmark, target, config.OnBroken)
 	}
 
+	debugf("bookmark %s: stored target %s evaluates to %s", name, bookmark.Target, targetPath)
+
 	// Verify target is a directory
 	targetInfo, err := os.Stat(targetPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' points to non-existent directory\n", name)
-		os.Exit(1)
+		return brokenJumpTarget(config, bookmark, target, config.OnBroken)
 	}
 
 	if !targetInfo.IsDir() {
 		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' points to a file, not a directory\n", name)
-		os.Exit(1)
+		os.Exit(exitBroken)
+	}
+
+	// --raw lands on the bookmarked path itself, even when it is a symlink
+	if raw {
+		targetPath = target
+	}
+
+	if subpath != "" {
+		dir := filepath.Join(targetPath, subpath)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: '%s' is not a directory under bookmark '%s'\n", subpath, name)
+			os.Exit(1)
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && !raw {
+			dir = resolved
+		}
+		return dir
+	}
+
+	return targetPath
+}
+
+// splitSubpath splits 'name/sub/dir' into a bookmark name and a path below
+// its target. arg is split on its first slash, and only when the part before
+// it is a bookmark; otherwise the subpath is empty. Names with a slash never
+// reach storage, where a marks directory would resolve 'beta/../a' to 'a'.
+func splitSubpath(storage Storage, arg string) (string, string) {
+	name, subpath, ok := strings.Cut(arg, "/")
+	if !ok || !isPlainName(name) {
+		return arg, ""
+	}
+	if _, err := storage.Get(name); err != nil {
+		return arg, ""
+	}
+	return name, subpath
+}
+
+// insideSubpath reports whether subpath stays below the bookmark's target:
+// relative and without ".." components, which would climb out of it
+func insideSubpath(subpath string) bool {
+	if filepath.IsAbs(subpath) || strings.HasPrefix(subpath, "/") {
+		return false
+	}
+	for _, part := range strings.FieldsFunc(subpath, func(r rune) bool { return r == '/' || r == filepath.Separator }) {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlainName reports whether arg can be a bookmark name on its own: not
+// empty, "." or "..", and without path separators
+func isPlainName(arg string) bool {
+	return marks.CheckName(arg) == nil
+}
+
+// lookupBookmark fetches a bookmark from storage, exiting if it is unavailable
+func lookupBookmark(storage Storage, name string) Bookmark {
+	bookmark, err := storage.Get(name)
+	if err != nil {
+		exitBookmarkError(name, err)
 	}
+	return bookmark
+}
 
-	// Print the target path to stdout (for shell function to capture)
-	fmt.Println(targetPath)
+// exitBookmarkError reports a storage error for the named bookmark and exits
+func exitBookmarkError(name string, err error) {
+	switch {
+	case errors.Is(err, marks.ErrNotFound):
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' does not exist\n", name)
+		os.Exit(exitNotFound)
+	case errors.Is(err, marks.ErrNotBookmark):
+		fmt.Fprintf(os.Stderr, "Error: '%s' is not a bookmark (not a symlink)\n", name)
+		os.Exit(exitNotFound)
+	case errors.Is(err, marks.ErrInvalidName):
+		fmt.Fprintf(os.Stderr, "Error: '%s' is not a valid bookmark name\n", name)
+		os.Exit(exitNotFound)
+	default:
+		fmt.Fprintf(os.Stderr, "Error accessing bookmark: %v\n", err)
+	}
+	os.Exit(1)
 }
 
 // ParsedFlags represents parsed command line flags
 type ParsedFlags struct {
-	List         bool
-	Delete       string
-	Jump         string
-	Config       bool
-	Autocomplete bool
-	Alias        bool
-	Help         bool
-	Version      bool
+	List            bool
+	Delete          string
+	Jump            string
+	Config          bool
+	Autocomplete    bool
+	Alias           bool
+	CheckNames      bool
+	Help            bool
+	Version         bool
+	Long            bool
+	Tree            bool // with -l, group bookmarks under shared path prefixes
+	ScreenReader    bool
+	Tag             string
+	Desc            string
+	Host            string
+	OnlyOn          string // with create, comma-separated hostname patterns the bookmark is active on
+	InContainer     string
+	Shell           string
+	Sort            string
+	OnBroken        string
+	LinkStyle       string
+	Profile         string
+	Defaults        string
+	MarksDir        string // setup answer for the marks directory
+	Yes             bool   // skip delete confirmation; answer yes to setup questions
+	NoAlias         bool
+	NoCompletion    bool
+	Pin             bool
+	GitRoot         bool // create: bookmark the root of the git repository containing the cwd
+	Suffix          bool // create: on a name collision use the first free name-2, name-3, ...
+	DirFallback     bool // with -j, accept a plain directory path that is not a bookmark
+	Handoff         bool // with -j, also write the target to the handoff file
+	Raw             bool // keep symlinks in the target unresolved when creating, with -j or exec
+	DryRun          bool // print what create, delete or import would change without changing it
+	Verbose         bool // trace config resolution, path handling and file writes to stderr
+	Literal         bool // '--' seen before any argument; never dispatch subcommands
+	CompleteCreate  bool
+	CompleteTags    bool
+	CompleteChooser bool
+	CompleteSubpath bool
+	RecordVisit     bool // called by the track_visits shell hook after each cd
+	DefaultJump     bool // called by the mark shell function for a lone name
+	FirstArg        int  // index in the parsed arguments of the first regular argument; -1 when there is none
 }
 
 // parseFlags implements Unix-like flag parsing
 func parseFlags(args []string) (*ParsedFlags, []string) {
-	flags := &ParsedFlags{}
+	flags := &ParsedFlags{FirstArg: -1}
 	var remainingArgs []string
+	addArg := func(i int) {
+		if len(remainingArgs) == 0 {
+			flags.FirstArg = i
+		}
+		remainingArgs = append(remainingArgs, args[i])
+	}
 
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 
-		if arg == "--help" {
+		if arg == "--" {
+			// End of flags: everything after is a regular argument
+			if len(remainingArgs) == 0 {
+				flags.Literal = true
+			}
+			for j := i + 1; j < len(args); j++ {
+				addArg(j)
+			}
+			break
+		} else if arg == "--help" {
 			flags.Help = true
 		} else if arg == "--version" {
 			flags.Version = true
@@ -676,9 +1618,53 @@ func parseFlags(args []string) (*ParsedFlags, []string) {
 			flags.Autocomplete = true
 		} else if arg == "--alias" {
 			flags.Alias = true
+		} else if arg == "--check-names" {
+			flags.CheckNames = true
+		} else if arg == "--long" {
+			flags.Long = true
+		} else if arg == "--tree" {
+			flags.Tree = true
+		} else if arg == "--screen-reader" {
+			flags.ScreenReader = true
+		} else if arg == "--pin" {
+			flags.Pin = true
+		} else if arg == "--git-root" {
+			flags.GitRoot = true
+		} else if arg == "--suffix" {
+			flags.Suffix = true
+		} else if arg == "--dir-fallback" {
+			flags.DirFallback = true
+		} else if arg == "--handoff" {
+			flags.Handoff = true
+		} else if arg == "--raw" {
+			flags.Raw = true
+		} else if arg == "--dry-run" {
+			flags.DryRun = true
+		} else if arg == "--verbose" {
+			flags.Verbose = true
+		} else if arg == "--yes" {
+			flags.Yes = true
+		} else if arg == "--no-alias" {
+			flags.NoAlias = true
+		} else if arg == "--no-completion" {
+			flags.NoCompletion = true
+		} else if arg == "--complete-create" {
+			flags.CompleteCreate = true
+		} else if arg == "--complete-tags" {
+			flags.CompleteTags = true
+		} else if arg == "--complete-chooser" {
+			flags.CompleteChooser = true
+		} else if arg == "--complete-subpath" {
+			flags.CompleteSubpath = true
+		} else if arg == "--record-visit" {
+			flags.RecordVisit = true
+		} else if arg == "--default-jump" {
+			flags.DefaultJump = true
+		} else if next, ok := parseValueFlag(flags, args, i); ok {
+			i = next
 		} else if strings.HasPrefix(arg, "--") {
 			// Unknown long flag, treat as regular argument
-			remainingArgs = append(remainingArgs, arg)
+			addArg(i)
 		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
 			// Handle short flags
 			flagChars := arg[1:] // Remove the '-' prefix
@@ -689,14 +1675,16 @@ func parseFlags(args []string) (*ParsedFlags, []string) {
 					flags.Version = true
 				case 'h':
 					flags.Help = true
+				case 'y':
+					flags.Yes = true
 				case 'l':
 					flags.List = true
 				case 'd':
 					// -d requires an argument
 					if j == len(flagChars)-1 {
 						// -d is the last flag, next arg is the bookmark name
-						if i+1 < len(args) {
-							i++
+						i = skipValueFlags(flags, args, i+1)
+						if i < len(args) {
 							flags.Delete = args[i]
 						} else {
 							fmt.Fprintf(os.Stderr, "Error: -d flag requires a bookmark name\n")
@@ -710,8 +1698,8 @@ func parseFlags(args []string) (*ParsedFlags, []string) {
 					// -j requires an argument
 					if j == len(flagChars)-1 {
 						// -j is the last flag, next arg is the bookmark name
-						if i+1 < len(args) {
-							i++
+						i = skipValueFlags(flags, args, i+1)
+						if i < len(args) {
 							flags.Jump = args[i]
 						} else {
 							fmt.Fprintf(os.Stderr, "Error: -j flag requires a bookmark name\n")
@@ -728,13 +1716,123 @@ func parseFlags(args []string) (*ParsedFlags, []string) {
 			}
 		} else {
 			// Regular argument
-			remainingArgs = append(remainingArgs, arg)
+			addArg(i)
 		}
 	}
 
 	return flags, remainingArgs
 }
 
+// parseValueFlag handles long flags that take a value, returning the index of
+// the value argument and whether args[i] was such a flag
+func parseValueFlag(flags *ParsedFlags, args []string, i int) (int, bool) {
+	switch args[i] {
+	case "--tag":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Tag = args[i+1]
+		return i + 1, true
+	case "--desc":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Desc = args[i+1]
+		return i + 1, true
+	case "--host":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Host = args[i+1]
+		return i + 1, true
+	case "--only-on":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.OnlyOn = args[i+1]
+		return i + 1, true
+	case "--in-container":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.InContainer = args[i+1]
+		return i + 1, true
+	case "--shell":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Shell = args[i+1]
+		return i + 1, true
+	case "--defaults":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Defaults = args[i+1]
+		return i + 1, true
+	case "--marksdir":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.MarksDir = args[i+1]
+		return i + 1, true
+	case "--profile":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Profile = args[i+1]
+		return i + 1, true
+	case "--on-broken":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.OnBroken = args[i+1]
+		return i + 1, true
+	case "--link-style":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.LinkStyle = args[i+1]
+		return i + 1, true
+	case "--sort":
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s flag requires a value\n", args[i])
+			os.Exit(1)
+		}
+		flags.Sort = args[i+1]
+		return i + 1, true
+	}
+	if value, ok := strings.CutPrefix(args[i], "--sort="); ok {
+		flags.Sort = value
+		return i, true
+	}
+	return i, false
+}
+
+// skipValueFlags consumes any value flags starting at index i (so that
+// 'mark -j --tag work name' still finds the name) and returns the index
+// of the next unconsumed argument
+func skipValueFlags(flags *ParsedFlags, args []string, i int) int {
+	for i < len(args) {
+		next, ok := parseValueFlag(flags, args, i)
+		if !ok {
+			break
+		}
+		i = next + 1
+	}
+	return i
+}
+
 // RunAliasSetup handles the standalone alias setup flow
 func RunAliasSetup() {
 	fmt.Println("mark - Shell Alias Setup")
@@ -751,10 +1849,8 @@ func RunAliasSetup() {
 		return
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-
 	// Use the existing setupAliases function for the core logic
-	setupAliases(reader)
+	setupAliases(newSetupPrompter())
 }
 
 func printVersion() {
@@ -768,19 +1864,175 @@ USAGE:
   mark                 Create bookmark with current directory name
   mark <name>          Create bookmark with custom name
   mark <name> <path>   Create bookmark pointing to custom path
+  mark <name> <path> <fallback>...
+                       Also jump to the first existing fallback when <path>
+                       is missing (e.g. a mount on another machine)
+  mark <name> '<dir>/snap-*'
+                       Jump to the most recently modified matching directory
+  mark <name> '!<command>'
+                       Jump to the directory the command prints, run at jump
+                       time (needs dynamic_targets=on; see dynamic_timeout)
+  mark -- <name>       Create bookmark whose name matches a command
+  mark <command> [ARGS]
   mark [OPTIONS]
 
+COMMANDS:
+  bench init [--shell <sh>] [--runs N]
+                       Measure the shell startup time added by mark's snippet
+  breadcrumb [path]    Print path through the nearest bookmark (work:src/api),
+                       or with ~ for home, for PS1/starship prompts
+  cdpath [--parents]   Print a CDPATH so plain 'cd name' reaches bookmarks:
+                       export CDPATH="$(mark cdpath)"; --parents lists
+                       the targets' parent directories instead
+  completion <shell> | install --system|--user [--prefix <dir>] [<shell>...]
+                       Print a completion script, or install completion files
+                       into the system (below --prefix, default /usr, and
+                       $DESTDIR) or per-user completion directories
+  config get <key> | set <key> <value> | list
+                       Read or change settings in ~/.mark without the wizard
+  dedupe               Find bookmarks resolving to the same directory, keep one
+                       name and remove the others or make them aliases of it
+  diff <manifest|marks-dir>
+                       Compare bookmarks with a 'mark export' JSON/CSV file or
+                       another marks directory (a synced copy, a backup)
+                       (+ only in manifest, - only here, ~ different target)
+  edit                 Edit all bookmarks (name, target, tags, description) as
+                       text in $VISUAL or $EDITOR and apply the changes on save
+  edit-dir <name>      Open the bookmark's directory in $VISUAL or $EDITOR
+  exec <name> [-- <command>]
+                       Run a command (default: sh) in the bookmark directory,
+                       inside a container with --in-container <id>
+  explain <name>       Show how a bookmark resolves, hop by hop
+  export [--format json|csv|toml|script] [file]
+                       Write bookmarks and metadata to stdout or a file;
+                       'script' emits mark commands that re-create them
+  fallback <name> [<path>...|--clear]
+                       Show or set the targets -j tries in order when the
+                       bookmark's own target is missing
+  history [N]          List the last N places jumped to (default 20)
+  import <tool> [--file <path>] [--prefix <prefix>]
+                       Import bookmarks from another tool (lf, mc, nnn, ranger,
+                       z); --prefix namespaces the names (work: gives work:src)
+  init [<shell>] [--lazy] [--hash-dirs]
+                       Print shell integration for eval; --lazy defers loading;
+                       --hash-dirs (zsh) makes bookmarks named directories (~name)
+  maintain [--max-age <age>] [--delete]
+                       List bookmarks not used within max_age (e.g. 180d, 26w,
+                       1y); --delete moves them to the trash after confirming
+  menu --rofi|--dmenu|--list [--tag <tag>]
+                       Pick a bookmark in rofi or dmenu and print its path
+                       (for window-manager hotkeys); --list prints the lines
+  merge <other-dir> [--strategy mine|theirs|rename]
+                       Fold another marks directory (a backup, another
+                       machine's) into this one, asking per name clash
+  only-on <name> [<pattern>...|--clear]
+                       Show or set the hostnames a bookmark is active on
+  open <name>          Open a URL bookmark in the browser ($BROWSER or the
+                       desktop default), or a directory in the file manager
+  os-target <name> [<os>=<path>...|--clear]
+                       Show or set per-OS targets (linux, macos, windows, ...)
+                       used instead of the stored one on that OS
+  recent [N]           List the last N bookmarks jumped to (default 10)
+  repair [<name>]      Find where broken bookmarks' targets were moved or
+                       renamed (below repair_root, default ~) and repoint them
+  report [--month YYYY-MM] [--json]
+                       Summarize a month of local usage (jumps, top bookmarks)
+  restore <name>       Bring back a deleted bookmark from the trash
+  scan <root> [--depth N] [--git-only]
+                       Find projects (git repositories, go.mod, package.json,
+                       ...) below <root> and offer to bookmark them; --yes
+                       creates them all
+  serve [--listen <addr>] [--write] [--token <token>]
+                       Serve bookmarks as a local HTTP JSON API (default
+                       127.0.0.1:7457); --write allows creating and deleting
+  shell <name>         Start $SHELL in the bookmark's directory (no jump
+                       function needed); exit to return
+  show <name>          Show a bookmark's target, resolved path, status,
+                       metadata and recent jumps
+  ssh <name> [--host <host>]
+                       Open an SSH session in the bookmark's remote path
+  stats [--json]       Count bookmarks and broken ones, list the most and least
+                       used, and chart jumps per day over the last 30 days
+  suggest [--from-history] [--limit N]
+                       Offer to bookmark directories you cd into most often,
+                       from the track_visits log or your shell history
+  tidy-names           Rename bookmarks to follow the configured name_policy
+  tidy-targets         Rewrite stored targets in clean form (no trailing
+                       slashes or '..'), for bookmarks from older versions
+  top [--interval N] [--limit N] [--once]
+                       Live view of the most-jumped bookmarks today and this week
+  trash [list|empty]   Show or empty deleted bookmarks kept for 'restore'
+                       (trash_days in ~/.mark, default 30)
+  tui                  Full-screen dashboard: search (/), create (n), rename (r),
+                       delete (d), tag (t); Enter prints the path for
+                       cd "$(mark tui)"
+  tutorial             Guided tour of creating, listing, jumping to and
+                       deleting bookmarks, in a sandbox
+  uninstall [--purge]  Remove shell integration, startup-file lines and ~/.mark;
+                       --purge (or answering yes) also deletes bookmarks
+  which [path]         Print the bookmarks whose target is or contains path
+                       (default: current directory) as name/rest; --first
+                       prints only the closest one, exits 2 if none
+  why-broken <name>    Diagnose why a bookmark target cannot be reached
+
 OPTIONS:
   -l                   List all bookmarks
   -d <name>            Delete bookmark
-  -j <name>            Jump to bookmark (prints path)
+  -j <name>            Jump to bookmark (prints path); '-j -' returns to where
+                       the last jump started
   -h                   Show this help message
   -v                   Print version number
 
   --help               Show this help message
   --config, --configure  Run setup/reconfigure
+  --defaults <file>    Answer setup questions from a file (marksdir=,
+                       completion=yes|no, aliases=yes|no)
+  -y, --yes            Delete without asking for confirmation; with scan,
+                       bookmark every project found; with setup, accept the
+                       default location and set up completion and aliases
+                       without prompting
+  --dry-run            With create, -d, import, scan or suggest, print what would
+                       change without changing anything
+  --verbose            Trace config resolution, path expansion, symlink
+                       evaluation and written files to stderr (or MARK_DEBUG=1)
+  --marksdir <path>    With setup, store bookmarks in <path>
+  --no-completion      With setup, skip command line completion
+  --no-alias           With setup, skip shell aliases
   --autocomplete       Setup/update command line autocompletion
   --alias              Setup/update shell aliases
+  --check-names        Find names that break the shell integration (quotes,
+                       spaces, globs) and offer to rename them
+  --long               With -l, show use count, last-used and creation dates,
+                       status and tags
+  --tree               With -l, group bookmarks by common path prefix
+  --screen-reader      With -l, describe each bookmark in a plain sentence
+  --tag <tags>         Tag a new bookmark (comma-separated) or filter -l by tag
+  --desc <text>        Describe a new bookmark (shown in -l and completion)
+  --host <host>        Record the SSH host of a new bookmark (or override it for ssh)
+  --only-on <patterns> Make a new bookmark active only on matching hostnames
+                       (comma-separated globs, e.g. laptop,build-*)
+  --in-container <id>  With -j or exec, use the path inside a dev container
+  --sort <keys>        With -l, order by comma-separated keys applied in turn:
+                       pinned, frecency, uses, recent, name, target
+  --pin                Pin a new bookmark (sorted first by the 'pinned' key)
+  --git-root           Bookmark the root of the git repository containing the
+                       current directory (named after it unless a name is given)
+  --suffix             When a new bookmark's name is taken, use the first free
+                       name-2, name-3, ... instead of asking
+  --dir-fallback       With -j, print an existing directory that is not a bookmark
+  --handoff            With -j, also write the target to
+                       $XDG_RUNTIME_DIR/mark/last-path for other programs
+  --raw                Keep symlinks unresolved: when creating, bookmark a
+                       symlinked directory as itself; with -j or exec, use the
+                       target as bookmarked (jump then lands on the symlink)
+  --on-broken <mode>   With -j, handle a missing target: fail, ancestor (jump to
+                       the nearest existing parent) or repair (ask for a new one)
+  --link-style <style> Store new targets as absolute paths (default), relative
+                       to the marks directory, or home (~/...)
+  --profile <name>     Use the marks directory of a profile from ~/.mark
+                       (default: $MARK_PROFILE, then a workspace rule
+                       matching the current directory, else marksdir)
+  --shell <name>       Use this shell (bash, zsh, fish) instead of detecting it
   --version            Print version number
 
 EXAMPLES:
@@ -788,6 +2040,12 @@ EXAMPLES:
   mark downloads       Create bookmark 'downloads' pointing to current dir
   mark work ~/work     Create bookmark 'work' pointing to ~/work
   mark tmp /tmp        Create bookmark 'tmp' pointing to /tmp
+  mark work ~/work --tag client,billing
+                       Create bookmark 'work' tagged 'client' and 'billing'
+  mark -l --tag client List bookmarks tagged 'client'
+  mark -l --long       List bookmarks with usage statistics
+  mark api --desc "client portal repo"
+                       Create bookmark 'api' with a description
   mark -l              List all bookmarks with their targets
   mark -d downloads    Delete the 'downloads' bookmark
   mark -j projects     Print path to 'projects' bookmark
@@ -804,6 +2062,21 @@ CONFIGURATION:
   Bookmarks are stored in ~/.marks/ as symbolic links
   Use 'mark --config' to reconfigure
 
+PLUGINS:
+  'mark foo' runs mark-foo from PATH when foo is not a builtin command, with
+  the rest of the command line and MARK_BIN, MARK_CONFIG, MARK_DIR,
+  MARK_PROFILE, MARK_STORAGE and MARK_STATE_DIR exported. A plugin name
+  takes precedence over creating a bookmark; 'mark -- foo' still creates one.
+
+EXIT STATUS:
+  0  Success
+  1  Any other failure ('mark diff' also uses it for differences)
+  2  Bookmark not found
+  3  Bookmark target missing or not a directory
+  4  Bookmark name already taken
+  5  Invalid config file or setting value
+  6  'mark diff' could not compare (usage or read error)
+
 RELEASE:
      Version:    ` + Version + `
   Build Date:    ` + BuildDate + `
@@ -816,8 +2089,16 @@ LICENSE:
 For more information, see: https://github.com/brockers/mark`)
 }
 
+// shellOverride is set by --shell to bypass detection, e.g. when $SHELL is
+// a custom wrapper
+var shellOverride string
+
 // detectShell detects the current shell from environment variables
 func detectShell() string {
+	if shellOverride != "" {
+		return shellOverride
+	}
+
 	shell := os.Getenv("SHELL")
 	if shell == "" {
 		return ""
@@ -18,10 +18,25 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
 )
 
 func TestExpandPath(t *testing.T) {
@@ -54,9 +69,9 @@ func TestExpandPath(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := expandPath(tt.input)
+			result := marks.ExpandPath(tt.input)
 			if result != tt.expected {
-				t.Errorf("expandPath(%q) = %q, want %q", tt.input, result, tt.expected)
+				t.Errorf("marks.ExpandPath(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
 		})
 	}
@@ -157,6 +172,125 @@ func TestParseFlags(t *testing.T) {
 			},
 			expectedArgs: []string{},
 		},
+		{
+			name: "complete-create flag",
+			args: []string{"--complete-create"},
+			expectedFlags: &ParsedFlags{
+				CompleteCreate: true,
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "tag flag with create args",
+			args: []string{"work", "/tmp", "--tag", "client,billing"},
+			expectedFlags: &ParsedFlags{
+				Tag: "client,billing",
+			},
+			expectedArgs: []string{"work", "/tmp"},
+		},
+		{
+			name: "tag flag before jump name",
+			args: []string{"-j", "--tag", "client", "work"},
+			expectedFlags: &ParsedFlags{
+				Jump: "work",
+				Tag:  "client",
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "in-container flag with jump",
+			args: []string{"-j", "web", "--in-container", "devbox"},
+			expectedFlags: &ParsedFlags{
+				Jump:        "web",
+				InContainer: "devbox",
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "shell override",
+			args: []string{"--alias", "--shell", "zsh"},
+			expectedFlags: &ParsedFlags{
+				Alias: true,
+				Shell: "zsh",
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "hidden chooser flag",
+			args: []string{"--complete-chooser"},
+			expectedFlags: &ParsedFlags{
+				CompleteChooser: true,
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "host flag",
+			args: []string{"ssh", "api", "--host", "dev1"},
+			expectedFlags: &ParsedFlags{
+				Host: "dev1",
+			},
+			expectedArgs: []string{"ssh", "api"},
+		},
+		{
+			name: "desc flag",
+			args: []string{"api", "--desc", "client portal repo"},
+			expectedFlags: &ParsedFlags{
+				Desc: "client portal repo",
+			},
+			expectedArgs: []string{"api"},
+		},
+		{
+			name: "double dash forces literal names",
+			args: []string{"--", "explain", "-l"},
+			expectedFlags: &ParsedFlags{
+				Literal: true,
+			},
+			expectedArgs: []string{"explain", "-l"},
+		},
+		{
+			name: "sort keys with equals sign",
+			args: []string{"-l", "--sort=pinned,name"},
+			expectedFlags: &ParsedFlags{
+				List: true,
+				Sort: "pinned,name",
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "dir fallback before jump",
+			args: []string{"--dir-fallback", "-j", "../src"},
+			expectedFlags: &ParsedFlags{
+				Jump:        "../src",
+				DirFallback: true,
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "long list flag",
+			args: []string{"-l", "--long"},
+			expectedFlags: &ParsedFlags{
+				List: true,
+				Long: true,
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "screen reader list flag",
+			args: []string{"-l", "--screen-reader"},
+			expectedFlags: &ParsedFlags{
+				List:         true,
+				ScreenReader: true,
+			},
+			expectedArgs: []string{},
+		},
+		{
+			name: "git root flag with name",
+			args: []string{"--git-root", "api"},
+			expectedFlags: &ParsedFlags{
+				GitRoot: true,
+			},
+			expectedArgs: []string{"api"},
+		},
 		{
 			name:          "no flags with args",
 			args:          []string{"mybookmark"},
@@ -200,6 +334,42 @@ func TestParseFlags(t *testing.T) {
 			if flags.Alias != tt.expectedFlags.Alias {
 				t.Errorf("Alias flag mismatch: got %v, want %v", flags.Alias, tt.expectedFlags.Alias)
 			}
+			if flags.Long != tt.expectedFlags.Long {
+				t.Errorf("Long flag mismatch: got %v, want %v", flags.Long, tt.expectedFlags.Long)
+			}
+			if flags.ScreenReader != tt.expectedFlags.ScreenReader {
+				t.Errorf("ScreenReader flag mismatch: got %v, want %v", flags.ScreenReader, tt.expectedFlags.ScreenReader)
+			}
+			if flags.Tag != tt.expectedFlags.Tag {
+				t.Errorf("Tag flag mismatch: got %q, want %q", flags.Tag, tt.expectedFlags.Tag)
+			}
+			if flags.Desc != tt.expectedFlags.Desc {
+				t.Errorf("Desc flag mismatch: got %q, want %q", flags.Desc, tt.expectedFlags.Desc)
+			}
+			if flags.Host != tt.expectedFlags.Host {
+				t.Errorf("Host flag mismatch: got %q, want %q", flags.Host, tt.expectedFlags.Host)
+			}
+			if flags.InContainer != tt.expectedFlags.InContainer {
+				t.Errorf("InContainer flag mismatch: got %q, want %q", flags.InContainer, tt.expectedFlags.InContainer)
+			}
+			if flags.Shell != tt.expectedFlags.Shell {
+				t.Errorf("Shell flag mismatch: got %q, want %q", flags.Shell, tt.expectedFlags.Shell)
+			}
+			if flags.Sort != tt.expectedFlags.Sort {
+				t.Errorf("Sort flag mismatch: got %q, want %q", flags.Sort, tt.expectedFlags.Sort)
+			}
+			if flags.DirFallback != tt.expectedFlags.DirFallback {
+				t.Errorf("DirFallback flag mismatch: got %v, want %v", flags.DirFallback, tt.expectedFlags.DirFallback)
+			}
+			if flags.Literal != tt.expectedFlags.Literal {
+				t.Errorf("Literal flag mismatch: got %v, want %v", flags.Literal, tt.expectedFlags.Literal)
+			}
+			if flags.CompleteCreate != tt.expectedFlags.CompleteCreate {
+				t.Errorf("CompleteCreate flag mismatch: got %v, want %v", flags.CompleteCreate, tt.expectedFlags.CompleteCreate)
+			}
+			if flags.CompleteChooser != tt.expectedFlags.CompleteChooser {
+				t.Errorf("CompleteChooser flag mismatch: got %v, want %v", flags.CompleteChooser, tt.expectedFlags.CompleteChooser)
+			}
 
 			// Check remaining args
 			if len(args) != len(tt.expectedArgs) {
@@ -254,6 +424,90 @@ func TestConfigSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestParseConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".mark")
+	content := "marksdir=" + filepath.Join(tmpDir, "marks") + "\ncreate_completion=dirs\n"
+	os.WriteFile(configPath, []byte(content), 0644)
+
+	config, err := marks.ParseConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("marks.ParseConfigFile failed: %v", err)
+	}
+	if config.MarksDir != filepath.Join(tmpDir, "marks") {
+		t.Errorf("MarksDir = %q, want %q", config.MarksDir, filepath.Join(tmpDir, "marks"))
+	}
+	if config.CreateCompletion != "dirs" {
+		t.Errorf("CreateCompletion = %q, want %q", config.CreateCompletion, "dirs")
+	}
+
+	if _, err := marks.ParseConfigFile(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("Should return error for non-existent config")
+	}
+}
+
+func TestCreateCompletionCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		dir      string
+		existing map[string]bool
+		expected []string
+	}{
+		{
+			name:     "path components nearest first",
+			dir:      "/home/user/src/api",
+			existing: map[string]bool{},
+			expected: []string{"api", "src", "user", "home"},
+		},
+		{
+			name:     "existing bookmarks excluded",
+			dir:      "/home/user/src/api",
+			existing: map[string]bool{"api": true, "home": true},
+			expected: []string{"src", "user"},
+		},
+		{
+			name:     "spaces sanitized",
+			dir:      "/data/my project",
+			existing: map[string]bool{},
+			expected: []string{"my_project", "data"},
+		},
+		{
+			name:     "name_policy applied",
+			policy:   "spaces=dash,max_length=6",
+			dir:      "/data/my pr/Long Name",
+			existing: map[string]bool{"data": true},
+			expected: []string{"my-pr"},
+		},
+		{
+			name:     "name_policy normalizes before the existing check",
+			policy:   "lowercase,spaces=reject",
+			dir:      "/srv/My Docs/API",
+			existing: map[string]bool{"api": true},
+			expected: []string{"srv"},
+		},
+		{
+			name:     "root has no candidates",
+			dir:      "/",
+			existing: map[string]bool{},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseNamePolicy(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := createCompletionCandidates(policy, tt.dir, tt.existing)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("createCompletionCandidates(%q) = %v, want %v", tt.dir, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestDetectShell(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -296,6 +550,19 @@ func TestDetectShell(t *testing.T) {
 	}
 }
 
+func TestDetectShellOverride(t *testing.T) {
+	originalShell := os.Getenv("SHELL")
+	os.Setenv("SHELL", "/usr/local/bin/myshell-wrapper")
+	defer os.Setenv("SHELL", originalShell)
+
+	shellOverride = "fish"
+	defer func() { shellOverride = "" }()
+
+	if result := detectShell(); result != "fish" {
+		t.Errorf("detectShell() = %q, want %q", result, "fish")
+	}
+}
+
 // Integration-style tests for bookmark operations
 func TestGenerateBashRC(t *testing.T) {
 	tests := []struct {
@@ -357,6 +624,11 @@ func TestGenerateBashRC(t *testing.T) {
 				t.Error("Found aliases but not expected")
 			}
 
+			// Subcommand placeholder must always be filled in
+			if strings.Contains(content, "@MARK_COMMANDS@") {
+				t.Error("Unfilled subcommand placeholder in generated script")
+			}
+
 			// Check completions content
 			hasCompletions := strings.Contains(content, "_mark_complete()") && strings.Contains(content, "complete -F")
 			if tt.expectCompletions && !hasCompletions {
@@ -369,6 +641,28 @@ func TestGenerateBashRC(t *testing.T) {
 	}
 }
 
+func TestJumpHooksInGeneratedFunctions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mark.conf")
+	os.WriteFile(configPath, []byte("marksdir=/tmp/marks\npre_jump=echo \"$target\" >> /tmp/jumps\npost_jump=ls\n"), 0644)
+	t.Setenv("MARK_CONFIG", configPath)
+
+	for shell, content := range map[string]string{
+		"bash": generateBashRC("/usr/bin/mark", true, false),
+		"zsh":  generateZshRC("/usr/bin/mark", true, false),
+		"fish": generateFishRC("/usr/bin/mark", true, false),
+	} {
+		want := "        echo \"$target\" >> /tmp/jumps\n        cd \"$target\"\n        ls\n"
+		if !strings.Contains(content, want) {
+			t.Errorf("%s jump function lacks the hooks around cd:\n%s", shell, content)
+		}
+	}
+
+	t.Setenv("MARK_CONFIG", filepath.Join(t.TempDir(), "missing"))
+	if content := generateBashRC("/usr/bin/mark", true, false); !strings.Contains(content, "then\n        cd \"$target\"\n    fi") {
+		t.Errorf("jump function without hooks changed:\n%s", content)
+	}
+}
+
 func TestGenerateZshRC(t *testing.T) {
 	content := generateZshRC("/usr/bin/mark", true, true)
 
@@ -389,6 +683,9 @@ func TestGenerateZshRC(t *testing.T) {
 	}
 
 	// Check completions
+	if !strings.Contains(content, "mark --complete-create") {
+		t.Error("Missing creation candidates lookup")
+	}
 	if !strings.Contains(content, "compdef _mark_complete mark") {
 		t.Error("Missing compdef for mark")
 	}
@@ -422,129 +719,665 @@ func TestGenerateFishRC(t *testing.T) {
 	}
 }
 
-func TestIsSourceLinePresent(t *testing.T) {
-	tmpDir := t.TempDir()
-
-	// Test file with source line
-	fileWithSource := filepath.Join(tmpDir, "with_source")
-	content := "# some content\n# mark shell integration\n[ -f ~/.mark_bash_rc ] && source ~/.mark_bash_rc\n"
-	os.WriteFile(fileWithSource, []byte(content), 0644)
+// fakeTUIOps records dashboard changes in memory
+type fakeTUIOps struct {
+	entries []tuiEntry
+	calls   []string
+}
 
-	if !isSourceLinePresent(fileWithSource) {
-		t.Error("Should detect source line in file")
-	}
+func (f *fakeTUIOps) load() ([]tuiEntry, error) {
+	return append([]tuiEntry(nil), f.entries...), nil
+}
 
-	// Test file without source line
-	fileWithoutSource := filepath.Join(tmpDir, "without_source")
-	content = "# some other content\nexport PATH=$PATH:/usr/bin\n"
-	os.WriteFile(fileWithoutSource, []byte(content), 0644)
+func (f *fakeTUIOps) create(name, target string) error {
+	f.calls = append(f.calls, "create "+name+" "+target)
+	f.entries = append(f.entries, tuiEntry{Name: name, Path: target})
+	return nil
+}
 
-	if isSourceLinePresent(fileWithoutSource) {
-		t.Error("Should not detect source line in file")
+func (f *fakeTUIOps) rename(oldName, newName string) error {
+	f.calls = append(f.calls, "rename "+oldName+" "+newName)
+	for i := range f.entries {
+		if f.entries[i].Name == oldName {
+			f.entries[i].Name = newName
+		}
 	}
+	return nil
+}
 
-	// Test non-existent file
-	if isSourceLinePresent(filepath.Join(tmpDir, "nonexistent")) {
-		t.Error("Should return false for non-existent file")
+func (f *fakeTUIOps) remove(name string) error {
+	f.calls = append(f.calls, "remove "+name)
+	for i := range f.entries {
+		if f.entries[i].Name == name {
+			f.entries = append(f.entries[:i], f.entries[i+1:]...)
+			break
+		}
 	}
+	return nil
 }
 
-func TestGetEnabledFeatures(t *testing.T) {
-	tmpDir := t.TempDir()
-	originalHome := os.Getenv("HOME")
-	os.Setenv("HOME", tmpDir)
-	defer os.Setenv("HOME", originalHome)
-
-	// Create RC file with both features
-	rcPath := filepath.Join(tmpDir, bashRCFile)
-	content := "#!/bin/bash\n# mark shell configuration\n# Generated by mark\n# Features: aliases completions\n"
-	os.WriteFile(rcPath, []byte(content), 0644)
+func (f *fakeTUIOps) setTags(name string, tags []string) error {
+	f.calls = append(f.calls, "tags "+name+" "+strings.Join(tags, ","))
+	return nil
+}
 
-	aliases, completions := getEnabledFeatures("bash")
-	if !aliases {
-		t.Error("Should detect aliases feature")
+func TestBookmarksCovering(t *testing.T) {
+	root := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
 	}
-	if !completions {
-		t.Error("Should detect completions feature")
+	project := filepath.Join(root, "src", "project")
+	if err := os.MkdirAll(filepath.Join(project, "cmd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	bookmarks := []Bookmark{
+		{Name: "src", Target: filepath.Join(root, "src")},
+		{Name: "proj", Target: project},
+		{Name: "sibling", Target: filepath.Join(root, "src", "proj")},
+		{Name: "site", Target: "https://example.com"},
 	}
 
-	// Test with only aliases
-	content = "#!/bin/bash\n# mark shell configuration\n# Generated by mark\n# Features: aliases\n"
-	os.WriteFile(rcPath, []byte(content), 0644)
+	matches := bookmarksCovering(Config{}, bookmarks, filepath.Join(project, "cmd"))
+	var specs []string
+	for _, match := range matches {
+		specs = append(specs, match.spec())
+	}
+	want := []string{"proj/cmd", "src/project/cmd"}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("specs = %q, want %q", specs, want)
+	}
 
-	aliases, completions = getEnabledFeatures("bash")
-	if !aliases {
-		t.Error("Should detect aliases feature")
+	matches = bookmarksCovering(Config{}, bookmarks, project)
+	if len(matches) != 2 || matches[0].Name != "proj" || matches[0].Rest != "" {
+		t.Errorf("exact match = %+v", matches)
 	}
-	if completions {
-		t.Error("Should not detect completions feature")
+	if matches := bookmarksCovering(Config{}, bookmarks, root); len(matches) != 0 {
+		t.Errorf("root covered by %+v", matches)
 	}
+}
 
-	// Test with non-existent file
-	os.Remove(rcPath)
-	aliases, completions = getEnabledFeatures("bash")
-	if aliases || completions {
-		t.Error("Should return false for non-existent file")
+func TestBreadcrumb(t *testing.T) {
+	home := filepath.Join(string(filepath.Separator), "home", "me")
+	api := filepath.Join(home, "work", "src", "api")
+	matches := []coverMatch{{Name: "work", Target: filepath.Join(home, "work"), Rest: filepath.Join("src", "api")}}
+
+	tests := []struct {
+		matches []coverMatch
+		path    string
+		want    string
+	}{
+		{matches, api, "work:src/api"},
+		{[]coverMatch{{Name: "api", Target: api}}, api, "api"},
+		{nil, api, "~/work/src/api"},
+		{nil, home, "~"},
+		{nil, home + "2", home + "2"},
+	}
+	for _, tt := range tests {
+		if got := breadcrumb(tt.matches, tt.path, home); got != tt.want {
+			t.Errorf("breadcrumb(%s) = %q, want %q", tt.path, got, tt.want)
+		}
 	}
 }
 
-func TestWriteShellRC(t *testing.T) {
-	tmpDir := t.TempDir()
-	originalHome := os.Getenv("HOME")
-	os.Setenv("HOME", tmpDir)
-	defer os.Setenv("HOME", originalHome)
+func TestZshHashDirs(t *testing.T) {
+	got := zshHashDirs(Config{}, []Bookmark{
+		{Name: "work", Target: "/srv/work"},
+		{Name: "it's", Target: "/srv/quote"},
+		{Name: "dave", Target: "/home/dave's files"},
+		{Name: "site", Target: "https://example.com"},
+	})
+	want := "hash -d work='/srv/work'\nhash -d dave='/home/dave'\\''s files'\n"
+	if got != want {
+		t.Errorf("zshHashDirs = %q, want %q", got, want)
+	}
+}
 
-	// Test bash RC file creation
-	err := writeShellRC("bash", true, true)
-	if err != nil {
-		t.Fatalf("Failed to write bash RC: %v", err)
+func TestCDPath(t *testing.T) {
+	config := Config{MarksDir: "/marks"}
+	bookmarks := []Bookmark{
+		{Name: "work", Target: "/srv/work"},
+		{Name: "api", Target: "/srv/work/api"},
+		{Name: "docs", Target: "/srv/docs"},
+		{Name: "rel", Target: "../rel"},
+		{Name: "site", Target: "https://example.com"},
 	}
 
-	rcPath := filepath.Join(tmpDir, bashRCFile)
-	if _, err := os.Stat(rcPath); os.IsNotExist(err) {
-		t.Error("Bash RC file not created")
+	links := cdpathLinks(config, bookmarks)
+	if len(links) != 4 || links["rel"] != "/rel" || links["api"] != "/srv/work/api" {
+		t.Errorf("links = %v", links)
+	}
+	if got, want := cdpathParents(config, bookmarks), []string{"/", "/srv", "/srv/work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parents = %q, want %q", got, want)
 	}
 
-	content, _ := os.ReadFile(rcPath)
-	if !strings.Contains(string(content), "alias marks=") {
-		t.Error("Bash RC missing aliases")
+	storage := marks.StaticStorage(bookmarks)
+	if !marksDirUsable(config, storage, bookmarks) {
+		t.Error("symlink marks directory should be usable")
 	}
-	if !strings.Contains(string(content), "_mark_complete()") {
-		t.Error("Bash RC missing completions")
+	if marksDirUsable(config, storage, append(bookmarks, Bookmark{Name: "h", Target: "~/h"})) {
+		t.Error("~/ targets cannot be followed by cd")
+	}
+	if marksDirUsable(Config{MarksDir: "/marks", Storage: "json"}, storage, bookmarks) {
+		t.Error("json storage has no symlinks to follow")
 	}
+	filtered := &hostFilteredStorage{Storage: storage, hidden: map[string]bool{"docs": true}}
+	if marksDirUsable(config, filtered, bookmarks) {
+		t.Error("cd would reach bookmarks restricted to other hosts through the marks directory")
+	}
+}
 
-	// Test fish RC file creation (should create conf.d directory)
-	err = writeShellRC("fish", true, true)
+func TestEditFile(t *testing.T) {
+	original := []editEntry{
+		{ID: 1, Name: "docs", Target: "/srv/docs", Description: "Project  docs"},
+		{ID: 2, Name: "work", Target: "/srv/work", Tags: []string{"job", "src"}},
+	}
+	content := formatEditFile(original)
+	parsed, err := parseEditFile(content)
 	if err != nil {
-		t.Fatalf("Failed to write fish RC: %v", err)
+		t.Fatal(err)
+	}
+	plan, err := planEdit(original, parsed)
+	if err != nil || !plan.empty() {
+		t.Errorf("unchanged file planned %+v (%v)", plan, err)
 	}
 
-	fishRcPath := filepath.Join(tmpDir, fishRCFile)
-	if _, err := os.Stat(fishRcPath); os.IsNotExist(err) {
-		t.Error("Fish RC file not created")
+	// Spaces instead of tabs, as left by expandtab
+	parsed, err = parseEditFile("2  job  /srv/job  job  Day job, mostly\n+  new  /srv/new\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []editEntry{
+		{ID: 2, Name: "job", Target: "/srv/job", Tags: []string{"job"}, Description: "Day job, mostly"},
+		{Name: "new", Target: "/srv/new"},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("parsed = %+v, want %+v", parsed, want)
+	}
+	plan, err = planEdit(original, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Deletes) != 1 || plan.Deletes[0].Name != "docs" ||
+		!reflect.DeepEqual(plan.Renames, []editRename{{"work", "job"}}) ||
+		len(plan.Repoints) != 1 || len(plan.Metadata) != 1 || len(plan.Creates) != 1 {
+		t.Errorf("plan = %+v", plan)
 	}
 
-	// Test unsupported shell
-	err = writeShellRC("unsupported", true, true)
-	if err == nil {
-		t.Error("Should return error for unsupported shell")
+	for _, bad := range []string{"x\tname\t/t\n", "1\tname\n", "3\tname\t/t\n"} {
+		entries, err := parseEditFile(bad)
+		if err == nil {
+			_, err = planEdit(original, entries)
+		}
+		if err == nil {
+			t.Errorf("%q was accepted", bad)
+		}
 	}
 }
 
-func TestEnsureSourceLine(t *testing.T) {
-	tmpDir := t.TempDir()
-	originalHome := os.Getenv("HOME")
-	os.Setenv("HOME", tmpDir)
-	defer os.Setenv("HOME", originalHome)
-
-	// Create empty .bashrc
-	bashrcPath := filepath.Join(tmpDir, ".bashrc")
-	os.WriteFile(bashrcPath, []byte("# existing content\n"), 0644)
+func TestPlanEditRenameOrder(t *testing.T) {
+	original := []editEntry{
+		{ID: 1, Name: "a", Target: "/a"},
+		{ID: 2, Name: "b", Target: "/b"},
+	}
 
-	// Add source line
-	err := ensureSourceLine("bash")
+	// b -> c must happen before a -> b
+	plan, err := planEdit(original, []editEntry{{ID: 1, Name: "b", Target: "/a"}, {ID: 2, Name: "c", Target: "/b"}})
 	if err != nil {
-		t.Fatalf("Failed to add source line: %v", err)
+		t.Fatal(err)
+	}
+	if want := []editRename{{"b", "c"}, {"a", "b"}}; !reflect.DeepEqual(plan.Renames, want) {
+		t.Errorf("renames = %v, want %v", plan.Renames, want)
+	}
+
+	if _, err := planEdit(original, []editEntry{{ID: 1, Name: "b", Target: "/a"}, {ID: 2, Name: "a", Target: "/b"}}); err == nil {
+		t.Error("swapping names should be reported as a cycle")
+	}
+	if _, err := planEdit(original, []editEntry{{ID: 1, Name: "a", Target: "/a"}, {Name: "a", Target: "/c"}}); err == nil {
+		t.Error("duplicate names should be rejected")
+	}
+}
+
+func TestMenuLines(t *testing.T) {
+	lines, byLine := menuLines([]menuEntry{
+		{name: "w", path: "/srv/work"},
+		{name: "docs", path: "/srv/docs"},
+	})
+	want := []string{"w     /srv/work", "docs  /srv/docs"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if byLine[lines[1]].name != "docs" {
+		t.Errorf("line %q maps to %+v", lines[1], byLine[lines[1]])
+	}
+}
+
+func TestTUIState(t *testing.T) {
+	ops := &fakeTUIOps{entries: []tuiEntry{
+		{Name: "work", Path: "/srv/work", Tags: []string{"job"}},
+		{Name: "docs", Path: "/srv/docs"},
+		{Name: "old", Path: "/gone", Broken: true},
+		{Name: "team", Path: "/srv/team", Shared: true},
+	}}
+	state, err := newTUIState(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	press := func(input string) {
+		for _, key := range parseKeys([]byte(input)) {
+			state.handleKey(key, 10)
+		}
+	}
+
+	// Searching matches tags too; Enter keeps the filter and picks the match
+	press("/job\r")
+	if entries := state.visible(); len(entries) != 1 || entries[0].Name != "work" {
+		t.Fatalf("visible after /job = %+v", entries)
+	}
+	press("\x1b")
+	if len(state.visible()) != 4 {
+		t.Errorf("Escape should clear the filter, got %d entries", len(state.visible()))
+	}
+
+	// Sorted by name: docs, old, team, work
+	press("j\r")
+	if state.done || !strings.Contains(state.message, "broken") {
+		t.Errorf("Enter on a broken bookmark should not jump (message %q)", state.message)
+	}
+	press("jd")
+	if state.confirm != "" || !strings.Contains(state.message, "shared") {
+		t.Errorf("Shared bookmarks cannot be deleted (message %q)", state.message)
+	}
+
+	press("kr\x7f\x7f\x7fancient\r") // old -> ancient; the cursor stays on row 2, docs
+	press("dn")                      // keep docs
+	press("dy")                      // delete docs
+	press("gtx, y\r")                // tag ancient
+	press("nnew\r/tmp\r")
+
+	want := []string{"rename old ancient", "remove docs", "tags ancient x,y", "create new /tmp"}
+	if !reflect.DeepEqual(ops.calls, want) {
+		t.Errorf("calls = %q, want %q", ops.calls, want)
+	}
+
+	press("\x1b[BG\r")
+	if !state.done || state.chosen != "/srv/work" {
+		t.Errorf("Enter should choose the selected path, got done=%v chosen=%q", state.done, state.chosen)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("a\x1b[A\x1b[B\x1b[6~\r\x7f\x1bé\x03"))
+	var codes []int
+	for _, key := range keys {
+		codes = append(codes, key.code)
+	}
+	want := []int{keyRune, keyUp, keyDown, keyPageDown, keyEnter, keyBackspace, keyEscape, keyRune, keyInterrupt}
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("codes = %v, want %v", codes, want)
+	}
+	if keys[7].r != 'é' {
+		t.Errorf("rune = %q, want é", keys[7].r)
+	}
+}
+
+func TestServeAPI(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(tmpDir, "state"))
+	config := Config{MarksDir: filepath.Join(tmpDir, "marks"), SystemMarks: "off"}
+	project := filepath.Join(tmpDir, "project")
+	os.Mkdir(project, 0755)
+	storage, _ := marks.Open(config)
+	storage.Create("project", project)
+	storage.Create("gone", filepath.Join(tmpDir, "gone"))
+
+	request := func(server *apiServer, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Host = defaultServeAddr
+		for key, value := range header {
+			if key == "Host" {
+				req.Host = value
+				continue
+			}
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		server.handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	readOnly := &apiServer{config: config}
+	rec := request(readOnly, "GET", "/bookmarks", "", nil)
+	var list []apiBookmark
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /bookmarks = %d %s", rec.Code, rec.Body)
+	}
+	if len(list) != 2 || list[0].Name != "gone" || !list[0].Broken || list[1].Path != project || list[1].Broken {
+		t.Errorf("Unexpected list: %+v", list)
+	}
+	if rec := request(readOnly, "GET", "/bookmarks/missing", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GET missing = %d, want 404", rec.Code)
+	}
+	if rec := request(readOnly, "POST", "/bookmarks", `{"name":"x","target":"`+project+`"}`, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("POST on a read-only server = %d, want 403", rec.Code)
+	}
+	if rec := request(readOnly, "GET", "/bookmarks", "", map[string]string{"Origin": "https://example.com"}); rec.Code != http.StatusForbidden {
+		t.Errorf("Browser request without token = %d, want 403", rec.Code)
+	}
+	if rec := request(readOnly, "GET", "/bookmarks", "", map[string]string{"Host": "evil.example"}); rec.Code != http.StatusForbidden {
+		t.Errorf("Request for a foreign Host without token = %d, want 403", rec.Code)
+	}
+	if rec := request(readOnly, "GET", "/bookmarks", "", map[string]string{"Host": "localhost"}); rec.Code != http.StatusOK {
+		t.Errorf("Request for Host localhost = %d, want 200", rec.Code)
+	}
+
+	writable := &apiServer{config: config, write: true, token: "secret"}
+	auth := map[string]string{"Authorization": "Bearer secret"}
+	if rec := request(writable, "GET", "/bookmarks", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Request without token = %d, want 401", rec.Code)
+	}
+	if rec := request(writable, "GET", "/bookmarks", "", map[string]string{"Authorization": "secret"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Token without the Bearer scheme = %d, want 401", rec.Code)
+	}
+	if rec := request(writable, "POST", "/bookmarks", `{"name":"proj two","target":"`+project+`","tags":["go"]}`, auth); rec.Code != http.StatusCreated {
+		t.Errorf("POST = %d %s", rec.Code, rec.Body)
+	}
+	if bookmark, err := storage.Get("proj_two"); err != nil || bookmark.Target != project {
+		t.Errorf("Created bookmark = %+v, %v", bookmark, err)
+	}
+	if rec := request(writable, "POST", "/bookmarks", `{"name":"project","target":"`+project+`"}`, auth); rec.Code != http.StatusConflict {
+		t.Errorf("POST existing = %d, want 409", rec.Code)
+	}
+	if rec := request(writable, "POST", "/bookmarks", `{"name":"rel","target":"relative/dir"}`, auth); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST relative target = %d, want 400", rec.Code)
+	}
+	if rec := request(writable, "GET", "/bookmarks?tag=go", "", auth); !strings.Contains(rec.Body.String(), `"proj_two"`) || strings.Contains(rec.Body.String(), `"gone"`) {
+		t.Errorf("GET ?tag=go = %s", rec.Body)
+	}
+	if rec := request(writable, "DELETE", "/bookmarks/proj_two", "", auth); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d %s", rec.Code, rec.Body)
+	}
+	if _, err := storage.Get("proj_two"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Expected deleted bookmark to be gone, got %v", err)
+	}
+
+	// Names reaching outside the marks directory are refused
+	victim := filepath.Join(tmpDir, "victim")
+	os.Symlink(project, victim)
+	for _, method := range []string{"GET", "DELETE"} {
+		if rec := request(writable, method, "/bookmarks/..%2Fvictim", "", auth); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s ..%%2Fvictim = %d, want 400", method, rec.Code)
+		}
+	}
+	if _, err := os.Lstat(victim); err != nil {
+		t.Errorf("Traversal request removed the outside link: %v", err)
+	}
+
+	// Internal errors stay in the server log
+	os.WriteFile(filepath.Join(config.MarksDir, marks.MetadataFile), []byte("{broken"), 0644)
+	if rec := request(writable, "GET", "/bookmarks", "", auth); rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), tmpDir) {
+		t.Errorf("GET with broken metadata = %d %s, want 500 without paths", rec.Code, rec.Body)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:7457": true,
+		"localhost:80":   true,
+		"[::1]:7457":     true,
+		"0.0.0.0:7457":   false,
+		":7457":          false,
+		"10.0.0.5:7457":  false,
+	} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"127.0.0.1:7457":  true,
+		"localhost":       true,
+		"[::1]:7457":      true,
+		"[::1]":           true,
+		"evil.example":    false,
+		"evil.example:80": false,
+		"":                false,
+	} {
+		if got := isLoopbackHost(host); got != want {
+			t.Errorf("isLoopbackHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestFindPlugin(t *testing.T) {
+	binDir := t.TempDir()
+	os.WriteFile(filepath.Join(binDir, "mark-hello"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", binDir)
+
+	if path := findPlugin("hello"); path != filepath.Join(binDir, "mark-hello") {
+		t.Errorf("findPlugin(hello) = %q", path)
+	}
+	for _, name := range []string{"missing", "", "-l", "../hello"} {
+		if path := findPlugin(name); path != "" {
+			t.Errorf("findPlugin(%q) = %q, want none", name, path)
+		}
+	}
+}
+
+func TestPluginArgs(t *testing.T) {
+	raw := []string{"--profile", "work", "hello", "-l", "--tag", "x"}
+	flags, _ := parseFlags(raw)
+	if got := pluginArgs(raw, flags); !reflect.DeepEqual(got, []string{"-l", "--tag", "x"}) {
+		t.Errorf("pluginArgs = %v", got)
+	}
+
+	// A flag value equal to the plugin name must not split the line there
+	raw = []string{"--profile", "hello", "hello", "--tag", "x"}
+	flags, args := parseFlags(raw)
+	if got := pluginArgs(raw, flags); args[0] != "hello" || !reflect.DeepEqual(got, []string{"--tag", "x"}) {
+		t.Errorf("pluginArgs = %v", got)
+	}
+	raw = []string{"--", "hello", "a"}
+	if flags, _ := parseFlags(raw); !reflect.DeepEqual(pluginArgs(raw, flags), []string{"a"}) {
+		t.Errorf("pluginArgs after -- = %v", pluginArgs(raw, flags))
+	}
+}
+
+func TestPluginEnv(t *testing.T) {
+	t.Setenv("MARK_CONFIG", "/etc/mark.conf")
+	environ := []string{"PATH=/bin", "MARK_DIR=/old", "MARK_PROFILE=stale"}
+	env := pluginEnv(environ, Config{MarksDir: "/srv/marks", Storage: "json"}, "")
+
+	vars := make(map[string]string)
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		if _, dup := vars[key]; dup {
+			t.Errorf("%s exported twice", key)
+		}
+		vars[key] = value
+	}
+	if vars["PATH"] != "/bin" || vars["MARK_DIR"] != "/srv/marks" || vars["MARK_STORAGE"] != "json" || vars["MARK_CONFIG"] != "/etc/mark.conf" {
+		t.Errorf("Unexpected plugin environment: %v", vars)
+	}
+	if _, ok := vars["MARK_PROFILE"]; ok {
+		t.Error("MARK_PROFILE should be dropped when no profile is selected")
+	}
+}
+
+func TestCompletionFile(t *testing.T) {
+	zsh, err := completionFile("zsh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// zsh only autoloads site-functions files starting with #compdef
+	if !strings.HasPrefix(zsh, "#compdef mark marks unmark jump\n") {
+		t.Errorf("zsh file should start with #compdef, got %q", strings.SplitN(zsh, "\n", 2)[0])
+	}
+	if !strings.HasSuffix(zsh, "_mark_complete \"$@\"\n") || strings.Contains(zsh, "compdef _mark_complete") {
+		t.Error("zsh file should call _mark_complete directly instead of binding it")
+	}
+
+	for _, shell := range completionShells {
+		content, err := completionFile(shell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(content, "alias marks") || strings.Contains(content, "@MARK_COMMANDS@") {
+			t.Errorf("%s file should hold completions only, with command names filled in", shell)
+		}
+	}
+
+	if _, err := completionFile("tcsh"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
+
+func TestCompletionInstallPath(t *testing.T) {
+	tests := map[string]string{
+		"bash": "/opt/homebrew/share/bash-completion/completions/mark",
+		"zsh":  "/opt/homebrew/share/zsh/site-functions/_mark",
+		"fish": "/opt/homebrew/share/fish/vendor_completions.d/mark.fish",
+	}
+	for shell, want := range tests {
+		if got := completionInstallPath(shell, "/opt/homebrew", true); got != want {
+			t.Errorf("system %s path = %q, want %q", shell, got, want)
+		}
+	}
+
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv("XDG_CONFIG_HOME", "/config")
+	if got := completionInstallPath("bash", "", false); got != "/data/bash-completion/completions/mark" {
+		t.Errorf("user bash path = %q", got)
+	}
+	if got := completionInstallPath("fish", "", false); got != "/config/fish/completions/mark.fish" {
+		t.Errorf("user fish path = %q", got)
+	}
+}
+
+func TestIsSourceLinePresent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Test file with source line
+	fileWithSource := filepath.Join(tmpDir, "with_source")
+	content := "# some content\n# mark shell integration\n[ -f ~/.mark_bash_rc ] && source ~/.mark_bash_rc\n"
+	os.WriteFile(fileWithSource, []byte(content), 0644)
+
+	if !isSourceLinePresent(fileWithSource) {
+		t.Error("Should detect source line in file")
+	}
+
+	// Test file without source line
+	fileWithoutSource := filepath.Join(tmpDir, "without_source")
+	content = "# some other content\nexport PATH=$PATH:/usr/bin\n"
+	os.WriteFile(fileWithoutSource, []byte(content), 0644)
+
+	if isSourceLinePresent(fileWithoutSource) {
+		t.Error("Should not detect source line in file")
+	}
+
+	// Test non-existent file
+	if isSourceLinePresent(filepath.Join(tmpDir, "nonexistent")) {
+		t.Error("Should return false for non-existent file")
+	}
+}
+
+func TestGetEnabledFeatures(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// Create RC file with both features
+	rcPath := filepath.Join(tmpDir, bashRCFile)
+	content := "#!/bin/bash\n# mark shell configuration\n# Generated by mark\n# Features: aliases completions\n"
+	os.WriteFile(rcPath, []byte(content), 0644)
+
+	aliases, completions := getEnabledFeatures("bash")
+	if !aliases {
+		t.Error("Should detect aliases feature")
+	}
+	if !completions {
+		t.Error("Should detect completions feature")
+	}
+
+	// Test with only aliases
+	content = "#!/bin/bash\n# mark shell configuration\n# Generated by mark\n# Features: aliases\n"
+	os.WriteFile(rcPath, []byte(content), 0644)
+
+	aliases, completions = getEnabledFeatures("bash")
+	if !aliases {
+		t.Error("Should detect aliases feature")
+	}
+	if completions {
+		t.Error("Should not detect completions feature")
+	}
+
+	// Test with non-existent file
+	os.Remove(rcPath)
+	aliases, completions = getEnabledFeatures("bash")
+	if aliases || completions {
+		t.Error("Should return false for non-existent file")
+	}
+}
+
+func TestWriteShellRC(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// Test bash RC file creation
+	err := writeShellRC("bash", true, true)
+	if err != nil {
+		t.Fatalf("Failed to write bash RC: %v", err)
+	}
+
+	rcPath := filepath.Join(tmpDir, bashRCFile)
+	if _, err := os.Stat(rcPath); os.IsNotExist(err) {
+		t.Error("Bash RC file not created")
+	}
+
+	content, _ := os.ReadFile(rcPath)
+	if !strings.Contains(string(content), "alias marks=") {
+		t.Error("Bash RC missing aliases")
+	}
+	if !strings.Contains(string(content), "_mark_complete()") {
+		t.Error("Bash RC missing completions")
+	}
+
+	// Test fish RC file creation (should create conf.d directory)
+	err = writeShellRC("fish", true, true)
+	if err != nil {
+		t.Fatalf("Failed to write fish RC: %v", err)
+	}
+
+	fishRcPath := filepath.Join(tmpDir, fishRCFile)
+	if _, err := os.Stat(fishRcPath); os.IsNotExist(err) {
+		t.Error("Fish RC file not created")
+	}
+
+	// Test unsupported shell
+	err = writeShellRC("unsupported", true, true)
+	if err == nil {
+		t.Error("Should return error for unsupported shell")
+	}
+}
+
+func TestEnsureSourceLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	// Create empty .bashrc
+	bashrcPath := filepath.Join(tmpDir, ".bashrc")
+	os.WriteFile(bashrcPath, []byte("# existing content\n"), 0644)
+
+	// Add source line
+	err := ensureSourceLine("bash")
+	if err != nil {
+		t.Fatalf("Failed to add source line: %v", err)
 	}
 
 	content, _ := os.ReadFile(bashrcPath)
@@ -733,3 +1566,2788 @@ func TestBookmarkOperations(t *testing.T) {
 		}
 	})
 }
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"client,billing", []string{"client", "billing"}},
+		{" client , billing ,", []string{"client", "billing"}},
+		{"client,client", []string{"client"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := parseTags(tt.input)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("parseTags(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMetadataOperations(t *testing.T) {
+	marksDir := t.TempDir()
+
+	// Missing metadata file yields an empty map
+	meta, err := marks.LoadMetadata(marksDir)
+	if err != nil {
+		t.Fatalf("loadMetadata failed: %v", err)
+	}
+	if len(meta) != 0 {
+		t.Errorf("Expected empty metadata, got %d entries", len(meta))
+	}
+
+	// Record tags for two bookmarks
+	if err := marks.UpdateMetadata(marksDir, "work", &Metadata{Tags: []string{"client", "billing"}}); err != nil {
+		t.Fatalf("updateMetadata failed: %v", err)
+	}
+	if err := marks.UpdateMetadata(marksDir, "home", &Metadata{Tags: []string{"personal"}, Description: "dotfiles"}); err != nil {
+		t.Fatalf("updateMetadata failed: %v", err)
+	}
+
+	meta, err = marks.LoadMetadata(marksDir)
+	if err != nil {
+		t.Fatalf("loadMetadata failed: %v", err)
+	}
+	if !hasTag(meta["work"], "client") {
+		t.Error("Expected 'work' to carry tag 'client'")
+	}
+	if meta["home"].Description != "dotfiles" {
+		t.Errorf("Description = %q, want %q", meta["home"].Description, "dotfiles")
+	}
+	if hasTag(meta["home"], "client") {
+		t.Error("Did not expect 'home' to carry tag 'client'")
+	}
+	if hasTag(meta["missing"], "client") {
+		t.Error("Missing bookmark should carry no tags")
+	}
+	if strings.Join(allTags(meta), ",") != "billing,client,personal" {
+		t.Errorf("allTags = %v", allTags(meta))
+	}
+
+	// Removing metadata drops the entry
+	if err := marks.UpdateMetadata(marksDir, "work", nil); err != nil {
+		t.Fatalf("updateMetadata failed: %v", err)
+	}
+	meta, _ = marks.LoadMetadata(marksDir)
+	if _, ok := meta["work"]; ok {
+		t.Error("Metadata for 'work' should have been removed")
+	}
+}
+
+func TestTraceSymlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Chain: mark -> link -> real
+	realDir := filepath.Join(tmpDir, "real")
+	os.MkdirAll(realDir, 0755)
+	link := filepath.Join(tmpDir, "link")
+	os.Symlink(realDir, link)
+	mark := filepath.Join(tmpDir, "mark")
+	os.Symlink("link", mark) // relative target
+
+	hops, err := traceSymlinks(mark)
+	if err != nil {
+		t.Fatalf("traceSymlinks failed: %v", err)
+	}
+	if len(hops) != 2 {
+		t.Fatalf("Expected 2 hops, got %d: %v", len(hops), hops)
+	}
+	if hops[0].to != link {
+		t.Errorf("First hop target = %q, want %q", hops[0].to, link)
+	}
+	if hops[1].to != realDir {
+		t.Errorf("Second hop target = %q, want %q", hops[1].to, realDir)
+	}
+
+	// A symlink loop is reported as an error
+	loopA := filepath.Join(tmpDir, "loopA")
+	loopB := filepath.Join(tmpDir, "loopB")
+	os.Symlink(loopB, loopA)
+	os.Symlink(loopA, loopB)
+	if _, err := traceSymlinks(loopA); err == nil {
+		t.Error("Expected error for symlink loop")
+	}
+}
+
+func TestSymlinkStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	marksDir := filepath.Join(tmpDir, ".marks")
+	targetDir := filepath.Join(tmpDir, "project")
+	os.MkdirAll(targetDir, 0755)
+
+	storage, err := marks.Open(Config{MarksDir: marksDir})
+	if err != nil {
+		t.Fatalf("marks.Open failed: %v", err)
+	}
+
+	// Listing a missing marks directory yields nothing
+	bookmarks, err := storage.List()
+	if err != nil || len(bookmarks) != 0 {
+		t.Fatalf("Expected empty list, got %v (err %v)", bookmarks, err)
+	}
+
+	if err := storage.Create("project", targetDir); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := storage.Create("project", targetDir); !errors.Is(err, marks.ErrExists) {
+		t.Errorf("Expected marks.ErrExists, got %v", err)
+	}
+
+	bookmark, err := storage.Get("project")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if bookmark.Target != targetDir {
+		t.Errorf("Target = %q, want %q", bookmark.Target, targetDir)
+	}
+
+	// Non-symlink entries are not bookmarks, and mark's own files are not
+	// even valid names
+	os.WriteFile(filepath.Join(marksDir, "notes"), []byte("todo\n"), 0644)
+	if _, err := storage.Get("notes"); !errors.Is(err, marks.ErrNotBookmark) {
+		t.Errorf("Expected marks.ErrNotBookmark, got %v", err)
+	}
+	os.WriteFile(filepath.Join(marksDir, marks.MetadataFile), []byte("{}"), 0644)
+	if _, err := storage.Get(marks.MetadataFile); !errors.Is(err, marks.ErrInvalidName) {
+		t.Errorf("Expected marks.ErrInvalidName, got %v", err)
+	}
+	bookmarks, _ = storage.List()
+	if len(bookmarks) != 1 || bookmarks[0].Name != "project" {
+		t.Errorf("List = %v, want only 'project'", bookmarks)
+	}
+
+	if err := storage.Delete("project"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := storage.Get("project"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Expected marks.ErrNotFound, got %v", err)
+	}
+	if err := storage.Delete("project"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Expected marks.ErrNotFound on second delete, got %v", err)
+	}
+}
+
+func TestExecStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbFile := filepath.Join(tmpDir, "db")
+	script := filepath.Join(tmpDir, "store.sh")
+
+	// A tiny tab-separated database implementing the exec protocol
+	content := `#!/bin/sh
+DB="` + dbFile + `"
+touch "$DB"
+case "$1" in
+list) cat "$DB" ;;
+get) line=$(grep "^$2	" "$DB") || exit 2; printf '%s\n' "${line#*	}" ;;
+create) grep -q "^$2	" "$DB" && exit 3; printf '%s\t%s\n' "$2" "$3" >> "$DB" ;;
+delete) grep -q "^$2	" "$DB" || exit 2; grep -v "^$2	" "$DB" > "$DB.tmp"; mv "$DB.tmp" "$DB" ;;
+*) echo "unknown command" >&2; exit 1 ;;
+esac
+`
+	os.WriteFile(script, []byte(content), 0755)
+
+	storage, err := marks.Open(Config{Storage: "exec", StorageCommand: script})
+	if err != nil {
+		t.Fatalf("marks.Open failed: %v", err)
+	}
+
+	if err := storage.Create("work", "/srv/work"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := storage.Create("work", "/srv/work"); !errors.Is(err, marks.ErrExists) {
+		t.Errorf("Expected marks.ErrExists, got %v", err)
+	}
+
+	bookmark, err := storage.Get("work")
+	if err != nil || bookmark.Target != "/srv/work" {
+		t.Errorf("Get = %v (err %v), want target /srv/work", bookmark, err)
+	}
+
+	bookmarks, err := storage.List()
+	if err != nil || len(bookmarks) != 1 || bookmarks[0].Name != "work" {
+		t.Errorf("List = %v (err %v)", bookmarks, err)
+	}
+
+	if err := storage.Delete("work"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := storage.Get("work"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Expected marks.ErrNotFound, got %v", err)
+	}
+
+	// Missing command is a configuration error
+	if _, err := marks.Open(Config{Storage: "exec"}); err == nil {
+		t.Error("Expected error when storage_command is missing")
+	}
+	if _, err := marks.Open(Config{Storage: "bogus"}); err == nil {
+		t.Error("Expected error for unknown storage backend")
+	}
+}
+
+func TestUsageTracking(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tmpDir)
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	if usageFilePath() != filepath.Join(tmpDir, "mark", "usage") {
+		t.Errorf("usageFilePath() = %q", usageFilePath())
+	}
+
+	// Missing log yields no events
+	events, err := loadUsage()
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Expected no events, got %v (err %v)", events, err)
+	}
+
+	recordUsage("work", "/srv/work")
+	recordUsage("home", "/home/user")
+	recordUsage("work", "/srv/work")
+
+	events, err = loadUsage()
+	if err != nil {
+		t.Fatalf("loadUsage failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	summary := summarizeUsage(events)
+	if summary["work"].Count != 2 {
+		t.Errorf("work count = %d, want 2", summary["work"].Count)
+	}
+	if summary["home"].Count != 1 {
+		t.Errorf("home count = %d, want 1", summary["home"].Count)
+	}
+	if summary["work"].LastUsed.IsZero() {
+		t.Error("work last-used time not recorded")
+	}
+	if summary["missing"].Count != 0 || formatLastUsed(summary["missing"].LastUsed) != "-" {
+		t.Error("Unused bookmark should have no usage")
+	}
+
+	// Compacting keeps the newest jumps that fit
+	for i := 0; i < 100; i++ {
+		recordUsage("mark"+strconv.Itoa(i), "/srv/work")
+	}
+	if err := compactUsage(200); err != nil {
+		t.Fatalf("compactUsage failed: %v", err)
+	}
+	info, _ := os.Stat(usageFilePath())
+	events, _ = loadUsage()
+	if info.Size() > 200 || len(events) == 0 || events[len(events)-1].Name != "mark99" {
+		t.Errorf("compacted log is %d bytes with %v", info.Size(), events)
+	}
+}
+
+func TestRecentBookmarks(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	events := []usageEvent{
+		{Time: base, Name: "work", Path: "/srv/work"},
+		{Time: base.Add(time.Minute), Name: "home", Path: "/home/user"},
+		{Time: base.Add(2 * time.Minute), Name: "work", Path: "/srv/work"},
+		{Time: base.Add(3 * time.Minute), Name: "logs", Path: "/var/log"},
+	}
+
+	recent := recentBookmarks(events, 10)
+	var names []string
+	for _, event := range recent {
+		names = append(names, event.Name)
+	}
+	if strings.Join(names, ",") != "logs,work,home" {
+		t.Errorf("recentBookmarks order = %v, want [logs work home]", names)
+	}
+	if !recent[1].Time.Equal(base.Add(2 * time.Minute)) {
+		t.Errorf("Expected latest jump time for 'work', got %v", recent[1].Time)
+	}
+
+	if len(recentBookmarks(events, 2)) != 2 {
+		t.Error("recentBookmarks should honor the limit")
+	}
+	if len(recentBookmarks(nil, 5)) != 0 {
+		t.Error("No events should yield no recent bookmarks")
+	}
+}
+
+func TestNamePolicy(t *testing.T) {
+	policy, err := parseNamePolicy("lowercase, kebab,ascii")
+	if err != nil {
+		t.Fatalf("parseNamePolicy failed: %v", err)
+	}
+	if !policy.Lowercase || !policy.Kebab || !policy.ASCII {
+		t.Errorf("Expected all rules enabled, got %+v", policy)
+	}
+	if _, err := parseNamePolicy("lowercase,shouting"); err == nil {
+		t.Error("Expected error for unknown rule")
+	}
+	if empty, _ := parseNamePolicy(""); !empty.isEmpty() {
+		t.Error("Empty value should produce an empty policy")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"MyProject", "my-project"},
+		{"Client_Portal repo", "client-portal-repo"},
+		{"Café Résumé", "cafe-resume"},
+		{"already-fine", "already-fine"},
+		{"--odd__name--", "odd-name"},
+		{"v1.2", "v1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := policy.apply(tt.input); result != tt.expected {
+				t.Errorf("apply(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNamePolicyValidation(t *testing.T) {
+	policy, err := parseNamePolicy("spaces=dash,max_length=8,chars=a-z0-9_.-")
+	if err != nil {
+		t.Fatalf("parseNamePolicy failed: %v", err)
+	}
+	for _, bad := range []string{"spaces=tabs", "max_length=0", "max_length=x", "chars=", "chars=z-a"} {
+		if _, err := parseNamePolicy(bad); err == nil {
+			t.Errorf("parseNamePolicy(%q) should fail", bad)
+		}
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"my proj", "my-proj", false},
+		{"api.v2", "api.v2", false},
+		{"Work", "Work", true},
+		{"much-too-long", "much-too-long", true},
+		{"", "", true},
+		{"..", "..", true},
+		{"a/b", "a/b", true},
+	}
+	for _, tt := range tests {
+		got, err := policy.normalize(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("normalize(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if got, _ := (namePolicy{}).normalize("my proj"); got != "my_proj" {
+		t.Errorf("default policy turned spaces into %q, want underscores", got)
+	}
+	reject, _ := parseNamePolicy("spaces=reject")
+	if _, err := reject.normalize("my proj"); err == nil {
+		t.Error("spaces=reject should refuse names with spaces")
+	}
+	if _, err := (namePolicy{}).normalize(marks.MetadataFile); err == nil {
+		t.Error("the metadata file name should be refused")
+	}
+}
+
+func TestPlanRenames(t *testing.T) {
+	policy := namePolicy{Lowercase: true}
+	renames, conflicts := planRenames([]string{"Work", "work2", "Home", "home"}, policy.apply)
+
+	if len(renames) != 1 || renames[0].from != "Work" || renames[0].to != "work" {
+		t.Errorf("renames = %v, want [Work -> work]", renames)
+	}
+	if len(conflicts) != 1 || conflicts[0].from != "Home" {
+		t.Errorf("conflicts = %v, want [Home -> home]", conflicts)
+	}
+}
+
+func TestRenameBookmark(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tmpDir)
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks")}
+	storage, _ := marks.Open(config)
+	storage.Create("Old", tmpDir)
+	marks.UpdateMetadata(config.MarksDir, "Old", &Metadata{Description: "kept"})
+	recordUsage("Old", tmpDir)
+
+	if err := renameBookmark(config, storage, "Old", "new"); err != nil {
+		t.Fatalf("renameBookmark failed: %v", err)
+	}
+
+	if _, err := storage.Get("Old"); !errors.Is(err, marks.ErrNotFound) {
+		t.Error("Old bookmark should be gone")
+	}
+	if bookmark, err := storage.Get("new"); err != nil || bookmark.Target != tmpDir {
+		t.Errorf("New bookmark = %v (err %v)", bookmark, err)
+	}
+
+	meta, _ := marks.LoadMetadata(config.MarksDir)
+	if meta["new"] == nil || meta["new"].Description != "kept" {
+		t.Error("Metadata did not follow the rename")
+	}
+
+	events, _ := loadUsage()
+	if summarizeUsage(events)["new"].Count != 1 {
+		t.Error("Usage history did not follow the rename")
+	}
+}
+
+func TestCompareBookmarks(t *testing.T) {
+	now := time.Now()
+	entries := []sortEntry{
+		{name: "alpha"},
+		{name: "beta", usage: usageSummary{Count: 3, LastUsed: now}, frecency: 6},
+		{name: "gamma", pinned: true},
+		{name: "delta", usage: usageSummary{Count: 5, LastUsed: now.Add(-time.Hour)}, frecency: 6},
+	}
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"", []string{"alpha", "beta", "delta", "gamma"}},
+		{"pinned,frecency,name", []string{"gamma", "beta", "delta", "alpha"}},
+		{"frecency,uses", []string{"delta", "beta", "alpha", "gamma"}},
+		{"recent", []string{"beta", "delta", "alpha", "gamma"}},
+	}
+
+	for _, tt := range tests {
+		keys, err := parseSortKeys(tt.sort)
+		if err != nil {
+			t.Fatalf("parseSortKeys(%q) failed: %v", tt.sort, err)
+		}
+		sorted := append([]sortEntry(nil), entries...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return compareBookmarks(sorted[i], sorted[j], keys) < 0
+		})
+		var names []string
+		for _, e := range sorted {
+			names = append(names, e.name)
+		}
+		if !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("sort %q = %v, want %v", tt.sort, names, tt.expected)
+		}
+	}
+
+	if _, err := parseSortKeys("pinned,size"); err == nil {
+		t.Error("Expected an error for an unknown sort key")
+	}
+}
+
+func TestFrecencyScores(t *testing.T) {
+	now := time.Now()
+	events := []usageEvent{
+		{Time: now.Add(-10 * time.Minute), Name: "fresh"},
+		{Time: now.Add(-30 * 24 * time.Hour), Name: "old"},
+		{Time: now.Add(-31 * 24 * time.Hour), Name: "old"},
+		{Time: now.Add(-32 * 24 * time.Hour), Name: "old"},
+	}
+	scores := frecencyScores(events, now)
+	if scores["fresh"] <= scores["old"] {
+		t.Errorf("A jump minutes ago should outrank three old ones: %v", scores)
+	}
+}
+
+func TestShellSafeName(t *testing.T) {
+	tests := map[string]string{
+		"work":          "work",
+		"client's repo": "client_s_repo",
+		"--old":         "old",
+		"logs*":         "logs_",
+		"a\nb":          "a_b",
+		"café":          "café",
+	}
+	for name, expected := range tests {
+		if got := shellSafeName(name); got != expected {
+			t.Errorf("shellSafeName(%q) = %q, want %q", name, got, expected)
+		}
+	}
+
+	renames, conflicts := planRenames([]string{"$HOME", "_HOME", "ok"}, shellSafeName)
+	if len(renames) != 0 || len(conflicts) != 1 || conflicts[0].from != "$HOME" {
+		t.Errorf("renames = %v, conflicts = %v", renames, conflicts)
+	}
+}
+
+func TestProjectStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := filepath.Join(tmpDir, "repo")
+	nested := filepath.Join(repo, "services", "api")
+	os.MkdirAll(nested, 0755)
+	os.WriteFile(filepath.Join(repo, ".marks"), []byte("# shared\napi=services/api\nhome=/srv\n"), 0644)
+
+	// A .marks directory is the user's marks dir, never a project file
+	os.Mkdir(filepath.Join(tmpDir, ".marks"), 0755)
+	if path := findProjectMarksFile(tmpDir); path != "" {
+		t.Errorf("findProjectMarksFile(%q) = %q, want none", tmpDir, path)
+	}
+
+	path := findProjectMarksFile(nested)
+	if path != filepath.Join(repo, ".marks") {
+		t.Fatalf("findProjectMarksFile(%q) = %q", nested, path)
+	}
+	project, err := parseProjectMarks(path)
+	if err != nil {
+		t.Fatalf("parseProjectMarks failed: %v", err)
+	}
+
+	user, _ := marks.Open(Config{MarksDir: filepath.Join(tmpDir, "user")})
+	user.Create("home", tmpDir)
+	storage := &marks.LayeredStorage{Storage: user, Sources: []marks.Source{{Storage: user, Origin: "user"}, {Storage: marks.StaticStorage(project), Shared: true, Origin: "project"}}}
+
+	if bookmark, err := storage.Get("api"); err != nil || bookmark.Target != nested {
+		t.Errorf("Get(api) = %v (err %v)", bookmark, err)
+	}
+	if _, source, err := storage.Find("api"); err != nil || source.Origin != "project" {
+		t.Errorf("Find(api) came from %q (err %v), want project", source.Origin, err)
+	}
+	if bookmark, _ := storage.Get("home"); bookmark.Target != tmpDir {
+		t.Errorf("User bookmark should win, got %v", bookmark)
+	}
+	if bookmarks, _ := storage.List(); len(bookmarks) != 2 {
+		t.Errorf("List() = %v, want home and api", bookmarks)
+	}
+
+	os.WriteFile(path, []byte("broken line\n"), 0644)
+	if _, err := parseProjectMarks(path); err == nil {
+		t.Error("Expected an error for a line without '='")
+	}
+}
+
+func TestSharedDirStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	systemDir := filepath.Join(tmpDir, "system")
+	os.MkdirAll(filepath.Join(tmpDir, "logs"), 0755)
+	os.Mkdir(systemDir, 0755)
+	os.Symlink("../logs", filepath.Join(systemDir, "logs"))
+	os.Symlink(tmpDir, filepath.Join(systemDir, "data"))
+
+	user, _ := marks.Open(Config{MarksDir: filepath.Join(tmpDir, "user")})
+	user.Create("data", "/srv/data")
+	storage := &marks.LayeredStorage{Storage: user, Sources: []marks.Source{
+		{Storage: user},
+		{Storage: &marks.SharedDirStorage{Dir: systemDir}, Shared: true},
+	}}
+
+	if bookmark, err := storage.Get("logs"); err != nil || bookmark.Target != filepath.Join(tmpDir, "logs") {
+		t.Errorf("Get(logs) = %v (err %v), want absolute target", bookmark, err)
+	}
+	if bookmark, _ := storage.Get("data"); bookmark.Target != "/srv/data" {
+		t.Errorf("User bookmark should shadow the system one, got %v", bookmark)
+	}
+	if err := storage.Delete("logs"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Deleting a system bookmark must not touch it, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(systemDir, "logs")); err != nil {
+		t.Error("System bookmark was removed")
+	}
+}
+
+func TestSetupAnswersFromFlags(t *testing.T) {
+	flags, _ := parseFlags([]string{"--config", "--yes", "--marksdir", "~/bm", "--no-alias"})
+	answers := setupAnswersFromFlags(flags)
+	expected := map[string]string{"marksdir": "~/bm", "completion": "yes", "aliases": "no"}
+	if !reflect.DeepEqual(answers, expected) {
+		t.Errorf("setupAnswersFromFlags = %v, want %v", answers, expected)
+	}
+
+	flags, _ = parseFlags([]string{"--config", "--no-completion"})
+	answers = setupAnswersFromFlags(flags)
+	if !reflect.DeepEqual(answers, map[string]string{"completion": "no"}) {
+		t.Errorf("without --yes only the given answers should be set, got %v", answers)
+	}
+}
+
+func TestConfirmDeletion(t *testing.T) {
+	tests := map[string]bool{
+		"y\n":   true,
+		"yes\n": true,
+		"\n":    false,
+		"n\n":   false,
+		"":      false,
+	}
+	for input, expected := range tests {
+		if got := confirmDeletion(bufio.NewReader(strings.NewReader(input)), "work", "/srv/work"); got != expected {
+			t.Errorf("confirmDeletion(%q) = %v, want %v", input, got, expected)
+		}
+	}
+}
+
+func TestSetupPrompter(t *testing.T) {
+	if got := cleanResponse("\x1b[200~y\x1b[201~\r\n"); got != "y" {
+		t.Errorf("cleanResponse stripped to %q, want %q", got, "y")
+	}
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"y\n", true},
+		{"\n", false},
+		{"", false},
+		{"maybe\n1\n", true},
+		{"maybe\n2\n", false},
+		{"maybe\nx\nx\nx\n", false},
+	}
+	for _, tt := range tests {
+		p := &setupPrompter{reader: bufio.NewReader(strings.NewReader(tt.input))}
+		if got := p.askYesNo("aliases", "Set up?"); got != tt.expected {
+			t.Errorf("askYesNo(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+
+	p := &setupPrompter{reader: bufio.NewReader(strings.NewReader("")), answers: map[string]string{"marksdir": "~/bm", "completion": "yes"}}
+	if dir := p.askText("marksdir", "Where?", "~/.marks"); dir != "~/bm" {
+		t.Errorf("askText from answers = %q", dir)
+	}
+	if !p.askYesNo("completion", "Completion?") || p.askYesNo("aliases", "Aliases?") {
+		t.Error("Answer file should say yes to completion and fall back to no for aliases")
+	}
+
+	path := filepath.Join(t.TempDir(), "answers")
+	os.WriteFile(path, []byte("# CI\nmarksdir=~/bm\naliases=no\n"), 0644)
+	if answers, err := loadSetupAnswers(path); err != nil || answers["aliases"] != "no" {
+		t.Errorf("loadSetupAnswers = %v (err %v)", answers, err)
+	}
+	os.WriteFile(path, []byte("shell=zsh\n"), 0644)
+	if _, err := loadSetupAnswers(path); err == nil {
+		t.Error("Expected an error for an unknown answer key")
+	}
+}
+
+func TestSourceOrder(t *testing.T) {
+	order, err := parseSourceOrder("shared, personal")
+	if err != nil || !reflect.DeepEqual(order, []string{"shared", "personal", "project", "system"}) {
+		t.Errorf("parseSourceOrder = %v (err %v)", order, err)
+	}
+	if _, err := parseSourceOrder("personal,nfs"); err == nil {
+		t.Error("Expected an error for an unknown source")
+	}
+
+	tmpDir := t.TempDir()
+	teamDir := filepath.Join(tmpDir, "team")
+	os.Mkdir(teamDir, 0755)
+	os.Symlink("/srv/team-api", filepath.Join(teamDir, "api"))
+	os.Symlink("/srv/team-logs", filepath.Join(teamDir, "logs"))
+
+	config := Config{MarksDir: filepath.Join(tmpDir, "personal"), SharedMarks: []string{teamDir}, SystemMarks: "off", ProjectMarks: "off", SourceOrder: "shared"}
+	personal, _ := marks.Open(config)
+	personal.Create("api", "/home/me/api")
+
+	storage := openLayeredStorage(config)
+	if bookmark, _ := storage.Get("api"); bookmark.Target != "/srv/team-api" || !bookmark.Shared {
+		t.Errorf("shared source should win with source_order=shared, got %+v", bookmark)
+	}
+
+	config.SourceOrder = ""
+	storage = openLayeredStorage(config)
+	if bookmark, _ := storage.Get("api"); bookmark.Target != "/home/me/api" || bookmark.Shared {
+		t.Errorf("personal source should win by default, got %+v", bookmark)
+	}
+	if err := storage.Create("new", tmpDir); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := personal.Get("new"); err != nil {
+		t.Error("Writes must go to the personal storage")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".mark")
+	os.WriteFile(configPath, []byte("marksdir="+tmpDir+"/personal\nprofile=work="+tmpDir+"/client\n"), 0644)
+
+	config, err := marks.ParseConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("marks.ParseConfigFile failed: %v", err)
+	}
+
+	selected, err := applyProfile(config, "work")
+	if err != nil || selected.MarksDir != tmpDir+"/client" {
+		t.Errorf("applyProfile(work) = %q (err %v)", selected.MarksDir, err)
+	}
+	if selected, _ := applyProfile(config, ""); selected.MarksDir != tmpDir+"/personal" {
+		t.Errorf("No profile should keep marksdir, got %q", selected.MarksDir)
+	}
+	if _, err := applyProfile(config, "home"); err == nil || !strings.Contains(err.Error(), "work") {
+		t.Errorf("Unknown profile error should list configured profiles, got %v", err)
+	}
+
+	// Workspace rules pick a profile by directory unless one is given
+	config.WorkspaceMap = []marks.PathMapping{{From: marks.ExpandPath(tmpDir), To: "work"}}
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	originalProfile := os.Getenv("MARK_PROFILE")
+	defer os.Setenv("MARK_PROFILE", originalProfile)
+	os.Setenv("MARK_PROFILE", "")
+	os.Chdir(tmpDir)
+	if name := profileName(config, ""); name != "work" {
+		t.Errorf("profileName inside workspace = %q, want work", name)
+	}
+	if name := profileName(config, "home"); name != "home" {
+		t.Errorf("Explicit profile should win, got %q", name)
+	}
+	os.Chdir(originalDir)
+	if name := profileName(config, ""); name != "" {
+		t.Errorf("profileName outside workspace = %q, want none", name)
+	}
+
+	os.WriteFile(configPath, []byte("profile=work\n"), 0644)
+	if _, err := marks.ParseConfigFile(configPath); err == nil {
+		t.Error("Expected an error for a profile without a directory")
+	}
+}
+
+func TestDefaultsToJump(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks"), SystemMarks: "off"}
+	storage, _ := marks.Open(config)
+	storage.Create("work", tmpDir)
+
+	if defaultsToJump(config, "work") {
+		t.Error("Without default_action, an existing name must not jump")
+	}
+	config.DefaultAction = "jump"
+	if !defaultsToJump(config, "work") {
+		t.Error("default_action=jump should jump to an existing bookmark")
+	}
+	if defaultsToJump(config, "new") {
+		t.Error("Unknown names should still be created")
+	}
+}
+
+func TestNormalizeTarget(t *testing.T) {
+	cwd, _ := os.Getwd()
+	tests := map[string]string{
+		"/home/me/src/":       "/home/me/src",
+		"/home/me//src":       "/home/me/src",
+		"/home/me/tmp/../src": "/home/me/src",
+		"src":                 filepath.Join(cwd, "src"),
+		"..":                  filepath.Dir(cwd),
+	}
+	for path, expected := range tests {
+		if got := normalizeTarget(path); got != expected {
+			t.Errorf("normalizeTarget(%q) = %q, want %q", path, got, expected)
+		}
+	}
+
+	fixes := planTargetFixes([]Bookmark{
+		{Name: "src", Target: "/home/me/src/"},
+		{Name: "ok", Target: "/home/me/ok"},
+		{Name: "rel", Target: "../shared/./docs"},
+	})
+	expected := []targetFix{
+		{name: "src", from: "/home/me/src/", to: "/home/me/src"},
+		{name: "rel", from: "../shared/./docs", to: "../shared/docs"},
+	}
+	if !reflect.DeepEqual(fixes, expected) {
+		t.Errorf("planTargetFixes = %+v, want %+v", fixes, expected)
+	}
+}
+
+func TestLinkTarget(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := Config{MarksDir: filepath.Join(home, ".marks")}
+	project := filepath.Join(home, "src", "proj")
+
+	tests := []struct {
+		storage string
+		style   string
+		dir     string
+		want    string
+	}{
+		{"", "", project, project},
+		{"", "absolute", project, project},
+		{"", "relative", project, filepath.Join("..", "src", "proj")},
+		// Symlinks get a real path other programs can follow, never "~/"
+		{"", "home", project, filepath.Join("..", "src", "proj")},
+		{"", "home", home, ".."},
+		{"", "home", "/srv/data", "/srv/data"},
+		{"json", "home", project, "~/src/proj"},
+		{"json", "home", home, "~"},
+		{"json", "home", "/srv/data", "/srv/data"},
+		{"", "relative", "https://go.dev", "https://go.dev"},
+	}
+	for _, tt := range tests {
+		config.Storage, config.LinkStyle = tt.storage, tt.style
+		got := linkTarget(config, tt.dir)
+		if got != tt.want {
+			t.Errorf("linkTarget(%q, %q, %q) = %q, want %q", tt.storage, tt.style, tt.dir, got, tt.want)
+		}
+		if back := marks.TargetPath(config, got); back != tt.dir {
+			t.Errorf("marks.TargetPath(%q) = %q, want %q", got, back, tt.dir)
+		}
+	}
+
+	config.Storage, config.LinkStyle = "", "home"
+	config.MarksDir = "/data/marks"
+	if got := linkTarget(config, project); got != project {
+		t.Errorf("marks directory outside home: linkTarget = %q, want %q", got, project)
+	}
+
+	if validLinkStyle("hardlink") {
+		t.Error("validLinkStyle accepted an unknown style")
+	}
+}
+
+func TestBrokenJumpAncestor(t *testing.T) {
+	for _, mode := range []string{"", "fail", "ancestor", "repair"} {
+		if !validOnBroken(mode) {
+			t.Errorf("validOnBroken(%q) = false, want true", mode)
+		}
+	}
+	if validOnBroken("ignore") {
+		t.Error("validOnBroken(\"ignore\") = true, want false")
+	}
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "gone", "deeper")
+	got := brokenJumpTarget(Config{MarksDir: dir}, Bookmark{Name: "deep"}, target, "ancestor")
+	if got != dir {
+		t.Errorf("brokenJumpTarget(ancestor) = %q, want %q", got, dir)
+	}
+}
+
+func TestWriteHandoff(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	for _, path := range []string{"/home/me/first", "/home/me/second"} {
+		if err := writeHandoff(path); err != nil {
+			t.Fatalf("writeHandoff(%q) failed: %v", path, err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(runtimeDir, "mark", "last-path"))
+	if err != nil {
+		t.Fatalf("reading handoff file failed: %v", err)
+	}
+	if string(content) != "/home/me/second\n" {
+		t.Errorf("handoff file = %q, want the last target", content)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("XDG_STATE_HOME", runtimeDir)
+	if got, want := handoffFilePath(), filepath.Join(runtimeDir, "mark", "last-path"); got != want {
+		t.Errorf("handoffFilePath() without a runtime dir = %q, want %q", got, want)
+	}
+}
+
+func TestConfigFreeCommandsAreSubcommands(t *testing.T) {
+	for name := range configFreeCommands {
+		if _, ok := subcommands[name]; !ok {
+			t.Errorf("config-free command %q is not a registered subcommand", name)
+		}
+	}
+}
+
+func TestUninstallHelpers(t *testing.T) {
+	home := t.TempDir()
+	if !removableDir(filepath.Join(home, ".marks"), home) || !removableDir("/data/marks", home) {
+		t.Error("marks directories below or beside home should be removable")
+	}
+	if removableDir(home, home) || removableDir(filepath.Dir(home), home) || removableDir("/", home) {
+		t.Error("home and its ancestors must never be removable")
+	}
+
+	bashrc := filepath.Join(home, ".bashrc")
+	os.WriteFile(bashrc, []byte("export EDITOR=vi\n\n"+sourceLineMarker+"\n[ -f ~/.mark_bash_rc ] && source ~/.mark_bash_rc\n"), 0644)
+	os.WriteFile(filepath.Join(home, bashRCFile), []byte("# mark\n"), 0644)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	if files := installedFiles(home); !reflect.DeepEqual(files, []string{filepath.Join(home, bashRCFile)}) {
+		t.Errorf("installedFiles = %v", files)
+	}
+
+	// Completion files from 'mark completion install --user'
+	userCompletions := []string{filepath.Join(home, bashRCFile), completionInstallPath("fish", "", false)}
+	for _, shell := range []string{"bash", "zsh"} {
+		path := completionInstallPath(shell, "", false)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("# mark\n"), 0644)
+		userCompletions = append(userCompletions, path)
+	}
+	os.MkdirAll(filepath.Dir(userCompletions[1]), 0755)
+	os.WriteFile(userCompletions[1], []byte("# mark\n"), 0644)
+	if files := installedFiles(home); !reflect.DeepEqual(files, userCompletions) {
+		t.Errorf("installedFiles = %v, want %v", files, userCompletions)
+	}
+	if files := sourcingFiles(home); !reflect.DeepEqual(files, []string{bashrc}) {
+		t.Errorf("sourcingFiles = %v", files)
+	}
+
+	cleanupShellConfigSourceLine(bashrc)
+	content, _ := os.ReadFile(bashrc)
+	if string(content) != "export EDITOR=vi\n" {
+		t.Errorf("cleaned .bashrc = %q", content)
+	}
+}
+
+func TestConfigEnvOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "mark.conf")
+	os.WriteFile(configPath, []byte("marksdir="+filepath.Join(tmpDir, "from-config")+"\n"), 0644)
+
+	t.Setenv("MARK_CONFIG", configPath)
+	t.Setenv("MARK_DIR", "")
+	if path, _ := marks.ConfigFilePath(); path != configPath {
+		t.Errorf("marks.ConfigFilePath() = %q, want %q", path, configPath)
+	}
+	config, err := readConfig()
+	if err != nil || config.MarksDir != filepath.Join(tmpDir, "from-config") {
+		t.Errorf("readConfig() = %q (err %v), want marksdir from $MARK_CONFIG", config.MarksDir, err)
+	}
+
+	t.Setenv("MARK_DIR", filepath.Join(tmpDir, "from-env"))
+	if config, _ := readConfig(); config.MarksDir != filepath.Join(tmpDir, "from-env") {
+		t.Errorf("$MARK_DIR should override marksdir, got %q", config.MarksDir)
+	}
+
+	t.Setenv("MARK_CONFIG", filepath.Join(tmpDir, "missing.conf"))
+	if config, err := readConfig(); err != nil || config.MarksDir != filepath.Join(tmpDir, "from-env") {
+		t.Errorf("$MARK_DIR without a config file = %q (err %v)", config.MarksDir, err)
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	lines := []string{"# my settings", "marksdir=~/.marks", "shared_marks=/a", "sort=name", "shared_marks=/b"}
+
+	updated := setConfigValue(lines, "sort", "frecency,name")
+	expected := []string{"# my settings", "marksdir=~/.marks", "shared_marks=/a", "sort=frecency,name", "shared_marks=/b"}
+	if !reflect.DeepEqual(updated, expected) {
+		t.Errorf("setConfigValue(sort) = %q, want %q", updated, expected)
+	}
+
+	updated = setConfigValue(lines, "shared_marks", "/c")
+	expected = []string{"# my settings", "marksdir=~/.marks", "shared_marks=/c", "sort=name"}
+	if !reflect.DeepEqual(updated, expected) {
+		t.Errorf("setConfigValue(shared_marks) = %q, want %q", updated, expected)
+	}
+
+	updated = setConfigValue(lines, "on_broken", "ancestor")
+	if updated[len(updated)-1] != "on_broken=ancestor" {
+		t.Errorf("new key should be appended, got %q", updated)
+	}
+
+	if values := configValues(lines, "shared_marks"); !reflect.DeepEqual(values, []string{"/a", "/b"}) {
+		t.Errorf("configValues(shared_marks) = %q", values)
+	}
+
+	if err := validateConfigValue("sort", "frecency,bogus"); err == nil {
+		t.Error("validateConfigValue should reject unknown sort keys")
+	}
+	if err := validateConfigValue("on_broken", "repair"); err != nil {
+		t.Errorf("validateConfigValue(on_broken=repair) = %v", err)
+	}
+}
+
+func TestTutorialArgs(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+		jump     bool
+		ok       bool
+	}{
+		{"", []string{"-l"}, false, true},
+		{"mark docs ~/docs", []string{"docs", "~/docs"}, false, true},
+		{"  jump docs ", []string{"--dir-fallback", "-j", "docs"}, true, true},
+		{"ls -la", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		args, jump, ok := tutorialArgs(tt.line, "mark -l")
+		if !reflect.DeepEqual(args, tt.expected) || jump != tt.jump || ok != tt.ok {
+			t.Errorf("tutorialArgs(%q) = %q, %v, %v; want %q, %v, %v", tt.line, args, jump, ok, tt.expected, tt.jump, tt.ok)
+		}
+	}
+}
+
+func TestDirectoryFallback(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks")}
+	storage, _ := marks.Open(config)
+	plain := filepath.Join(tmpDir, "plain")
+	os.Mkdir(plain, 0755)
+	storage.Create("work", tmpDir)
+
+	if dir, ok := directoryFallback(storage, plain); !ok || dir != plain {
+		t.Errorf("directoryFallback(%q) = %q, %v", plain, dir, ok)
+	}
+	if _, ok := directoryFallback(storage, "work"); ok {
+		t.Error("An existing bookmark must not fall back to a directory")
+	}
+	if _, ok := directoryFallback(storage, filepath.Join(tmpDir, "missing")); ok {
+		t.Error("A missing directory must not be accepted")
+	}
+
+	// Relative paths leaving the current directory are never bookmark names
+	t.Chdir(plain)
+	for arg, want := range map[string]string{"..": tmpDir, "../plain": plain, "../.marks": config.MarksDir} {
+		if dir, ok := directoryFallback(storage, arg); !ok || dir != want {
+			t.Errorf("directoryFallback(%q) = %q, %v; want %q", arg, dir, ok, want)
+		}
+	}
+}
+
+// getRecorder remembers the names looked up in the storage it wraps
+type getRecorder struct {
+	Storage
+	names []string
+}
+
+func (r *getRecorder) Get(name string) (Bookmark, error) {
+	r.names = append(r.names, name)
+	return r.Storage.Get(name)
+}
+
+func TestSubpaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks")}
+	storage, _ := marks.Open(config)
+	project := filepath.Join(tmpDir, "project")
+	for _, dir := range []string{"src/app", "docs", ".git"} {
+		os.MkdirAll(filepath.Join(project, dir), 0755)
+	}
+	os.WriteFile(filepath.Join(project, "README"), nil, 0644)
+	storage.Create("proj", project)
+
+	splits := map[string][2]string{
+		"proj":          {"proj", ""},
+		"proj/src/app":  {"proj", "src/app"},
+		"other/src":     {"other/src", ""},
+		"../relative":   {"../relative", ""},
+		"/absolute/dir": {"/absolute/dir", ""},
+		"beta/../proj":  {"beta/../proj", ""},
+	}
+	recorder := &getRecorder{Storage: storage}
+	for arg, expected := range splits {
+		if name, subpath := splitSubpath(recorder, arg); name != expected[0] || subpath != expected[1] {
+			t.Errorf("splitSubpath(%q) = %q, %q; want %q, %q", arg, name, subpath, expected[0], expected[1])
+		}
+	}
+	for _, name := range recorder.names {
+		if strings.Contains(name, "/") {
+			t.Errorf("splitSubpath looked up %q in storage", name)
+		}
+	}
+
+	completions := map[string][]string{
+		"proj/":        {"proj/docs/", "proj/src/"},
+		"proj/s":       {"proj/src/"},
+		"proj/src/":    {"proj/src/app/"},
+		"proj/.":       {"proj/.git/"},
+		"proj/READ":    nil,
+		"nope/":        nil,
+		"proj/../":     nil,
+		"proj/src/../": nil,
+	}
+	for word, expected := range completions {
+		if got := subpathCompletions(config, storage, word); !reflect.DeepEqual(got, expected) {
+			t.Errorf("subpathCompletions(%q) = %q, want %q", word, got, expected)
+		}
+	}
+
+	for subpath, inside := range map[string]bool{"": true, "src/app": true, "src/..app": true, "..": false, "src/../..": false, "/etc": false} {
+		if got := insideSubpath(subpath); got != inside {
+			t.Errorf("insideSubpath(%q) = %v, want %v", subpath, got, inside)
+		}
+	}
+}
+
+func TestJSONStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks"), Storage: "json"}
+
+	storage, err := marks.Open(config)
+	if err != nil {
+		t.Fatalf("marks.Open failed: %v", err)
+	}
+
+	if err := storage.Create("work", "/srv/work"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := storage.Create("work", "/srv/other"); !errors.Is(err, marks.ErrExists) {
+		t.Errorf("Expected marks.ErrExists, got %v", err)
+	}
+
+	// Index lives in the marks directory and no symlink is created
+	if _, err := os.Stat(filepath.Join(config.MarksDir, marks.JSONIndexFile)); err != nil {
+		t.Errorf("Index file not created: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(config.MarksDir, "work")); err == nil {
+		t.Error("JSON storage should not create symlinks")
+	}
+
+	bookmark, err := storage.Get("work")
+	if err != nil || bookmark.Target != "/srv/work" {
+		t.Errorf("Get = %v (err %v)", bookmark, err)
+	}
+
+	bookmarks, _ := storage.List()
+	if len(bookmarks) != 1 {
+		t.Errorf("Expected 1 bookmark, got %d", len(bookmarks))
+	}
+
+	if err := storage.Delete("work"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := storage.Delete("work"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Expected marks.ErrNotFound, got %v", err)
+	}
+
+	// Custom index location
+	custom := filepath.Join(tmpDir, "bookmarks.json")
+	storage, _ = marks.Open(Config{Storage: "json", StorageFile: custom})
+	storage.Create("home", tmpDir)
+	if _, err := os.Stat(custom); err != nil {
+		t.Errorf("Custom index file not created: %v", err)
+	}
+}
+
+func TestExpandWindowsPath(t *testing.T) {
+	env := map[string]string{
+		"USERPROFILE": `C:\Users\me`,
+		"APPDATA":     `C:\Users\me\AppData\Roaming`,
+	}
+	getenv := func(name string) string { return env[name] }
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`%USERPROFILE%\src`, `C:\Users\me\src`},
+		{`%APPDATA%\mark`, `C:\Users\me\AppData\Roaming\mark`},
+		{`%UNSET%\dir`, `%UNSET%\dir`},
+		{`100%\done`, `100%\done`},
+		{`%UNSET%%USERPROFILE%`, `%UNSET%C:\Users\me`},
+		{`C:\projects`, `C:\projects`},
+		{`\\server\share\dir`, `\\server\share\dir`},
+		{`~\src`, `~/src`},
+	}
+
+	for _, tt := range tests {
+		if result := marks.ExpandWindowsPath(tt.input, getenv); result != tt.expected {
+			t.Errorf("marks.ExpandWindowsPath(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestIsDriveRelative(t *testing.T) {
+	tests := map[string]bool{
+		`C:`:             true,
+		`c:projects`:     true,
+		`C:\projects`:    false,
+		`D:/projects`:    false,
+		`\\server\share`: false,
+		`/home/me`:       false,
+		`1:foo`:          false,
+	}
+
+	for path, expected := range tests {
+		if result := isDriveRelative(path); result != expected {
+			t.Errorf("isDriveRelative(%q) = %v, want %v", path, result, expected)
+		}
+	}
+}
+
+func TestScreenReaderSentence(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		broken      bool
+		description string
+		expected    string
+	}{
+		{"projects", "/home/me/src", false, "", "bookmark projects points to slash home slash me slash src, status ok."},
+		{"old", "/gone", true, "", "bookmark old points to slash gone, status broken, the target does not exist."},
+		{"docs", "../docs", false, "team wiki", "bookmark docs points to .. slash docs, status ok, description: team wiki."},
+	}
+
+	for _, tt := range tests {
+		result := screenReaderSentence(tt.name, tt.target, tt.broken, tt.description)
+		if result != tt.expected {
+			t.Errorf("screenReaderSentence(%q) = %q, want %q", tt.name, result, tt.expected)
+		}
+		if strings.ContainsAny(result, "\x1b->") {
+			t.Errorf("sentence contains color codes or arrows: %q", result)
+		}
+	}
+}
+
+func TestDiagnoseTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "projects")
+	os.MkdirAll(existing, 0755)
+
+	t.Run("reachable", func(t *testing.T) {
+		if d := diagnoseTarget(existing); !d.Reachable {
+			t.Errorf("Expected %s to be reachable: %+v", existing, d)
+		}
+	})
+
+	t.Run("missing component", func(t *testing.T) {
+		d := diagnoseTarget(filepath.Join(existing, "gone", "deeper"))
+		if d.Missing != filepath.Join(existing, "gone") {
+			t.Errorf("Missing = %q, want %q", d.Missing, filepath.Join(existing, "gone"))
+		}
+		if d.Existing != existing {
+			t.Errorf("Existing = %q, want %q", d.Existing, existing)
+		}
+	})
+
+	t.Run("dangling symlink", func(t *testing.T) {
+		link := filepath.Join(tmpDir, "dangling")
+		os.Symlink(filepath.Join(tmpDir, "nowhere"), link)
+		d := diagnoseTarget(filepath.Join(link, "sub"))
+		if d.Missing != link || !d.Dangling {
+			t.Errorf("Expected dangling symlink at %s: %+v", link, d)
+		}
+	})
+
+	t.Run("file in path", func(t *testing.T) {
+		file := filepath.Join(tmpDir, "notes.txt")
+		os.WriteFile(file, []byte("x"), 0644)
+		if d := diagnoseTarget(filepath.Join(file, "sub")); d.NotDir != file {
+			t.Errorf("NotDir = %q, want %q", d.NotDir, file)
+		}
+		if d := diagnoseTarget(file); !d.TargetFile {
+			t.Errorf("Expected TargetFile for %s", file)
+		}
+	})
+}
+
+func TestUnmountedMountPoint(t *testing.T) {
+	configured := []string{"/", "/mnt/nas", "/mnt/nas/media", "/home", "none"}
+	mounted := []string{"/", "/home"}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/mnt/nas/media/films", "/mnt/nas/media"},
+		{"/mnt/nas/docs", "/mnt/nas"},
+		{"/mnt/nasty", ""},
+		{"/home/me/src", ""},
+	}
+
+	for _, tt := range tests {
+		if result := unmountedMountPoint(tt.path, configured, mounted); result != tt.expected {
+			t.Errorf("unmountedMountPoint(%q) = %q, want %q", tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestReadMountPoints(t *testing.T) {
+	fstab := filepath.Join(t.TempDir(), "fstab")
+	os.WriteFile(fstab, []byte("# comment\nUUID=abc / ext4 defaults 0 1\n\nnas:/vol /mnt/nas nfs defaults 0 0\n"), 0644)
+
+	points := readMountPoints(fstab)
+	if len(points) != 2 || points[0] != "/" || points[1] != "/mnt/nas" {
+		t.Errorf("readMountPoints = %v", points)
+	}
+}
+
+func TestBenchInitArgs(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		argv, err := benchInitArgs(shell, "true")
+		if err != nil {
+			t.Fatalf("benchInitArgs(%q) failed: %v", shell, err)
+		}
+		if argv[0] != shell || !strings.HasSuffix(argv[len(argv)-1], "true") {
+			t.Errorf("benchInitArgs(%q) = %v", shell, argv)
+		}
+	}
+
+	if _, err := benchInitArgs("tcsh", "true"); err == nil {
+		t.Error("Expected error for unsupported shell")
+	}
+}
+
+func TestMedianDuration(t *testing.T) {
+	samples := []time.Duration{5 * time.Millisecond, 1 * time.Millisecond, 9 * time.Millisecond}
+	if m := medianDuration(samples); m != 5*time.Millisecond {
+		t.Errorf("medianDuration = %v, want 5ms", m)
+	}
+	if m := medianDuration(nil); m != 0 {
+		t.Errorf("medianDuration(nil) = %v, want 0", m)
+	}
+}
+
+func TestParseZData(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), ".z")
+	content := "/home/me/src|12|1700000000\n" +
+		"/srv/www/src|40.5|1700000100\n" +
+		"3|1700000200|/home/me/docs\n" +
+		"garbage line\n" +
+		"/bad/rank|x|1700000000\n"
+	os.WriteFile(dataFile, []byte(content), 0644)
+
+	entries, err := parseZData(dataFile)
+	if err != nil {
+		t.Fatalf("parseZData failed: %v", err)
+	}
+
+	expected := []importEntry{
+		{Name: "src", Path: "/srv/www/src"},
+		{Name: "src", Path: "/home/me/src"},
+		{Name: "docs", Path: "/home/me/docs"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %v", len(expected), entries)
+	}
+	for i, e := range expected {
+		if entries[i].Name != e.Name || entries[i].Path != e.Path {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], e)
+		}
+	}
+}
+
+func TestImportEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", tmpDir)
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks")}
+	storage, _ := marks.Open(config)
+
+	first := filepath.Join(tmpDir, "a", "src")
+	second := filepath.Join(tmpDir, "b", "src")
+	os.MkdirAll(first, 0755)
+	os.MkdirAll(second, 0755)
+
+	entries := []importEntry{
+		{Name: "src", Path: first, Tags: []string{"z"}},
+		{Name: "src", Path: second},
+		{Name: "gone", Path: filepath.Join(tmpDir, "gone")},
+	}
+
+	if imported, skipped := importEntries(config, storage, entries, "", true); imported != 1 || skipped != 2 {
+		t.Errorf("dry-run importEntries = (%d, %d), want (1, 2)", imported, skipped)
+	}
+	if existing, _ := storage.List(); len(existing) != 0 {
+		t.Errorf("dry run created bookmarks: %v", existing)
+	}
+
+	imported, skipped := importEntries(config, storage, entries, "", false)
+	if imported != 1 || skipped != 2 {
+		t.Errorf("importEntries = (%d, %d), want (1, 2)", imported, skipped)
+	}
+
+	bookmark, err := storage.Get("src")
+	if err != nil || bookmark.Target != first {
+		t.Errorf("Expected src -> %s, got %v (err %v)", first, bookmark, err)
+	}
+
+	meta, _ := marks.LoadMetadata(config.MarksDir)
+	if !hasTag(meta["src"], "z") {
+		t.Error("Expected imported tags to be saved")
+	}
+
+	// A prefix avoids the collision with the bookmark imported above
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if imported, _ := importEntries(config, storage, entries[:1], "work:", false); imported != 1 {
+		t.Errorf("importEntries with prefix imported %d, want 1", imported)
+	}
+	if bookmark, err := storage.Get("work:src"); err != nil || bookmark.Target != first {
+		t.Errorf("Expected work:src -> %s, got %v (err %v)", first, bookmark, err)
+	}
+}
+
+func TestMarkFunction(t *testing.T) {
+	t.Setenv("MARK_CONFIG", filepath.Join(t.TempDir(), "missing"))
+	for shell, content := range map[string]string{
+		"bash": generateBashRC("/usr/bin/mark", true, false),
+		"zsh":  generateZshRC("/usr/bin/mark", true, false),
+		"fish": generateFishRC("/usr/bin/mark", true, false),
+	} {
+		if !strings.Contains(content, "'/usr/bin/mark' --default-jump") {
+			t.Errorf("%s integration does not ask mark whether to jump:\n%s", shell, content)
+		}
+		if !strings.Contains(content, "        cd \"$target\"\n") {
+			t.Errorf("%s mark function does not change directory:\n%s", shell, content)
+		}
+	}
+	if content := generateBashRC("/usr/bin/mark", false, true); strings.Contains(content, "--default-jump") {
+		t.Error("mark function generated without aliases")
+	}
+}
+
+func TestGenerateLazyRC(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh"} {
+		content, err := generateLazyRC(shell, "/usr/local/bin/mark")
+		if err != nil {
+			t.Fatalf("generateLazyRC(%q) failed: %v", shell, err)
+		}
+
+		for _, want := range []string{
+			"__mark_lazy_load()",
+			"'/usr/local/bin/mark' init " + shell,
+			"jump() {",
+			"mark() { __mark_lazy_load; mark \"$@\"; }",
+			"__mark_lazy_complete mark marks unmark jump",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("%s lazy script missing %q", shell, want)
+			}
+		}
+
+		// The full completion must not be defined up front
+		if strings.Contains(content, "_mark_tag_filter") {
+			t.Errorf("%s lazy script contains the full completion", shell)
+		}
+	}
+
+	if _, err := generateLazyRC("fish", "mark"); err == nil {
+		t.Error("Expected error for fish lazy loading")
+	}
+}
+
+func TestRemapPath(t *testing.T) {
+	mappings := []marks.PathMapping{
+		{From: "/Users/me", To: "/home/me"},
+		{From: "/Users/me/work", To: "/srv/work/"},
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/Users/me/src/app", "/home/me/src/app"},
+		{"/Users/me/work/api", "/srv/work/api"},
+		{"/Users/me", "/home/me"},
+		{"/Users/meg/src", "/Users/meg/src"},
+		{"/opt/tools", "/opt/tools"},
+	}
+
+	for _, tt := range tests {
+		if result := remapPath(tt.path, mappings); result != tt.expected {
+			t.Errorf("remapPath(%q) = %q, want %q", tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestParsePathMapping(t *testing.T) {
+	m, err := marks.ParsePathMapping("/Users/me = /home/me")
+	if err != nil || m.From != "/Users/me" || m.To != "/home/me" {
+		t.Errorf("marks.ParsePathMapping = %+v (err %v)", m, err)
+	}
+
+	for _, bad := range []string{"/Users/me", "=/home/me", "/Users/me="} {
+		if _, err := marks.ParsePathMapping(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	args := sshArgs("dev1", "/home/me/it's here")
+	if args[0] != "-t" || args[1] != "dev1" {
+		t.Errorf("sshArgs = %v", args)
+	}
+	if !strings.HasPrefix(args[2], `cd '/home/me/it'\''s here' && exec`) {
+		t.Errorf("Remote command not quoted correctly: %s", args[2])
+	}
+}
+
+func TestParseConfigFilePathMaps(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ".mark")
+	os.WriteFile(configPath, []byte("marksdir=/tmp/marks\nssh_path_map=/Users/me=/home/me\nssh_path_map=/Volumes/data=/data\ncontainer_path_map=/Users/me/src=/workspaces\n"), 0644)
+
+	config, err := marks.ParseConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("marks.ParseConfigFile failed: %v", err)
+	}
+	if len(config.SSHPathMap) != 2 || config.SSHPathMap[1].To != "/data" {
+		t.Errorf("SSHPathMap = %+v", config.SSHPathMap)
+	}
+	if len(config.ContainerPathMap) != 1 || config.ContainerPathMap[0].To != "/workspaces" {
+		t.Errorf("ContainerPathMap = %+v", config.ContainerPathMap)
+	}
+}
+
+func TestContainerExecArgs(t *testing.T) {
+	args := containerExecArgs("devbox", "/workspaces/app", []string{"make", "test"}, false)
+	expected := []string{"exec", "-i", "-w", "/workspaces/app", "devbox", "make", "test"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("containerExecArgs = %v, want %v", args, expected)
+	}
+
+	args = containerExecArgs("devbox", "/workspaces/app", []string{"sh"}, true)
+	if args[2] != "-t" {
+		t.Errorf("Expected -t with a terminal, got %v", args)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, ".mark")
+	os.WriteFile(path, []byte("marksdir=/old\n"), 0600)
+
+	if err := marks.WriteFileAtomic(path, []byte("marksdir=/new\n"), 0644); err != nil {
+		t.Fatalf("marks.WriteFileAtomic failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "marksdir=/new\n" {
+		t.Errorf("content = %q", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the target file, found %d entries", len(entries))
+	}
+
+	// A missing directory fails without touching anything
+	if err := marks.WriteFileAtomic(filepath.Join(tmpDir, "missing", "file"), []byte("x"), 0644); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
+
+func TestWriteFileAtomicFollowsSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	real := filepath.Join(tmpDir, "dotfiles", "mark")
+	os.MkdirAll(filepath.Dir(real), 0755)
+	os.WriteFile(real, []byte("old"), 0644)
+	link := filepath.Join(tmpDir, ".mark")
+	os.Symlink(real, link)
+
+	if err := marks.WriteFileAtomic(link, []byte("new"), 0644); err != nil {
+		t.Fatalf("marks.WriteFileAtomic failed: %v", err)
+	}
+
+	if info, _ := os.Lstat(link); info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Symlink was replaced by a regular file")
+	}
+	if content, _ := os.ReadFile(real); string(content) != "new" {
+		t.Errorf("Link target content = %q, want %q", content, "new")
+	}
+}
+
+func TestRecordAudit(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "alice")
+
+	if err := recordAudit(Config{}, "create", "work", "/srv/work"); err != nil {
+		t.Fatalf("recordAudit failed: %v", err)
+	}
+	if _, err := os.Stat(auditFilePath()); !os.IsNotExist(err) {
+		t.Fatal("audit log written without audit_log=on")
+	}
+
+	config := Config{AuditLog: "on"}
+	recordAudit(config, "create", "work", "/srv/work")
+	recordAudit(config, "delete", "work", "/srv/work")
+
+	content, err := os.ReadFile(auditFilePath())
+	if err != nil {
+		t.Fatalf("reading audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log has %d lines, want 2", len(lines))
+	}
+	fields := strings.Split(lines[1], "\t")
+	if len(fields) != 5 || fields[1] != "alice" || fields[2] != "delete" || fields[3] != "work" || fields[4] != "/srv/work" {
+		t.Errorf("audit line = %q", lines[1])
+	}
+	if _, err := time.Parse(time.RFC3339, fields[0]); err != nil {
+		t.Errorf("audit time %q is not RFC 3339: %v", fields[0], err)
+	}
+
+	if got := auditUser(func(key string) string { return map[string]string{"USER": "bob"}[key] }); got != "bob" {
+		t.Errorf("auditUser without sudo = %q, want bob", got)
+	}
+}
+
+func TestDebugFromEnv(t *testing.T) {
+	for value, want := range map[string]bool{"1": true, "yes": true, "": false, "0": false, "nope": false} {
+		getenv := func(string) string { return value }
+		if got := debugFromEnv(getenv); got != want {
+			t.Errorf("debugFromEnv with MARK_DEBUG=%q = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestWriteFileBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".mark")
+	os.WriteFile(path, []byte("marksdir=/old\n"), 0644)
+
+	if err := marks.WriteFileBackup(path, []byte("marksdir=/new\n"), 0644); err != nil {
+		t.Fatalf("marks.WriteFileBackup failed: %v", err)
+	}
+	if content, _ := os.ReadFile(path + marks.BackupSuffix); string(content) != "marksdir=/old\n" {
+		t.Errorf("backup = %q, want the previous content", content)
+	}
+	if marks.BackupHint(path) == "" {
+		t.Error("marks.BackupHint should point at the existing backup")
+	}
+
+	// A damaged file is set aside when the backup is restored
+	os.WriteFile(path, []byte("marks"), 0644)
+	if err := marks.RestoreBackup(path); err != nil {
+		t.Fatalf("marks.RestoreBackup failed: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "marksdir=/old\n" {
+		t.Errorf("restored content = %q", content)
+	}
+	if content, _ := os.ReadFile(path + ".broken"); string(content) != "marks" {
+		t.Errorf("damaged copy = %q", content)
+	}
+}
+
+func TestParseRangerBookmarks(t *testing.T) {
+	bookmarksFile := filepath.Join(t.TempDir(), "bookmarks")
+	os.WriteFile(bookmarksFile, []byte("a:/home/me/src\n':/home/me\n`:/tmp\nd:/home/me/Downloads\nbroken line\n"), 0644)
+
+	entries, err := parseRangerBookmarks(bookmarksFile)
+	if err != nil {
+		t.Fatalf("parseRangerBookmarks failed: %v", err)
+	}
+
+	expected := []importEntry{
+		{Name: "a", Path: "/home/me/src"},
+		{Name: "d", Path: "/home/me/Downloads"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("entries = %+v, want %+v", entries, expected)
+	}
+}
+
+func TestPromptImportNames(t *testing.T) {
+	entries := []importEntry{
+		{Name: "a", Path: "/home/me/src"},
+		{Name: "d", Path: "/home/me/Downloads"},
+		{Name: "project", Path: "/opt/project"},
+		{Name: "w", Path: "/srv/www"},
+	}
+
+	// Descriptive names are not prompted for
+	input := bufio.NewReader(strings.NewReader("\n-\nwebsite\n"))
+	entries = promptImportNames(entries, input)
+
+	names := []string{entries[0].Name, entries[1].Name, entries[2].Name, entries[3].Name}
+	expected := []string{"src", "d", "project", "website"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("names = %v, want %v", names, expected)
+	}
+}
+
+func TestParseNnnBookmarks(t *testing.T) {
+	tmpDir := t.TempDir()
+	homeDir, _ := os.UserHomeDir()
+
+	oldBMS := os.Getenv("NNN_BMS")
+	defer os.Setenv("NNN_BMS", oldBMS)
+	os.Setenv("NNN_BMS", "d:~/Documents;w:/srv/www")
+
+	// Bookmarks directory of symlinks
+	bookmarksDir := filepath.Join(tmpDir, "bookmarks")
+	os.MkdirAll(bookmarksDir, 0755)
+	os.Symlink("/opt/project", filepath.Join(bookmarksDir, "project"))
+
+	entries, err := parseNnnBookmarks(bookmarksDir)
+	if err != nil {
+		t.Fatalf("parseNnnBookmarks failed: %v", err)
+	}
+	expected := []importEntry{
+		{Name: "d", Path: filepath.Join(homeDir, "Documents")},
+		{Name: "w", Path: "/srv/www"},
+		{Name: "project", Path: "/opt/project"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("entries = %+v, want %+v", entries, expected)
+	}
+
+	// File holding an NNN_BMS export line
+	os.Setenv("NNN_BMS", "")
+	bmsFile := filepath.Join(tmpDir, "nnn.env")
+	os.WriteFile(bmsFile, []byte("export NNN_BMS='m:/mnt;t:/tmp'\n"), 0644)
+	entries, err = parseNnnBookmarks(bmsFile)
+	if err != nil || len(entries) != 2 || entries[1].Path != "/tmp" {
+		t.Errorf("entries = %+v (err %v)", entries, err)
+	}
+}
+
+func TestParseLfMarks(t *testing.T) {
+	marksFile := filepath.Join(t.TempDir(), "marks")
+	os.WriteFile(marksFile, []byte("':/home/me\nc:/home/me/.config\n"), 0644)
+
+	entries, err := parseLfMarks(marksFile)
+	if err != nil {
+		t.Fatalf("parseLfMarks failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "c" || entries[0].Path != "/home/me/.config" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestBuildStats(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	bookmarks := []Bookmark{
+		{Name: "work", Target: "/work"},
+		{Name: "home", Target: "/home"},
+		{Name: "gone", Target: "/gone"},
+		{Name: "docs", Target: "https://example.com"},
+	}
+	broken := map[string]bool{"gone": true}
+	events := []usageEvent{
+		{Time: now.Add(-time.Hour), Name: "work"},
+		{Time: now.Add(-2 * time.Hour), Name: "work"},
+		{Time: now.AddDate(0, 0, -1), Name: "home"},
+		{Time: now.AddDate(0, 0, -29), Name: "work"},
+		{Time: now.AddDate(0, 0, -30), Name: "work"},   // before the window
+		{Time: now.AddDate(0, 0, -2), Name: "deleted"}, // no longer a bookmark
+	}
+
+	stats := buildStats(bookmarks, broken, events, now, 2)
+
+	if stats.Total != 4 || stats.Broken != 1 || stats.URLs != 1 {
+		t.Errorf("counts = %d/%d/%d, want 4/1/1", stats.Total, stats.Broken, stats.URLs)
+	}
+	want := []bookmarkCount{{Name: "work", Count: 4}, {Name: "home", Count: 1}}
+	if !reflect.DeepEqual(stats.MostUsed, want) {
+		t.Errorf("MostUsed = %v, want %v", stats.MostUsed, want)
+	}
+	want = []bookmarkCount{{Name: "gone", Count: 0}, {Name: "docs", Count: 0}}
+	if !reflect.DeepEqual(stats.LeastUsed, want) {
+		t.Errorf("LeastUsed = %v, want %v", stats.LeastUsed, want)
+	}
+	if len(stats.JumpsPerDay) != statsDays {
+		t.Fatalf("JumpsPerDay has %d days, want %d", len(stats.JumpsPerDay), statsDays)
+	}
+	if stats.JumpsPerDay[29] != 2 || stats.JumpsPerDay[28] != 1 || stats.JumpsPerDay[27] != 1 || stats.JumpsPerDay[0] != 1 {
+		t.Errorf("JumpsPerDay = %v", stats.JumpsPerDay)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.UTC) }
+
+	usage := []usageEvent{
+		{Time: time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC), Name: "work"},
+		{Time: day(1, 9), Name: "work"},
+		{Time: day(1, 10), Name: "home"},
+		{Time: day(2, 9), Name: "work"},
+		{Time: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), Name: "home"},
+	}
+	changes := []changeEvent{
+		{Time: day(1, 8), Action: "create", Name: "work"},
+		{Time: day(3, 8), Action: "create", Name: "tmp"},
+		{Time: day(4, 8), Action: "delete", Name: "tmp"},
+		{Time: time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC), Action: "create", Name: "later"},
+	}
+
+	report := buildReport(usage, changes, day(15, 0), 1)
+
+	if report.Month != "2026-03" || report.Jumps != 3 {
+		t.Errorf("Month/Jumps = %s/%d, want 2026-03/3", report.Month, report.Jumps)
+	}
+	if report.JumpsPerDay["2026-03-01"] != 2 || report.JumpsPerDay["2026-03-02"] != 1 {
+		t.Errorf("JumpsPerDay = %v", report.JumpsPerDay)
+	}
+	if len(report.TopBookmarks) != 1 || report.TopBookmarks[0] != (bookmarkCount{Name: "work", Count: 2}) {
+		t.Errorf("TopBookmarks = %v", report.TopBookmarks)
+	}
+	if report.Created != 2 || report.Deleted != 1 {
+		t.Errorf("Created/Deleted = %d/%d, want 2/1", report.Created, report.Deleted)
+	}
+}
+
+func TestRecordChange(t *testing.T) {
+	originalState := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	defer os.Setenv("XDG_STATE_HOME", originalState)
+
+	if err := recordChange("create", "work"); err != nil {
+		t.Fatalf("recordChange failed: %v", err)
+	}
+	recordChange("delete", "work")
+
+	changes, err := loadChanges()
+	if err != nil {
+		t.Fatalf("loadChanges failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Action != "create" || changes[1].Action != "delete" || changes[1].Name != "work" {
+		t.Errorf("changes = %+v", changes)
+	}
+}
+
+func TestURLTargets(t *testing.T) {
+	for target, want := range map[string]bool{
+		"https://go.dev/doc": true,
+		"HTTP://example.com": true,
+		"/srv/https://x":     false,
+		"ftp://example.com":  false,
+		"projects":           false,
+	} {
+		if got := marks.IsURLTarget(target); got != want {
+			t.Errorf("marks.IsURLTarget(%q) = %v, want %v", target, got, want)
+		}
+	}
+
+	if err := validateURLTarget("https://"); err == nil {
+		t.Error("validateURLTarget should reject a URL without a host")
+	}
+	if got := marks.TargetPath(Config{MarksDir: "/home/me/.marks"}, "https://go.dev"); got != "https://go.dev" {
+		t.Errorf("marks.TargetPath changed a URL target: %s", got)
+	}
+	if got := treePathParts("https://go.dev/doc/", "/home/me"); !reflect.DeepEqual(got, []string{"https://go.dev", "doc"}) {
+		t.Errorf("treePathParts(url) = %v", got)
+	}
+}
+
+func TestOpenerCommand(t *testing.T) {
+	env := map[string]string{"BROWSER": "firefox --new-tab:chromium"}
+	getenv := func(key string) string { return env[key] }
+
+	if got := openerCommand("linux", "https://go.dev", getenv); !reflect.DeepEqual(got, []string{"firefox", "--new-tab", "https://go.dev"}) {
+		t.Errorf("openerCommand with $BROWSER = %v", got)
+	}
+	if got := openerCommand("linux", "/srv/work", getenv); !reflect.DeepEqual(got, []string{"xdg-open", "/srv/work"}) {
+		t.Errorf("openerCommand for a directory = %v", got)
+	}
+	delete(env, "BROWSER")
+	if got := openerCommand("darwin", "https://go.dev", getenv); !reflect.DeepEqual(got, []string{"open", "https://go.dev"}) {
+		t.Errorf("openerCommand on darwin = %v", got)
+	}
+}
+
+func TestEditorCommandLine(t *testing.T) {
+	env := map[string]string{"VISUAL": "code --new-window", "EDITOR": "vim"}
+	getenv := func(key string) string { return env[key] }
+
+	if got := editorCommandLine(getenv); !reflect.DeepEqual(got, []string{"code", "--new-window"}) {
+		t.Errorf("editorCommandLine = %v, want VISUAL split into fields", got)
+	}
+
+	env["VISUAL"] = "  "
+	if got := editorCommandLine(getenv); !reflect.DeepEqual(got, []string{"vim"}) {
+		t.Errorf("editorCommandLine = %v, want EDITOR when VISUAL is blank", got)
+	}
+
+	delete(env, "EDITOR")
+	if got := editorCommandLine(getenv); got != nil {
+		t.Errorf("editorCommandLine = %v, want nil without an editor", got)
+	}
+}
+
+func TestPathTreeLines(t *testing.T) {
+	root := buildPathTree([]treeBookmark{
+		{label: "api", path: "/home/me/projects/api"},
+		{label: "web", path: "/home/me/projects/web"},
+		{label: "src", path: "/home/me/projects/web/src"},
+		{label: "logs", path: "/var/log/app"},
+	}, "/home/me")
+
+	want := []string{
+		"/var/log/app  [logs]",
+		"~/projects",
+		"├── api  [api]",
+		"└── web  [web]",
+		"    └── src  [src]",
+	}
+	if got := pathTreeLines(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("pathTreeLines =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCreatedTimes(t *testing.T) {
+	first := time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC)
+	again := first.AddDate(0, 1, 0)
+	created := createdTimes([]changeEvent{
+		{Time: first, Action: "create", Name: "work"},
+		{Time: first.Add(time.Hour), Action: "delete", Name: "work"},
+		{Time: again, Action: "create", Name: "work"},
+		{Time: first, Action: "delete", Name: "gone"},
+	})
+
+	if !created["work"].Equal(again) {
+		t.Errorf("created[work] = %v, want the latest creation %v", created["work"], again)
+	}
+	if _, ok := created["gone"]; ok {
+		t.Errorf("deletions should not count as creations: %v", created)
+	}
+}
+
+func TestCreationTime(t *testing.T) {
+	marksDir := t.TempDir()
+	config := Config{MarksDir: marksDir}
+	if err := os.Symlink("/srv/old", filepath.Join(marksDir, "old")); err != nil {
+		t.Fatal(err)
+	}
+	stored := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	logged := map[string]time.Time{"work": stored.AddDate(1, 0, 0)}
+
+	if got := creationTime(config, "work", &Metadata{Created: stored}, logged, false); !got.Equal(stored) {
+		t.Errorf("metadata time = %v, want %v", got, stored)
+	}
+	if got := creationTime(config, "work", &Metadata{}, logged, false); !got.Equal(logged["work"]) {
+		t.Errorf("change log time = %v, want %v", got, logged["work"])
+	}
+	if got := creationTime(config, "old", nil, logged, false); got.IsZero() {
+		t.Error("symlink mtime fallback missing")
+	}
+	if got := creationTime(config, "old", nil, logged, true); !got.IsZero() {
+		t.Errorf("shared bookmark got local symlink time %v", got)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	day := 24 * time.Hour
+	for d, want := range map[time.Duration]string{
+		time.Hour:  "today",
+		day:        "1 day ago",
+		45 * day:   "45 days ago",
+		400 * day:  "13 months ago",
+		1100 * day: "3 years ago",
+	} {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	day := 24 * time.Hour
+	for value, want := range map[string]time.Duration{"180d": 180 * day, "2w": 14 * day, "1y": 365 * day} {
+		if got, err := parseMaxAge(value); err != nil || got != want {
+			t.Errorf("parseMaxAge(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "d", "180", "0d", "-3d", "6m", "1.5y"} {
+		if _, err := parseMaxAge(value); err == nil {
+			t.Errorf("parseMaxAge(%q) accepted", value)
+		}
+	}
+}
+
+func TestFindStale(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := now.AddDate(0, 0, -180)
+	stale := findStale([]staleBookmark{
+		{Name: "recent", LastUsed: now.AddDate(0, 0, -3), Created: now.AddDate(-2, 0, 0)},
+		{Name: "old", LastUsed: now.AddDate(-1, 0, 0), Created: now.AddDate(-2, 0, 0)},
+		{Name: "ancient", Created: now.AddDate(-3, 0, 0)},
+		{Name: "new", Created: now.AddDate(0, 0, -10)},
+		{Name: "pinned", Created: now.AddDate(-3, 0, 0), Pinned: true},
+		{Name: "unknown"},
+	}, cutoff)
+
+	var names []string
+	for _, bookmark := range stale {
+		names = append(names, bookmark.Name)
+	}
+	if want := []string{"unknown", "ancient", "old"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("stale = %v, want %v", names, want)
+	}
+	if got := describeStale(stale[1], now); got != "never used, created 3 years ago" {
+		t.Errorf("describeStale = %q", got)
+	}
+}
+
+func TestActiveFallback(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "present")
+	if err := os.Mkdir(present, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{MarksDir: dir}
+
+	m := &Metadata{Fallbacks: []string{filepath.Join(dir, "missing"), present, dir}}
+	if got, ok := marks.ActiveFallback(config, m); !ok || got != present {
+		t.Errorf("activeFallback = %q, %v; want %q", got, ok, present)
+	}
+	if _, ok := marks.ActiveFallback(config, &Metadata{Fallbacks: []string{filepath.Join(dir, "missing")}}); ok {
+		t.Error("no fallback exists, but one was chosen")
+	}
+	if _, ok := marks.ActiveFallback(config, nil); ok {
+		t.Error("nil metadata has no fallbacks")
+	}
+
+	if got, err := normalizeFallback("~/data/"); err != nil || got != "~/data" {
+		t.Errorf("normalizeFallback(~/data/) = %q, %v", got, err)
+	}
+	if _, err := normalizeFallback("https://example.com"); err == nil {
+		t.Error("URL fallback accepted")
+	}
+}
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		hostname string
+		want     bool
+	}{
+		{[]string{"laptop"}, "laptop", true},
+		{[]string{"laptop"}, "Laptop.example.com", true},
+		{[]string{"build-*"}, "build-07", true},
+		{[]string{"laptop", "desk"}, "desk", true},
+		{[]string{"laptop.example.com"}, "laptop", false},
+		{[]string{"laptop"}, "server", false},
+	}
+	for _, tt := range tests {
+		if got := hostMatches(tt.patterns, tt.hostname); got != tt.want {
+			t.Errorf("hostMatches(%v, %q) = %v, want %v", tt.patterns, tt.hostname, got, tt.want)
+		}
+	}
+}
+
+func TestHostFilteredStorage(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{MarksDir: dir}
+	storage := &marks.SymlinkStorage{Dir: dir}
+	for _, name := range []string{"everywhere", "laptop-only"} {
+		if err := storage.Create(name, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := marks.UpdateMetadata(dir, "laptop-only", &Metadata{OnlyOn: []string{"laptop"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("MARK_HOSTNAME", "server")
+	filtered := hostFiltered(config, storage)
+	bookmarks, err := filtered.List()
+	if err != nil || len(bookmarks) != 1 || bookmarks[0].Name != "everywhere" {
+		t.Errorf("List on server = %v, %v", bookmarks, err)
+	}
+	if _, err := filtered.Get("laptop-only"); !errors.Is(err, marks.ErrNotFound) {
+		t.Errorf("Get(laptop-only) on server = %v, want ErrNotFound", err)
+	}
+
+	t.Setenv("MARK_HOSTNAME", "laptop")
+	if filtered := hostFiltered(config, storage); filtered != Storage(storage) {
+		t.Error("nothing is hidden on the laptop, storage should be returned as is")
+	}
+}
+
+func TestOSTargets(t *testing.T) {
+	for arg, want := range map[string][2]string{
+		"macos=~/Downloads":   {"darwin", "~/Downloads"},
+		"Linux='/srv/dl/'":    {"linux", "/srv/dl"},
+		`windows=C:\Users\me`: {"windows", `C:\Users\me`},
+	} {
+		goos, target, err := parseOSTarget(arg)
+		if err != nil || goos != want[0] || target != want[1] {
+			t.Errorf("parseOSTarget(%q) = %q, %q, %v; want %q", arg, goos, target, err, want)
+		}
+	}
+	for _, arg := range []string{"beos=/x", "linux=", "/just/a/path", "linux=https://example.com"} {
+		if _, _, err := parseOSTarget(arg); err == nil {
+			t.Errorf("parseOSTarget(%q) accepted", arg)
+		}
+	}
+
+	config := Config{MarksDir: t.TempDir()}
+	here := filepath.Join(config.MarksDir, "here")
+	m := &Metadata{OSTargets: map[string]string{runtime.GOOS: here, "plan9": "/elsewhere"}}
+	if got, ok := marks.OSTarget(config, m); !ok || got != here {
+		t.Errorf("OSTarget = %q, %v; want %q", got, ok, here)
+	}
+	if _, ok := marks.OSTarget(config, &Metadata{OSTargets: map[string]string{"plan9": "/elsewhere"}}); ok {
+		t.Error("another OS's target was used")
+	}
+}
+
+func TestRunTargetCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("target commands run through sh in this test")
+	}
+	dir := t.TempDir()
+
+	got, err := runTargetCommand("echo; echo "+dir+"/; echo /other", time.Second)
+	if err != nil || got != dir {
+		t.Errorf("runTargetCommand = %q, %v; want %q", got, err, dir)
+	}
+	for command, want := range map[string]string{
+		"true":             "printed nothing",
+		"echo relative":    "relative path",
+		"echo /x; exit 3":  "command failed",
+		"sleep 5; echo /x": "timed out",
+	} {
+		if _, err := runTargetCommand(command, 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("runTargetCommand(%q) error = %v, want %q", command, err, want)
+		}
+	}
+
+	if timeout, err := parseDynamicTimeout(""); err != nil || timeout != defaultDynamicTimeout {
+		t.Errorf("parseDynamicTimeout(\"\") = %v, %v", timeout, err)
+	}
+	if timeout, err := parseDynamicTimeout("12"); err != nil || timeout != 12*time.Second {
+		t.Errorf("parseDynamicTimeout(12) = %v, %v", timeout, err)
+	}
+	for _, value := range []string{"0", "-1", "5s"} {
+		if _, err := parseDynamicTimeout(value); err == nil {
+			t.Errorf("parseDynamicTimeout(%q) accepted", value)
+		}
+	}
+}
+
+func TestGitRoot(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "init", "-q", repo).Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	sub := filepath.Join(repo, "src", "api")
+	os.MkdirAll(sub, 0755)
+
+	if got, err := gitRoot(sub); err != nil || got != repo {
+		t.Errorf("gitRoot(%q) = %q, %v; want %q", sub, got, err, repo)
+	}
+	outside := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(outside))
+	if _, err := gitRoot(outside); err == nil {
+		t.Error("gitRoot outside a repository succeeded")
+	}
+}
+
+func TestNameSuggestions(t *testing.T) {
+	existing := map[string]bool{"api": true, "api-2": true, "work-api": false}
+	taken := func(name string) bool { return existing[name] }
+
+	got := nameSuggestions(namePolicy{}, taken, "api", "/srv/work/api")
+	if want := []string{"work-api", "api-3", "api-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nameSuggestions = %v, want %v", got, want)
+	}
+	got = nameSuggestions(namePolicy{Lowercase: true}, taken, "api", "/srv/Work/api")
+	if got[0] != "work-api" {
+		t.Errorf("nameSuggestions with lowercase policy = %v, want work-api first", got)
+	}
+	if got, err := suffixedName(namePolicy{}, taken, "api"); err != nil || got != "api-3" {
+		t.Errorf("suffixedName = %q (err %v), want api-3", got, err)
+	}
+	if got, err := suffixedName(namePolicy{MaxLength: 4}, taken, "apis"); err != nil || got != "ap-2" {
+		t.Errorf("suffixedName with max_length = %q (err %v), want ap-2", got, err)
+	}
+	chars, _ := parseNamePolicy("chars=a-z")
+	if got, err := suffixedName(chars, taken, "api"); err == nil {
+		t.Errorf("suffixedName with chars=a-z = %q, want an error", got)
+	}
+
+	suggestions := []string{"work-api", "api-3"}
+	for answer, want := range map[string]nameConflictChoice{
+		"":          {},
+		"a":         {},
+		"O":         {Overwrite: true},
+		"overwrite": {Overwrite: true},
+		"2":         {Name: "api-3"},
+	} {
+		if got, ok := parseConflictAnswer(answer, suggestions); !ok || got != want {
+			t.Errorf("parseConflictAnswer(%q) = %+v, %v; want %+v", answer, got, ok, want)
+		}
+	}
+	for _, answer := range []string{"3", "0", "maybe"} {
+		if _, ok := parseConflictAnswer(answer, suggestions); ok {
+			t.Errorf("parseConflictAnswer(%q) accepted", answer)
+		}
+	}
+}
+
+func TestMergeMetadata(t *testing.T) {
+	created := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	ours := Metadata{Tags: []string{"mine"}, Description: "ours", Pinned: true, Created: created}
+	theirs := Metadata{Description: "theirs", Host: "dev1", Created: created.AddDate(1, 0, 0)}
+	want := Metadata{Tags: []string{"mine"}, Description: "theirs", Host: "dev1", Pinned: true, Created: created}
+	if got := mergeMetadata(ours, theirs); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeMetadata = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeItems(t *testing.T) {
+	config := Config{MarksDir: "/home/me/.marks"}
+	mine := []Bookmark{
+		{Name: "docs", Target: "/srv/docs"},
+		{Name: "api", Target: "/srv/api"},
+		{Name: "rel", Target: "../shared"},
+	}
+	theirs := []Bookmark{
+		{Name: "api", Target: "/work/api"},
+		{Name: "docs", Target: "/srv/docs/"},
+		{Name: "new", Target: "projects/new"},
+		{Name: "rel", Target: "/home/me/shared"},
+		{Name: "env", Target: "$WORK/env"},
+	}
+
+	items, identical := mergeItems(config, mine, theirs, "/backup/marks")
+	want := []mergeItem{
+		{From: "api", Name: "api", Target: "/work/api", Mine: "/srv/api"},
+		{From: "new", Name: "new", Target: "/backup/marks/projects/new"},
+		{From: "env", Name: "env", Target: "$WORK/env"},
+	}
+	if identical != 2 || !reflect.DeepEqual(items, want) {
+		t.Errorf("mergeItems = %+v, %d; want %+v, 2", items, identical, want)
+	}
+
+	// Their names follow the naming policy, as on import
+	config.NamePolicy = "lowercase,spaces=reject"
+	theirs = []Bookmark{
+		{Name: "API", Target: "/srv/api"},
+		{Name: "New", Target: "/srv/new"},
+		{Name: "NEW", Target: "/srv/other"},
+		{Name: "my docs", Target: "/srv/docs"},
+	}
+	items, identical = mergeItems(config, mine, theirs, "/backup/marks")
+	want = []mergeItem{
+		{From: "New", Name: "new", Target: "/srv/new"},
+		{From: "NEW", Name: "new", Target: "/srv/other", Mine: "/srv/new"},
+		{From: "my docs", Name: "my docs", Target: "/srv/docs", Skip: "bookmark name 'my docs' contains spaces (name_policy spaces=reject)"},
+	}
+	if identical != 1 || !reflect.DeepEqual(items, want) {
+		t.Errorf("mergeItems with name_policy = %+v, %d; want %+v, 1", items, identical, want)
+	}
+}
+
+func TestTrashRoundTripAndExpiry(t *testing.T) {
+	marksDir := t.TempDir()
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	entries := []trashEntry{
+		{Name: "old", Target: "/srv/old", Deleted: now.AddDate(0, 0, -40)},
+		{Name: "work", Target: "/srv/work", Deleted: now.AddDate(0, 0, -2), Metadata: &Metadata{Tags: []string{"client"}}},
+	}
+	if err := saveTrash(marksDir, entries); err != nil {
+		t.Fatalf("saveTrash failed: %v", err)
+	}
+
+	loaded, err := loadTrash(marksDir)
+	if err != nil {
+		t.Fatalf("loadTrash failed: %v", err)
+	}
+	kept := expireTrash(loaded, now, 30)
+	if len(kept) != 1 || kept[0].Name != "work" || kept[0].Metadata == nil || kept[0].Metadata.Tags[0] != "client" {
+		t.Errorf("expireTrash kept %+v", kept)
+	}
+
+	if missing, err := loadTrash(t.TempDir()); err != nil || missing != nil {
+		t.Errorf("missing trash = %v, %v; want empty", missing, err)
+	}
+}
+
+func TestParseTrashDays(t *testing.T) {
+	for value, want := range map[string]int{"": defaultTrashDays, "0": 0, "7": 7} {
+		if days, err := parseTrashDays(value); err != nil || days != want {
+			t.Errorf("parseTrashDays(%q) = %d, %v; want %d", value, days, err, want)
+		}
+	}
+	for _, value := range []string{"-1", "week"} {
+		if _, err := parseTrashDays(value); err == nil {
+			t.Errorf("parseTrashDays(%q) should fail", value)
+		}
+	}
+}
+
+func TestJumpHistory(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	start, _ := os.Getwd()
+
+	if _, ok := previousLocation(nil); ok {
+		t.Error("previousLocation should fail without history")
+	}
+
+	if err := recordJump("/srv/work"); err != nil {
+		t.Fatalf("recordJump failed: %v", err)
+	}
+	events, err := loadJumpHistory()
+	if err != nil {
+		t.Fatalf("loadJumpHistory failed: %v", err)
+	}
+	if len(events) != 1 || events[0].From != start || events[0].To != "/srv/work" {
+		t.Errorf("events = %+v", events)
+	}
+	if prev, ok := previousLocation(events); !ok || prev != start {
+		t.Errorf("previousLocation = %q, %v; want %q", prev, ok, start)
+	}
+}
+
+func TestVisitTracking(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "mark.conf")
+	t.Setenv("MARK_CONFIG", configPath)
+
+	os.WriteFile(configPath, []byte("marksdir=/tmp/marks\n"), 0644)
+	if strings.Contains(generateBashRC("/usr/bin/mark", true, true), "--record-visit") {
+		t.Error("visit hook generated without track_visits=on")
+	}
+
+	os.WriteFile(configPath, []byte("marksdir=/tmp/marks\ntrack_visits=on\n"), 0644)
+	for shell, content := range map[string]string{
+		"bash": generateBashRC("/usr/bin/mark", true, true),
+		"zsh":  generateZshRC("/usr/bin/mark", true, true),
+		"fish": generateFishRC("/usr/bin/mark", true, true),
+	} {
+		if !strings.Contains(content, "'/usr/bin/mark' --record-visit") {
+			t.Errorf("%s integration lacks the visit hook", shell)
+		}
+	}
+
+	recordVisit("/srv/work")
+	recordVisit("/srv/work/")
+	recordVisit("/srv/other")
+	visits, err := loadVisits()
+	if err != nil {
+		t.Fatalf("loadVisits failed: %v", err)
+	}
+	if ranked := rankCounts(visits); len(ranked) != 2 || ranked[0].Path != "/srv/work" || ranked[0].Count != 2 || ranked[0].LastSeen.IsZero() {
+		t.Errorf("ranked visits = %+v", ranked)
+	}
+	if content, _ := os.ReadFile(visitsFilePath()); strings.Count(string(content), "\n") != 2 {
+		t.Errorf("visit counts should hold one line per directory, got:\n%s", content)
+	}
+}
+
+func TestVisitCountsMigrateAndCap(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	os.MkdirAll(filepath.Dir(visitsFilePath()), 0755)
+	os.WriteFile(visitsFilePath(), []byte("100\t/srv/old\n200\t/srv/old/\n3\t300\t/srv/new\nbogus\n"), 0644)
+
+	visits, err := loadVisits()
+	if err != nil {
+		t.Fatalf("loadVisits failed: %v", err)
+	}
+	if old := visits["/srv/old"]; old.Count != 2 || old.LastSeen.Unix() != 200 {
+		t.Errorf("old visit log entry = %+v", old)
+	}
+	if recent := visits["/srv/new"]; recent.Count != 3 || recent.LastSeen.Unix() != 300 {
+		t.Errorf("visit count entry = %+v", recent)
+	}
+
+	for i := 0; i < maxVisitDirs+5; i++ {
+		dir := filepath.Join("/srv", strings.Repeat("d", i+1))
+		visits[dir] = dirCount{Path: dir, Count: 1, LastSeen: time.Unix(int64(1000+i), 0)}
+	}
+	if err := writeVisits(visits); err != nil {
+		t.Fatalf("writeVisits failed: %v", err)
+	}
+	visits, _ = loadVisits()
+	if len(visits) != maxVisitDirs {
+		t.Errorf("kept %d directories, want %d", len(visits), maxVisitDirs)
+	}
+	if _, ok := visits["/srv/old"]; ok {
+		t.Error("least recently seen directory should be dropped")
+	}
+}
+
+func TestFindScanCandidates(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"work/api/.git",
+		"work/api/nested/.git",
+		"work/web",
+		"client/api/.git",
+		"deep/a/b/c/.git",
+		".hidden/repo/.git",
+		"node_modules/pkg/.git",
+	} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+	}
+	os.WriteFile(filepath.Join(root, "work", "web", "package.json"), []byte("{}"), 0644)
+
+	var got []string
+	for _, dir := range findScanCandidates(root, 3, false) {
+		rel, _ := filepath.Rel(root, dir)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"client/api", "work/api", "work/web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findScanCandidates = %v, want %v", got, want)
+	}
+
+	if gitOnly := findScanCandidates(root, 3, true); len(gitOnly) != 2 {
+		t.Errorf("--git-only candidates = %v, want the two repositories", gitOnly)
+	}
+	if deep := findScanCandidates(root, 4, true); len(deep) != 3 {
+		t.Errorf("depth 4 candidates = %v, want deep/a/b/c too", deep)
+	}
+}
+
+func TestUniqueScanName(t *testing.T) {
+	taken := map[string]bool{"api": true}
+	if got := uniqueScanName("/src/client/api", taken, namePolicy{}); got != "client-api" {
+		t.Errorf("uniqueScanName = %q, want client-api", got)
+	}
+
+	taken["client-api"] = true
+	if got := uniqueScanName("/src/client/api", taken, namePolicy{}); got != "api-2" {
+		t.Errorf("uniqueScanName = %q, want api-2", got)
+	}
+
+	if got := uniqueScanName("/src/My Repo", nil, namePolicy{Lowercase: true}); got != "my_repo" {
+		t.Errorf("uniqueScanName with policy = %q, want my_repo", got)
+	}
+}
+
+func TestRepairCandidates(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"old/project", "archive/project", "renamed", "node_modules/project"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+	}
+	renamedID := dirID(filepath.Join(root, "renamed"))
+
+	idx := indexDirectories([]string{root}, 3)
+	candidates := idx.repairCandidates("/gone/project", renamedID)
+	var got []string
+	for _, c := range candidates {
+		rel, _ := filepath.Rel(root, c.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"archive/project", "old/project"}
+	if renamedID != "" {
+		want = append([]string{"renamed"}, want...)
+		if !candidates[0].SameID {
+			t.Errorf("the directory with the recorded ID should be marked SameID")
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repairCandidates = %v, want %v", got, want)
+	}
+
+	if none := idx.repairCandidates("/gone/elsewhere", ""); len(none) != 0 {
+		t.Errorf("repairCandidates for an unknown name = %v, want none", none)
+	}
+}
+
+func TestParseMcHotlist(t *testing.T) {
+	hotlist := filepath.Join(t.TempDir(), "hotlist")
+	content := `ENTRY "home" URL "/home/me"
+GROUP "Client Work"
+  ENTRY "api" URL "/home/me/src/api"
+  GROUP "legacy"
+    ENTRY "old \"site\"" URL "/srv/old"
+  ENDGROUP
+  ENTRY "ftp" URL "ftp://example.com/pub"
+  ENTRY "remote" URL "/#sh:host/home"
+ENDGROUP
+ENTRY "tmp" URL "/tmp"
+`
+	os.WriteFile(hotlist, []byte(content), 0644)
+
+	entries, err := parseMcHotlist(hotlist)
+	if err != nil {
+		t.Fatalf("parseMcHotlist failed: %v", err)
+	}
+
+	expected := []importEntry{
+		{Name: "home", Path: "/home/me"},
+		{Name: "api", Path: "/home/me/src/api", Tags: []string{"Client-Work"}},
+		{Name: `old "site"`, Path: "/srv/old", Tags: []string{"Client-Work", "legacy"}},
+		{Name: "tmp", Path: "/tmp"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i, e := range expected {
+		if entries[i].Name != e.Name || entries[i].Path != e.Path || strings.Join(entries[i].Tags, ",") != strings.Join(e.Tags, ",") {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], e)
+		}
+	}
+}
+
+func TestGenerateBashRCChooser(t *testing.T) {
+	content := generateBashRC("/usr/local/bin/mark", false, true)
+	if !strings.Contains(content, "_mark_choose()") {
+		t.Error("Bash completion missing chooser helper")
+	}
+	if strings.Count(content, `! _mark_choose "$cur"`) != 2 {
+		t.Error("Chooser should be offered wherever the double-Tab list is shown")
+	}
+}
+
+func TestNormalizeTargetArg(t *testing.T) {
+	tmpDir := t.TempDir()
+	spaced := filepath.Join(tmpDir, "My Documents")
+	os.MkdirAll(spaced, 0755)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"file URI", "file:///home/me/src", "/home/me/src"},
+		{"file URI with escapes", "file:///home/me/My%20Documents/", "/home/me/My Documents"},
+		{"file URI with localhost", "file://localhost/srv/www", "/srv/www"},
+		{"pasted with quotes and newline", "'/home/me/src/'\n", "/home/me/src"},
+		{"trailing slash with spaces", spaced + "/", spaced},
+		{"backslash-escaped spaces", strings.ReplaceAll(spaced, " ", `\ `), spaced},
+		{"plain path unchanged", "/tmp", "/tmp"},
+		{"root kept", "/", "/"},
+		{"remote URI left alone", "file://server/share", "file://server/share"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := normalizeTargetArg(tt.input); result != tt.expected {
+				t.Errorf("normalizeTargetArg(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCdHistory(t *testing.T) {
+	homeDir, _ := os.UserHomeDir()
+	history := "cd /srv/www\n" +
+		": 1700000000:0;cd ~/src/api\n" +
+		"git pull && cd /srv/www; ls\n" +
+		"cd relative/dir\n" +
+		"cd -\n" +
+		`cd /home/me/My\ Docs` + "\n" +
+		"cd '/opt/quoted dir'\n" +
+		"echo cd /nope\n"
+
+	targets := parseCdHistory(history)
+	expected := []string{
+		"/srv/www",
+		filepath.Join(homeDir, "src/api"),
+		"/srv/www",
+		"/home/me/My Docs",
+		"/opt/quoted dir",
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("parseCdHistory = %v, want %v", targets, expected)
+	}
+}
+
+func TestSuggestionCandidates(t *testing.T) {
+	tmpDir := t.TempDir()
+	often := filepath.Join(tmpDir, "often")
+	marked := filepath.Join(tmpDir, "marked")
+	rare := filepath.Join(tmpDir, "rare")
+	for _, dir := range []string{often, marked, rare} {
+		os.MkdirAll(dir, 0755)
+	}
+
+	visits := []string{rare, often, marked, often, marked, marked, filepath.Join(tmpDir, "gone"), filepath.Join(tmpDir, "gone")}
+	ranked := rankDirectories(visits)
+	if ranked[0].Path != marked || ranked[0].Count != 3 {
+		t.Errorf("ranked[0] = %+v, want %s with 3 visits", ranked[0], marked)
+	}
+
+	candidates := suggestionCandidates(ranked, map[string]bool{marked: true}, 1)
+	if len(candidates) != 1 || candidates[0].Path != often {
+		t.Errorf("candidates = %+v, want only %s", candidates, often)
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	records := []exportRecord{
+		{Name: "api", Target: "/srv/api", Tags: []string{"work", "go"}, Description: `the "main" service`, Fallbacks: []string{"/mnt/api"}, OSTargets: map[string]string{"windows": `D:\api`, "darwin": "~/api"}, OnlyOn: []string{"laptop", "*.corp"}},
+		{Name: "home", Target: "/home/me"},
+	}
+
+	content, err := encodeExportJSON(records)
+	if err != nil {
+		t.Fatalf("encodeExportJSON failed: %v", err)
+	}
+	var decoded []exportRecord
+	if err := json.Unmarshal(content, &decoded); err != nil || !reflect.DeepEqual(decoded, records) {
+		t.Errorf("JSON round trip = %+v (err %v)", decoded, err)
+	}
+
+	content, _ = encodeExportCSV(records)
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV not readable: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "name" || rows[1][2] != "work,go" || rows[1][3] != `the "main" service` || rows[1][5] != "/mnt/api" || rows[1][6] != `darwin=~/api,windows=D:\api` || rows[1][7] != "laptop,*.corp" {
+		t.Errorf("CSV rows = %v", rows)
+	}
+	if decoded, err := decodeManifestCSV(content); err != nil || !reflect.DeepEqual(decoded, records) {
+		t.Errorf("CSV round trip = %+v (err %v)", decoded, err)
+	}
+
+	content, _ = encodeExportTOML(records)
+	expected := `[[bookmark]]
+name = "api"
+target = "/srv/api"
+tags = ["work", "go"]
+description = "the \"main\" service"
+fallbacks = ["/mnt/api"]
+os_targets = { darwin = "~/api", windows = "D:\\api" }
+only_on = ["laptop", "*.corp"]
+
+[[bookmark]]
+name = "home"
+target = "/home/me"
+`
+	if string(content) != expected {
+		t.Errorf("TOML = %q, want %q", content, expected)
+	}
+}
+
+func TestBuildTopRows(t *testing.T) {
+	now := time.Date(2025, 3, 14, 12, 0, 0, 0, time.Local)
+	events := []usageEvent{
+		{Time: now.Add(-time.Hour), Name: "api"},
+		{Time: now.Add(-2 * time.Hour), Name: "api"},
+		{Time: now.AddDate(0, 0, -3), Name: "docs"},
+		{Time: now.AddDate(0, 0, -3), Name: "docs"},
+		{Time: now.AddDate(0, 0, -3), Name: "docs"},
+		{Time: now.AddDate(0, 0, -30), Name: "old"},
+	}
+
+	rows := buildTopRows(events, now, 10)
+	if len(rows) != 2 || rows[0].Name != "api" || rows[1].Name != "docs" {
+		t.Fatalf("rows = %+v, want api then docs", rows)
+	}
+	if rows[0].Today != 2 || rows[0].Week != 2 || rows[0].Daily[6] != 2 {
+		t.Errorf("api row = %+v", rows[0])
+	}
+	if rows[1].Today != 0 || rows[1].Week != 3 || rows[1].Daily[3] != 3 {
+		t.Errorf("docs row = %+v", rows[1])
+	}
+	if rows := buildTopRows(events, now, 1); len(rows) != 1 {
+		t.Errorf("limit not applied: %d rows", len(rows))
+	}
+
+	if got := sparkline([]int{0, 1, 4, 8}); got != "·▁▄█" {
+		t.Errorf("sparkline = %q", got)
+	}
+}
+
+func TestExportScript(t *testing.T) {
+	records := []exportRecord{
+		{Name: "api", Target: "/srv/it's here", Tags: []string{"work", "go"}, Description: "main service", Fallbacks: []string{"/mnt/a", "~/b"}, OSTargets: map[string]string{"darwin": "~/Downloads"}, OnlyOn: []string{"laptop"}},
+		{Name: "export", Target: "/tmp"},
+	}
+
+	content, _ := encodeExportScript(records)
+	expected := `#!/bin/sh
+# Bookmarks exported by 'mark export --format script'
+mark --tag 'work,go' --desc 'main service' --only-on 'laptop' 'api' '/srv/it'\''s here'
+mark fallback 'api' '/mnt/a' '~/b'
+mark os-target 'api' 'darwin=~/Downloads'
+mark -- 'export' '/tmp'
+`
+	if string(content) != expected {
+		t.Errorf("script = %q, want %q", content, expected)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	root := t.TempDir()
+	marksDir := filepath.Join(root, "marks")
+	project := filepath.Join(root, "project")
+	os.MkdirAll(project, 0755)
+	os.MkdirAll(filepath.Join(root, "other"), 0755)
+	os.Symlink(project, filepath.Join(root, "link"))
+
+	bookmarks := []Bookmark{
+		{Name: "proj", Target: project},
+		{Name: "p", Target: project + "/"},
+		{Name: "via-link", Target: filepath.Join(root, "link")},
+		{Name: "alias", Target: "proj"},
+		{Name: "other", Target: filepath.Join(root, "other")},
+		{Name: "gone", Target: filepath.Join(root, "gone")},
+		{Name: "gone2", Target: filepath.Join(root, "gone")},
+		{Name: "docs", Target: "https://go.dev"},
+		{Name: "docs2", Target: "https://go.dev"},
+	}
+	groups := findDuplicates(Config{MarksDir: marksDir}, bookmarks)
+
+	resolved, _ := filepath.EvalSymlinks(project)
+	expected := []duplicateGroup{{Path: resolved, Names: []string{"p", "proj", "via-link"}}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("findDuplicates = %+v, want %+v", groups, expected)
+	}
+}
+
+func TestDiffRecords(t *testing.T) {
+	local := []exportRecord{
+		{Name: "api", Target: "/srv/api"},
+		{Name: "old", Target: "/tmp/old"},
+		{Name: "work", Target: "/home/a/work"},
+	}
+	manifest := []exportRecord{
+		{Name: "api", Target: "/srv/api/"},
+		{Name: "new", Target: "/opt/new"},
+		{Name: "work", Target: "/home/b/work"},
+	}
+
+	diff := diffRecords(local, manifest)
+	if len(diff.Added) != 1 || diff.Added[0].Name != "new" {
+		t.Errorf("Added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "old" {
+		t.Errorf("Removed = %+v", diff.Removed)
+	}
+	if len(diff.Drifted) != 1 || diff.Drifted[0][0].Target != "/home/a/work" || diff.Drifted[0][1].Target != "/home/b/work" {
+		t.Errorf("Drifted = %+v", diff.Drifted)
+	}
+	if !diffRecords(local, local).empty() {
+		t.Error("diff of a set against itself should be empty")
+	}
+
+	tagged := []exportRecord{{Name: "api", Target: "/srv/api", Tags: []string{"b", "a"}, Description: "old"}}
+	retagged := []exportRecord{{Name: "api", Target: "/srv/api", Tags: []string{"a", "b"}, Description: "new", Host: "dev1", Fallbacks: []string{"/mnt/api"}, OSTargets: map[string]string{"darwin": "~/api"}, OnlyOn: []string{"server"}}}
+	diff = diffRecords(tagged, retagged)
+	if len(diff.Changed) != 1 || len(diff.Drifted) != 0 {
+		t.Fatalf("Changed = %+v, Drifted = %+v", diff.Changed, diff.Drifted)
+	}
+	want := []string{`description "old" -> "new"`, "host - -> dev1", "fallbacks - -> /mnt/api", "os targets - -> darwin=~/api", "only on - -> server"}
+	if got := detailChanges(diff.Changed[0][0], diff.Changed[0][1]); !reflect.DeepEqual(got, want) {
+		t.Errorf("detailChanges = %q, want %q", got, want)
+	}
+}
+
+func TestLoadManifestCSV(t *testing.T) {
+	records := []exportRecord{{Name: "api", Target: "/srv/api", Tags: []string{"work", "go"}, Host: "dev1"}}
+	content, _ := encodeExportCSV(records)
+	path := filepath.Join(t.TempDir(), "marks.csv")
+	os.WriteFile(path, content, 0644)
+
+	loaded, err := loadManifest(path)
+	if err != nil {
+		t.Fatalf("loadManifest failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, records) {
+		t.Errorf("loadManifest = %+v, want %+v", loaded, records)
+	}
+}
+
+func TestCollectExportRecords(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{MarksDir: filepath.Join(tmpDir, ".marks")}
+	storage, _ := marks.Open(config)
+	storage.Create("zeta", tmpDir)
+	storage.Create("alpha", tmpDir)
+	marks.UpdateMetadata(config.MarksDir, "zeta", &Metadata{Tags: []string{"x"}, Host: "dev1"})
+
+	records, err := collectExportRecords(config, storage)
+	if err != nil {
+		t.Fatalf("collectExportRecords failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Name != "alpha" || records[1].Host != "dev1" || records[1].Tags[0] != "x" {
+		t.Errorf("records = %+v", records)
+	}
+
+	// $VAR targets are exported literally, relative ones made absolute
+	t.Setenv("PROJECTS", tmpDir)
+	storage.Create("api", "$PROJECTS/api")
+	storage.Create("rel", "../work")
+	records, _ = collectExportRecords(config, storage)
+	targets := map[string]string{}
+	for _, r := range records {
+		targets[r.Name] = r.Target
+	}
+	if targets["api"] != "$PROJECTS/api" || targets["rel"] != filepath.Join(tmpDir, "work") {
+		t.Errorf("exported targets = %v", targets)
+	}
+}
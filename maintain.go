@@ -0,0 +1,202 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// parseMaxAge parses a retention window for 'mark maintain': a number of
+// days, weeks or years such as "180d", "26w" or "1y"
+func parseMaxAge(value string) (time.Duration, error) {
+	units := map[byte]int{'d': 1, 'w': 7, 'y': 365}
+	if len(value) >= 2 {
+		if days, ok := units[value[len(value)-1]]; ok {
+			if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n > 0 {
+				return time.Duration(n*days) * 24 * time.Hour, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid max_age '%s' (expected e.g. 180d, 26w or 1y)", value)
+}
+
+// staleBookmark is a bookmark considered by the retention policy
+type staleBookmark struct {
+	Name     string
+	Target   string
+	LastUsed time.Time // zero when never jumped to
+	Created  time.Time // zero when unknown
+	Pinned   bool
+}
+
+// lastActive is the last jump, or the creation for a bookmark never used
+func (s staleBookmark) lastActive() time.Time {
+	if s.LastUsed.After(s.Created) {
+		return s.LastUsed
+	}
+	return s.Created
+}
+
+// findStale returns the bookmarks not used since cutoff, oldest first. A
+// bookmark never used counts from its creation, so new ones are kept;
+// pinned bookmarks are always kept.
+func findStale(bookmarks []staleBookmark, cutoff time.Time) []staleBookmark {
+	var stale []staleBookmark
+	for _, bookmark := range bookmarks {
+		if !bookmark.Pinned && bookmark.lastActive().Before(cutoff) {
+			stale = append(stale, bookmark)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].lastActive().Before(stale[j].lastActive())
+	})
+	return stale
+}
+
+// describeStale says when a stale bookmark was last active
+func describeStale(bookmark staleBookmark, now time.Time) string {
+	switch {
+	case !bookmark.LastUsed.IsZero():
+		return "last used " + formatAge(now.Sub(bookmark.LastUsed))
+	case !bookmark.Created.IsZero():
+		return "never used, created " + formatAge(now.Sub(bookmark.Created))
+	}
+	return "never used"
+}
+
+// maintainCommand applies the retention policy ('mark maintain [--max-age
+// <age>] [--delete]'): it lists personal bookmarks not jumped to within
+// max_age and, with --delete, moves them to the trash after confirmation.
+// Nothing happens unless a window is given on the command line or in the
+// config.
+func maintainCommand(config Config, flags *ParsedFlags, args []string) {
+	maxAge := config.MaxAge
+	remove := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--max-age":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-age requires a value (e.g. 180d)\n")
+				os.Exit(1)
+			}
+			maxAge = args[i+1]
+			i++
+		case "--delete":
+			remove = true
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown maintain option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+	if maxAge == "" {
+		fmt.Fprintf(os.Stderr, "Error: No retention window: pass --max-age (e.g. 180d) or set max_age in ~/.mark\n")
+		os.Exit(1)
+	}
+	window, err := parseMaxAge(maxAge)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	storage := hostFiltered(config, openStorage(config))
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+	events, err := loadUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	changes, err := loadChanges()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	usage := summarizeUsage(events)
+	created := createdTimes(changes)
+	var candidates []staleBookmark
+	for _, bookmark := range bookmarks {
+		m := meta[bookmark.Name]
+		candidates = append(candidates, staleBookmark{
+			Name:     bookmark.Name,
+			Target:   bookmark.Target,
+			LastUsed: usage[bookmark.Name].LastUsed,
+			Created:  creationTime(config, bookmark.Name, m, created, false),
+			Pinned:   m != nil && m.Pinned,
+		})
+	}
+
+	now := time.Now()
+	stale := findStale(candidates, now.Add(-window))
+	if len(stale) == 0 {
+		fmt.Printf("No bookmarks unused for %s.\n", maxAge)
+		return
+	}
+
+	fmt.Printf("Bookmarks unused for %s:\n", maxAge)
+	for _, bookmark := range stale {
+		fmt.Printf("  %-20s %-32s %s\n", bookmark.Name, describeStale(bookmark, now), bookmark.Target)
+	}
+	if !remove {
+		fmt.Printf("\nRun 'mark maintain --max-age %s --delete' to move them to the trash.\n", maxAge)
+		return
+	}
+	if flags.DryRun {
+		fmt.Printf("\nWould delete %d bookmark(s).\n", len(stale))
+		return
+	}
+
+	if !flags.Yes {
+		fmt.Printf("\nDelete these %d bookmark(s)? (y/N): ", len(stale))
+		response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if yes, _ := parseYesNo(cleanResponse(response)); !yes {
+			fmt.Println("No bookmarks deleted.")
+			return
+		}
+	}
+
+	failed := false
+	for _, bookmark := range stale {
+		if _, err := removeBookmark(config, storage, Bookmark{Name: bookmark.Name, Target: bookmark.Target}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting '%s': %v\n", bookmark.Name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Removed bookmark '%s'\n", bookmark.Name)
+	}
+	if trashDays(config) > 0 {
+		fmt.Println("Restore any of them with 'mark restore <name>'.")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// menuCommands are the launchers 'mark menu' drives: each reads one choice
+// per line on stdin and prints the selected line
+var menuCommands = map[string][]string{
+	"rofi":  {"rofi", "-dmenu", "-i", "-p", "mark"},
+	"dmenu": {"dmenu", "-i", "-l", "20", "-p", "mark"},
+}
+
+// defaultMenuSort puts the likeliest choices first when sort= is unset
+const defaultMenuSort = "pinned,frecency,name"
+
+// menuEntry is one bookmark offered in the menu
+type menuEntry struct {
+	name string
+	path string
+}
+
+// menuLines formats entries as aligned "name  path" lines, returning them
+// with a lookup from line back to entry
+func menuLines(entries []menuEntry) ([]string, map[string]menuEntry) {
+	width := 0
+	for _, entry := range entries {
+		width = max(width, len(entry.name))
+	}
+
+	lines := make([]string, 0, len(entries))
+	byLine := make(map[string]menuEntry, len(entries))
+	for _, entry := range entries {
+		line := fmt.Sprintf("%-*s  %s", width, entry.name, entry.path)
+		lines = append(lines, line)
+		byLine[line] = entry
+	}
+	return lines, byLine
+}
+
+// menuEntries lists the bookmarks worth offering: broken ones are left out,
+// the rest are ordered by sort= (or defaultMenuSort)
+func menuEntries(config Config, flags *ParsedFlags) []menuEntry {
+	bookmarks, err := openLayeredStorage(config).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sortValue := config.Sort
+	if flags.Sort != "" {
+		sortValue = flags.Sort
+	}
+	if sortValue == "" {
+		sortValue = defaultMenuSort
+	}
+	keys, err := parseSortKeys(sortValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	var usage map[string]usageSummary
+	var frecency map[string]float64
+	if sortNeedsUsage(keys) {
+		events, _ := loadUsage()
+		usage = summarizeUsage(events)
+		frecency = frecencyScores(events, time.Now())
+	}
+
+	var sortable []sortEntry
+	paths := make(map[string]string)
+	for _, bookmark := range bookmarks {
+		if flags.Tag != "" && !hasTag(meta[bookmark.Name], flags.Tag) {
+			continue
+		}
+		path := marks.TargetPath(config, bookmark.Target)
+		if !marks.IsURLTarget(path) {
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				continue
+			}
+		}
+		paths[bookmark.Name] = path
+		entry := sortEntry{name: bookmark.Name, target: bookmark.Target, usage: usage[bookmark.Name], frecency: frecency[bookmark.Name]}
+		if m := meta[bookmark.Name]; m != nil {
+			entry.pinned = m.Pinned
+		}
+		sortable = append(sortable, entry)
+	}
+	sort.SliceStable(sortable, func(i, j int) bool {
+		return compareBookmarks(sortable[i], sortable[j], keys) < 0
+	})
+
+	entries := make([]menuEntry, 0, len(sortable))
+	for _, entry := range sortable {
+		entries = append(entries, menuEntry{name: entry.name, path: paths[entry.name]})
+	}
+	return entries
+}
+
+// menuCommand shows the bookmarks in rofi or dmenu and prints the chosen
+// path ('mark menu --rofi|--dmenu|--list [--tag <tag>]'), for window-manager
+// hotkeys such as 'd=$(mark menu --rofi) && foot -D "$d"'. --list only
+// prints the menu lines. Dismissing the menu exits with status 1.
+func menuCommand(config Config, flags *ParsedFlags, args []string) {
+	launcher := ""
+	for _, arg := range args {
+		switch arg {
+		case "--rofi", "--dmenu", "--list":
+			launcher = strings.TrimPrefix(arg, "--")
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown menu option: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+	if launcher == "" {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark menu --rofi|--dmenu|--list\n")
+		os.Exit(1)
+	}
+
+	lines, byLine := menuLines(menuEntries(config, flags))
+	if launcher == "list" {
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return
+	}
+
+	command := menuCommands[launcher]
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// rofi and dmenu exit 1 when the menu is dismissed
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", command[0], err)
+		os.Exit(1)
+	}
+
+	choice := strings.TrimRight(out.String(), "\r\n")
+	if choice == "" {
+		os.Exit(1)
+	}
+	entry, ok := byLine[choice]
+	if !ok {
+		// Typed instead of picked: take it as a bookmark name
+		name := strings.TrimSpace(choice)
+		bookmark := lookupBookmark(openLayeredStorage(config), name)
+		entry = menuEntry{name: name, path: marks.TargetPath(config, bookmark.Target)}
+	}
+
+	_ = recordUsage(entry.name, entry.path)
+	_ = recordAudit(config, "jump", entry.name, entry.path)
+	fmt.Println(entry.path)
+}
@@ -0,0 +1,313 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// mergeStrategies are the ways a name clash in 'mark merge' is settled
+var mergeStrategies = []string{"mine", "theirs", "rename"}
+
+// mergeVerbs describes what happens to a merged bookmark under each choice,
+// for dry-run and done messages
+var mergeVerbs = map[string][2]string{
+	"add":    {"add", "Added"},
+	"theirs": {"replace", "Replaced"},
+	"rename": {"add", "Added"},
+}
+
+// mergeItem is a bookmark from the other marks directory to fold in
+type mergeItem struct {
+	From   string // name in the other directory
+	Name   string // name here, after the naming policy
+	Target string // target to store here
+	Mine   string // target of the bookmark already using the name here; "" when free
+	Skip   string // why the name cannot be used here; "" when it can
+}
+
+// mergeItems compares the bookmarks of otherDir (theirs) with ours and
+// returns the ones to merge in name order; bookmarks both sides already
+// have with the same target are only counted. Their names go through the
+// naming policy first, as on import, so names the policy rejects are
+// returned with Skip set.
+func mergeItems(config Config, mine, theirs []Bookmark, otherDir string) (items []mergeItem, identical int) {
+	ours := make(map[string]string, len(mine))
+	for _, bookmark := range mine {
+		ours[bookmark.Name] = bookmark.Target
+	}
+
+	policy := configNamePolicy(config)
+	for _, bookmark := range theirs {
+		target := portableTarget(otherDir, bookmark.Target)
+		name, err := policy.normalize(bookmark.Name)
+		if err != nil {
+			items = append(items, mergeItem{From: bookmark.Name, Name: name, Target: target, Skip: err.Error()})
+			continue
+		}
+		mineTarget, clash := ours[name]
+		if clash && (mineTarget == target || filepath.Clean(marks.TargetPath(config, mineTarget)) == filepath.Clean(marks.TargetPath(config, target))) {
+			identical++
+			continue
+		}
+		if !clash {
+			// A later name the policy maps to the same name clashes with this one
+			ours[name] = target
+		}
+		items = append(items, mergeItem{From: bookmark.Name, Name: name, Target: target, Mine: mineTarget})
+	}
+	return items, identical
+}
+
+// askMergeConflict asks how to settle one name clash and returns a merge
+// strategy, asking again after an unknown answer; empty input keeps mine
+func askMergeConflict(reader *bufio.Reader, item mergeItem) string {
+	fmt.Printf("Bookmark '%s' differs:\n", item.Name)
+	fmt.Printf("  mine:   %s\n", item.Mine)
+	fmt.Printf("  theirs: %s\n", item.Target)
+	for {
+		fmt.Print("Keep (m)ine, take (t)heirs or (r)ename theirs? [m]: ")
+		response, err := reader.ReadString('\n')
+		switch strings.ToLower(cleanResponse(response)) {
+		case "", "m", "mine":
+			return "mine"
+		case "t", "theirs":
+			return "theirs"
+		case "r", "rename":
+			return "rename"
+		}
+		if err != nil {
+			return "mine"
+		}
+	}
+}
+
+// replaceWithTheirs points the bookmark item.Name at their target in place
+// and merges their metadata into ours, their details winning where they
+// have any. Only once that succeeded does our old bookmark go to the trash.
+func replaceWithTheirs(config Config, storage Storage, item mergeItem, theirs Metadata) error {
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		return err
+	}
+	var ours Metadata
+	if meta[item.Name] != nil {
+		ours = *meta[item.Name]
+	}
+	old := ours
+
+	if err := repointBookmark(config, storage, item.Name, item.Target); err != nil {
+		return err
+	}
+	merged := mergeMetadata(ours, theirs)
+	merged.DirID = ""
+	if !marks.IsURLTarget(item.Target) && !marks.IsCommandTarget(item.Target) && !marks.IsGlobTarget(item.Target) {
+		merged.DirID = dirID(marks.TargetPath(config, item.Target))
+	}
+	if err := marks.UpdateMetadata(config.MarksDir, item.Name, &merged); err != nil {
+		return err
+	}
+
+	if trashDays(config) > 0 {
+		if err := moveToTrash(config, Bookmark{Name: item.Name, Target: item.Mine}, &old); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// mergeMetadata returns ours with every detail theirs has taking its place;
+// pins are kept from either side and our creation time is kept
+func mergeMetadata(ours, theirs Metadata) Metadata {
+	if len(theirs.Tags) > 0 {
+		ours.Tags = theirs.Tags
+	}
+	if theirs.Description != "" {
+		ours.Description = theirs.Description
+	}
+	if theirs.Host != "" {
+		ours.Host = theirs.Host
+	}
+	ours.Pinned = ours.Pinned || theirs.Pinned
+	if ours.Created.IsZero() {
+		ours.Created = theirs.Created
+	}
+	if len(theirs.Fallbacks) > 0 {
+		ours.Fallbacks = theirs.Fallbacks
+	}
+	if len(theirs.OnlyOn) > 0 {
+		ours.OnlyOn = theirs.OnlyOn
+	}
+	if len(theirs.OSTargets) > 0 {
+		ours.OSTargets = theirs.OSTargets
+	}
+	return ours
+}
+
+// mergeCommand folds another marks directory into this one
+// ('mark merge <other-dir> [--strategy mine|theirs|rename]'). Name clashes
+// are settled one by one on a terminal, otherwise by --strategy (default
+// mine, which changes none of your bookmarks).
+func mergeCommand(config Config, flags *ParsedFlags, args []string) {
+	otherDir := ""
+	strategy := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--strategy":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --strategy requires a value (%s)\n", strings.Join(mergeStrategies, ", "))
+				os.Exit(1)
+			}
+			strategy = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--"):
+			fmt.Fprintf(os.Stderr, "Error: Unknown merge option: %s\n", args[i])
+			os.Exit(1)
+		case otherDir == "":
+			otherDir = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "Error: merge takes one marks directory\n")
+			os.Exit(1)
+		}
+	}
+	if otherDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: Marks directory required: mark merge <other-dir> [--strategy %s]\n", strings.Join(mergeStrategies, "|"))
+		os.Exit(1)
+	}
+	if strategy != "" && !slices.Contains(mergeStrategies, strategy) {
+		fmt.Fprintf(os.Stderr, "Error: Unknown merge strategy '%s' (supported: %s)\n", strategy, strings.Join(mergeStrategies, ", "))
+		os.Exit(1)
+	}
+
+	otherDir = normalizeTarget(marks.ExpandPath(otherDir))
+	if info, err := os.Stat(otherDir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: Not a directory: %s\n", otherDir)
+		os.Exit(1)
+	}
+	if otherDir == normalizeTarget(marks.ExpandPath(config.MarksDir)) {
+		fmt.Fprintf(os.Stderr, "Error: %s is the current marks directory\n", otherDir)
+		os.Exit(1)
+	}
+
+	storage := openStorage(config)
+	mine, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+	theirs, err := (&marks.SymlinkStorage{Dir: otherDir}).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", otherDir, err)
+		os.Exit(1)
+	}
+	theirMeta, err := marks.LoadMetadata(otherDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	items, identical := mergeItems(config, mine, theirs, otherDir)
+	interactive := strategy == "" && !flags.DryRun && stdinIsTerminal()
+	if strategy == "" {
+		strategy = "mine"
+	}
+	reader := bufio.NewReader(os.Stdin)
+	taken := func(name string) bool { return nameTaken(storage, name) }
+	policy := configNamePolicy(config)
+
+	var added, replaced, renamed, kept, skipped int
+	for _, item := range items {
+		if item.Skip != "" {
+			fmt.Printf("  skip %-20s (no usable name: %s)\n", item.From, item.Skip)
+			skipped++
+			continue
+		}
+
+		name, choice := item.Name, "add"
+		if item.Mine != "" {
+			choice = strategy
+			if interactive {
+				choice = askMergeConflict(reader, item)
+			}
+		}
+
+		switch choice {
+		case "mine":
+			fmt.Printf("  keep %-20s (yours -> %s)\n", item.Name, item.Mine)
+			kept++
+			continue
+		case "rename":
+			suffixed, err := suffixedName(policy, taken, item.Name)
+			if err != nil {
+				fmt.Printf("  skip %-20s (no usable name: %v)\n", item.From, err)
+				skipped++
+				continue
+			}
+			name = suffixed
+		}
+
+		note := ""
+		if name != item.From {
+			note = fmt.Sprintf(" (renamed from '%s')", item.From)
+		}
+		if flags.DryRun {
+			fmt.Printf("Would %s bookmark '%s' -> %s%s\n", mergeVerbs[choice][0], name, item.Target, note)
+		} else {
+			var meta Metadata
+			if m := theirMeta[item.From]; m != nil {
+				meta = *m
+			}
+			if choice == "theirs" {
+				err = replaceWithTheirs(config, storage, item, meta)
+			} else {
+				err = storeBookmark(config, storage, name, item.Target, meta)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error merging '%s': %v\n", name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ %s bookmark '%s' -> %s%s\n", mergeVerbs[choice][1], name, item.Target, note)
+		}
+
+		switch choice {
+		case "add":
+			added++
+		case "theirs":
+			replaced++
+		case "rename":
+			renamed++
+		}
+	}
+
+	summary := fmt.Sprintf("%d added, %d replaced, %d renamed, %d kept, %d already present", added, replaced, renamed, kept, identical)
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	if flags.DryRun {
+		fmt.Printf("Would merge %s: %s\n", otherDir, summary)
+		return
+	}
+	fmt.Printf("Merged %s: %s\n", otherDir, summary)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// dirID returns the identity of the directory at path (see fileInfoID),
+// or "" when it cannot be read
+func dirID(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fileInfoID(info)
+}
+
+// parseTags splits a comma-separated tag list, trimming blanks and duplicates
+func parseTags(value string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// hasTag reports whether the bookmark metadata includes tag
+func hasTag(m *Metadata, tag string) bool {
+	if m == nil {
+		return false
+	}
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// allTags returns every tag in use, sorted alphabetically
+func allTags(meta map[string]*Metadata) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, m := range meta {
+		for _, tag := range m.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// printTagCompletions prints all known tags, one per line, for shell completion
+func printTagCompletions() {
+	config, err := readConfig()
+	if err != nil {
+		return
+	}
+
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		return
+	}
+
+	for _, tag := range allTags(meta) {
+		fmt.Println(tag)
+	}
+}
@@ -0,0 +1,403 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// namePolicy lists the normalization and validation rules applied to
+// bookmark names when they are created, imported or renamed
+type namePolicy struct {
+	Lowercase bool           // "lowercase": fold to lower case
+	Kebab     bool           // "kebab": words joined by single dashes
+	ASCII     bool           // "ascii": strip diacritics, drop other non-ASCII
+	Spaces    string         // "spaces=": underscore (default), dash, keep or reject
+	MaxLength int            // "max_length=": longest accepted name in characters, 0 for no limit
+	Chars     string         // "chars=": the characters names may use, as a character class ("a-z0-9_.-")
+	chars     *regexp.Regexp // compiled from Chars
+}
+
+// nameSpaceModes are the values of the spaces= rule
+var nameSpaceModes = []string{"underscore", "dash", "keep", "reject"}
+
+// parseNamePolicy reads a comma-separated rule list such as
+// "lowercase,kebab,max_length=24"
+func parseNamePolicy(value string) (namePolicy, error) {
+	var policy namePolicy
+	for _, rule := range strings.Split(value, ",") {
+		rule = strings.TrimSpace(rule)
+		key, arg, _ := strings.Cut(rule, "=")
+		switch key {
+		case "":
+		case "lowercase":
+			policy.Lowercase = true
+		case "kebab":
+			policy.Kebab = true
+		case "ascii":
+			policy.ASCII = true
+		case "spaces":
+			known := false
+			for _, mode := range nameSpaceModes {
+				known = known || mode == arg
+			}
+			if !known {
+				return policy, fmt.Errorf("invalid name_policy rule %s (spaces= takes %s)", rule, strings.Join(nameSpaceModes, ", "))
+			}
+			policy.Spaces = arg
+		case "max_length":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return policy, fmt.Errorf("invalid name_policy rule %s (max_length= takes a positive number)", rule)
+			}
+			policy.MaxLength = n
+		case "chars":
+			re, err := regexp.Compile("^[" + arg + "]*$")
+			if arg == "" || err != nil {
+				return policy, fmt.Errorf("invalid name_policy rule %s (chars= takes a character class such as a-z0-9_.-)", rule)
+			}
+			policy.Chars = arg
+			policy.chars = re
+		default:
+			return policy, fmt.Errorf("unknown name_policy rule: %s", rule)
+		}
+	}
+	return policy, nil
+}
+
+// configNamePolicy returns the name_policy from the config or exits with an
+// error
+func configNamePolicy(config Config) namePolicy {
+	policy, err := parseNamePolicy(config.NamePolicy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	return policy
+}
+
+// isEmpty reports whether the policy has no rules
+func (p namePolicy) isEmpty() bool {
+	return p == namePolicy{}
+}
+
+// apply normalizes name according to the policy. Spaces become underscores
+// unless the spaces= rule says otherwise.
+func (p namePolicy) apply(name string) string {
+	switch p.Spaces {
+	case "", "underscore":
+		name = strings.ReplaceAll(name, " ", "_")
+	case "dash":
+		name = strings.ReplaceAll(name, " ", "-")
+	}
+	if p.ASCII {
+		name = stripDiacritics(name)
+	}
+	if p.Kebab {
+		name = kebabCase(name)
+	}
+	if p.Lowercase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// check reports why name is not acceptable as a bookmark name under the
+// policy, or nil when it is
+func (p namePolicy) check(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("bookmark name cannot be empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("bookmark name cannot be '%s'", name)
+	case strings.ContainsAny(name, nameSeparators()):
+		return fmt.Errorf("bookmark name cannot contain path separators")
+	case marks.IsReservedName(name):
+		return fmt.Errorf("bookmark name '%s' is reserved for mark's own files", name)
+	case p.Spaces == "reject" && strings.Contains(name, " "):
+		return fmt.Errorf("bookmark name '%s' contains spaces (name_policy spaces=reject)", name)
+	case p.MaxLength > 0 && utf8.RuneCountInString(name) > p.MaxLength:
+		return fmt.Errorf("bookmark name '%s' is longer than %d characters (name_policy max_length)", name, p.MaxLength)
+	}
+	if p.chars != nil && !p.chars.MatchString(name) {
+		for _, r := range name {
+			if !p.chars.MatchString(string(r)) {
+				return fmt.Errorf("bookmark name '%s' contains '%c', outside name_policy chars=%s", name, r, p.Chars)
+			}
+		}
+	}
+	return nil
+}
+
+// normalize applies the policy to name and checks the result
+func (p namePolicy) normalize(name string) (string, error) {
+	name = p.apply(name)
+	return name, p.check(name)
+}
+
+// diacritics maps accented Latin letters to their plain ASCII equivalents
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a",
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Ā': "A",
+	'æ': "ae", 'Æ': "AE", 'ç': "c", 'Ç': "C", 'č': "c", 'Č': "C",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ě': "e",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ē': "E", 'Ě': "E",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'ñ': "n", 'Ñ': "N", 'ň': "n", 'Ň': "N",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o",
+	'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O", 'Ō': "O",
+	'œ': "oe", 'Œ': "OE", 'ř': "r", 'Ř': "R", 'š': "s", 'Š': "S", 'ß': "ss",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ū': "U", 'Ů': "U",
+	'ý': "y", 'ÿ': "y", 'Ý': "Y", 'ž': "z", 'Ž': "Z",
+}
+
+// stripDiacritics replaces accented letters and drops remaining non-ASCII runes
+func stripDiacritics(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if plain, ok := diacritics[r]; ok {
+			sb.WriteString(plain)
+		} else if r < unicode.MaxASCII {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// kebabCase joins the words of name with single dashes, splitting on
+// separators and camelCase boundaries ("My Project_v2" -> "My-Project-v2")
+func kebabCase(name string) string {
+	var words []string
+	var current []rune
+	runes := []rune(name)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.':
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return strings.Join(words, "-")
+}
+
+// nameRename is one planned rename in a tidy-names run
+type nameRename struct {
+	from string
+	to   string
+}
+
+// planRenames computes the renames needed to bring names in line with
+// normalize (such as namePolicy.apply). Renames that would collide with
+// another bookmark are returned separately and must be skipped.
+func planRenames(names []string, normalize func(string) string) (renames, conflicts []nameRename) {
+	taken := make(map[string]bool)
+	for _, name := range names {
+		taken[name] = true
+	}
+
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		newName := normalize(name)
+		if newName == name {
+			continue
+		}
+		if newName == "" || taken[newName] {
+			conflicts = append(conflicts, nameRename{from: name, to: newName})
+			continue
+		}
+		taken[newName] = true
+		delete(taken, name)
+		renames = append(renames, nameRename{from: name, to: newName})
+	}
+	return renames, conflicts
+}
+
+// renameBookmark moves a bookmark to a new name along with its metadata and
+// usage history
+func renameBookmark(config Config, storage Storage, oldName, newName string) error {
+	policy, err := parseNamePolicy(config.NamePolicy)
+	if err != nil {
+		return err
+	}
+	if err := policy.check(newName); err != nil {
+		return err
+	}
+
+	bookmark, err := storage.Get(oldName)
+	if err != nil {
+		return err
+	}
+	if err := marks.Rename(storage, config, oldName, newName); err != nil {
+		return err
+	}
+
+	_ = recordAudit(config, "rename", oldName+" -> "+newName, marks.TargetPath(config, bookmark.Target))
+	return renameUsage(oldName, newName)
+}
+
+// tidyNamesCommand renames all bookmarks to match the configured name_policy
+// ('mark tidy-names'), showing the plan and asking before applying it
+func tidyNamesCommand(config Config, flags *ParsedFlags, args []string) {
+	policy := configNamePolicy(config)
+	if policy.isEmpty() {
+		fmt.Fprintf(os.Stderr, "Error: No naming policy configured. Add e.g. 'name_policy=lowercase,kebab,ascii' to ~/.mark\n")
+		os.Exit(1)
+	}
+
+	storage := openStorage(config)
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	var names []string
+	for _, bookmark := range bookmarks {
+		names = append(names, bookmark.Name)
+	}
+
+	renames, conflicts := planRenames(names, policy.apply)
+	for _, c := range conflicts {
+		fmt.Printf("  skip %-20s -> %s (name already taken)\n", c.from, c.to)
+	}
+	if len(renames) == 0 {
+		fmt.Println("All bookmark names already follow the naming policy.")
+		return
+	}
+
+	confirmRenames(config, storage, renames)
+}
+
+// confirmRenames shows a rename plan and applies it once the user agrees
+func confirmRenames(config Config, storage Storage, renames []nameRename) {
+	fmt.Println("Rename plan:")
+	for _, r := range renames {
+		fmt.Printf("  %-20s -> %s\n", displayName(r.from), r.to)
+	}
+
+	fmt.Print("Apply these renames? (y/N): ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("No bookmarks renamed.")
+		return
+	}
+
+	for _, r := range renames {
+		if err := renameBookmark(config, storage, r.from, r.to); err != nil {
+			fmt.Fprintf(os.Stderr, "Error renaming '%s': %v\n", r.from, err)
+			if errors.Is(err, marks.ErrExists) {
+				os.Exit(exitConflict)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Renamed bookmark '%s' -> '%s'\n", displayName(r.from), r.to)
+	}
+}
+
+// shellUnsafeRunes are the characters that break the generated shell
+// integration when they appear in a bookmark name: word splitting, quoting,
+// globbing, expansion and command separators
+const shellUnsafeRunes = " \t\n\r'\"`$\\*?[]{}()<>|&;!#~"
+
+// shellSafeName replaces every shell-unsafe or control character in name with
+// an underscore and strips leading dashes that would read as flags
+func shellSafeName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(shellUnsafeRunes, r) || unicode.IsControl(r) {
+			sb.WriteByte('_')
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(sb.String(), "-")
+}
+
+// displayName quotes names holding control characters so a plan stays on
+// one line per bookmark
+func displayName(name string) string {
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// unsafeTarget reports whether target contains characters that break the
+// line-based 'mark -l' output completion parses
+func unsafeTarget(target string) bool {
+	return strings.IndexFunc(target, unicode.IsControl) >= 0
+}
+
+// checkNames scans bookmarks for names and targets the shell integration
+// cannot handle ('mark --check-names') and offers to rename the names
+func checkNames(config Config) {
+	storage := openStorage(config)
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	var names []string
+	for _, bookmark := range bookmarks {
+		names = append(names, bookmark.Name)
+		if unsafeTarget(bookmark.Target) {
+			fmt.Printf("  warn %-20s target %q contains control characters; recreate it with a plain path\n", bookmark.Name, bookmark.Target)
+		}
+	}
+
+	renames, conflicts := planRenames(names, shellSafeName)
+	for _, c := range conflicts {
+		fmt.Printf("  skip %-20s -> %s (name already taken)\n", displayName(c.from), c.to)
+	}
+	if len(renames) == 0 {
+		fmt.Println("All bookmark names are safe for the shell integration.")
+		return
+	}
+
+	confirmRenames(config, storage, renames)
+}
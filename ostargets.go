@@ -0,0 +1,126 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// targetOSes are the operating systems a bookmark can have its own target
+// for, as runtime.GOOS names them
+var targetOSes = []string{"darwin", "freebsd", "linux", "netbsd", "openbsd", "windows"}
+
+// parseOSTarget splits an 'os=path' argument, accepting macos for darwin.
+// The path is stored as given, since it may only make sense on that OS.
+func parseOSTarget(arg string) (goos, target string, err error) {
+	goos, target, ok := strings.Cut(arg, "=")
+	goos = strings.ToLower(strings.TrimSpace(goos))
+	target = normalizeTargetArg(target)
+	if goos == "macos" {
+		goos = "darwin"
+	}
+	if !ok || target == "" {
+		return "", "", fmt.Errorf("invalid OS target '%s' (expected <os>=<path>, e.g. darwin=~/Downloads)", arg)
+	}
+	if marks.IsURLTarget(target) {
+		return "", "", fmt.Errorf("OS target must be a directory, not a URL: %s", target)
+	}
+	for _, known := range targetOSes {
+		if goos == known {
+			return goos, target, nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown OS '%s' (supported: macos, %s)", goos, strings.Join(targetOSes, ", "))
+}
+
+// osTargetCommand shows or replaces a bookmark's per-OS targets ('mark
+// os-target <name> [<os>=<path>...|--clear]'). On an OS with its own
+// target -j, -l and show use it instead of the stored one.
+func osTargetCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark os-target <name> [<os>=<path>...|--clear]\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	bookmark := lookupBookmark(openStorage(config), name)
+	if marks.IsURLTarget(bookmark.Target) {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' is a URL; OS targets need a directory bookmark\n", name)
+		os.Exit(1)
+	}
+
+	meta, err := marks.LoadMetadata(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m := Metadata{}
+	if meta[name] != nil {
+		m = *meta[name]
+	}
+
+	if len(args) == 1 {
+		fmt.Printf("  %-8s %s\n", "default", bookmark.Target)
+		var oses []string
+		for goos := range m.OSTargets {
+			oses = append(oses, goos)
+		}
+		sort.Strings(oses)
+		for _, goos := range oses {
+			current := ""
+			if goos == runtime.GOOS {
+				current = " (this system)"
+			}
+			fmt.Printf("  %-8s %s%s\n", goos, m.OSTargets[goos], current)
+		}
+		return
+	}
+
+	m.OSTargets = nil
+	if len(args) != 2 || args[1] != "--clear" {
+		m.OSTargets = make(map[string]string)
+		for _, arg := range args[1:] {
+			goos, target, err := parseOSTarget(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			m.OSTargets[goos] = target
+		}
+	}
+
+	if marks.IsEmptyMetadata(&m) {
+		err = marks.UpdateMetadata(config.MarksDir, name, nil)
+	} else {
+		err = marks.UpdateMetadata(config.MarksDir, name, &m)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(m.OSTargets) == 0 {
+		fmt.Printf("✓ Bookmark '%s' uses %s on every OS\n", name, bookmark.Target)
+		return
+	}
+	fmt.Printf("✓ Bookmark '%s' has its own target on %d OS(es)\n", name, len(m.OSTargets))
+}
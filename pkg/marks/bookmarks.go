@@ -0,0 +1,298 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package marks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBroken is returned by Resolve when a bookmark's target is missing or
+// not a directory
+var ErrBroken = errors.New("bookmark target is missing")
+
+// IsURLTarget reports whether a bookmark target is a web address rather
+// than a directory
+func IsURLTarget(target string) bool {
+	lower := strings.ToLower(target)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// IsHomeTarget reports whether a stored target is relative to the home
+// directory, as written with link_style=home
+func IsHomeTarget(target string) bool {
+	return target == "~" || strings.HasPrefix(target, "~/")
+}
+
+// HomeTargetPath resolves a ~/ target against the current home directory
+func HomeTargetPath(target string) string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, strings.TrimPrefix(target, "~"))
+}
+
+// IsCommandTarget reports whether a stored target is a shell command
+// (!command) whose output names the directory. The mark command runs it at
+// jump time when dynamic_targets=on; this package never does, so Resolve
+// reports such bookmarks as ErrBroken.
+func IsCommandTarget(target string) bool {
+	return strings.HasPrefix(target, "!")
+}
+
+// IsEnvTarget reports whether a stored target starts with an environment
+// variable ($PROJECTS/api or ${PROJECTS}/api). Such targets are stored
+// literally and expanded each time they are resolved.
+func IsEnvTarget(target string) bool {
+	if strings.HasPrefix(target, "${") {
+		return true
+	}
+	if len(target) < 2 || target[0] != '$' {
+		return false
+	}
+	c := target[1]
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// EnvTargetPath expands the environment variables in target. Unset or
+// empty variables are left in place, so the target reads as missing rather
+// than silently pointing somewhere else.
+func EnvTargetPath(target string) string {
+	return os.Expand(target, func(name string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return "${" + name + "}"
+	})
+}
+
+// IsGlobTarget reports whether a stored target contains glob characters
+// (/var/backups/snap-*). Such targets resolve to the most recently modified
+// matching directory each time.
+func IsGlobTarget(target string) bool {
+	return strings.ContainsAny(target, "*?[")
+}
+
+// GlobTargetPath returns the most recently modified directory matching
+// pattern, or pattern itself when it names an existing path literally or
+// nothing matches (so the bookmark reads as missing). Equal times go to the
+// match that sorts last, which is the newest for dated names.
+func GlobTargetPath(pattern string) string {
+	if !IsGlobTarget(pattern) {
+		return pattern
+	}
+	if _, err := os.Lstat(pattern); err == nil {
+		return pattern
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return pattern
+	}
+
+	newest, newestTime := pattern, time.Time{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if newestTime.IsZero() || !info.ModTime().Before(newestTime) {
+			newest, newestTime = match, info.ModTime()
+		}
+	}
+	return newest
+}
+
+// TargetPath returns a path that reaches the bookmark target, resolving
+// relative targets against the marks directory like a symlink would, ~/
+// targets (link_style=home) against the home directory, $VAR targets
+// against the environment and glob targets to their newest match. URL and
+// !command targets are returned unchanged.
+func TargetPath(config Config, target string) string {
+	if IsEnvTarget(target) {
+		target = EnvTargetPath(target)
+		if IsEnvTarget(target) {
+			return target
+		}
+	}
+	if IsURLTarget(target) || IsCommandTarget(target) {
+		return target
+	}
+	switch {
+	case IsHomeTarget(target):
+		target = HomeTargetPath(target)
+	case !filepath.IsAbs(target):
+		target = filepath.Join(config.MarksDir, target)
+	}
+	return GlobTargetPath(target)
+}
+
+// Resolve returns the directory the named bookmark points to: its target
+// for the running OS when it has one, else its stored target, or its first
+// existing fallback target when that is missing. The error wraps
+// ErrNotFound for an unknown name and ErrBroken when no target is a
+// directory; URL bookmarks resolve to the URL itself.
+func Resolve(storage Storage, config Config, name string) (string, error) {
+	bookmark, err := storage.Get(name)
+	if err != nil {
+		return "", err
+	}
+
+	m := storedMetadata(config, bookmark)
+	path := TargetPath(config, bookmark.Target)
+	if osPath, ok := OSTarget(config, m); ok {
+		path = osPath
+	}
+	if IsURLTarget(path) || isDir(path) {
+		return path, nil
+	}
+	if fallback, ok := ActiveFallback(config, m); ok {
+		Tracef("bookmark %s: %s is missing, using fallback %s", name, path, fallback)
+		return fallback, nil
+	}
+	return path, fmt.Errorf("%w: %s", ErrBroken, path)
+}
+
+// ActiveFallback returns the first fallback target in m that is an
+// existing directory, for when a bookmark's own target is missing
+func ActiveFallback(config Config, m *Metadata) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	for _, fallback := range m.Fallbacks {
+		if path := TargetPath(config, fallback); isDir(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// OSTarget returns the path a bookmark has for the running OS, expanding ~
+// (and on Windows, environment variables), when one is set
+func OSTarget(config Config, m *Metadata) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	target, ok := m.OSTargets[runtime.GOOS]
+	if !ok {
+		return "", false
+	}
+	return TargetPath(config, ExpandHome(target)), true
+}
+
+// storedMetadata returns the metadata recorded for a bookmark of the user's
+// own storage, or nil for shared bookmarks and unreadable metadata
+func storedMetadata(config Config, bookmark Bookmark) *Metadata {
+	if bookmark.Shared {
+		return nil
+	}
+	meta, err := LoadMetadata(config.MarksDir)
+	if err != nil {
+		Tracef("metadata: %v", err)
+		return nil
+	}
+	return meta[bookmark.Name]
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Rename moves a bookmark to a new name, keeping its target and the
+// metadata recorded in the marks directory. It fails with ErrExists when
+// the new name is taken.
+func Rename(storage Storage, config Config, oldName, newName string) error {
+	bookmark, err := storage.Get(oldName)
+	if err != nil {
+		return err
+	}
+	if _, err := storage.Get(newName); err == nil {
+		return ErrExists
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err := storage.Create(newName, bookmark.Target); err != nil {
+		return err
+	}
+	if err := storage.Delete(oldName); err != nil {
+		return err
+	}
+
+	meta, err := LoadMetadata(config.MarksDir)
+	if err != nil {
+		return err
+	}
+	if m, ok := meta[oldName]; ok {
+		meta[newName] = m
+		delete(meta, oldName)
+		return SaveMetadata(config.MarksDir, meta)
+	}
+	return nil
+}
+
+// Retarget points an existing bookmark at a new target without ever
+// leaving it missing. A symlink is replaced by renaming a new link over the
+// old one; other backends get the old target back when storing the new one
+// fails.
+func Retarget(storage Storage, name, target string) error {
+	bookmark, err := storage.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if s, ok := storage.(*SymlinkStorage); ok {
+		symlinkPath := filepath.Join(s.Dir, name)
+		tmpPath := filepath.Join(s.Dir, "."+name+".tmp"+strconv.Itoa(os.Getpid()))
+		os.Remove(tmpPath)
+		Tracef("symlink %s -> %s", tmpPath, target)
+		if err := os.Symlink(target, tmpPath); err != nil {
+			return err
+		}
+		Tracef("rename %s -> %s", tmpPath, symlinkPath)
+		if err := os.Rename(tmpPath, symlinkPath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		return nil
+	}
+
+	if err := storage.Delete(name); err != nil {
+		return err
+	}
+	if err := storage.Create(name, target); err != nil {
+		if restoreErr := storage.Create(name, bookmark.Target); restoreErr != nil {
+			return fmt.Errorf("%w (and restoring %s failed: %v)", err, bookmark.Target, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a bookmark and the metadata recorded for it
+func Delete(storage Storage, config Config, name string) error {
+	if err := storage.Delete(name); err != nil {
+		return err
+	}
+	return UpdateMetadata(config.MarksDir, name, nil)
+}
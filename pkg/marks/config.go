@@ -0,0 +1,299 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package marks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Config holds the settings of the mark config file (~/.mark)
+type Config struct {
+	MarksDir         string
+	CreateCompletion string            // "dirs" suggests new names from the current path
+	Chooser          string            // "fzf" or "fzf-tmux" replaces the double-Tab list
+	Storage          string            // "symlink" (default), "json" or "exec"
+	StorageFile      string            // index file for the json storage
+	StorageCommand   string            // script backing the exec storage
+	LinkStyle        string            // how new targets are stored: "absolute" (default), "relative" to the marks directory or "home"
+	NamePolicy       string            // comma-separated rules: lowercase, kebab, ascii, spaces=, max_length=, chars=
+	SSHPathMap       []PathMapping     // local -> remote prefixes for 'mark ssh'
+	ContainerPathMap []PathMapping     // host -> container prefixes for --in-container
+	ContainerRuntime string            // "docker" (default) or e.g. "podman"
+	JumpDirFallback  string            // "off" stops jump from cd-ing into plain directory paths
+	DefaultAction    string            // "jump" makes 'mark <existing-name>' jump instead of failing to create
+	OnBroken         string            // what -j does for a missing target: "fail" (default), "ancestor" or "repair"
+	TrashDays        string            // days deleted bookmarks stay restorable; "0" deletes immediately
+	MaxAge           string            // retention window for 'mark maintain', e.g. "180d"
+	DynamicTargets   string            // "on" lets !command targets run at jump time
+	DynamicTimeout   string            // seconds a !command target may run (default 5)
+	PreJump          string            // shell command the jump function runs before cd ($target is set)
+	PostJump         string            // shell command the jump function runs after cd
+	TrackVisits      string            // "on" adds a shell hook recording cd destinations for 'mark suggest'
+	AuditLog         string            // "on" appends create, delete, rename and jump operations to the audit log
+	Sort             string            // default sort keys for -l and completion, e.g. "pinned,frecency,name"
+	ProjectMarks     string            // "off" ignores per-project .marks files
+	SystemMarks      string            // shared read-only marks directory (default /etc/mark/marks), "off" disables
+	SharedMarks      []string          // read-only team marks directories, e.g. on an NFS share
+	RepairRoots      []string          // directories 'mark repair' searches for moved targets (default: home)
+	SourceOrder      string            // precedence of personal, project, shared and system bookmarks
+	Profiles         map[string]string // profile name -> marks directory, selected by --profile or $MARK_PROFILE
+	WorkspaceMap     []PathMapping     // directory -> profile used when none is selected explicitly
+}
+
+// PathMapping translates paths under From to the same relative path under To
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// ParsePathMapping reads a "from=to" mapping from the config file
+func ParsePathMapping(value string) (PathMapping, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return PathMapping{}, fmt.Errorf("invalid path mapping '%s' (expected <from>=<to>)", value)
+	}
+	return PathMapping{
+		From: filepath.Clean(ExpandPath(strings.TrimSpace(parts[0]))),
+		To:   strings.TrimSpace(parts[1]),
+	}, nil
+}
+
+// LoadConfig reads the user's config file and applies $MARK_DIR. A missing
+// config file is only an error when $MARK_DIR does not name the marks
+// directory instead.
+func LoadConfig() (Config, error) {
+	configPath, err := ConfigFilePath()
+	if err != nil {
+		return Config{}, err
+	}
+	config, err := ParseConfigFile(configPath)
+	if err != nil && !(errors.Is(err, os.ErrNotExist) && os.Getenv("MARK_DIR") != "") {
+		return config, err
+	}
+	return ApplyMarksDirEnv(config), nil
+}
+
+// ConfigFilePath returns the location of the config file: $MARK_CONFIG when
+// set, otherwise ~/.mark
+func ConfigFilePath() (string, error) {
+	if path := os.Getenv("MARK_CONFIG"); path != "" {
+		Tracef("$MARK_CONFIG=%s", path)
+		return ExpandPath(path), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".mark"), nil
+}
+
+// ApplyMarksDirEnv points config at $MARK_DIR when it is set. The variable
+// overrides marksdir and any profile for a single invocation.
+func ApplyMarksDirEnv(config Config) Config {
+	if dir := os.Getenv("MARK_DIR"); dir != "" {
+		config.MarksDir = ExpandPath(dir)
+		Tracef("$MARK_DIR overrides marksdir: %s", config.MarksDir)
+	}
+	return config
+}
+
+// ParseConfigFile reads key=value settings from the config file at configPath
+func ParseConfigFile(configPath string) (Config, error) {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+
+	config := Config{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "marksdir":
+			config.MarksDir = ExpandPath(value)
+		case "create_completion":
+			config.CreateCompletion = value
+		case "completion_chooser":
+			config.Chooser = value
+		case "storage":
+			config.Storage = value
+		case "storage_file":
+			config.StorageFile = ExpandPath(value)
+		case "storage_command":
+			config.StorageCommand = ExpandPath(value)
+		case "name_policy":
+			config.NamePolicy = value
+		case "ssh_path_map":
+			mapping, err := ParsePathMapping(value)
+			if err != nil {
+				return config, err
+			}
+			config.SSHPathMap = append(config.SSHPathMap, mapping)
+		case "container_path_map":
+			mapping, err := ParsePathMapping(value)
+			if err != nil {
+				return config, err
+			}
+			config.ContainerPathMap = append(config.ContainerPathMap, mapping)
+		case "container_runtime":
+			config.ContainerRuntime = value
+		case "workspace":
+			mapping, err := ParsePathMapping(value)
+			if err != nil {
+				return config, err
+			}
+			config.WorkspaceMap = append(config.WorkspaceMap, mapping)
+		case "jump_dir_fallback":
+			config.JumpDirFallback = value
+		case "sort":
+			config.Sort = value
+		case "default_action":
+			config.DefaultAction = value
+		case "on_broken":
+			config.OnBroken = value
+		case "link_style":
+			config.LinkStyle = value
+		case "trash_days":
+			config.TrashDays = value
+		case "max_age":
+			config.MaxAge = value
+		case "dynamic_targets":
+			config.DynamicTargets = value
+		case "dynamic_timeout":
+			config.DynamicTimeout = value
+		case "pre_jump":
+			config.PreJump = value
+		case "post_jump":
+			config.PostJump = value
+		case "track_visits":
+			config.TrackVisits = value
+		case "audit_log":
+			config.AuditLog = value
+		case "project_marks":
+			config.ProjectMarks = value
+		case "system_marks":
+			if value != "off" {
+				value = ExpandPath(value)
+			}
+			config.SystemMarks = value
+		case "shared_marks":
+			config.SharedMarks = append(config.SharedMarks, ExpandPath(value))
+		case "repair_root":
+			config.RepairRoots = append(config.RepairRoots, ExpandPath(value))
+		case "source_order":
+			config.SourceOrder = value
+		case "profile":
+			name, dir, ok := strings.Cut(value, "=")
+			name, dir = strings.TrimSpace(name), strings.TrimSpace(dir)
+			if !ok || name == "" || dir == "" {
+				return config, fmt.Errorf("invalid profile '%s' (expected <name>=<marksdir>)", value)
+			}
+			if config.Profiles == nil {
+				config.Profiles = make(map[string]string)
+			}
+			config.Profiles[name] = ExpandPath(dir)
+		}
+	}
+
+	return config, scanner.Err()
+}
+
+// ExpandPath expands ~/ and resolves symbolic links, returning the path
+// unchanged past ~/ expansion when it cannot be resolved (e.g. it does
+// not exist yet)
+func ExpandPath(path string) string {
+	original := path
+	path = ExpandHome(path)
+
+	// Resolve symbolic links to get the actual path
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// If we can't resolve symlinks, return the original path
+		// This handles cases where the path doesn't exist yet or other errors
+		Tracef("expand %s -> %s (not resolved: %v)", original, path, err)
+		return path
+	}
+
+	Tracef("expand %s -> %s", original, resolvedPath)
+	return resolvedPath
+}
+
+// ExpandHome expands ~/ (and Windows environment variables) without
+// resolving symbolic links
+func ExpandHome(path string) string {
+	if runtime.GOOS == "windows" {
+		path = ExpandWindowsPath(path, os.Getenv)
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		path = filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
+
+// ExpandWindowsPath expands %VAR% references (e.g. %USERPROFILE%) and a
+// leading "~\" so Windows paths reach the same tilde handling as Unix ones.
+// Drive (C:\) and UNC (\\server\share) paths pass through untouched.
+func ExpandWindowsPath(path string, getenv func(string) string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(path, "%")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(path[start+1:], "%")
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		name := path[start+1 : end]
+		if value := getenv(name); name != "" && value != "" {
+			sb.WriteString(path[:start])
+			sb.WriteString(value)
+		} else {
+			// Unknown variables are left as-is, like cmd.exe does
+			sb.WriteString(path[:end])
+			path = path[end:]
+			continue
+		}
+		path = path[end+1:]
+	}
+	sb.WriteString(path)
+	path = sb.String()
+
+	if strings.HasPrefix(path, `~\`) {
+		path = "~/" + path[2:]
+	}
+	return path
+}
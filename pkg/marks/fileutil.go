@@ -0,0 +1,109 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package marks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces path with data by writing a temporary file in the
+// same directory and renaming it over the original, so a crash mid-write
+// leaves either the old or the new content, never a truncated file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	// Write through symlinks (e.g. dotfile managers) instead of replacing them
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file on any failure before the rename
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("error syncing %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing %s: %w", path, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("error setting permissions on %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error replacing %s: %w", path, err)
+	}
+
+	success = true
+	Tracef("wrote %s", path)
+	return nil
+}
+
+// BackupSuffix marks the previous version of a file kept by WriteFileBackup
+const BackupSuffix = ".bak"
+
+// WriteFileBackup replaces path atomically like WriteFileAtomic, first
+// keeping its current content in path.bak so a damaged or unwanted new
+// version can be rolled back
+func WriteFileBackup(path string, data []byte, perm os.FileMode) error {
+	if old, err := os.ReadFile(path); err == nil && len(old) > 0 && !bytes.Equal(old, data) {
+		if err := WriteFileAtomic(path+BackupSuffix, old, perm); err != nil {
+			return fmt.Errorf("error keeping backup of %s: %w", path, err)
+		}
+	}
+	return WriteFileAtomic(path, data, perm)
+}
+
+// BackupHint returns advice for recovering path from its backup when one
+// exists, for appending to an error about a damaged file
+func BackupHint(path string) string {
+	if _, err := os.Stat(path + BackupSuffix); err != nil {
+		return ""
+	}
+	return fmt.Sprintf(" (the previous version is kept in %s%s; copy it over %s to recover)", path, BackupSuffix, filepath.Base(path))
+}
+
+// RestoreBackup puts the backup of path back in place, keeping the damaged
+// file as path.broken for inspection
+func RestoreBackup(path string) error {
+	backup, err := os.ReadFile(path + BackupSuffix)
+	if err != nil {
+		return err
+	}
+	if damaged, err := os.ReadFile(path); err == nil {
+		if err := WriteFileAtomic(path+".broken", damaged, 0600); err != nil {
+			return err
+		}
+	}
+	return WriteFileAtomic(path, backup, 0644)
+}
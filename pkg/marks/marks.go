@@ -0,0 +1,37 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package marks is mark's bookmark store as a library: reading the config
+// file, the storage backends (symlinks, a JSON index or an external
+// command), the metadata kept next to the bookmarks and resolving
+// bookmarks to directories. Every function reports
+// failures as errors, so TUIs, editor plugins and prompt generators can
+// embed the store without going through the mark command.
+//
+// A typical caller loads the user's config and opens their storage:
+//
+//	config, err := marks.LoadConfig()
+//	...
+//	store, err := marks.Open(config)
+//	...
+//	dir, err := marks.Resolve(store, config, "work")
+package marks
+
+// Tracef receives trace lines about expanded paths, written files and
+// storage commands. It discards them unless the caller installs a function,
+// as mark does for --verbose.
+var Tracef = func(format string, args ...any) {}
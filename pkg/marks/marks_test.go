@@ -0,0 +1,254 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package marks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := Config{MarksDir: filepath.Join(tmpDir, "marks")}
+	storage, err := Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := filepath.Join(tmpDir, "project")
+	os.Mkdir(project, 0755)
+	storage.Create("project", project)
+	storage.Create("gone", filepath.Join(tmpDir, "gone"))
+	storage.Create("docs", "https://example.com/docs")
+
+	if dir, err := Resolve(storage, config, "project"); err != nil || dir != project {
+		t.Errorf("Resolve(project) = %q, %v; want %q", dir, err, project)
+	}
+	if dir, err := Resolve(storage, config, "docs"); err != nil || dir != "https://example.com/docs" {
+		t.Errorf("Resolve(docs) = %q, %v", dir, err)
+	}
+	if _, err := Resolve(storage, config, "gone"); !errors.Is(err, ErrBroken) {
+		t.Errorf("Expected ErrBroken, got %v", err)
+	}
+	if _, err := Resolve(storage, config, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// A missing target resolves to the first fallback that exists
+	UpdateMetadata(config.MarksDir, "gone", &Metadata{Fallbacks: []string{filepath.Join(tmpDir, "also-gone"), project}})
+	if dir, err := Resolve(storage, config, "gone"); err != nil || dir != project {
+		t.Errorf("Resolve(gone) with fallbacks = %q, %v; want %q", dir, err, project)
+	}
+
+	// A target for the running OS replaces the stored one
+	UpdateMetadata(config.MarksDir, "docs", &Metadata{OSTargets: map[string]string{runtime.GOOS: project}})
+	if dir, err := Resolve(storage, config, "docs"); err != nil || dir != project {
+		t.Errorf("Resolve(docs) with an OS target = %q, %v; want %q", dir, err, project)
+	}
+}
+
+func TestEnvTargets(t *testing.T) {
+	t.Setenv("MARK_TEST_PROJECTS", "/srv/projects")
+	t.Setenv("MARK_TEST_EMPTY", "")
+	config := Config{MarksDir: "/marks"}
+
+	for target, want := range map[string]string{
+		"$MARK_TEST_PROJECTS/api":   "/srv/projects/api",
+		"${MARK_TEST_PROJECTS}/api": "/srv/projects/api",
+		"$MARK_TEST_UNSET/api":      "${MARK_TEST_UNSET}/api",
+		"$MARK_TEST_EMPTY/api":      "${MARK_TEST_EMPTY}/api",
+	} {
+		if !IsEnvTarget(target) {
+			t.Errorf("IsEnvTarget(%q) = false", target)
+		}
+		if got := TargetPath(config, target); got != want {
+			t.Errorf("TargetPath(%q) = %q, want %q", target, got, want)
+		}
+	}
+
+	for _, target := range []string{"/srv/$dir", "$", "$1/x", "relative"} {
+		if IsEnvTarget(target) {
+			t.Errorf("IsEnvTarget(%q) = true", target)
+		}
+	}
+}
+
+func TestGlobTargets(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"snap-3", "snap-1", "snap-2"} {
+		path := filepath.Join(dir, name)
+		os.Mkdir(path, 0755)
+		os.Chtimes(path, now, now.Add(time.Duration(i)*time.Hour))
+	}
+	os.WriteFile(filepath.Join(dir, "snap-9"), nil, 0644)
+	config := Config{MarksDir: dir}
+
+	if got, want := TargetPath(config, filepath.Join(dir, "snap-*")), filepath.Join(dir, "snap-2"); got != want {
+		t.Errorf("TargetPath(snap-*) = %q, want newest directory %q", got, want)
+	}
+	if got, want := TargetPath(config, "snap-[13]"), filepath.Join(dir, "snap-1"); got != want {
+		t.Errorf("TargetPath(snap-[13]) = %q, want %q", got, want)
+	}
+	if got, want := TargetPath(config, "none-*"), filepath.Join(dir, "none-*"); got != want {
+		t.Errorf("TargetPath(none-*) = %q, want the pattern %q", got, want)
+	}
+
+	literal := filepath.Join(dir, "odd[1]")
+	os.Mkdir(literal, 0755)
+	if got := GlobTargetPath(literal); got != literal {
+		t.Errorf("GlobTargetPath(%q) = %q, want the existing path", literal, got)
+	}
+}
+
+func TestSymlinkStorageRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	storage := &SymlinkStorage{Dir: filepath.Join(dir, "marks")}
+	os.Symlink(dir, filepath.Join(dir, "victim"))
+
+	for _, name := range []string{"", ".", "..", "../victim", "a/b", MetadataFile, ".Metadata.json.bak", TrashFile, JSONIndexFile} {
+		if _, err := storage.Get(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidName", name, err)
+		}
+		if err := storage.Create(name, dir); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Create(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+	if err := storage.Delete("../victim"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Delete(../victim) = %v, want ErrInvalidName", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "victim")); err != nil {
+		t.Errorf("outside link was removed: %v", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	config := Config{MarksDir: t.TempDir()}
+	storage := &JSONStorage{Path: filepath.Join(config.MarksDir, JSONIndexFile)}
+	storage.Create("old", "/srv/old")
+	storage.Create("taken", "/srv/taken")
+	UpdateMetadata(config.MarksDir, "old", &Metadata{Tags: []string{"work"}})
+
+	if err := Rename(storage, config, "old", "taken"); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	if err := Rename(storage, config, "old", "new"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if bookmark, err := storage.Get("new"); err != nil || bookmark.Target != "/srv/old" {
+		t.Errorf("Get(new) = %+v, %v", bookmark, err)
+	}
+	if _, err := storage.Get("old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected old name to be gone, got %v", err)
+	}
+	if meta, _ := LoadMetadata(config.MarksDir); meta["old"] != nil || meta["new"] == nil || meta["new"].Tags[0] != "work" {
+		t.Errorf("Metadata after rename = %v, want the tags under new", meta)
+	}
+	if err := Rename(storage, config, "missing", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := Delete(storage, config, "new"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if meta, _ := LoadMetadata(config.MarksDir); len(meta) != 0 {
+		t.Errorf("Metadata after delete = %v, want none", meta)
+	}
+}
+
+// failingStorage refuses to store one target
+type failingStorage struct {
+	*JSONStorage
+	refuse string
+}
+
+func (s failingStorage) Create(name, target string) error {
+	if target == s.refuse {
+		return errors.New("refused")
+	}
+	return s.JSONStorage.Create(name, target)
+}
+
+func TestRetarget(t *testing.T) {
+	dir := t.TempDir()
+	symlinks := &SymlinkStorage{Dir: dir}
+	symlinks.Create("work", "/srv/old")
+	if err := Retarget(symlinks, "work", "/srv/new"); err != nil {
+		t.Fatalf("Retarget failed: %v", err)
+	}
+	if bookmark, err := symlinks.Get("work"); err != nil || bookmark.Target != "/srv/new" {
+		t.Errorf("Get(work) = %+v, %v", bookmark, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the bookmark in the marks directory, got %v", entries)
+	}
+	if err := Retarget(symlinks, "missing", "/srv/new"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	storage := failingStorage{&JSONStorage{Path: filepath.Join(dir, JSONIndexFile)}, "/srv/bad"}
+	storage.Create("work", "/srv/old")
+	if err := Retarget(storage, "work", "/srv/bad"); err == nil {
+		t.Error("Expected the refused target to fail")
+	}
+	if bookmark, err := storage.Get("work"); err != nil || bookmark.Target != "/srv/old" {
+		t.Errorf("Failed retarget should keep the old target, got %+v, %v", bookmark, err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config")
+	os.WriteFile(configPath, []byte("marksdir=/srv/marks\nstorage=json\nshared_marks=/srv/team\n"), 0644)
+	t.Setenv("MARK_CONFIG", configPath)
+	t.Setenv("MARK_DIR", "")
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.MarksDir != "/srv/marks" || config.Storage != "json" || len(config.SharedMarks) != 1 {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	// $MARK_DIR stands in for a missing config file
+	t.Setenv("MARK_CONFIG", filepath.Join(tmpDir, "missing"))
+	t.Setenv("MARK_DIR", tmpDir)
+	config, err = LoadConfig()
+	if err != nil || config.MarksDir != ExpandPath(tmpDir) {
+		t.Errorf("LoadConfig with $MARK_DIR = %+v, %v", config, err)
+	}
+
+	t.Setenv("MARK_DIR", "")
+	if _, err := LoadConfig(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing config error, got %v", err)
+	}
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open(Config{Storage: "sqlite"}); err == nil {
+		t.Error("Expected an error for an unknown storage backend")
+	}
+	if _, err := Open(Config{Storage: "exec"}); err == nil {
+		t.Error("Expected an error for exec storage without a command")
+	}
+}
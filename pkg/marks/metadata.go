@@ -0,0 +1,104 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package marks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MetadataFile is stored alongside the bookmark symlinks
+const MetadataFile = ".metadata.json"
+
+// Metadata holds per-bookmark information that a symlink cannot carry
+type Metadata struct {
+	Tags        []string          `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Host        string            `json:"host,omitempty"` // SSH host for 'mark ssh'
+	Pinned      bool              `json:"pinned,omitempty"`
+	DirID       string            `json:"dir_id,omitempty"` // device:inode of the target when bookmarked, for 'mark repair'
+	Created     time.Time         `json:"created,omitzero"`
+	Fallbacks   []string          `json:"fallbacks,omitempty"`  // targets -j tries in order when the bookmark's own is missing
+	OnlyOn      []string          `json:"only_on,omitempty"`    // hostname patterns the bookmark is active on; empty means everywhere
+	OSTargets   map[string]string `json:"os_targets,omitempty"` // GOOS -> target used instead of the stored one on that OS
+}
+
+// LoadMetadata reads the metadata file from the marks directory.
+// A missing file yields an empty map.
+func LoadMetadata(marksDir string) (map[string]*Metadata, error) {
+	meta := make(map[string]*Metadata)
+
+	path := filepath.Join(marksDir, MetadataFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return nil, fmt.Errorf("error reading metadata: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return nil, fmt.Errorf("error parsing metadata: %w%s", err, BackupHint(path))
+	}
+	return meta, nil
+}
+
+// SaveMetadata writes the metadata file, dropping entries with nothing to store
+func SaveMetadata(marksDir string, meta map[string]*Metadata) error {
+	for name, m := range meta {
+		if m == nil || IsEmptyMetadata(m) {
+			delete(meta, name)
+		}
+	}
+
+	content, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding metadata: %w", err)
+	}
+
+	if err := WriteFileBackup(filepath.Join(marksDir, MetadataFile), append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing metadata: %w", err)
+	}
+	return nil
+}
+
+// IsEmptyMetadata reports whether m carries no information
+func IsEmptyMetadata(m *Metadata) bool {
+	return len(m.Tags) == 0 && m.Description == "" && m.Host == "" && !m.Pinned && m.DirID == "" && m.Created.IsZero() && len(m.Fallbacks) == 0 && len(m.OnlyOn) == 0 && len(m.OSTargets) == 0
+}
+
+// UpdateMetadata replaces the metadata recorded for name; nil removes it
+func UpdateMetadata(marksDir string, name string, m *Metadata) error {
+	meta, err := LoadMetadata(marksDir)
+	if err != nil {
+		return err
+	}
+
+	if m == nil {
+		if _, ok := meta[name]; !ok {
+			return nil
+		}
+		delete(meta, name)
+	} else {
+		meta[name] = m
+	}
+	return SaveMetadata(marksDir, meta)
+}
@@ -0,0 +1,462 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package marks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// JSONIndexFile is the default JSON index file, stored in the marks directory
+const JSONIndexFile = ".index.json"
+
+// Storage errors shared by all backends
+var (
+	ErrNotFound    = errors.New("bookmark does not exist")
+	ErrExists      = errors.New("bookmark already exists")
+	ErrNotBookmark = errors.New("not a bookmark")
+	ErrReadOnly    = errors.New("bookmark source is read-only")
+	ErrInvalidName = errors.New("invalid bookmark name")
+)
+
+// TrashFile holds deleted bookmarks for 'mark restore', stored in the marks
+// directory
+const TrashFile = ".trash.json"
+
+// reservedNames are the files mark keeps in the marks directory itself
+var reservedNames = []string{MetadataFile, MetadataFile + BackupSuffix, TrashFile, TrashFile + BackupSuffix, JSONIndexFile, JSONIndexFile + BackupSuffix}
+
+// IsReservedName reports whether name is one of mark's own files in the
+// marks directory. Case is ignored, as on case-insensitive filesystems.
+func IsReservedName(name string) bool {
+	for _, reserved := range reservedNames {
+		if strings.EqualFold(name, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckName rejects names that cannot be a bookmark in a marks directory:
+// empty, "." and "..", names containing a path separator, which would
+// otherwise reach files outside it, and the names of mark's own files. The
+// error wraps ErrInvalidName.
+func CheckName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/"+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	if IsReservedName(name) {
+		return fmt.Errorf("%w: %q is reserved for mark's own files", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// Bookmark is a name -> target mapping as held by a storage backend
+type Bookmark struct {
+	Name   string
+	Target string // raw target, possibly relative to the marks directory
+	Shared bool   // comes from a read-only source rather than the user's storage
+}
+
+// Storage is the backend holding bookmarks
+type Storage interface {
+	List() ([]Bookmark, error)
+	Get(name string) (Bookmark, error)
+	Create(name, target string) error
+	Delete(name string) error
+}
+
+// Open returns the storage backend selected in the config
+func Open(config Config) (Storage, error) {
+	switch config.Storage {
+	case "", "symlink":
+		return &SymlinkStorage{Dir: config.MarksDir}, nil
+	case "json":
+		path := config.StorageFile
+		if path == "" {
+			path = filepath.Join(config.MarksDir, JSONIndexFile)
+		}
+		return &JSONStorage{Path: path}, nil
+	case "exec":
+		if config.StorageCommand == "" {
+			return nil, fmt.Errorf("storage=exec requires storage_command in config")
+		}
+		return &ExecStorage{Command: config.StorageCommand}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", config.Storage)
+	}
+}
+
+// SymlinkStorage keeps each bookmark as a symbolic link in the marks directory
+type SymlinkStorage struct {
+	Dir string
+}
+
+func (s *SymlinkStorage) List() ([]Bookmark, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var bookmarks []Bookmark
+	for _, entry := range entries {
+		bookmark, err := s.Get(entry.Name())
+		if err != nil {
+			// Not a symlink or unreadable, skip
+			continue
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, nil
+}
+
+func (s *SymlinkStorage) Get(name string) (Bookmark, error) {
+	if err := CheckName(name); err != nil {
+		return Bookmark{}, err
+	}
+	symlinkPath := filepath.Join(s.Dir, name)
+
+	fileInfo, err := os.Lstat(symlinkPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Bookmark{}, ErrNotFound
+		}
+		return Bookmark{}, err
+	}
+
+	if fileInfo.Mode()&os.ModeSymlink == 0 {
+		return Bookmark{}, ErrNotBookmark
+	}
+
+	target, err := os.Readlink(symlinkPath)
+	if err != nil {
+		return Bookmark{}, err
+	}
+	return Bookmark{Name: name, Target: target}, nil
+}
+
+func (s *SymlinkStorage) Create(name, target string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("error creating marks directory: %w", err)
+	}
+
+	symlinkPath := filepath.Join(s.Dir, name)
+	if _, err := os.Lstat(symlinkPath); err == nil {
+		return ErrExists
+	}
+
+	Tracef("symlink %s -> %s", symlinkPath, target)
+	return os.Symlink(target, symlinkPath)
+}
+
+func (s *SymlinkStorage) Delete(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+	Tracef("remove %s", filepath.Join(s.Dir, name))
+	return os.Remove(filepath.Join(s.Dir, name))
+}
+
+// ExecStorage delegates every operation to a user-supplied command:
+//
+//	<command> list                  prints "name<TAB>target" lines
+//	<command> get <name>            prints the target
+//	<command> create <name> <path>  stores a new bookmark
+//	<command> delete <name>         removes a bookmark
+//
+// Exit status 2 means the bookmark does not exist, 3 that it already exists.
+type ExecStorage struct {
+	Command string
+}
+
+// run invokes the storage command, mapping its exit status to storage errors
+func (s *ExecStorage) run(args ...string) (string, error) {
+	Tracef("storage command: %s %s", s.Command, strings.Join(args, " "))
+	cmd := exec.Command(s.Command, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			switch exitErr.ExitCode() {
+			case 2:
+				return "", ErrNotFound
+			case 3:
+				return "", ErrExists
+			}
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("storage command '%s %s' failed: %s", s.Command, args[0], msg)
+		}
+		return "", fmt.Errorf("storage command '%s %s' failed: %w", s.Command, args[0], err)
+	}
+	return string(out), nil
+}
+
+func (s *ExecStorage) List() ([]Bookmark, error) {
+	out, err := s.run("list")
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []Bookmark
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		bookmarks = append(bookmarks, Bookmark{Name: parts[0], Target: parts[1]})
+	}
+	return bookmarks, nil
+}
+
+func (s *ExecStorage) Get(name string) (Bookmark, error) {
+	out, err := s.run("get", name)
+	if err != nil {
+		return Bookmark{}, err
+	}
+
+	target := strings.TrimSpace(out)
+	if target == "" {
+		return Bookmark{}, ErrNotFound
+	}
+	return Bookmark{Name: name, Target: target}, nil
+}
+
+func (s *ExecStorage) Create(name, target string) error {
+	_, err := s.run("create", name, target)
+	return err
+}
+
+func (s *ExecStorage) Delete(name string) error {
+	_, err := s.run("delete", name)
+	return err
+}
+
+// JSONStorage keeps all bookmarks in a single JSON index file, for
+// filesystems without symlink support
+type JSONStorage struct {
+	Path string
+}
+
+// jsonEntry is the stored form of one bookmark in the JSON index
+type jsonEntry struct {
+	Target string `json:"target"`
+}
+
+// load reads the index; a missing file is an empty index
+func (s *JSONStorage) load() (map[string]jsonEntry, error) {
+	index := make(map[string]jsonEntry)
+
+	content, err := os.ReadFile(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("error reading bookmark index: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("error parsing bookmark index %s: %w%s", s.Path, err, BackupHint(s.Path))
+	}
+	return index, nil
+}
+
+// save writes the index back to disk
+func (s *JSONStorage) save(index map[string]jsonEntry) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return fmt.Errorf("error creating index directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding bookmark index: %w", err)
+	}
+	return WriteFileBackup(s.Path, append(content, '\n'), 0644)
+}
+
+func (s *JSONStorage) List() ([]Bookmark, error) {
+	index, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []Bookmark
+	for name, entry := range index {
+		bookmarks = append(bookmarks, Bookmark{Name: name, Target: entry.Target})
+	}
+	return bookmarks, nil
+}
+
+func (s *JSONStorage) Get(name string) (Bookmark, error) {
+	index, err := s.load()
+	if err != nil {
+		return Bookmark{}, err
+	}
+
+	entry, ok := index[name]
+	if !ok {
+		return Bookmark{}, ErrNotFound
+	}
+	return Bookmark{Name: name, Target: entry.Target}, nil
+}
+
+func (s *JSONStorage) Create(name, target string) error {
+	index, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := index[name]; ok {
+		return ErrExists
+	}
+	index[name] = jsonEntry{Target: target}
+	return s.save(index)
+}
+
+func (s *JSONStorage) Delete(name string) error {
+	index, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := index[name]; !ok {
+		return ErrNotFound
+	}
+	delete(index, name)
+	return s.save(index)
+}
+
+// LayeredStorage combines the user's own storage with read-only bookmark
+// sources. Lookups go through sources in precedence order, so earlier
+// sources win on a name clash; changes always go to the user's storage.
+type LayeredStorage struct {
+	Storage          // the user's own storage, which receives all writes
+	Sources []Source // every source in precedence order, the user's included
+}
+
+// Source is one place bookmarks are read from
+type Source struct {
+	Storage Storage
+	Shared  bool   // read-only source maintained by someone else
+	Origin  string // where the bookmarks come from, for messages
+}
+
+func (s *LayeredStorage) List() ([]Bookmark, error) {
+	var bookmarks []Bookmark
+	seen := make(map[string]bool)
+	for _, source := range s.Sources {
+		entries, err := source.Storage.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, bookmark := range entries {
+			if !seen[bookmark.Name] {
+				seen[bookmark.Name] = true
+				bookmark.Shared = source.Shared
+				bookmarks = append(bookmarks, bookmark)
+			}
+		}
+	}
+	return bookmarks, nil
+}
+
+func (s *LayeredStorage) Get(name string) (Bookmark, error) {
+	bookmark, _, err := s.Find(name)
+	return bookmark, err
+}
+
+// Find is Get that also returns the source the bookmark came from
+func (s *LayeredStorage) Find(name string) (Bookmark, Source, error) {
+	for _, source := range s.Sources {
+		bookmark, err := source.Storage.Get(name)
+		if !errors.Is(err, ErrNotFound) {
+			bookmark.Shared = source.Shared
+			return bookmark, source, err
+		}
+	}
+	return Bookmark{}, Source{}, ErrNotFound
+}
+
+// StaticStorage is a fixed, read-only list of bookmarks with absolute targets
+type StaticStorage []Bookmark
+
+func (s StaticStorage) List() ([]Bookmark, error) {
+	return s, nil
+}
+
+func (s StaticStorage) Get(name string) (Bookmark, error) {
+	for _, bookmark := range s {
+		if bookmark.Name == name {
+			return bookmark, nil
+		}
+	}
+	return Bookmark{}, ErrNotFound
+}
+
+func (s StaticStorage) Create(name, target string) error { return ErrReadOnly }
+func (s StaticStorage) Delete(name string) error         { return ErrReadOnly }
+
+// SharedDirStorage reads a marks directory maintained by someone else, such
+// as the system-wide /etc/mark/marks. Relative symlinks are resolved against
+// that directory so the targets stay valid outside it.
+type SharedDirStorage struct {
+	Dir string
+}
+
+func (s *SharedDirStorage) List() ([]Bookmark, error) {
+	bookmarks, err := (&SymlinkStorage{Dir: s.Dir}).List()
+	for i := range bookmarks {
+		bookmarks[i].Target = s.absolute(bookmarks[i].Target)
+	}
+	return bookmarks, err
+}
+
+func (s *SharedDirStorage) Get(name string) (Bookmark, error) {
+	bookmark, err := (&SymlinkStorage{Dir: s.Dir}).Get(name)
+	bookmark.Target = s.absolute(bookmark.Target)
+	return bookmark, err
+}
+
+func (s *SharedDirStorage) Create(name, target string) error { return ErrReadOnly }
+func (s *SharedDirStorage) Delete(name string) error         { return ErrReadOnly }
+
+// absolute resolves a relative symlink target against the shared directory
+func (s *SharedDirStorage) absolute(target string) string {
+	if target == "" || filepath.IsAbs(target) || IsURLTarget(target) {
+		return target
+	}
+	if IsHomeTarget(target) {
+		return HomeTargetPath(target)
+	}
+	return filepath.Join(s.Dir, target)
+}
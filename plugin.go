@@ -0,0 +1,99 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// pluginPrefix names external subcommands: 'mark foo' runs mark-foo from PATH
+// when foo is not a builtin, like git does
+const pluginPrefix = "mark-"
+
+// findPlugin returns the executable implementing 'mark <name>', or "" when
+// there is none and name is a bookmark to create as usual
+func findPlugin(name string) string {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return ""
+	}
+	debugf("plugin %s: %s", name, path)
+	return path
+}
+
+// pluginArgs returns the command line after the plugin name untouched, so
+// options meant for the plugin never go through mark's flag parsing. The
+// name is located by the index parsing recorded, never by its text, since a
+// flag value may equal it.
+func pluginArgs(rawArgs []string, flags *ParsedFlags) []string {
+	if flags.FirstArg < 0 || flags.FirstArg >= len(rawArgs) {
+		return nil
+	}
+	return rawArgs[flags.FirstArg+1:]
+}
+
+// pluginEnv returns environ with the settings mark resolved exported, so a
+// plugin (and any mark it runs) sees the same marks directory, profile and
+// config file without parsing them again
+func pluginEnv(environ []string, config Config, profile string) []string {
+	vars := map[string]string{
+		"MARK_BIN":       getMarkPath(),
+		"MARK_DIR":       config.MarksDir,
+		"MARK_PROFILE":   profile,
+		"MARK_STORAGE":   storageName(config),
+		"MARK_STATE_DIR": stateDir(),
+		"MARK_VERSION":   Version,
+	}
+	if path, err := marks.ConfigFilePath(); err == nil {
+		vars["MARK_CONFIG"] = path
+	}
+	if debugEnabled {
+		vars["MARK_DEBUG"] = "1"
+	}
+
+	var env []string
+	for _, entry := range environ {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, ok := vars[key]; !ok {
+			env = append(env, entry)
+		}
+	}
+	for key, value := range vars {
+		// Empty values are unset rather than exported, e.g. no profile
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// runPlugin runs the external subcommand at path with the rest of the
+// command line and exits with its status
+func runPlugin(path string, config Config, flags *ParsedFlags) {
+	cmd := exec.Command(path, pluginArgs(os.Args[1:], flags)...)
+	cmd.Env = pluginEnv(os.Environ(), config, profileName(config, flags.Profile))
+	runInteractive(cmd)
+	os.Exit(0)
+}
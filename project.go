@@ -0,0 +1,80 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// Per-project bookmark file, looked up from the current directory upwards
+const projectMarksFile = ".marks"
+
+// findProjectMarksFile returns the nearest regular .marks file in dir or one
+// of its parents, or "" if there is none. A directory named .marks (such as
+// the default ~/.marks) is not a project file.
+func findProjectMarksFile(dir string) string {
+	for {
+		path := filepath.Join(dir, projectMarksFile)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseProjectMarks reads name=path lines from a project .marks file.
+// Relative paths are resolved against the directory holding the file; blank
+// lines and lines starting with # are ignored.
+func parseProjectMarks(path string) ([]Bookmark, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	root := filepath.Dir(path)
+	var bookmarks []Bookmark
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, target, ok := strings.Cut(line, "=")
+		name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+		if !ok || name == "" || target == "" {
+			return nil, fmt.Errorf("%s:%d: expected <name>=<path>", path, lineNum)
+		}
+		if !filepath.IsAbs(target) && !strings.HasPrefix(target, "~") {
+			target = filepath.Join(root, target)
+		}
+		target = marks.ExpandPath(target)
+		bookmarks = append(bookmarks, Bookmark{Name: name, Target: target})
+	}
+	return bookmarks, scanner.Err()
+}
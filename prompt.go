@@ -0,0 +1,242 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// setupDefaultsFile is set by --defaults to answer setup questions from a
+// key=value file instead of the terminal
+var setupDefaultsFile string
+
+// setupFlagAnswers holds answers given as flags (--marksdir, --yes,
+// --no-completion, --no-alias); they take precedence over a --defaults file
+var setupFlagAnswers map[string]string
+
+// setupAnswersFromFlags turns the setup flags into prompter answers. --yes
+// accepts the default location and says yes to every question not answered
+// by another flag.
+func setupAnswersFromFlags(flags *ParsedFlags) map[string]string {
+	answers := make(map[string]string)
+	if flags.Yes {
+		answers["marksdir"] = ""
+		answers["completion"] = "yes"
+		answers["aliases"] = "yes"
+	}
+	if flags.MarksDir != "" {
+		answers["marksdir"] = flags.MarksDir
+	}
+	if flags.NoCompletion {
+		answers["completion"] = "no"
+	}
+	if flags.NoAlias {
+		answers["aliases"] = "no"
+	}
+	return answers
+}
+
+// setupAnswerKeys lists the questions an answer file may answer
+var setupAnswerKeys = map[string]bool{
+	"marksdir":   true,
+	"completion": true,
+	"aliases":    true,
+}
+
+// terminalEscapes matches ANSI escape sequences (bracketed paste markers,
+// cursor keys) that some terminals leak into plain line input
+var terminalEscapes = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1bO.`)
+
+// setupPrompter asks the setup questions, reading answers from a --defaults
+// file when one is given and from stdin otherwise
+type setupPrompter struct {
+	reader  *bufio.Reader
+	answers map[string]string
+	eof     bool // stdin closed; every further question takes its default
+}
+
+// newSetupPrompter returns a prompter reading stdin, with answers from
+// setupDefaultsFile when --defaults was given and from setupFlagAnswers
+func newSetupPrompter() *setupPrompter {
+	p := &setupPrompter{reader: bufio.NewReader(os.Stdin), answers: make(map[string]string)}
+	if setupDefaultsFile != "" {
+		answers, err := loadSetupAnswers(marks.ExpandPath(setupDefaultsFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		p.answers = answers
+	}
+
+	for key, answer := range setupFlagAnswers {
+		p.answers[key] = answer
+	}
+	return p
+}
+
+// loadSetupAnswers reads key=value answers (marksdir, completion, aliases);
+// blank lines and # comments are ignored
+func loadSetupAnswers(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading setup defaults: %w", err)
+	}
+
+	answers := make(map[string]string)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !setupAnswerKeys[key] {
+			return nil, fmt.Errorf("%s:%d: expected marksdir=, completion= or aliases=", path, i+1)
+		}
+		answers[key] = strings.TrimSpace(value)
+	}
+	return answers, nil
+}
+
+// readLine reads one answer from stdin, dropping terminal escape sequences
+// and control characters. It returns false once stdin is exhausted.
+func (p *setupPrompter) readLine() (string, bool) {
+	if p.eof {
+		fmt.Println()
+		return "", false
+	}
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		p.eof = true
+		fmt.Println()
+		return "", false
+	}
+	return cleanResponse(line), true
+}
+
+// cleanResponse strips escape sequences, control characters and surrounding
+// whitespace from a line typed at a prompt
+func cleanResponse(line string) string {
+	line = terminalEscapes.ReplaceAllString(line, "")
+	line = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, line)
+	return strings.TrimSpace(line)
+}
+
+// askText asks question and returns the answer, or def when it is empty
+func (p *setupPrompter) askText(key, question, def string) string {
+	if answer, ok := p.answers[key]; ok {
+		fmt.Printf("%s (%s): %s\n", question, def, answer)
+		if answer == "" {
+			return def
+		}
+		return answer
+	}
+
+	fmt.Printf("%s (%s): ", question, def)
+	answer, _ := p.readLine()
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// askYesNo asks a question defaulting to no. An answer that is neither yes
+// nor no switches to a numbered menu, which only needs a single digit and
+// so survives terminals that mangle line input.
+func (p *setupPrompter) askYesNo(key, question string) bool {
+	if answer, ok := p.answers[key]; ok {
+		yes, known := parseYesNo(answer)
+		if !known {
+			fmt.Fprintf(os.Stderr, "Error: %s=%s in %s is not yes or no\n", key, answer, setupDefaultsFile)
+			os.Exit(1)
+		}
+		fmt.Printf("%s (y/N): %s\n", question, answer)
+		return yes
+	}
+
+	fmt.Printf("%s (y/N): ", question)
+	answer, ok := p.readLine()
+	if yes, known := parseYesNo(answer); known || !ok {
+		return yes
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		fmt.Println("  1) Yes")
+		fmt.Println("  2) No")
+		fmt.Print("Choose 1 or 2 [2]: ")
+		answer, ok := p.readLine()
+		if !ok {
+			return false
+		}
+		switch {
+		case answer == "" || strings.HasPrefix(answer, "2"):
+			return false
+		case strings.HasPrefix(answer, "1"):
+			return true
+		}
+	}
+	fmt.Println("No valid choice, assuming no.")
+	return false
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal rather
+// than a pipe or file
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// /dev/null is a character device too, but nobody is there to answer
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// confirmDeletion shows the bookmark about to be deleted and reads a yes/no
+// answer, defaulting to no
+func confirmDeletion(reader *bufio.Reader, name, target string) bool {
+	fmt.Printf("Delete bookmark '%s' -> %s? (y/N): ", name, target)
+	response, _ := reader.ReadString('\n')
+	yes, _ := parseYesNo(cleanResponse(response))
+	return yes
+}
+
+// parseYesNo interprets a yes/no answer; the empty answer is a known "no"
+func parseYesNo(answer string) (yes, known bool) {
+	switch strings.ToLower(answer) {
+	case "y", "yes", "true", "1":
+		return true, true
+	case "", "n", "no", "false", "0":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,184 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// bestMapping returns the index of the most specific mapping whose From
+// contains path, or -1 if none does
+func bestMapping(path string, mappings []marks.PathMapping) int {
+	best := -1
+	for i, m := range mappings {
+		if path != m.From && !strings.HasPrefix(path, m.From+string(os.PathSeparator)) {
+			continue
+		}
+		if best < 0 || len(m.From) > len(mappings[best].From) {
+			best = i
+		}
+	}
+	return best
+}
+
+// remapPath rewrites path using the most specific mapping whose From
+// contains it. Paths outside every mapping are returned unchanged.
+func remapPath(path string, mappings []marks.PathMapping) string {
+	path = filepath.Clean(path)
+
+	best := bestMapping(path, mappings)
+	if best < 0 {
+		return path
+	}
+
+	rel := strings.TrimPrefix(path, mappings[best].From)
+	return strings.TrimSuffix(mappings[best].To, "/") + filepath.ToSlash(rel)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sshArgs returns the ssh arguments that open an interactive login shell on
+// host already in remotePath
+func sshArgs(host, remotePath string) []string {
+	return []string{"-t", host, "cd " + shellQuote(remotePath) + ` && exec "${SHELL:-/bin/sh}" -l`}
+}
+
+// sshCommand opens an SSH session in the remote counterpart of a bookmark
+// ('mark ssh <name> [--host <host>]')
+func sshCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for ssh\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	bookmark := lookupBookmark(openStorage(config), name)
+
+	host := flags.Host
+	if host == "" {
+		meta, err := marks.LoadMetadata(config.MarksDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if m := meta[name]; m != nil {
+			host = m.Host
+		}
+	}
+	if host == "" {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' has no host. Use 'mark ssh %s --host <host>'\n", name, name)
+		os.Exit(1)
+	}
+
+	remotePath := remapPath(marks.TargetPath(config, bookmark.Target), config.SSHPathMap)
+
+	runInteractive(exec.Command("ssh", sshArgs(host, remotePath)...))
+}
+
+// runInteractive runs cmd attached to the terminal and exits with its status
+// if it fails
+func runInteractive(cmd *exec.Cmd) {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", filepath.Base(cmd.Path), err)
+		os.Exit(1)
+	}
+}
+
+// containerExecArgs returns the runtime arguments that run command in
+// container with dir as the working directory
+func containerExecArgs(container, dir string, command []string, tty bool) []string {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	args = append(args, "-w", dir, container)
+	return append(args, command...)
+}
+
+// execCommand runs a command in a bookmark's directory, or in the matching
+// directory of a dev container ('mark exec <name> [--in-container <id>] [-- cmd]')
+func execCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for exec\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	targetPath := resolveJumpTarget(config, name, flags.Raw)
+
+	command := args[1:]
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+
+	if flags.InContainer == "" {
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Dir = targetPath
+		runInteractive(cmd)
+		return
+	}
+
+	containerCLI := config.ContainerRuntime
+	if containerCLI == "" {
+		containerCLI = "docker"
+	}
+
+	containerPath := remapPath(targetPath, config.ContainerPathMap)
+	runInteractive(exec.Command(containerCLI, containerExecArgs(flags.InContainer, containerPath, command, stdinIsTerminal())...))
+}
+
+// shellCommand starts an interactive $SHELL in a bookmark's directory, for
+// machines without the jump function ('mark shell <name>'). Exiting the
+// shell returns to where it was started.
+func shellCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for shell\n")
+		os.Exit(1)
+	}
+	name := args[0]
+	targetPath := resolveJumpTarget(config, name, flags.Raw)
+	if strings.Contains(name, "/") {
+		name, _ = splitSubpath(openLayeredStorage(config), name)
+	}
+	_ = recordUsage(name, targetPath)
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+
+	cmd := exec.Command(shell)
+	cmd.Dir = targetPath
+	cmd.Env = append(os.Environ(), "PWD="+targetPath)
+	runInteractive(cmd)
+}
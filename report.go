@@ -0,0 +1,176 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// bookmarkCount is a bookmark name with its number of jumps
+type bookmarkCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// monthlyReport summarizes one month of local usage for 'mark report'
+type monthlyReport struct {
+	Month        string          `json:"month"`
+	Jumps        int             `json:"jumps"`
+	JumpsPerDay  map[string]int  `json:"jumps_per_day"`
+	TopBookmarks []bookmarkCount `json:"top_bookmarks"`
+	Created      int             `json:"created"`
+	Deleted      int             `json:"deleted"`
+}
+
+// buildReport aggregates the usage and change logs for the month containing
+// month, listing at most top bookmarks
+func buildReport(usage []usageEvent, changes []changeEvent, month time.Time, top int) monthlyReport {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	end := start.AddDate(0, 1, 0)
+	inMonth := func(t time.Time) bool {
+		t = t.In(month.Location())
+		return !t.Before(start) && t.Before(end)
+	}
+
+	report := monthlyReport{
+		Month:        start.Format("2006-01"),
+		JumpsPerDay:  make(map[string]int),
+		TopBookmarks: []bookmarkCount{},
+	}
+
+	counts := make(map[string]int)
+	for _, event := range usage {
+		if !inMonth(event.Time) {
+			continue
+		}
+		report.Jumps++
+		report.JumpsPerDay[event.Time.In(month.Location()).Format("2006-01-02")]++
+		counts[event.Name]++
+	}
+
+	for name, count := range counts {
+		report.TopBookmarks = append(report.TopBookmarks, bookmarkCount{Name: name, Count: count})
+	}
+	sort.Slice(report.TopBookmarks, func(i, j int) bool {
+		a, b := report.TopBookmarks[i], report.TopBookmarks[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	if len(report.TopBookmarks) > top {
+		report.TopBookmarks = report.TopBookmarks[:top]
+	}
+
+	for _, change := range changes {
+		if !inMonth(change.Time) {
+			continue
+		}
+		switch change.Action {
+		case "create":
+			report.Created++
+		case "delete":
+			report.Deleted++
+		}
+	}
+
+	return report
+}
+
+// printReport writes the report as plain text
+func printReport(report monthlyReport) {
+	fmt.Printf("mark report for %s\n", report.Month)
+	fmt.Printf("  Jumps:    %d (on %d days)\n", report.Jumps, len(report.JumpsPerDay))
+	fmt.Printf("  Created:  %d\n", report.Created)
+	fmt.Printf("  Deleted:  %d\n", report.Deleted)
+
+	if len(report.TopBookmarks) > 0 {
+		fmt.Println("\nTop bookmarks:")
+		for _, b := range report.TopBookmarks {
+			fmt.Printf("  %5d  %s\n", b.Count, b.Name)
+		}
+	}
+
+	if len(report.JumpsPerDay) > 0 {
+		var days []string
+		for day := range report.JumpsPerDay {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+
+		fmt.Println("\nJumps per day:")
+		for _, day := range days {
+			fmt.Printf("  %s  %d\n", day, report.JumpsPerDay[day])
+		}
+	}
+}
+
+// reportCommand prints a local monthly usage summary
+// ('mark report [--month YYYY-MM] [--json]'). Nothing leaves the machine.
+func reportCommand(config Config, flags *ParsedFlags, args []string) {
+	month := time.Now()
+	asJSON := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--json":
+			asJSON = true
+		case "--month":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --month requires a value (YYYY-MM)\n")
+				os.Exit(1)
+			}
+			parsed, err := time.ParseInLocation("2006-01", args[i+1], time.Local)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Invalid month '%s' (expected YYYY-MM)\n", args[i+1])
+				os.Exit(1)
+			}
+			month = parsed
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown report option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	usage, err := loadUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	changes, err := loadChanges()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := buildReport(usage, changes, month, 10)
+	if asJSON {
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+	printReport(report)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// projectMarkers are files that make a directory a scan candidate besides
+// a .git entry
+var projectMarkers = []string{"go.mod", "package.json", "Cargo.toml", "pyproject.toml", "pom.xml", "Makefile"}
+
+// scanSkipDirs are dependency and build directories never descended into
+var scanSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"__pycache__":  true,
+}
+
+// isScanCandidate reports whether dir looks like a project worth a bookmark:
+// a git repository or, unless gitOnly, a directory with a project marker
+func isScanCandidate(dir string, gitOnly bool) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+	if gitOnly {
+		return false
+	}
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findScanCandidates walks up to depth levels below root and returns the
+// candidate directories in walk order. Hidden directories and symlinks are
+// skipped, and candidates are not descended into.
+func findScanCandidates(root string, depth int, gitOnly bool) []string {
+	var found []string
+	var walk func(dir string, level int)
+	walk = func(dir string, level int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || scanSkipDirs[entry.Name()] {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if isScanCandidate(path, gitOnly) {
+				found = append(found, path)
+				continue
+			}
+			if level < depth {
+				walk(path, level+1)
+			}
+		}
+	}
+	walk(root, 1)
+	return found
+}
+
+// uniqueScanName names a bookmark after its directory. On a collision it
+// tries "<parent>-<dir>", then a numeric suffix.
+func uniqueScanName(path string, taken map[string]bool, policy namePolicy) string {
+	base := policy.apply(filepath.Base(path))
+	for _, name := range []string{base, policy.apply(filepath.Base(filepath.Dir(path)) + "-" + filepath.Base(path))} {
+		if !taken[name] {
+			return name
+		}
+	}
+	for i := 2; ; i++ {
+		if name := fmt.Sprintf("%s-%d", base, i); !taken[name] {
+			return name
+		}
+	}
+}
+
+// scanCommand bookmarks the projects found below a directory, asking for
+// each one unless --yes is given ('mark scan <root> [--depth N] [--git-only]')
+func scanCommand(config Config, flags *ParsedFlags, args []string) {
+	root := ""
+	depth := 3
+	gitOnly := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--git-only":
+			gitOnly = true
+		case "--depth":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --depth requires a number\n")
+				os.Exit(1)
+			}
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil || value < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --depth must be a positive number\n")
+				os.Exit(1)
+			}
+			depth = value
+			i++
+		default:
+			if root != "" || strings.HasPrefix(args[i], "--") {
+				fmt.Fprintf(os.Stderr, "Error: Unknown scan option: %s\n", args[i])
+				os.Exit(1)
+			}
+			root = args[i]
+		}
+	}
+	if root == "" {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark scan <root> [--depth N] [--git-only]\n")
+		os.Exit(1)
+	}
+
+	root = normalizeTarget(marks.ExpandPath(root))
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: Not a directory: %s\n", root)
+		os.Exit(1)
+	}
+
+	policy := configNamePolicy(config)
+
+	bookmarks, err := openLayeredStorage(config).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+	taken := make(map[string]bool)
+	bookmarked := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		taken[bookmark.Name] = true
+		bookmarked[normalizeTarget(marks.TargetPath(config, bookmark.Target))] = true
+	}
+
+	var candidates []string
+	for _, dir := range findScanCandidates(root, depth, gitOnly) {
+		if !bookmarked[dir] {
+			candidates = append(candidates, dir)
+		}
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("No new project directories found under %s\n", root)
+		return
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var accepted []importEntry
+	for _, dir := range candidates {
+		name := uniqueScanName(dir, taken, policy)
+		if !flags.Yes {
+			fmt.Printf("Bookmark %s as '%s'? (y/N/other name): ", dir, name)
+			response, _ := reader.ReadString('\n')
+			response = strings.TrimSpace(response)
+
+			switch strings.ToLower(response) {
+			case "", "n", "no":
+				continue
+			case "y", "yes":
+			default:
+				name = response
+			}
+		}
+		taken[name] = true
+		accepted = append(accepted, importEntry{Name: name, Path: dir})
+	}
+
+	if len(accepted) == 0 {
+		fmt.Println("No bookmarks created.")
+		return
+	}
+	importEntries(config, openStorage(config), accepted, "", flags.DryRun)
+}
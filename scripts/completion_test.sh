@@ -357,6 +357,118 @@ else
     ((TESTS_FAILED++))
 fi
 
+echo
+echo "Testing creation completion candidates (create_completion=dirs)..."
+
+CREATE_TEST_HOME=$(mktemp -d)
+mkdir -p "$CREATE_TEST_HOME/.marks" "$CREATE_TEST_HOME/src/api"
+ln -s "$CREATE_TEST_HOME/src" "$CREATE_TEST_HOME/.marks/src"
+
+# Without the opt-in the binary declines so scripts fall back to bookmark names
+echo "marksdir=$CREATE_TEST_HOME/.marks" > "$CREATE_TEST_HOME/.mark"
+if ! (cd "$CREATE_TEST_HOME/src/api" && HOME="$CREATE_TEST_HOME" "$MARK_BINARY_ABS" --complete-create >/dev/null 2>&1); then
+    echo -e "${GREEN}✓${NC} Creation candidates disabled by default"
+    ((TESTS_PASSED++))
+else
+    echo -e "${RED}✗${NC} Creation candidates should be opt-in"
+    ((TESTS_FAILED++))
+fi
+
+# With the opt-in, path names are offered and existing bookmarks excluded
+echo "create_completion=dirs" >> "$CREATE_TEST_HOME/.mark"
+candidates=$(cd "$CREATE_TEST_HOME/src/api" && HOME="$CREATE_TEST_HOME" "$MARK_BINARY_ABS" --complete-create 2>/dev/null)
+if echo "$candidates" | grep -qx "api" && ! echo "$candidates" | grep -qx "src"; then
+    echo -e "${GREEN}✓${NC} Creation candidates offer path names and exclude existing bookmarks"
+    ((TESTS_PASSED++))
+else
+    echo -e "${RED}✗${NC} Unexpected creation candidates"
+    echo "$candidates"
+    ((TESTS_FAILED++))
+fi
+rm -rf "$CREATE_TEST_HOME"
+
+echo
+echo "Testing full-screen chooser (completion_chooser=fzf)..."
+
+CHOOSER_HOME=$(mktemp -d)
+mkdir -p "$CHOOSER_HOME/.marks" "$CHOOSER_HOME/bin" "$CHOOSER_HOME/alpha" "$CHOOSER_HOME/beta"
+ln -s "$CHOOSER_HOME/alpha" "$CHOOSER_HOME/.marks/alpha"
+ln -s "$CHOOSER_HOME/beta" "$CHOOSER_HOME/.marks/beta"
+ln -s "$MARK_BINARY_ABS" "$CHOOSER_HOME/bin/mark"
+# Fake fzf picks the last line it is offered
+printf '#!/bin/sh\ntail -1\n' > "$CHOOSER_HOME/bin/fzf"
+chmod +x "$CHOOSER_HOME/bin/fzf"
+
+echo "marksdir=$CHOOSER_HOME/.marks" > "$CHOOSER_HOME/.mark"
+if ! HOME="$CHOOSER_HOME" PATH="$CHOOSER_HOME/bin:$PATH" "$MARK_BINARY_ABS" --complete-chooser >/dev/null 2>&1; then
+    echo -e "${GREEN}✓${NC} Chooser disabled by default"
+    ((TESTS_PASSED++))
+else
+    echo -e "${RED}✗${NC} Chooser should be opt-in"
+    ((TESTS_FAILED++))
+fi
+
+echo "completion_chooser=fzf" >> "$CHOOSER_HOME/.mark"
+chosen=$(HOME="$CHOOSER_HOME" PATH="$CHOOSER_HOME/bin:$PATH" bash --norc --noprofile -c '
+    eval "$(mark init bash)"
+    COMP_WORDS=(jump "")
+    COMP_CWORD=1
+    COMP_TYPE=63
+    _mark_complete
+    echo "${COMPREPLY[*]}"
+' 2>/dev/null)
+if [[ "$chosen" == "beta" ]]; then
+    echo -e "${GREEN}✓${NC} Double-Tab on jump uses the chooser selection"
+    ((TESTS_PASSED++))
+else
+    echo -e "${RED}✗${NC} Chooser selection not used (got: $chosen)"
+    ((TESTS_FAILED++))
+fi
+rm -rf "$CHOOSER_HOME"
+
+echo
+echo "Testing completion with a custom marksdir..."
+
+CUSTOM_HOME=$(mktemp -d)
+mkdir -p "$CUSTOM_HOME/bookmarks" "$CUSTOM_HOME/bin" "$CUSTOM_HOME/gamma"
+ln -s "$CUSTOM_HOME/gamma" "$CUSTOM_HOME/bookmarks/gamma"
+ln -s "$MARK_BINARY_ABS" "$CUSTOM_HOME/bin/mark"
+echo "marksdir=$CUSTOM_HOME/bookmarks" > "$CUSTOM_HOME/.mark"
+custom=$(HOME="$CUSTOM_HOME" PATH="$CUSTOM_HOME/bin:$PATH" bash --norc --noprofile -c '
+    eval "$(mark init bash)"
+    COMP_WORDS=(jump g)
+    COMP_CWORD=1
+    _mark_complete
+    first="${COMPREPLY[*]}"
+    COMP_WORDS=(mark -j g)
+    COMP_CWORD=2
+    _mark_complete
+    echo "$first|${COMPREPLY[*]}"
+' 2>/dev/null)
+mkdir -p "$CUSTOM_HOME/gamma/src" "$CUSTOM_HOME/gamma/docs"
+subdirs=$(HOME="$CUSTOM_HOME" PATH="$CUSTOM_HOME/bin:$PATH" bash --norc --noprofile -c '
+    eval "$(mark init bash)"
+    COMP_WORDS=(jump gamma/s)
+    COMP_CWORD=1
+    _mark_complete
+    echo "${COMPREPLY[*]}"
+' 2>/dev/null)
+if [[ "$subdirs" == "gamma/src/" ]]; then
+    echo -e "${GREEN}✓${NC} jump name/<TAB> completes subdirectories below the bookmark"
+    ((TESTS_PASSED++))
+else
+    echo -e "${RED}✗${NC} Subdirectory completion failed (got: $subdirs)"
+    ((TESTS_FAILED++))
+fi
+if [[ "$custom" == "gamma|gamma" ]]; then
+    echo -e "${GREEN}✓${NC} Bookmarks complete without a ~/.marks directory"
+    ((TESTS_PASSED++))
+else
+    echo -e "${RED}✗${NC} Custom marksdir bookmarks not completed (got: $custom)"
+    ((TESTS_FAILED++))
+fi
+rm -rf "$CUSTOM_HOME"
+
 echo
 echo "==================================="
 echo "Test Summary:"
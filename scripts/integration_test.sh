@@ -38,6 +38,7 @@ run_test() {
 # Setup test environment
 setup_test_env() {
     export HOME="/tmp/mark-test-$$"
+    unset XDG_STATE_HOME
     mkdir -p "$HOME"
     export PATH="$SCRIPT_DIR/..:$PATH"
 }
@@ -190,6 +191,1376 @@ else
     test_fail "Non-existent path not properly handled"
 fi
 
+# Test 13: Create bookmark with tags
+run_test "Create bookmark with tags"
+TAGGED_DIR="$HOME/tagged-location"
+mkdir -p "$TAGGED_DIR"
+if "$MARK_BINARY" tagged "$TAGGED_DIR" --tag client,billing 2>/dev/null | grep -q "Created bookmark 'tagged'"; then
+    test_pass "Created tagged bookmark"
+else
+    test_fail "Failed to create tagged bookmark"
+fi
+
+# Test 14: Filter list by tag
+run_test "List filtered by tag"
+TAG_LIST=$("$MARK_BINARY" -l --tag client 2>/dev/null)
+if echo "$TAG_LIST" | grep -q "tagged" && ! echo "$TAG_LIST" | grep -q "customloc"; then
+    test_pass "Tag filter shows only tagged bookmarks"
+else
+    test_fail "Tag filter did not work (got: $TAG_LIST)"
+fi
+
+# Test 15: Bookmark description shown in list
+run_test "Bookmark description shown in list"
+DESC_DIR="$HOME/portal"
+mkdir -p "$DESC_DIR"
+"$MARK_BINARY" portal "$DESC_DIR" --desc "client portal repo" >/dev/null 2>&1
+if "$MARK_BINARY" -l 2>/dev/null | grep "portal" | grep -q "# client portal repo"; then
+    test_pass "Description displayed in list"
+else
+    test_fail "Description missing from list"
+fi
+
+# Test 16: Explain shows resolution trace
+run_test "Explain shows resolution trace"
+EXPLAIN_OUTPUT=$("$MARK_BINARY" explain customloc 2>/dev/null)
+if echo "$EXPLAIN_OUTPUT" | grep -q "Raw target: $CUSTOM_DIR" && echo "$EXPLAIN_OUTPUT" | grep -q "Resolved:   $CUSTOM_DIR"; then
+    test_pass "Explain shows raw target and resolved path"
+else
+    test_fail "Explain output incomplete (got: $EXPLAIN_OUTPUT)"
+fi
+
+# Test 17: Double dash creates bookmark named like a command
+run_test "Double dash creates bookmark named like a command"
+if "$MARK_BINARY" -- explain "$CUSTOM_DIR" 2>/dev/null | grep -q "Created bookmark 'explain'"; then
+    test_pass "Created bookmark named 'explain'"
+else
+    test_fail "Failed to create bookmark named 'explain'"
+fi
+
+# Test 18: Jumps are counted in the long listing
+run_test "Long listing shows usage count"
+"$MARK_BINARY" -j portal >/dev/null 2>&1
+"$MARK_BINARY" -j portal >/dev/null 2>&1
+if "$MARK_BINARY" -l --long 2>/dev/null | grep -qE "^ +portal +2 "; then
+    test_pass "Long listing shows two uses"
+else
+    test_fail "Long listing did not show usage count"
+fi
+
+# Test 19: Recent jumps listed most recent first
+run_test "Recent jumps listed most recent first"
+"$MARK_BINARY" -j customloc >/dev/null 2>&1
+if "$MARK_BINARY" recent 2>/dev/null | head -1 | grep -q "customloc"; then
+    test_pass "Most recent jump listed first"
+else
+    test_fail "Recent did not list latest jump first"
+fi
+
+# Test 20: tidy-names applies the naming policy
+run_test "tidy-names renames bookmarks to follow name_policy"
+echo "name_policy=lowercase,kebab" >> "$HOME/.mark"
+"$MARK_BINARY" MixedCase "$CUSTOM_DIR" >/dev/null 2>&1
+echo "y" | "$MARK_BINARY" tidy-names >/dev/null 2>&1
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "mixed-case" && ! "$MARK_BINARY" -l 2>/dev/null | grep -q "MixedCase"; then
+    test_pass "Bookmark renamed to policy-conforming name"
+else
+    test_fail "tidy-names did not rename bookmark"
+fi
+
+# Test 21: Screen reader listing uses plain sentences
+run_test "Screen reader listing uses plain sentences"
+SR_OUTPUT=$("$MARK_BINARY" -l --screen-reader 2>/dev/null)
+if echo "$SR_OUTPUT" | grep -q "^bookmark customloc points to slash .*, status ok.$" && ! echo "$SR_OUTPUT" | grep -q -- "->"; then
+    test_pass "Screen reader listing has no arrows or columns"
+else
+    test_fail "Screen reader listing not in sentence form (got: $SR_OUTPUT)"
+fi
+
+# Test 22: why-broken names the missing path component
+run_test "why-broken names the missing path component"
+GONE_DIR="$HOME/soon-gone/inner"
+mkdir -p "$GONE_DIR"
+"$MARK_BINARY" soongone "$GONE_DIR" >/dev/null 2>&1
+rm -rf "$HOME/soon-gone"
+if "$MARK_BINARY" why-broken soongone 2>/dev/null | grep -q "Problem:    $HOME/soon-gone does not exist"; then
+    test_pass "why-broken reports the first missing component"
+else
+    test_fail "why-broken did not locate the missing component"
+fi
+
+# Test 23: bench init times the shell snippet
+run_test "bench init reports shell startup overhead"
+if "$MARK_BINARY" bench init --shell bash --runs 2 2>/dev/null | grep -q "^mark adds:"; then
+    test_pass "bench init reported overhead"
+else
+    test_fail "bench init did not report overhead"
+fi
+
+# Test 24: Import from z.sh data file
+run_test "Import from z.sh data file"
+ZDIR="$HOME/zproject"
+mkdir -p "$ZDIR"
+printf '%s|10|1700000000\n%s|5|1700000000\n' "$ZDIR" "$HOME/zmissing" > "$HOME/.z"
+"$MARK_BINARY" import z --file "$HOME/.z" >/dev/null 2>&1
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "zproject" && ! "$MARK_BINARY" -l 2>/dev/null | grep -q "zmissing"; then
+    test_pass "Existing z directories imported, missing ones skipped"
+else
+    test_fail "z import did not create the expected bookmarks"
+fi
+
+# Test 25: Lazy bash integration loads the full script on first use
+run_test "Lazy init loads full integration on first jump"
+LAZY_OUTPUT=$(bash --norc --noprofile -c '
+    eval "$("$1" init bash --lazy)"
+    type -t _mark_complete || echo "no-completion-yet"
+    jump zproject
+    pwd
+    type -t _mark_complete
+' _ "$MARK_BINARY" 2>/dev/null)
+if echo "$LAZY_OUTPUT" | head -1 | grep -q "no-completion-yet" && echo "$LAZY_OUTPUT" | grep -q "zproject" && echo "$LAZY_OUTPUT" | tail -1 | grep -q "function"; then
+    test_pass "Stubs loaded full integration on first jump"
+else
+    test_fail "Lazy init did not load as expected (got: $LAZY_OUTPUT)"
+fi
+
+# Test 26: ssh opens a session in the mapped remote path
+run_test "ssh uses bookmark host and path mapping"
+FAKE_BIN="$HOME/fake-bin"
+mkdir -p "$FAKE_BIN"
+printf '#!/bin/sh\necho "ssh $*"\n' > "$FAKE_BIN/ssh"
+chmod +x "$FAKE_BIN/ssh"
+echo "ssh_path_map=$HOME=/home/remote" >> "$HOME/.mark"
+"$MARK_BINARY" remotework "$ZDIR" --host dev1 >/dev/null 2>&1
+SSH_OUTPUT=$(PATH="$FAKE_BIN:$PATH" "$MARK_BINARY" ssh remotework 2>&1)
+if echo "$SSH_OUTPUT" | grep -q "ssh -t dev1 cd '/home/remote/zproject'"; then
+    test_pass "ssh called with host and remapped path"
+else
+    test_fail "ssh not invoked as expected (got: $SSH_OUTPUT)"
+fi
+
+# Test 27: Jump and exec map paths into a container
+run_test "Jump and exec use container path mapping"
+printf '#!/bin/sh\necho "docker $*"\n' > "$FAKE_BIN/docker"
+chmod +x "$FAKE_BIN/docker"
+echo "container_path_map=$HOME=/workspaces" >> "$HOME/.mark"
+JUMP_OUTPUT=$("$MARK_BINARY" -j zproject --in-container devbox 2>/dev/null)
+EXEC_OUTPUT=$(echo | PATH="$FAKE_BIN:$PATH" "$MARK_BINARY" exec zproject --in-container devbox -- make test 2>&1)
+if [ "$JUMP_OUTPUT" = "/workspaces/zproject" ] && echo "$EXEC_OUTPUT" | grep -q "docker exec -i -w /workspaces/zproject devbox make test"; then
+    test_pass "Container paths used for jump and exec"
+else
+    test_fail "Container mapping not applied (jump: $JUMP_OUTPUT, exec: $EXEC_OUTPUT)"
+fi
+
+# Test 28: Import ranger bookmarks with descriptive names
+run_test "Import ranger bookmarks with renaming prompt"
+RANGER_DIR="$HOME/rangerdir"
+mkdir -p "$RANGER_DIR"
+printf 'r:%s\n' "$RANGER_DIR" > "$HOME/ranger-bookmarks"
+echo "" | "$MARK_BINARY" import ranger --file "$HOME/ranger-bookmarks" >/dev/null 2>&1
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "rangerdir"; then
+    test_pass "Ranger bookmark imported under its directory name"
+else
+    test_fail "Ranger bookmark not imported"
+fi
+
+# Test 29: Monthly report summarizes local usage
+run_test "Report summarizes jumps and changes"
+REPORT_JSON=$("$MARK_BINARY" report --json 2>/dev/null)
+if echo "$REPORT_JSON" | grep -q '"jumps": [1-9]' && echo "$REPORT_JSON" | grep -q '"created": [1-9]'; then
+    test_pass "Report counts jumps and created bookmarks"
+else
+    test_fail "Report missing counts (got: $REPORT_JSON)"
+fi
+
+# Test 30: Import Midnight Commander hotlist with groups as tags
+run_test "Import mc hotlist with groups as tags"
+MC_DIR="$HOME/mcdir"
+mkdir -p "$MC_DIR"
+printf 'GROUP "infra"\n  ENTRY "mcdir" URL "%s"\nENDGROUP\n' "$MC_DIR" > "$HOME/hotlist"
+"$MARK_BINARY" import mc --file "$HOME/hotlist" >/dev/null 2>&1
+if "$MARK_BINARY" -l --tag infra 2>/dev/null | grep -q "mcdir"; then
+    test_pass "mc entry imported with group tag"
+else
+    test_fail "mc hotlist import failed"
+fi
+
+# Test 31: file:// URI targets are decoded
+run_test "Create bookmark from a file:// URI"
+URI_DIR="$HOME/Dropped Folder"
+mkdir -p "$URI_DIR"
+"$MARK_BINARY" dropped "file://$HOME/Dropped%20Folder/" >/dev/null 2>&1
+if [ "$("$MARK_BINARY" -j dropped 2>/dev/null)" = "$URI_DIR" ]; then
+    test_pass "URI target decoded to the directory"
+else
+    test_fail "URI target not decoded"
+fi
+
+# Test 32: Suggest bookmarks from shell history
+run_test "Suggest bookmarks from cd history"
+HIST_DIR="$HOME/histdir"
+mkdir -p "$HIST_DIR"
+printf 'cd %s\ncd %s\nls\n' "$HIST_DIR" "$HIST_DIR" > "$HOME/.bash_history"
+echo "y" | HISTFILE="$HOME/.bash_history" "$MARK_BINARY" suggest --from-history --limit 1 >/dev/null 2>&1
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "histdir"; then
+    test_pass "Frequently visited directory bookmarked"
+else
+    test_fail "suggest did not create the bookmark"
+fi
+
+# Test 33: Export bookmarks as CSV to a file
+run_test "Export bookmarks as CSV"
+"$MARK_BINARY" export --format csv "$HOME/marks.csv" >/dev/null 2>&1
+if head -1 "$HOME/marks.csv" 2>/dev/null | grep -q "^name,target,tags,description,host,fallbacks,os_targets,only_on$" && grep -q "^tagged,.*,\"client,billing\"" "$HOME/marks.csv"; then
+    test_pass "CSV export written with tags"
+else
+    test_fail "CSV export missing or incomplete"
+fi
+
+# Test 34: --shell overrides an unrecognized $SHELL
+run_test "Force shell family with --shell"
+if SHELL=/usr/local/bin/myshell-wrapper "$MARK_BINARY" init --shell bash 2>/dev/null | grep -q "_mark_complete"; then
+    test_pass "init generated bash integration despite unknown \$SHELL"
+else
+    test_fail "--shell did not override detection"
+fi
+
+# Test 35: Diff against an exported manifest
+run_test "Diff bookmarks against a manifest"
+"$MARK_BINARY" export "$HOME/manifest.json" >/dev/null 2>&1
+"$MARK_BINARY" diffonly "$HOME" >/dev/null 2>&1
+DIFF_STATUS=0
+DIFF_OUTPUT=$("$MARK_BINARY" diff "$HOME/manifest.json" 2>&1) || DIFF_STATUS=$?
+if [ $DIFF_STATUS -eq 1 ] && echo "$DIFF_OUTPUT" | grep -q "^- diffonly"; then
+    test_pass "Local-only bookmark reported"
+else
+    test_fail "diff output unexpected (status $DIFF_STATUS): $DIFF_OUTPUT"
+fi
+
+# Test 36: Export as a script that re-creates the bookmarks
+run_test "Export bookmarks as a shell script"
+"$MARK_BINARY" export --format script "$HOME/marks.sh" >/dev/null 2>&1
+"$MARK_BINARY" -d diffonly >/dev/null 2>&1
+PATH="$(dirname "$MARK_BINARY"):$PATH" "$HOME/marks.sh" >/dev/null 2>&1 || true
+if [ -x "$HOME/marks.sh" ] && "$MARK_BINARY" -l 2>/dev/null | grep -q "diffonly"; then
+    test_pass "Running the exported script restored the bookmark"
+else
+    test_fail "exported script missing or did not re-create bookmarks"
+fi
+
+# Test 37: Jump wrapper falls back to plain directories
+run_test "Jump falls back to a plain directory"
+PLAIN_DIR="$HOME/plain dir"
+mkdir -p "$PLAIN_DIR"
+if [ "$("$MARK_BINARY" --dir-fallback -j "$PLAIN_DIR" 2>/dev/null)" = "$PLAIN_DIR" ] && ! "$MARK_BINARY" -j "$PLAIN_DIR" >/dev/null 2>&1; then
+    test_pass "--dir-fallback accepted an existing directory"
+else
+    test_fail "--dir-fallback did not print the directory"
+fi
+
+# Test 38: Pinned bookmarks sort first with --sort
+run_test "Sort listing by pinned, then name"
+"$MARK_BINARY" zzpinned "$HOME" --pin >/dev/null 2>&1
+FIRST_MARK=$("$MARK_BINARY" -l --sort=pinned,name 2>/dev/null | awk 'NR == 1 {print $1}')
+if [ "$FIRST_MARK" = "zzpinned" ]; then
+    test_pass "Pinned bookmark listed first"
+else
+    test_fail "Expected zzpinned first, got '$FIRST_MARK'"
+fi
+
+# Test 39: Profiles keep separate marks directories
+run_test "Profiles with separate marks directories"
+echo "profile=client=$HOME/.marks-client" >> "$HOME/.mark"
+"$MARK_BINARY" --profile client clientonly "$HOME" >/dev/null 2>&1
+if MARK_PROFILE=client "$MARK_BINARY" -l 2>/dev/null | grep -q "clientonly" && ! "$MARK_BINARY" -l 2>/dev/null | grep -q "clientonly"; then
+    test_pass "Profile bookmark only visible in its profile"
+else
+    test_fail "Profile bookmarks leaked or missing"
+fi
+
+# Test 40: --check-names renames names that break the shell integration
+run_test "Check names for shell safety"
+ln -s "$HOME" "$HOME/.marks/it's here"
+echo "y" | "$MARK_BINARY" --check-names >/dev/null 2>&1
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "it_s_here"; then
+    test_pass "Unsafe name renamed"
+else
+    test_fail "--check-names did not rename the unsafe bookmark"
+fi
+
+# Test 41: Project .marks file adds bookmarks inside its tree
+run_test "Project bookmarks from a .marks file"
+PROJECT_DIR="$HOME/project"
+mkdir -p "$PROJECT_DIR/src"
+echo "projsrc=src" > "$PROJECT_DIR/.marks"
+if [ "$(cd "$PROJECT_DIR" && "$MARK_BINARY" -j projsrc 2>/dev/null)" = "$PROJECT_DIR/src" ] && ! "$MARK_BINARY" -j projsrc >/dev/null 2>&1; then
+    test_pass "Project bookmark only available inside the project"
+else
+    test_fail "project bookmark not resolved as expected"
+fi
+
+# Test 42: Workspace rules switch profiles by directory
+run_test "Workspace rule selects a profile"
+mkdir -p "$HOME/clientwork"
+echo "workspace=$HOME/clientwork=client" >> "$HOME/.mark"
+if (cd "$HOME/clientwork" && "$MARK_BINARY" -l 2>/dev/null | grep -q "clientonly"); then
+    test_pass "Client profile used inside its workspace"
+else
+    test_fail "workspace rule did not select the profile"
+fi
+
+# Test 43: System-wide marks directory is merged read-only
+run_test "System-wide shared bookmarks"
+SYSTEM_MARKS="$HOME/system-marks"
+mkdir -p "$SYSTEM_MARKS"
+ln -s "$HOME" "$SYSTEM_MARKS/sharedlogs"
+echo "system_marks=$SYSTEM_MARKS" >> "$HOME/.mark"
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "sharedlogs" && ! "$MARK_BINARY" -d sharedlogs >/dev/null 2>&1 && [ -L "$SYSTEM_MARKS/sharedlogs" ]; then
+    test_pass "System bookmark listed and left untouched by -d"
+else
+    test_fail "system bookmark missing or modified"
+fi
+
+# Test 44: Team-shared source annotated and ranked by source_order
+run_test "Team-shared bookmark source"
+TEAM_MARKS="$HOME/team-marks"
+mkdir -p "$TEAM_MARKS" "$HOME/teamdir"
+ln -s "$HOME/teamdir" "$TEAM_MARKS/teamonly"
+echo "shared_marks=$TEAM_MARKS" >> "$HOME/.mark"
+if "$MARK_BINARY" -l 2>/dev/null | grep "teamonly" | grep -q "(shared)"; then
+    test_pass "Shared bookmark listed with (shared) annotation"
+else
+    test_fail "shared bookmark missing or not annotated"
+fi
+
+# Test 45: top prints a single dashboard frame
+run_test "Top dashboard single frame"
+"$MARK_BINARY" -j customloc >/dev/null 2>&1 || true
+if "$MARK_BINARY" top --once 2>/dev/null | grep -q "customloc"; then
+    test_pass "top --once rendered the dashboard"
+else
+    test_fail "top --once output unexpected"
+fi
+
+# Test 46: default_action=jump resolves existing names
+run_test "default_action=jump"
+echo "default_action=jump" >> "$HOME/.mark"
+JUMP_OUTPUT=$("$MARK_BINARY" customloc 2>/dev/null)
+"$MARK_BINARY" freshname "$HOME" >/dev/null 2>&1
+if [ "$JUMP_OUTPUT" = "$("$MARK_BINARY" -j customloc 2>/dev/null)" ] && "$MARK_BINARY" -l 2>/dev/null | grep -q "freshname"; then
+    test_pass "Existing name jumped, new name created"
+else
+    test_fail "default_action=jump misbehaved (got: $JUMP_OUTPUT)"
+fi
+
+# Test 47: Targets are normalized at creation and by tidy-targets
+run_test "Target normalization"
+(cd "$HOME/project/src" && "$MARK_BINARY" -- relparent .. >/dev/null 2>&1)
+ln -s "$HOME/project/" "$HOME/.marks/slashed"
+echo "y" | "$MARK_BINARY" tidy-targets >/dev/null 2>&1
+if [ "$(readlink "$HOME/.marks/relparent")" = "$HOME/project" ] && [ "$(readlink "$HOME/.marks/slashed")" = "$HOME/project" ]; then
+    test_pass "Relative and trailing-slash targets stored in clean absolute form"
+else
+    test_fail "targets not normalized: $(readlink "$HOME/.marks/relparent") $(readlink "$HOME/.marks/slashed")"
+fi
+
+# Test 48: on_broken modes for jumping to a missing target
+run_test "Broken bookmark handling"
+mkdir -p "$HOME/vanishing/deep" "$HOME/replacement"
+"$MARK_BINARY" vanishing "$HOME/vanishing/deep" >/dev/null 2>&1
+rmdir "$HOME/vanishing/deep"
+if ! "$MARK_BINARY" -j vanishing >/dev/null 2>&1 && \
+   [ "$("$MARK_BINARY" --on-broken ancestor -j vanishing 2>/dev/null)" = "$HOME/vanishing" ] && \
+   [ "$(echo "$HOME/replacement" | "$MARK_BINARY" --on-broken repair -j vanishing 2>/dev/null)" = "$HOME/replacement" ] && \
+   [ "$(readlink "$HOME/.marks/vanishing")" = "$HOME/replacement" ]; then
+    test_pass "fail, ancestor and repair modes behave as configured"
+else
+    test_fail "on_broken modes did not behave as expected"
+fi
+
+# Test 49: --handoff leaves the jump target in the handoff file
+run_test "Jump handoff file"
+export XDG_RUNTIME_DIR="$HOME/runtime"
+handoff_out=$("$MARK_BINARY" -j vanishing --handoff 2>/dev/null)
+if [ "$handoff_out" = "$HOME/replacement" ] && [ "$(cat "$XDG_RUNTIME_DIR/mark/last-path")" = "$HOME/replacement" ]; then
+    test_pass "Target printed and written to \$XDG_RUNTIME_DIR/mark/last-path"
+else
+    test_fail "handoff file not written: $handoff_out"
+fi
+unset XDG_RUNTIME_DIR
+
+# Test 50: tutorial runs every step in a sandbox
+run_test "Tutorial sandbox"
+before_tutorial=$(ls "$HOME/.marks" | sort)
+tutorial_out=$(printf '\n\n\n\n\n' | "$MARK_BINARY" tutorial 2>&1)
+if echo "$tutorial_out" | grep -q "Removed bookmark 'demo'" && \
+   echo "$tutorial_out" | grep -q "cd ~/docs" && \
+   [ "$(ls "$HOME/.marks" | sort)" = "$before_tutorial" ]; then
+    test_pass "All steps ran without touching the real marks directory"
+else
+    test_fail "tutorial output: $tutorial_out"
+fi
+
+# Test 51: init works on a fresh machine without running setup
+run_test "init before first-time setup"
+FRESH_HOME="$HOME/fresh-home"
+mkdir -p "$FRESH_HOME"
+init_out=$(HOME="$FRESH_HOME" "$MARK_BINARY" init bash </dev/null 2>/dev/null)
+if echo "$init_out" | grep -q "jump()" && [ ! -e "$FRESH_HOME/.mark" ] && \
+   HOME="$FRESH_HOME" bash --norc --noprofile -c 'eval "$("$1" init bash)" && type -t jump' _ "$MARK_BINARY" </dev/null 2>/dev/null | grep -q "function"; then
+    test_pass "Integration printed for eval without prompting or writing config"
+else
+    test_fail "init on a fresh machine did not behave (got: $(echo "$init_out" | head -3))"
+fi
+
+# Test 52: MARK_CONFIG and MARK_DIR override the config per invocation
+run_test "Environment overrides"
+ENV_DIR="$HOME/env-marks"
+MARK_DIR="$ENV_DIR" "$MARK_BINARY" envmark "$HOME" >/dev/null 2>&1
+printf 'marksdir=%s\n' "$HOME/alt-marks" > "$HOME/alt.conf"
+MARK_CONFIG="$HOME/alt.conf" "$MARK_BINARY" altmark "$HOME" >/dev/null 2>&1
+if [ -L "$ENV_DIR/envmark" ] && [ -L "$HOME/alt-marks/altmark" ] && \
+   ! "$MARK_BINARY" -l 2>/dev/null | grep -q "envmark\|altmark"; then
+    test_pass "Bookmarks went to the overridden directories only"
+else
+    test_fail "MARK_DIR/MARK_CONFIG not honored"
+fi
+
+# Test 53: config get/set/list edit ~/.mark in place
+run_test "config get/set/list"
+echo "# keep this comment" >> "$HOME/.mark"
+"$MARK_BINARY" config set on_broken ancestor >/dev/null 2>&1
+if [ "$("$MARK_BINARY" config get on_broken 2>/dev/null)" = "ancestor" ] && \
+   "$MARK_BINARY" config list 2>/dev/null | grep -q "^marksdir=" && \
+   grep -q "# keep this comment" "$HOME/.mark" && \
+   ! "$MARK_BINARY" config set on_broken sometimes >/dev/null 2>&1 && \
+   ! "$MARK_BINARY" config get no_such_key >/dev/null 2>&1; then
+    test_pass "Settings read and written, comments kept, bad values rejected"
+else
+    test_fail "config subcommand did not behave as expected"
+fi
+"$MARK_BINARY" config set on_broken fail >/dev/null 2>&1
+
+# Test 54: Jumping to a subdirectory below a bookmark
+run_test "Subpath jump"
+mkdir -p "$HOME/subproj/src/app"
+"$MARK_BINARY" subproj "$HOME/subproj" >/dev/null 2>&1
+if [ "$("$MARK_BINARY" -j subproj/src/app 2>/dev/null)" = "$HOME/subproj/src/app" ] && \
+   [ "$("$MARK_BINARY" --complete-subpath subproj/s 2>/dev/null)" = "subproj/src/" ] && \
+   ! "$MARK_BINARY" -j subproj/missing >/dev/null 2>&1; then
+    test_pass "jump name/sub resolves below the bookmark and completes subdirectories"
+else
+    test_fail "subpath jump or completion failed"
+fi
+
+# Test 55: Deleting at a terminal asks first; -y skips the question
+run_test "Delete confirmation"
+"$MARK_BINARY" confirmme "$HOME" >/dev/null 2>&1
+"$MARK_BINARY" confirmyes "$HOME" >/dev/null 2>&1
+if script --version 2>/dev/null | grep -q util-linux; then
+    echo n | script -qec "'$MARK_BINARY' -d confirmme" /dev/null >/dev/null 2>&1
+    script -qec "'$MARK_BINARY' -d confirmyes -y" /dev/null </dev/null >/dev/null 2>&1
+    if [ -L "$HOME/.marks/confirmme" ] && [ ! -e "$HOME/.marks/confirmyes" ]; then
+        test_pass "Answering no kept the bookmark, -y deleted without asking"
+    else
+        test_fail "confirmation prompt not honored"
+    fi
+else
+    test_pass "Skipped (needs util-linux script for a pseudo-terminal)"
+fi
+"$MARK_BINARY" -d confirmme </dev/null >/dev/null 2>&1
+
+# Test 56: Deleted bookmarks go to the trash and can be restored
+run_test "Trash and restore"
+"$MARK_BINARY" trashme "$HOME" --tag keep >/dev/null 2>&1
+"$MARK_BINARY" -d trashme </dev/null >/dev/null 2>&1
+if [ ! -e "$HOME/.marks/trashme" ] && \
+   "$MARK_BINARY" trash list 2>/dev/null | grep -q "trashme" && \
+   "$MARK_BINARY" restore trashme >/dev/null 2>&1 && \
+   [ "$(readlink "$HOME/.marks/trashme")" = "$HOME" ] && \
+   "$MARK_BINARY" -l --tag keep 2>/dev/null | grep -q "trashme" && \
+   ! "$MARK_BINARY" restore trashme >/dev/null 2>&1; then
+    test_pass "Deleted bookmark listed in the trash and restored with its tags"
+else
+    test_fail "trash or restore failed"
+fi
+"$MARK_BINARY" -d trashme </dev/null >/dev/null 2>&1
+"$MARK_BINARY" trash empty >/dev/null 2>&1
+
+# Test 57: --dry-run reports changes without making them
+run_test "Dry run"
+"$MARK_BINARY" keepme "$HOME" >/dev/null 2>&1
+if "$MARK_BINARY" drynew "$HOME" --dry-run 2>/dev/null | grep -q "Would create bookmark 'drynew'" && \
+   [ ! -e "$HOME/.marks/drynew" ] && \
+   "$MARK_BINARY" -d keepme --dry-run 2>/dev/null | grep -q "Would remove bookmark 'keepme'" && \
+   [ -L "$HOME/.marks/keepme" ]; then
+    test_pass "Create and delete with --dry-run left the bookmarks alone"
+else
+    test_fail "--dry-run changed bookmarks or printed nothing"
+fi
+"$MARK_BINARY" -d keepme </dev/null >/dev/null 2>&1
+
+# Test 58: Long listing shows creation date, status and tags
+run_test "Long listing columns"
+mkdir -p "$HOME/longgone"
+"$MARK_BINARY" longtagged "$HOME" --tag alpha,beta >/dev/null 2>&1
+"$MARK_BINARY" longbroken "$HOME/longgone" >/dev/null 2>&1
+rmdir "$HOME/longgone"
+long_out=$("$MARK_BINARY" -l --long 2>/dev/null)
+today=$(date +%Y-%m-%d)
+if echo "$long_out" | grep -q "CREATED.*STATUS.*TAGS" && \
+   echo "$long_out" | grep -E "^ +longtagged " | grep -q "$today.*ok.*alpha,beta" && \
+   echo "$long_out" | grep -E "^ +longbroken " | grep -q "broken"; then
+    test_pass "Long listing shows created, status and tags columns"
+else
+    test_fail "Long listing columns missing: $long_out"
+fi
+"$MARK_BINARY" -d longtagged </dev/null >/dev/null 2>&1
+"$MARK_BINARY" -d longbroken </dev/null >/dev/null 2>&1
+
+# Test 59: Tree view groups bookmarks under shared path prefixes
+run_test "Tree listing"
+mkdir -p "$HOME/treeproj/one" "$HOME/treeproj/two"
+"$MARK_BINARY" treeone "$HOME/treeproj/one" >/dev/null 2>&1
+"$MARK_BINARY" treetwo "$HOME/treeproj/two" >/dev/null 2>&1
+tree_out=$("$MARK_BINARY" -l --tree 2>/dev/null)
+if echo "$tree_out" | grep -q "treeproj$" && \
+   echo "$tree_out" | grep -q "── one  \[treeone\]" && \
+   echo "$tree_out" | grep -q "── two  \[treetwo\]"; then
+    test_pass "Bookmarks below one directory share a subtree"
+else
+    test_fail "Tree listing not grouped: $tree_out"
+fi
+"$MARK_BINARY" -d treeone </dev/null >/dev/null 2>&1
+"$MARK_BINARY" -d treetwo </dev/null >/dev/null 2>&1
+
+# Test 60: show prints one bookmark's details
+run_test "Show bookmark"
+"$MARK_BINARY" showme "$HOME" --tag inspect --desc "home dir" >/dev/null 2>&1
+"$MARK_BINARY" -j showme >/dev/null 2>&1
+show_out=$("$MARK_BINARY" show showme 2>/dev/null)
+if echo "$show_out" | grep -q "^Status: *ok" && \
+   echo "$show_out" | grep -q "^Tags: *inspect" && \
+   echo "$show_out" | grep -q "^Description: *home dir" && \
+   echo "$show_out" | grep -q "^Uses: *1" && \
+   ! "$MARK_BINARY" show no-such-mark >/dev/null 2>&1; then
+    test_pass "show lists status, metadata and usage"
+else
+    test_fail "show output incomplete: $show_out"
+fi
+"$MARK_BINARY" -d showme </dev/null >/dev/null 2>&1
+
+# Test 61: --raw keeps a symlinked target instead of resolving it
+run_test "Raw jump target"
+mkdir -p "$HOME/rawreal"
+ln -sfn "$HOME/rawreal" "$HOME/rawlink"
+"$MARK_BINARY" rawmark "$HOME/rawlink" --raw >/dev/null 2>&1
+if [ "$("$MARK_BINARY" -j rawmark --raw 2>/dev/null)" = "$HOME/rawlink" ] && \
+   [ "$("$MARK_BINARY" -j rawmark 2>/dev/null)" = "$(cd "$HOME/rawreal" && pwd -P)" ]; then
+    test_pass "--raw prints the symlink, plain -j its resolution"
+else
+    test_fail "--raw did not keep the symlinked target"
+fi
+"$MARK_BINARY" -d rawmark </dev/null >/dev/null 2>&1
+
+# Test 62: edit-dir opens the bookmark directory in $VISUAL
+run_test "Open bookmark in editor"
+"$MARK_BINARY" editme "$HOME" >/dev/null 2>&1
+edit_out=$(VISUAL="echo opened" "$MARK_BINARY" edit-dir editme 2>/dev/null)
+if [ "$edit_out" = "opened $HOME" ] && \
+   ! VISUAL= EDITOR= "$MARK_BINARY" edit-dir editme >/dev/null 2>&1; then
+    test_pass "edit-dir launched \$VISUAL with the bookmark directory"
+else
+    test_fail "edit-dir output: $edit_out"
+fi
+"$MARK_BINARY" -d editme </dev/null >/dev/null 2>&1
+
+# Test 63: shell starts $SHELL in the bookmark directory
+run_test "Subshell in bookmark"
+mkdir -p "$HOME/shelldir"
+"$MARK_BINARY" shellme "$HOME/shelldir" >/dev/null 2>&1
+shell_out=$(echo 'pwd -P' | SHELL=/bin/sh "$MARK_BINARY" shell shellme 2>/dev/null)
+if [ "$shell_out" = "$(cd "$HOME/shelldir" && pwd -P)" ]; then
+    test_pass "shell ran \$SHELL inside the bookmark target"
+else
+    test_fail "shell started in '$shell_out'"
+fi
+"$MARK_BINARY" -d shellme </dev/null >/dev/null 2>&1
+
+# Test 64: pre_jump/post_jump hooks run around the cd in the jump function
+run_test "Jump hooks"
+mkdir -p "$HOME/hookdir"
+touch "$HOME/hookdir/hooked-file"
+"$MARK_BINARY" hookme "$HOME/hookdir" >/dev/null 2>&1
+"$MARK_BINARY" config set post_jump ls >/dev/null 2>&1
+"$MARK_BINARY" config set pre_jump 'echo "to $target"' >/dev/null 2>&1
+hook_out=$(bash -c "eval \"\$('$MARK_BINARY' init bash)\"; jump hookme" 2>/dev/null)
+if echo "$hook_out" | grep -q "^to $HOME/hookdir$" && echo "$hook_out" | grep -q "^hooked-file$"; then
+    test_pass "jump ran pre_jump before and post_jump after the cd"
+else
+    test_fail "hook output: $hook_out"
+fi
+"$MARK_BINARY" config set pre_jump '' >/dev/null 2>&1
+"$MARK_BINARY" config set post_jump '' >/dev/null 2>&1
+"$MARK_BINARY" -d hookme </dev/null >/dev/null 2>&1
+
+# Test 65: URL bookmarks are listed distinctly, opened with open, refused by -j
+run_test "URL bookmarks"
+"$MARK_BINARY" godocs https://go.dev/doc >/dev/null 2>&1
+open_out=$(BROWSER=echo "$MARK_BINARY" open godocs 2>/dev/null)
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "godocs *-> \[url\] https://go.dev/doc" && \
+   [ "$open_out" = "https://go.dev/doc" ] && \
+   ! "$MARK_BINARY" -j godocs >/dev/null 2>&1 && \
+   "$MARK_BINARY" -j godocs 2>&1 | grep -q "mark open godocs"; then
+    test_pass "URL bookmark listed as [url], opened in \$BROWSER, rejected by -j"
+else
+    test_fail "URL bookmark handling failed (open printed '$open_out')"
+fi
+"$MARK_BINARY" -d godocs </dev/null >/dev/null 2>&1
+
+# Test 66: jump - returns to where the last jump started; history lists jumps
+run_test "Jump back and history"
+mkdir -p "$HOME/backfrom" "$HOME/backto"
+"$MARK_BINARY" backto "$HOME/backto" >/dev/null 2>&1
+back_out=$(cd "$HOME/backfrom" && "$MARK_BINARY" -j backto >/dev/null 2>&1 && cd "$HOME/backto" && "$MARK_BINARY" -j - 2>/dev/null)
+if [ "$back_out" = "$HOME/backfrom" ] && \
+   "$MARK_BINARY" history 2 2>/dev/null | head -1 | grep -q "$HOME/backfrom$" && \
+   "$MARK_BINARY" history 2 2>/dev/null | tail -1 | grep -q "$HOME/backto$"; then
+    test_pass "-j - went back and history lists both destinations"
+else
+    test_fail "jump back printed '$back_out'"
+fi
+"$MARK_BINARY" -d backto </dev/null >/dev/null 2>&1
+
+# Test 67: track_visits records cd destinations that suggest then offers
+run_test "Suggest from visited directories"
+mkdir -p "$HOME/visited"
+"$MARK_BINARY" config set track_visits on >/dev/null 2>&1
+bash -c "eval \"\$('$MARK_BINARY' init bash)\"; cd '$HOME/visited'; for i in 1 2 3; do __mark_record_visit; __mark_last_pwd=; done" >/dev/null 2>&1
+suggest_out=$(echo y | "$MARK_BINARY" suggest --limit 1 2>/dev/null)
+if echo "$suggest_out" | grep -q "Bookmark $HOME/visited (3 visits)" && [ -L "$HOME/.marks/visited" ]; then
+    test_pass "Visited directory suggested and bookmarked"
+else
+    test_fail "suggest output: $suggest_out"
+fi
+"$MARK_BINARY" config set track_visits off >/dev/null 2>&1
+"$MARK_BINARY" -d visited </dev/null >/dev/null 2>&1
+
+# Test 68: scan bookmarks project directories, renaming on collisions
+run_test "Scan for projects"
+mkdir -p "$HOME/scanroot/team/tool/.git" "$HOME/scanroot/other/tool/.git"
+scan_out=$("$MARK_BINARY" scan "$HOME/scanroot" --git-only --yes 2>/dev/null)
+if [ "$(readlink "$HOME/.marks/tool")" = "$HOME/scanroot/other/tool" ] && \
+   [ "$(readlink "$HOME/.marks/team-tool")" = "$HOME/scanroot/team/tool" ] && \
+   "$MARK_BINARY" scan "$HOME/scanroot" --yes 2>/dev/null | grep -q "No new project directories"; then
+    test_pass "scan created 'tool' and 'team-tool', and skips them on a rerun"
+else
+    test_fail "scan output: $scan_out"
+fi
+"$MARK_BINARY" -d tool </dev/null >/dev/null 2>&1
+"$MARK_BINARY" -d team-tool </dev/null >/dev/null 2>&1
+
+# Test 69: repair repoints a broken bookmark at its moved directory
+run_test "Repair moved target"
+mkdir -p "$HOME/repairroot/old/widget"
+"$MARK_BINARY" widget "$HOME/repairroot/old/widget" >/dev/null 2>&1
+mkdir -p "$HOME/repairroot/new"
+mv "$HOME/repairroot/old/widget" "$HOME/repairroot/new/widget"
+"$MARK_BINARY" config set repair_root "$HOME/repairroot" >/dev/null 2>&1
+repair_out=$("$MARK_BINARY" repair --yes 2>&1)
+if [ "$(readlink "$HOME/.marks/widget")" = "$HOME/repairroot/new/widget" ] && \
+   "$MARK_BINARY" repair widget 2>&1 | grep -q "not broken"; then
+    test_pass "repair found the moved directory and repointed 'widget'"
+else
+    test_fail "repair output: $repair_out"
+fi
+"$MARK_BINARY" config set repair_root '' >/dev/null 2>&1
+"$MARK_BINARY" -d widget </dev/null >/dev/null 2>&1
+
+# Test 70: link_style stores targets relative to the marks directory or home
+run_test "Relative link styles"
+mkdir -p "$HOME/linkstyle"
+"$MARK_BINARY" --link-style relative lsrel "$HOME/linkstyle" >/dev/null 2>&1
+"$MARK_BINARY" --link-style home lshome "$HOME/linkstyle" >/dev/null 2>&1
+if [ "$(readlink "$HOME/.marks/lsrel")" = "../linkstyle" ] && \
+   [ "$(readlink "$HOME/.marks/lshome")" = "../linkstyle" ] && [ -d "$HOME/.marks/lshome/" ] && \
+   [ "$("$MARK_BINARY" -j lsrel 2>/dev/null)" = "$HOME/linkstyle" ] && \
+   [ "$("$MARK_BINARY" -j lshome 2>/dev/null)" = "$HOME/linkstyle" ]; then
+    test_pass "relative and home targets are stored as such and still jump"
+else
+    test_fail "lsrel -> $(readlink "$HOME/.marks/lsrel"), lshome -> $(readlink "$HOME/.marks/lshome")"
+fi
+"$MARK_BINARY" -d lsrel </dev/null >/dev/null 2>&1
+"$MARK_BINARY" -d lshome </dev/null >/dev/null 2>&1
+
+# Test 71: dedupe keeps one name and turns the others into aliases
+run_test "Dedupe into aliases"
+mkdir -p "$HOME/dupdir"
+export MARK_DIR="$HOME/dedupe-marks"
+"$MARK_BINARY" dupa "$HOME/dupdir" >/dev/null 2>&1
+"$MARK_BINARY" dupb "$HOME/dupdir/" >/dev/null 2>&1
+dedupe_out=$(printf '1\ny\n' | "$MARK_BINARY" dedupe 2>&1)
+if [ "$(readlink "$MARK_DIR/dupb")" = "dupa" ] && \
+   [ "$("$MARK_BINARY" -j dupb 2>/dev/null)" = "$HOME/dupdir" ] && \
+   "$MARK_BINARY" dedupe 2>&1 | grep -q "No duplicate bookmarks"; then
+    test_pass "dedupe kept 'dupa' and made 'dupb' an alias that still jumps"
+else
+    test_fail "dedupe output: $dedupe_out"
+fi
+unset MARK_DIR
+
+# Test 72: name_policy validation applies when creating and importing
+run_test "Name policy validation"
+mkdir -p "$HOME/policy dir"
+"$MARK_BINARY" config set name_policy "lowercase,spaces=dash,max_length=10" >/dev/null 2>&1
+"$MARK_BINARY" "Policy Dir" "$HOME/policy dir" >/dev/null 2>&1
+policy_out=$("$MARK_BINARY" waytoolongname "$HOME/policy dir" 2>&1 || true)
+printf '%s\n' "$HOME/policy dir|10|1700000000" > "$HOME/policy.z"
+import_out=$("$MARK_BINARY" import z --file "$HOME/policy.z" 2>&1 || true)
+if "$MARK_BINARY" -l 2>/dev/null | grep -q "policy-dir" && \
+   echo "$policy_out" | grep -q "longer than 10" && \
+   echo "$import_out" | grep -q "bookmark already exists"; then
+    test_pass "names coerced to 'policy-dir' on create and import, long name refused"
+else
+    test_fail "create: $policy_out; import: $import_out"
+fi
+"$MARK_BINARY" config set name_policy "lowercase,kebab" >/dev/null 2>&1
+"$MARK_BINARY" -d policy-dir </dev/null >/dev/null 2>&1
+
+# Test 73: config and metadata writes keep the previous version as .bak
+run_test "Config and metadata backups"
+"$MARK_BINARY" config set sort name >/dev/null 2>&1
+"$MARK_BINARY" config set sort uses >/dev/null 2>&1
+"$MARK_BINARY" --tag one bakmark "$HOME" >/dev/null 2>&1
+"$MARK_BINARY" -d bakmark </dev/null >/dev/null 2>&1
+echo '{"broken' > "$HOME/.marks/.metadata.json"
+meta_err=$("$MARK_BINARY" -l 2>&1 || true)
+if grep -q "^sort=name" "$HOME/.mark.bak" && grep -q "^sort=uses" "$HOME/.mark" && \
+   grep -q '"bakmark"' "$HOME/.marks/.metadata.json.bak" && \
+   echo "$meta_err" | grep -q "metadata.json.bak"; then
+    test_pass "previous config and metadata kept, damaged metadata error points at the backup"
+else
+    test_fail "metadata error: $meta_err"
+fi
+cp "$HOME/.marks/.metadata.json.bak" "$HOME/.marks/.metadata.json"
+"$MARK_BINARY" config set sort '' >/dev/null 2>&1
+
+# Test 74: failures exit with a status that tells their kind apart
+run_test "Distinct exit codes"
+mkdir -p "$HOME/exitdir"
+"$MARK_BINARY" exitmark "$HOME/exitdir" >/dev/null 2>&1
+rmdir "$HOME/exitdir"
+codes=""
+for cmd in "-j no-such-mark" "-j exitmark" "exitmark $HOME" "--sort bogus -l"; do
+    status=0
+    "$MARK_BINARY" $cmd </dev/null >/dev/null 2>&1 || status=$?
+    codes="$codes $status"
+done
+if [ "$codes" = " 2 3 4 5" ]; then
+    test_pass "not found, broken, conflict and config errors exit 2, 3, 4 and 5"
+else
+    test_fail "exit codes were$codes, want 2 3 4 5"
+fi
+"$MARK_BINARY" -d exitmark </dev/null >/dev/null 2>&1
+
+# Test 75: --verbose and MARK_DEBUG trace to stderr only
+run_test "Verbose tracing"
+mkdir -p "$HOME/tracedir"
+create_trace=$("$MARK_BINARY" --verbose tracemark "$HOME/tracedir" 2>&1 >/dev/null)
+jump_out=$(MARK_DEBUG=1 "$MARK_BINARY" -j tracemark 2>/dev/null)
+jump_trace=$(MARK_DEBUG=1 "$MARK_BINARY" -j tracemark 2>&1 >/dev/null)
+if echo "$create_trace" | grep -q "^mark: config file " && \
+   echo "$create_trace" | grep -q "^mark: symlink .*tracemark -> " && \
+   echo "$jump_trace" | grep -q "^mark: bookmark tracemark: stored target" && \
+   [ "$jump_out" = "$HOME/tracedir" ]; then
+    test_pass "traces cover config, symlinks and evaluation without touching stdout"
+else
+    test_fail "create trace: $create_trace; jump trace: $jump_trace; jump output: $jump_out"
+fi
+"$MARK_BINARY" -d tracemark </dev/null >/dev/null 2>&1
+
+# Test 76: audit_log=on records operations with who ran them
+run_test "Audit log"
+mkdir -p "$HOME/auditdir"
+"$MARK_BINARY" config set audit_log on >/dev/null 2>&1
+"$MARK_BINARY" auditmark "$HOME/auditdir" >/dev/null 2>&1
+"$MARK_BINARY" -j auditmark >/dev/null 2>&1
+"$MARK_BINARY" -d auditmark </dev/null >/dev/null 2>&1
+audit_log="$HOME/.local/state/mark/log"
+if [ -n "$XDG_STATE_HOME" ]; then
+    audit_log="$XDG_STATE_HOME/mark/log"
+fi
+audit_ops=$(cut -f3,4 "$audit_log" 2>/dev/null | grep auditmark | cut -f1 | tr '\n' ' ')
+if [ "$audit_ops" = "create jump delete " ]; then
+    test_pass "create, jump and delete appear in the audit log"
+else
+    test_fail "audit log operations: '$audit_ops'"
+fi
+"$MARK_BINARY" config set audit_log off >/dev/null 2>&1
+
+# Test 77: stats counts bookmarks and ranks them by jumps
+run_test "Stats"
+export MARK_DIR="$HOME/statsmarks"
+mkdir -p "$HOME/statsused" "$HOME/statsidle" "$HOME/statsgone"
+"$MARK_BINARY" statsused "$HOME/statsused" >/dev/null 2>&1
+"$MARK_BINARY" statsidle "$HOME/statsidle" >/dev/null 2>&1
+"$MARK_BINARY" statsgone "$HOME/statsgone" >/dev/null 2>&1
+rmdir "$HOME/statsgone"
+"$MARK_BINARY" -j statsused >/dev/null 2>&1
+stats_output=$("$MARK_BINARY" stats 2>&1 || true)
+if echo "$stats_output" | grep -q "(1 broken" && \
+   echo "$stats_output" | grep -A1 "Most used:" | grep -q "statsused"; then
+    test_pass "stats reports totals, broken count and most used"
+else
+    test_fail "stats output: $stats_output"
+fi
+unset MARK_DIR
+
+# Test 78: completion install --system writes vendor files below $DESTDIR
+run_test "Completion install"
+completion_stage="$HOME/completion-stage"
+DESTDIR="$completion_stage" "$MARK_BINARY" completion install --system >/dev/null 2>&1 || true
+if [ -f "$completion_stage/usr/share/bash-completion/completions/mark" ] && \
+   head -1 "$completion_stage/usr/share/zsh/site-functions/_mark" 2>/dev/null | grep -q "^#compdef mark" && \
+   grep -q "complete -c mark" "$completion_stage/usr/share/fish/vendor_completions.d/mark.fish" 2>/dev/null; then
+    test_pass "bash, zsh and fish completion files installed"
+else
+    test_fail "completion files missing below $completion_stage"
+fi
+
+# Test 79: unknown subcommands run mark-<name> plugins from PATH
+run_test "Plugin subcommands"
+mkdir -p "$HOME/plugin-bin"
+cat > "$HOME/plugin-bin/mark-hello" <<'PLUGIN'
+#!/bin/sh
+echo "dir=$MARK_DIR args=$*"
+exit 7
+PLUGIN
+chmod +x "$HOME/plugin-bin/mark-hello"
+plugin_status=0
+plugin_output=$(PATH="$HOME/plugin-bin:$PATH" MARK_DIR="$HOME/pluginmarks" "$MARK_BINARY" hello -l --x 2>&1) || plugin_status=$?
+if [ "$plugin_status" -eq 7 ] && echo "$plugin_output" | grep -q "args=-l --x" && \
+   echo "$plugin_output" | grep -q "dir=.*pluginmarks" && [ ! -e "$HOME/pluginmarks/hello" ]; then
+    test_pass "plugin ran with its arguments, MARK_DIR and exit status"
+else
+    test_fail "plugin output: $plugin_output (status $plugin_status)"
+fi
+
+# Test 80: serve answers JSON requests for bookmarks
+run_test "HTTP API"
+if command -v curl >/dev/null 2>&1; then
+    mkdir -p "$HOME/servedir"
+    "$MARK_BINARY" servemark "$HOME/servedir" >/dev/null 2>&1
+    "$MARK_BINARY" serve --listen 127.0.0.1:17457 >/dev/null 2>&1 &
+    serve_pid=$!
+    serve_output=""
+    for _ in 1 2 3 4 5 6 7 8 9 10; do
+        serve_output=$(curl -s http://127.0.0.1:17457/bookmarks/servemark 2>/dev/null || true)
+        [ -n "$serve_output" ] && break
+        sleep 0.2
+    done
+    kill "$serve_pid" 2>/dev/null || true
+    wait "$serve_pid" 2>/dev/null || true
+    if echo "$serve_output" | grep -q '"path":".*servedir"'; then
+        test_pass "GET /bookmarks/<name> returns the bookmark"
+    else
+        test_fail "serve output: $serve_output"
+    fi
+else
+    test_pass "curl not installed, skipped"
+fi
+
+# Test 81: tui prints the bookmark found by search and chosen with Enter
+run_test "TUI dashboard"
+if command -v script >/dev/null 2>&1 && script -qec true /dev/null >/dev/null 2>&1; then
+    export MARK_DIR="$HOME/tuimarks"
+    mkdir -p "$HOME/tuione" "$HOME/tuitwo"
+    "$MARK_BINARY" tuione "$HOME/tuione" >/dev/null 2>&1
+    "$MARK_BINARY" tuitwo "$HOME/tuitwo" >/dev/null 2>&1
+    (sleep 0.5; printf '/tuitwo\r'; sleep 0.3; printf '\r') | script -qec "'$MARK_BINARY' tui > '$HOME/tui-choice'" /dev/null >/dev/null 2>&1 || true
+    unset MARK_DIR
+    if grep -q "tuitwo$" "$HOME/tui-choice" 2>/dev/null; then
+        test_pass "Enter printed the selected bookmark's path"
+    else
+        test_fail "tui printed: $(cat "$HOME/tui-choice" 2>/dev/null)"
+    fi
+else
+    test_pass "script(1) not available, skipped"
+fi
+
+# Test 82: menu runs dmenu and prints the chosen bookmark's path
+run_test "Menu selection"
+export MARK_DIR="$HOME/menumarks"
+mkdir -p "$HOME/menuone" "$HOME/menutwo" "$HOME/menu-bin"
+"$MARK_BINARY" menuone "$HOME/menuone" >/dev/null 2>&1
+"$MARK_BINARY" menutwo "$HOME/menutwo" >/dev/null 2>&1
+cat > "$HOME/menu-bin/dmenu" <<'MENU'
+#!/bin/sh
+grep '^menutwo '
+MENU
+chmod +x "$HOME/menu-bin/dmenu"
+menu_choice=$(PATH="$HOME/menu-bin:$PATH" "$MARK_BINARY" menu --dmenu 2>&1 || true)
+unset MARK_DIR
+if [ "$menu_choice" = "$(cd "$HOME/menutwo" && pwd -P)" ]; then
+    test_pass "dmenu choice printed as a path"
+else
+    test_fail "menu printed: $menu_choice"
+fi
+
+# Test 83: which finds the bookmarks covering a directory
+run_test "Which reverse lookup"
+export MARK_DIR="$HOME/whichmarks"
+mkdir -p "$HOME/whichroot/deep/er"
+"$MARK_BINARY" whichroot "$HOME/whichroot" >/dev/null 2>&1
+"$MARK_BINARY" whichdeep "$HOME/whichroot/deep" >/dev/null 2>&1
+which_all=$(cd "$HOME/whichroot/deep/er" && "$MARK_BINARY" which 2>&1 | head -2 || true)
+which_first=$("$MARK_BINARY" which --first "$HOME/whichroot/deep/er" 2>&1 || true)
+which_status=0
+"$MARK_BINARY" which / >/dev/null 2>&1 || which_status=$?
+unset MARK_DIR
+if [ "$which_all" = "$(printf 'whichdeep/er\nwhichroot/deep/er')" ] && [ "$which_first" = "whichdeep/er" ] && [ "$which_status" = "2" ]; then
+    test_pass "which prints covering bookmarks closest first"
+else
+    test_fail "which printed: $which_all / $which_first (status $which_status)"
+fi
+
+# Test 84: breadcrumb shortens the path through the nearest bookmark
+run_test "Breadcrumb"
+export MARK_DIR="$HOME/whichmarks"
+crumb=$(cd "$HOME/whichroot/deep/er" && "$MARK_BINARY" breadcrumb 2>&1 || true)
+crumb_home=$(cd "$HOME" && "$MARK_BINARY" breadcrumb 2>&1 || true)
+unset MARK_DIR
+if [ "$crumb" = "whichdeep:er" ] && [ "$crumb_home" = "~" ]; then
+    test_pass "breadcrumb prints name:rest and ~"
+else
+    test_fail "breadcrumb printed: $crumb / $crumb_home"
+fi
+
+# Test 85: init zsh --hash-dirs adds bookmarks as named directories
+run_test "Init zsh --hash-dirs"
+export MARK_DIR="$HOME/whichmarks"
+hash_output=$("$MARK_BINARY" init zsh --hash-dirs 2>&1 || true)
+hash_bash=0
+"$MARK_BINARY" init bash --hash-dirs >/dev/null 2>&1 || hash_bash=$?
+unset MARK_DIR
+if echo "$hash_output" | grep -qF "hash -d whichdeep='$HOME/whichroot/deep'" && echo "$hash_output" | grep -q "_mark_complete" && [ "$hash_bash" != "0" ]; then
+    test_pass "hash -d lines appended to the zsh integration"
+else
+    test_fail "init zsh --hash-dirs printed: $(echo "$hash_output" | grep 'hash -d')"
+fi
+
+# Test 86: cdpath lets plain cd reach bookmarks
+run_test "CDPATH from bookmarks"
+export MARK_DIR="$HOME/whichmarks"
+cdpath_value=$("$MARK_BINARY" cdpath 2>&1 || true)
+cdpath_parents=$("$MARK_BINARY" cdpath --parents 2>&1 || true)
+unset MARK_DIR
+cdpath_pwd=$(cd / && CDPATH="$cdpath_value" cd -P whichdeep >/dev/null && pwd)
+if [ "$cdpath_value" = ".:$HOME/whichmarks" ] && [ "$cdpath_pwd" = "$(cd "$HOME/whichroot/deep" && pwd -P)" ] && echo "$cdpath_parents" | grep -qF ":$HOME/whichroot"; then
+    test_pass "cd whichdeep works with CDPATH=$cdpath_value"
+else
+    test_fail "cdpath printed: $cdpath_value / $cdpath_parents (cd went to $cdpath_pwd)"
+fi
+
+# Test 87: edit applies renames, repoints, deletes and creates from the editor
+run_test "Bulk edit in \$EDITOR"
+export MARK_DIR="$HOME/editmarks"
+mkdir -p "$HOME/editone" "$HOME/edittwo" "$HOME/editthree" "$HOME/editnew"
+"$MARK_BINARY" editone "$HOME/editone" >/dev/null 2>&1
+"$MARK_BINARY" edittwo "$HOME/edittwo" >/dev/null 2>&1
+"$MARK_BINARY" editthree "$HOME/editthree" >/dev/null 2>&1
+cat > "$HOME/edit-script" <<EDIT
+#!/bin/sh
+sed -i -e 's/\teditone\t/\tfirst\t/' -e '/\tedittwo\t/d' -e 's|$HOME/editthree|$HOME/editnew|' "\$1"
+printf '+\tfresh\t%s\tnew\tAdded in the editor\n' "$HOME/editnew" >> "\$1"
+EDIT
+chmod +x "$HOME/edit-script"
+edit_output=$(EDITOR="$HOME/edit-script" VISUAL= "$MARK_BINARY" edit 2>&1 </dev/null || true)
+edit_list=$("$MARK_BINARY" -l 2>&1 || true)
+unset MARK_DIR
+if echo "$edit_list" | grep -q "^ *first " && ! echo "$edit_list" | grep -q "edittwo" && echo "$edit_list" | grep -q "editthree .*editnew" && echo "$edit_list" | grep -q "^ *fresh "; then
+    test_pass "edit renamed, deleted, repointed and created bookmarks"
+else
+    test_fail "edit output: $edit_output; list: $edit_list"
+fi
+
+# Test 88: creation time is stored in the metadata and shown
+run_test "Creation timestamps"
+export MARK_DIR="$HOME/createdmarks"
+mkdir -p "$HOME/createdone"
+"$MARK_BINARY" createdone "$HOME/createdone" >/dev/null 2>&1
+created_show=$("$MARK_BINARY" show createdone 2>&1 || true)
+created_long=$("$MARK_BINARY" --long -l 2>&1 || true)
+unset MARK_DIR
+if grep -q '"created"' "$HOME/createdmarks/.metadata.json" && echo "$created_show" | grep -q "^Created: .*(today)" && echo "$created_long" | grep -q "createdone .*$(date +%Y-%m-%d)"; then
+    test_pass "created-at stored and shown"
+else
+    test_fail "show: $created_show; long: $created_long"
+fi
+
+# Test 89: maintain previews and trashes bookmarks unused within max_age
+run_test "Retention policy"
+export MARK_DIR="$HOME/maintainmarks"
+mkdir -p "$HOME/maintainold" "$HOME/maintainnew"
+"$MARK_BINARY" maintainold "$HOME/maintainold" >/dev/null 2>&1
+"$MARK_BINARY" maintainnew "$HOME/maintainnew" >/dev/null 2>&1
+# Backdate both creation times; only the jump keeps maintainnew
+sed -i 's/"created": "[^"]*"/"created": "2020-01-01T00:00:00Z"/' "$HOME/maintainmarks/.metadata.json"
+"$MARK_BINARY" -j maintainnew >/dev/null 2>&1 || true
+maintain_preview=$("$MARK_BINARY" maintain --max-age 180d 2>&1 || true)
+maintain_delete=$("$MARK_BINARY" maintain --max-age 180d --delete --yes 2>&1 || true)
+maintain_list=$("$MARK_BINARY" -l 2>&1 || true)
+unset MARK_DIR
+if echo "$maintain_preview" | grep -q "maintainold" && ! echo "$maintain_preview" | grep -q "maintainnew" && ! echo "$maintain_list" | grep -q "maintainold" && echo "$maintain_list" | grep -q "maintainnew"; then
+    test_pass "stale bookmark listed, then trashed"
+else
+    test_fail "preview: $maintain_preview; delete: $maintain_delete; list: $maintain_list"
+fi
+
+# Test 90: -j falls back to the first existing fallback target
+run_test "Fallback targets"
+export MARK_DIR="$HOME/fallbackmarks"
+mkdir -p "$HOME/fbprimary" "$HOME/fbsecond"
+"$MARK_BINARY" fbdata "$HOME/fbprimary" "$HOME/fbmissing" "$HOME/fbsecond" >/dev/null 2>&1
+fb_before=$("$MARK_BINARY" -j fbdata 2>&1 || true)
+rmdir "$HOME/fbprimary"
+fb_after=$("$MARK_BINARY" -j fbdata 2>&1 || true)
+fb_list=$("$MARK_BINARY" -l 2>&1 || true)
+"$MARK_BINARY" fallback fbdata --clear >/dev/null 2>&1
+fb_cleared=0
+"$MARK_BINARY" -j fbdata >/dev/null 2>&1 || fb_cleared=$?
+unset MARK_DIR
+if [ "$fb_before" = "$HOME/fbprimary" ] && [ "$fb_after" = "$HOME/fbsecond" ] && ! echo "$fb_list" | grep -q "broken" && [ "$fb_cleared" != "0" ]; then
+    test_pass "jump used the fallback while the primary was missing"
+else
+    test_fail "before: $fb_before; after: $fb_after; list: $fb_list; cleared status $fb_cleared"
+fi
+
+# Test 91: --only-on hides bookmarks on other hosts
+run_test "Host-conditional bookmarks"
+export MARK_DIR="$HOME/hostmarks"
+mkdir -p "$HOME/hostlaptop"
+MARK_HOSTNAME=laptop "$MARK_BINARY" hostlaptop "$HOME/hostlaptop" --only-on "lap*" >/dev/null 2>&1
+host_laptop=$(MARK_HOSTNAME=laptop "$MARK_BINARY" -l 2>&1 || true)
+host_server=$(MARK_HOSTNAME=server "$MARK_BINARY" -l 2>&1 || true)
+host_jump=0
+MARK_HOSTNAME=server "$MARK_BINARY" -j hostlaptop >/dev/null 2>&1 || host_jump=$?
+unset MARK_DIR
+if echo "$host_laptop" | grep -q "hostlaptop" && ! echo "$host_server" | grep -q "hostlaptop" && [ "$host_jump" = "2" ]; then
+    test_pass "bookmark only listed and resolved on matching hosts"
+else
+    test_fail "laptop: $host_laptop; server: $host_server; jump status $host_jump"
+fi
+
+# Test 92: os-target overrides the stored target on this OS
+run_test "OS-conditional targets"
+export MARK_DIR="$HOME/osmarks"
+mkdir -p "$HOME/osdefault" "$HOME/oslinux"
+"$MARK_BINARY" osdl "$HOME/osdefault" >/dev/null 2>&1
+"$MARK_BINARY" os-target osdl "linux=$HOME/oslinux" "darwin=$HOME/osmac" "windows=C:\\dl" >/dev/null 2>&1
+os_jump=$("$MARK_BINARY" -j osdl 2>&1 || true)
+os_list=$("$MARK_BINARY" os-target osdl 2>&1 || true)
+unset MARK_DIR
+if [ "$(uname -s)" != "Linux" ]; then
+    test_pass "not Linux, skipped"
+elif [ "$os_jump" = "$HOME/oslinux" ] && echo "$os_list" | grep -q "linux .*(this system)"; then
+    test_pass "jump used the linux target"
+else
+    test_fail "jump: $os_jump; targets: $os_list"
+fi
+
+# Test 93: $VAR targets are stored literally and expanded at jump time
+run_test "Environment variables in targets"
+export MARK_DIR="$HOME/envmarks"
+mkdir -p "$HOME/envone/api" "$HOME/envtwo/api"
+MARK_TEST_PROJECTS="$HOME/envone" "$MARK_BINARY" envapi '$MARK_TEST_PROJECTS/api' >/dev/null 2>&1
+env_stored=$(readlink "$HOME/envmarks/envapi" 2>/dev/null || true)
+env_one=$(MARK_TEST_PROJECTS="$HOME/envone" "$MARK_BINARY" -j envapi 2>&1 || true)
+env_two=$(MARK_TEST_PROJECTS="$HOME/envtwo" "$MARK_BINARY" -j envapi 2>&1 || true)
+env_unset=0
+"$MARK_BINARY" -j envapi >/dev/null 2>&1 || env_unset=$?
+unset MARK_DIR
+if [ "$env_stored" = '$MARK_TEST_PROJECTS/api' ] && [ "$env_one" = "$HOME/envone/api" ] && [ "$env_two" = "$HOME/envtwo/api" ] && [ "$env_unset" != "0" ]; then
+    test_pass "target follows \$MARK_TEST_PROJECTS"
+else
+    test_fail "stored: $env_stored; one: $env_one; two: $env_two; unset status $env_unset"
+fi
+
+# Test 94: !command targets run at jump time only when dynamic_targets=on
+run_test "Dynamic command targets"
+export MARK_DIR="$HOME/dynmarks"
+mkdir -p "$HOME/dynbuilds/one" "$HOME/dynbuilds/two"
+"$MARK_BINARY" latest "!echo $HOME/dynbuilds/two" >/dev/null 2>&1
+"$MARK_BINARY" slow '!sleep 5; echo /' >/dev/null 2>&1
+dyn_off=0
+"$MARK_BINARY" -j latest >/dev/null 2>&1 || dyn_off=$?
+echo "dynamic_targets=on" >> "$HOME/.mark"
+echo "dynamic_timeout=1" >> "$HOME/.mark"
+dyn_on=$("$MARK_BINARY" -j latest 2>&1 || true)
+dyn_slow=0
+"$MARK_BINARY" -j slow >/dev/null 2>&1 || dyn_slow=$?
+dyn_list=$("$MARK_BINARY" -l 2>&1 || true)
+sed -i.bak '/^dynamic_/d' "$HOME/.mark" && rm -f "$HOME/.mark.bak"
+unset MARK_DIR
+if [ "$dyn_off" = "5" ] && [ "$dyn_on" = "$HOME/dynbuilds/two" ] && [ "$dyn_slow" != "0" ] && \
+   echo "$dyn_list" | grep -q "latest.*\[cmd\] !echo"; then
+    test_pass "command output used as the jump target"
+else
+    test_fail "off status $dyn_off; on: $dyn_on; slow status $dyn_slow; list: $dyn_list"
+fi
+
+# Test 95: glob targets resolve to the newest matching directory
+run_test "Glob targets"
+export MARK_DIR="$HOME/globmarks"
+mkdir -p "$HOME/globsnaps/snap-1" "$HOME/globsnaps/snap-2"
+touch -t 202001010000 "$HOME/globsnaps/snap-2"
+"$MARK_BINARY" snaps "$HOME/globsnaps/snap-*" >/dev/null 2>&1
+glob_stored=$(readlink "$HOME/globmarks/snaps" 2>/dev/null || true)
+glob_first=$("$MARK_BINARY" -j snaps 2>&1 || true)
+mkdir -p "$HOME/globsnaps/snap-3"
+glob_second=$("$MARK_BINARY" -j snaps 2>&1 || true)
+glob_none=0
+"$MARK_BINARY" nosnaps "$HOME/globsnaps/none-*" >/dev/null 2>&1 || glob_none=$?
+unset MARK_DIR
+if [ "$glob_stored" = "$HOME/globsnaps/snap-*" ] && [ "$glob_first" = "$HOME/globsnaps/snap-1" ] && \
+   [ "$glob_second" = "$HOME/globsnaps/snap-3" ] && [ "$glob_none" != "0" ]; then
+    test_pass "jump follows the newest snapshot"
+else
+    test_fail "stored: $glob_stored; first: $glob_first; second: $glob_second; no match status $glob_none"
+fi
+
+# Test 96: --git-root bookmarks the repository root from a subdirectory
+run_test "Bookmark the git repository root"
+export MARK_DIR="$HOME/gitmarks"
+mkdir -p "$HOME/gitrepo/src/api" "$HOME/notrepo"
+git init -q "$HOME/gitrepo" 2>/dev/null || true
+(cd "$HOME/gitrepo/src/api" && "$MARK_BINARY" --git-root >/dev/null 2>&1 || true)
+(cd "$HOME/gitrepo/src/api" && "$MARK_BINARY" --git-root repo >/dev/null 2>&1 || true)
+git_default=$(readlink "$HOME/gitmarks/gitrepo" 2>/dev/null || true)
+git_named=$(readlink "$HOME/gitmarks/repo" 2>/dev/null || true)
+git_outside=0
+(cd "$HOME/notrepo" && GIT_CEILING_DIRECTORIES="$HOME" "$MARK_BINARY" --git-root outside >/dev/null 2>&1) || git_outside=$?
+unset MARK_DIR
+if [ "$git_default" = "$HOME/gitrepo" ] && [ "$git_named" = "$HOME/gitrepo" ] && [ "$git_outside" != "0" ]; then
+    test_pass "root bookmarked by default and custom name"
+else
+    test_fail "default: $git_default; named: $git_named; outside status $git_outside"
+fi
+
+# Test 97: a taken name fails with a suggestion, --suffix picks name-N
+run_test "Name collisions"
+export MARK_DIR="$HOME/collidemarks"
+mkdir -p "$HOME/collide/one/api" "$HOME/collide/two/api"
+"$MARK_BINARY" api "$HOME/collide/one/api" >/dev/null 2>&1
+collide_status=0
+collide_out=$("$MARK_BINARY" api "$HOME/collide/two/api" 2>&1 < /dev/null) || collide_status=$?
+"$MARK_BINARY" api "$HOME/collide/two/api" --suffix >/dev/null 2>&1 < /dev/null
+collide_first=$(readlink "$HOME/collidemarks/api" 2>/dev/null || true)
+collide_suffixed=$(readlink "$HOME/collidemarks/api-2" 2>/dev/null || true)
+unset MARK_DIR
+if [ "$collide_status" = "4" ] && echo "$collide_out" | grep -q "two-api" && \
+   [ "$collide_first" = "$HOME/collide/one/api" ] && [ "$collide_suffixed" = "$HOME/collide/two/api" ]; then
+    test_pass "conflict suggested two-api, --suffix created api-2"
+else
+    test_fail "status $collide_status ($collide_out); api: $collide_first; api-2: $collide_suffixed"
+fi
+
+# Test 98: import --prefix namespaces names; jump and bash completion handle ':'
+run_test "Import with a name prefix"
+export MARK_DIR="$HOME/prefixmarks"
+mkdir -p "$HOME/prefixed/src"
+printf '%s|10|1700000000\n' "$HOME/prefixed/src" > "$HOME/prefix.z"
+"$MARK_BINARY" import z --file "$HOME/prefix.z" --prefix work: >/dev/null 2>&1
+prefix_jump=$("$MARK_BINARY" -j work:src 2>&1 || true)
+prefix_complete=$(PATH="$(dirname "$MARK_BINARY"):$PATH" bash --norc --noprofile -c '
+    eval "$("$1" completion bash)"
+    COMP_LINE="mark -j work:s" COMP_POINT=14 COMP_WORDS=(mark -j work : s) COMP_CWORD=4
+    _mark_complete
+    echo "${COMPREPLY[*]}"
+' _ "$MARK_BINARY" 2>/dev/null || true)
+unset MARK_DIR
+if [ "$prefix_jump" = "$HOME/prefixed/src" ] && [ "$prefix_complete" = "src" ]; then
+    test_pass "work:src imported, jumped to and completed"
+else
+    test_fail "jump: $prefix_jump; completion: $prefix_complete"
+fi
+
+# Test 99: merge folds another marks directory in, settling clashes by --strategy
+run_test "Merge marks directories"
+export MARK_DIR="$HOME/mergemine"
+mkdir -p "$HOME/mergetheirs" "$HOME/mergedirs/a" "$HOME/mergedirs/b" "$HOME/mergedirs/c"
+"$MARK_BINARY" same "$HOME/mergedirs/a" >/dev/null 2>&1
+"$MARK_BINARY" clash "$HOME/mergedirs/a" >/dev/null 2>&1
+ln -s "$HOME/mergedirs/a" "$HOME/mergetheirs/same"
+ln -s "$HOME/mergedirs/b" "$HOME/mergetheirs/clash"
+ln -s "$HOME/mergedirs/c" "$HOME/mergetheirs/extra"
+printf '{"extra":{"tags":["restored"]}}' > "$HOME/mergetheirs/.metadata.json"
+merge_dry=$("$MARK_BINARY" merge "$HOME/mergetheirs" --strategy rename --dry-run 2>&1 < /dev/null || true)
+merge_out=$("$MARK_BINARY" merge "$HOME/mergetheirs" --strategy rename 2>&1 < /dev/null || true)
+merge_clash=$(readlink "$HOME/mergemine/clash" 2>/dev/null || true)
+merge_renamed=$(readlink "$HOME/mergemine/clash-2" 2>/dev/null || true)
+merge_tagged=$("$MARK_BINARY" -l --tag restored 2>&1 || true)
+unset MARK_DIR
+if echo "$merge_dry" | grep -q "Would add bookmark 'clash-2'" && echo "$merge_out" | grep -q "1 added, 0 replaced, 1 renamed, 0 kept, 1 already present" && \
+   [ "$merge_clash" = "$HOME/mergedirs/a" ] && [ "$merge_renamed" = "$HOME/mergedirs/b" ] && echo "$merge_tagged" | grep -q "extra"; then
+    test_pass "extra added with its tags, clash renamed to clash-2"
+else
+    test_fail "dry run: $merge_dry; merge: $merge_out; clash: $merge_clash; clash-2: $merge_renamed; tagged: $merge_tagged"
+fi
+
+# Test 100: diff against another marks directory reports target and tag changes
+run_test "Diff against a marks directory"
+export MARK_DIR="$HOME/diffmine"
+mkdir -p "$HOME/diffdirs/a" "$HOME/diffdirs/b" "$HOME/diffsynced"
+"$MARK_BINARY" moved "$HOME/diffdirs/a" >/dev/null 2>&1
+"$MARK_BINARY" retagged "$HOME/diffdirs/a" --tag old >/dev/null 2>&1
+ln -s "$HOME/diffdirs/b" "$HOME/diffsynced/moved"
+ln -s "$HOME/diffdirs/a" "$HOME/diffsynced/retagged"
+printf '{"retagged":{"tags":["new"]}}' > "$HOME/diffsynced/.metadata.json"
+dirdiff_status=0
+dirdiff_out=$("$MARK_BINARY" diff "$HOME/diffsynced" 2>&1) || dirdiff_status=$?
+unset MARK_DIR
+if [ "$dirdiff_status" = "1" ] && echo "$dirdiff_out" | grep -q "^~ moved .*diffdirs/a -> .*diffdirs/b" && \
+   echo "$dirdiff_out" | grep -q "^\* retagged .*tags old -> new"; then
+    test_pass "target drift and tag change reported"
+else
+    test_fail "status $dirdiff_status: $dirdiff_out"
+fi
+
+# Test 101: explain and why-broken find project bookmarks and name their source
+run_test "Explain and why-broken name the matching source"
+mkdir -p "$HOME/explainproj/src"
+printf 'projexplain=src\nprojgone=missing\n' > "$HOME/explainproj/.marks"
+explain_out=$(cd "$HOME/explainproj" && "$MARK_BINARY" explain projexplain 2>&1)
+whybroken_out=$(cd "$HOME/explainproj" && "$MARK_BINARY" why-broken projgone 2>&1)
+if echo "$explain_out" | grep -q "Source:     project marks file ($HOME/explainproj/.marks)" && \
+   echo "$explain_out" | grep -q "Resolved:   $HOME/explainproj/src" && \
+   echo "$whybroken_out" | grep -q "Source:     project marks file" && \
+   echo "$whybroken_out" | grep -q "Problem:    $HOME/explainproj/missing does not exist"; then
+    test_pass "project bookmark explained and diagnosed"
+else
+    test_fail "explain: $explain_out; why-broken: $whybroken_out"
+fi
+
+# Test 102: diff failures have their own exit status, apart from differences
+run_test "Diff reports usage and read errors with exit 6"
+diffusage_status=0
+"$MARK_BINARY" diff >/dev/null 2>&1 || diffusage_status=$?
+diffmissing_status=0
+"$MARK_BINARY" diff "$HOME/no-such-manifest.json" >/dev/null 2>&1 || diffmissing_status=$?
+configbogus_status=0
+"$MARK_BINARY" config set bogus 1 >/dev/null 2>&1 || configbogus_status=$?
+if [ "$diffusage_status" = "6" ] && [ "$diffmissing_status" = "6" ] && [ "$configbogus_status" = "1" ]; then
+    test_pass "usage and unreadable manifest exit 6, unknown config key exits 1"
+else
+    test_fail "usage exited $diffusage_status, missing manifest exited $diffmissing_status, config set bogus exited $configbogus_status"
+fi
+
+# Test 103: glob bookmarks survive an export round trip as patterns
+run_test "Export keeps glob patterns"
+export MARK_DIR="$HOME/globexport"
+mkdir -p "$HOME/globexportsnaps/snap-1" "$HOME/globexportsnaps/snap-2"
+"$MARK_BINARY" expsnaps "$HOME/globexportsnaps/snap-*" >/dev/null 2>&1
+globexport_json=$("$MARK_BINARY" export 2>&1)
+"$MARK_BINARY" export --format script "$HOME/globexport.sh" >/dev/null 2>&1
+rm -rf "$HOME/globexport"
+PATH="$(dirname "$MARK_BINARY"):$PATH" sh "$HOME/globexport.sh" >/dev/null 2>&1
+globexport_stored=$(readlink "$HOME/globexport/expsnaps" 2>/dev/null || true)
+unset MARK_DIR
+if echo "$globexport_json" | grep -q "\"target\": \"$HOME/globexportsnaps/snap-\*\"" && \
+   [ "$globexport_stored" = "$HOME/globexportsnaps/snap-*" ]; then
+    test_pass "pattern exported and re-created by the script"
+else
+    test_fail "export: $globexport_json; re-created link: $globexport_stored"
+fi
+
+# Test 104: bookmarks restricted to other hosts stay out of cdpath and hash -d but are exported
+run_test "Host-restricted bookmarks hidden from cdpath and init, kept in export"
+export MARK_DIR="$HOME/hostcdmarks"
+mkdir -p "$HOME/hostcdshared" "$HOME/hostcdlaptop"
+"$MARK_BINARY" hostcdshared "$HOME/hostcdshared" >/dev/null 2>&1
+MARK_HOSTNAME=laptop "$MARK_BINARY" hostcdlaptop "$HOME/hostcdlaptop" --only-on laptop >/dev/null 2>&1
+hostcd_value=$(MARK_HOSTNAME=server "$MARK_BINARY" cdpath 2>&1 || true)
+hostcd_linkdir=${hostcd_value#.:}
+hostcd_hash=$(MARK_HOSTNAME=server "$MARK_BINARY" init zsh --hash-dirs 2>&1 || true)
+hostcd_export=$(MARK_HOSTNAME=server "$MARK_BINARY" export 2>&1 || true)
+MARK_HOSTNAME=server "$MARK_BINARY" export "$HOME/hostcd.json" >/dev/null 2>&1
+hostcd_diff_status=0
+MARK_HOSTNAME=server "$MARK_BINARY" diff "$HOME/hostcd.json" >/dev/null 2>&1 || hostcd_diff_status=$?
+unset MARK_DIR
+if [ "$hostcd_linkdir" != "$HOME/hostcdmarks" ] && [ -L "$hostcd_linkdir/hostcdshared" ] && [ ! -e "$hostcd_linkdir/hostcdlaptop" ] && \
+   echo "$hostcd_hash" | grep -q "hash -d hostcdshared=" && ! echo "$hostcd_hash" | grep -q "hostcdlaptop" && \
+   echo "$hostcd_export" | grep -q "hostcdshared" && echo "$hostcd_export" | grep -q '"only_on": \[' && [ "$hostcd_diff_status" = "0" ]; then
+    test_pass "laptop-only bookmark hidden on another host but exported with only_on"
+else
+    test_fail "cdpath: $hostcd_value; hash: $hostcd_hash; export: $hostcd_export; diff exited $hostcd_diff_status"
+fi
+
+# Test 105: with default_action=jump the mark shell function changes directory
+run_test "mark function jumps under default_action=jump"
+mkdir -p "$HOME/defjumpmarks" "$HOME/defjumptarget"
+printf 'marksdir=%s\ndefault_action=jump\n' "$HOME/defjumpmarks" > "$HOME/defjump.conf"
+export MARK_CONFIG="$HOME/defjump.conf"
+"$MARK_BINARY" defjumped "$HOME/defjumptarget" >/dev/null 2>&1
+defjump_pwd=$(cd / && bash -c "eval \"\$('$MARK_BINARY' init bash)\"; mark defjumped >/dev/null; pwd" 2>/dev/null)
+defjump_create=$(cd "$HOME/defjumptarget" && bash -c "eval \"\$('$MARK_BINARY' init bash)\"; mark defjumpnew; pwd" 2>&1)
+unset MARK_CONFIG
+if [ "$defjump_pwd" = "$(cd "$HOME/defjumptarget" && pwd -P)" ] && [ -L "$HOME/defjumpmarks/defjumpnew" ] && \
+   echo "$defjump_create" | grep -q "Created bookmark 'defjumpnew'"; then
+    test_pass "existing name changed directory, new name created"
+else
+    test_fail "pwd after jump: $defjump_pwd; create: $defjump_create"
+fi
+
+# Test 106: merge --strategy theirs retargets in place and keeps our details
+run_test "Merge takes theirs without deleting ours first"
+export MARK_DIR="$HOME/mergetheirsmine"
+mkdir -p "$HOME/mergetheirsother" "$HOME/mergedirs/d"
+"$MARK_BINARY" --tag keep clash "$HOME/mergedirs/a" >/dev/null 2>&1
+ln -s "$HOME/mergedirs/d" "$HOME/mergetheirsother/clash"
+printf '{"clash":{"description":"from backup"}}' > "$HOME/mergetheirsother/.metadata.json"
+mergetheirs_out=$("$MARK_BINARY" merge "$HOME/mergetheirsother" --strategy theirs 2>&1 < /dev/null || true)
+mergetheirs_link=$(readlink "$HOME/mergetheirsmine/clash" 2>/dev/null || true)
+mergetheirs_show=$("$MARK_BINARY" show clash 2>&1 || true)
+mergetheirs_trash=$("$MARK_BINARY" trash 2>&1 || true)
+unset MARK_DIR
+if echo "$mergetheirs_out" | grep -q "1 replaced" && [ "$mergetheirs_link" = "$HOME/mergedirs/d" ] && \
+   echo "$mergetheirs_show" | grep -q "keep" && echo "$mergetheirs_show" | grep -q "from backup" && echo "$mergetheirs_trash" | grep -q "clash"; then
+    test_pass "clash points at theirs with both sides' details, ours in the trash"
+else
+    test_fail "merge: $mergetheirs_out; link: $mergetheirs_link; show: $mergetheirs_show; trash: $mergetheirs_trash"
+fi
+
+# Test 107: a subpath cannot climb out of the bookmark's target
+run_test "Subpaths stay below the bookmark"
+mkdir -p "$HOME/subpathjail/inner"
+"$MARK_BINARY" subjail "$HOME/subpathjail" >/dev/null 2>&1
+subjail_inner=$("$MARK_BINARY" -j subjail/inner 2>/dev/null || true)
+subjail_up_status=0
+"$MARK_BINARY" -j subjail/.. >/dev/null 2>&1 || subjail_up_status=$?
+subjail_deep_status=0
+"$MARK_BINARY" -j subjail/inner/../../.. >/dev/null 2>&1 || subjail_deep_status=$?
+"$MARK_BINARY" -d subjail </dev/null >/dev/null 2>&1
+if [ "$subjail_inner" = "$HOME/subpathjail/inner" ] && [ "$subjail_up_status" != "0" ] && [ "$subjail_deep_status" != "0" ]; then
+    test_pass "subjail/inner jumps, subjail/.. is refused"
+else
+    test_fail "inner: $subjail_inner; .. exited $subjail_up_status; ../../.. exited $subjail_deep_status"
+fi
+
+# Test 108: show leaves personal metadata off shared bookmarks
+run_test "Show shared bookmark without personal metadata"
+"$MARK_BINARY" teamnote "$HOME" --tag personal --desc "my notes" >/dev/null 2>&1
+rm -f "$HOME/.marks/teamnote"
+ln -s "$HOME/teamdir" "$TEAM_MARKS/teamnote"
+teamnote_show=$("$MARK_BINARY" show teamnote 2>/dev/null || true)
+rm -f "$TEAM_MARKS/teamnote"
+if echo "$teamnote_show" | grep -q "^Source: *shared" && \
+   ! echo "$teamnote_show" | grep -q "^Tags:" && ! echo "$teamnote_show" | grep -q "^Description:"; then
+    test_pass "Shared bookmark shown without the personal tags and description"
+else
+    test_fail "show mixed personal metadata into a shared bookmark: $teamnote_show"
+fi
+
 # Print summary
 echo ""
 echo "========================================"
@@ -157,6 +157,40 @@ else
     test_fail "Missing aliases or completions in RC file"
 fi
 
+# Test 12: Setup answered from a --defaults file without reading stdin
+run_test "Setup from a --defaults answer file"
+printf 'marksdir=%s\ncompletion=no\naliases=no\n' "$HOME/answered-marks" > "$HOME/mark-defaults"
+"$MARK_BINARY" --config --defaults "$HOME/mark-defaults" >/dev/null 2>&1 </dev/null || true
+if grep -q "answered-marks" "$HOME/.mark" && [ -d "$HOME/answered-marks" ]; then
+    test_pass "Answer file configured the marks directory"
+else
+    test_fail "--defaults answers were not applied"
+fi
+
+# Test 13: Unattended setup driven by flags, without a TTY
+run_test "Setup from --yes, --marksdir and --no-alias flags"
+UNATTENDED_HOME="$HOME/unattended"
+mkdir -p "$UNATTENDED_HOME"
+HOME="$UNATTENDED_HOME" SHELL=/bin/bash "$MARK_BINARY" --config --yes --marksdir "$UNATTENDED_HOME/bookmarks" --no-alias >/dev/null 2>&1 </dev/null || true
+if grep -q "marksdir=~/bookmarks" "$UNATTENDED_HOME/.mark" 2>/dev/null && [ -d "$UNATTENDED_HOME/bookmarks" ] && \
+   grep -q "_mark_complete()" "$UNATTENDED_HOME/.mark_bash_rc" 2>/dev/null && ! grep -q "alias marks=" "$UNATTENDED_HOME/.mark_bash_rc"; then
+    test_pass "Flags configured marks directory and completion, skipped aliases"
+else
+    test_fail "Setup flags were not applied"
+fi
+
+# Test 14: Uninstall removes integration and config but keeps bookmarks
+run_test "Uninstall removes integration, keeps bookmarks"
+HOME="$UNATTENDED_HOME" "$MARK_BINARY" kept /tmp >/dev/null 2>&1 || true
+cp "$UNATTENDED_HOME/.mark" "$UNATTENDED_HOME/.mark.bak"
+HOME="$UNATTENDED_HOME" "$MARK_BINARY" uninstall --yes >/dev/null 2>&1 </dev/null || true
+if [ ! -e "$UNATTENDED_HOME/.mark" ] && [ ! -e "$UNATTENDED_HOME/.mark.bak" ] && [ ! -e "$UNATTENDED_HOME/.mark_bash_rc" ] && \
+   ! grep -q "mark shell integration" "$UNATTENDED_HOME/.bashrc" && [ -L "$UNATTENDED_HOME/bookmarks/kept" ]; then
+    test_pass "Integration, source line, config and its backup removed; bookmarks kept"
+else
+    test_fail "uninstall left files behind or removed bookmarks"
+fi
+
 # Print summary
 echo ""
 echo "========================================"
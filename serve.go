@@ -0,0 +1,378 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// defaultServeAddr is where 'mark serve' listens without --listen
+const defaultServeAddr = "127.0.0.1:7457"
+
+// apiBookmark is one bookmark as served by 'mark serve'
+type apiBookmark struct {
+	Name        string   `json:"name"`
+	Target      string   `json:"target"` // as stored, possibly relative
+	Path        string   `json:"path"`   // resolved directory or URL
+	Broken      bool     `json:"broken"`
+	Shared      bool     `json:"shared,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Pinned      bool     `json:"pinned,omitempty"`
+}
+
+// apiServer answers the HTTP API of 'mark serve'
+type apiServer struct {
+	config Config
+	write  bool   // allow creating and deleting bookmarks
+	token  string // required bearer token, if set
+
+	mu sync.Mutex // serializes changes to storage, metadata and logs
+}
+
+// handler routes the API endpoints:
+//
+//	GET    /bookmarks[?tag=<tag>]  list bookmarks
+//	GET    /bookmarks/{name}       one bookmark
+//	POST   /bookmarks              create {"name": ..., "target": ...} (--write)
+//	DELETE /bookmarks/{name}       delete, to the trash like 'mark -d' (--write)
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /bookmarks", s.list)
+	mux.HandleFunc("GET /bookmarks/{name}", s.get)
+	mux.HandleFunc("POST /bookmarks", s.create)
+	mux.HandleFunc("DELETE /bookmarks/{name}", s.delete)
+	return s.guard(mux)
+}
+
+// guard checks the token and keeps web pages out: browsers send an Origin
+// header, and such requests are only answered (with CORS headers) when a
+// token is configured, so an arbitrary site cannot read or change bookmarks.
+// Without a token the Host must also be a loopback name, so a page that
+// rebinds its own domain to 127.0.0.1 is refused too.
+func (s *apiServer) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" && !isLoopbackHost(r.Host) {
+			writeAPIError(w, http.StatusForbidden, fmt.Sprintf("host '%s' is not local; use 'mark serve --token' for other names", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if s.token == "" {
+				writeAPIError(w, http.StatusForbidden, "requests from browsers need 'mark serve --token'")
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+			w.Header().Set("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if s.token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				writeAPIError(w, http.StatusUnauthorized, "missing or wrong token")
+				return
+			}
+		}
+		debugf("serve: %s %s", r.Method, r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON sends value as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeAPIError sends {"error": message}
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeInternalError logs err on the server and sends a generic 500, so
+// clients do not learn file paths from the error text
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	fmt.Fprintf(os.Stderr, "Error: serve: %s %s: %v\n", r.Method, r.URL.Path, err)
+	writeAPIError(w, http.StatusInternalServerError, "internal error; see the server log")
+}
+
+// apiEntry describes bookmark for the API, including whether it is broken
+func (s *apiServer) apiEntry(bookmark Bookmark, meta map[string]*Metadata) apiBookmark {
+	entry := apiBookmark{
+		Name:   bookmark.Name,
+		Target: bookmark.Target,
+		Path:   marks.TargetPath(s.config, bookmark.Target),
+		Shared: bookmark.Shared,
+	}
+	if !marks.IsURLTarget(entry.Path) {
+		info, err := os.Stat(entry.Path)
+		entry.Broken = err != nil || !info.IsDir()
+	}
+	if m := meta[bookmark.Name]; m != nil {
+		entry.Tags = m.Tags
+		entry.Description = m.Description
+		entry.Pinned = m.Pinned
+	}
+	return entry
+}
+
+func (s *apiServer) list(w http.ResponseWriter, r *http.Request) {
+	bookmarks, err := openLayeredStorage(s.config).List()
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	meta, err := marks.LoadMetadata(s.config.MarksDir)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	tag := r.URL.Query().Get("tag")
+	entries := []apiBookmark{}
+	for _, bookmark := range bookmarks {
+		if tag != "" && !hasTag(meta[bookmark.Name], tag) {
+			continue
+		}
+		entries = append(entries, s.apiEntry(bookmark, meta))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *apiServer) get(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := marks.CheckName(name); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bookmark, err := openLayeredStorage(s.config).Get(name)
+	if err != nil {
+		writeStorageError(w, r, name, err)
+		return
+	}
+	meta, _ := marks.LoadMetadata(s.config.MarksDir)
+	writeJSON(w, http.StatusOK, s.apiEntry(bookmark, meta))
+}
+
+func (s *apiServer) create(w http.ResponseWriter, r *http.Request) {
+	if !s.write {
+		writeAPIError(w, http.StatusForbidden, "read-only server; start it with 'mark serve --write'")
+		return
+	}
+
+	var request struct {
+		Name        string   `json:"name"`
+		Target      string   `json:"target"`
+		Tags        []string `json:"tags"`
+		Description string   `json:"description"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	targetDir, err := apiTarget(request.Target)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := request.Name
+	if name == "" {
+		name = filepath.Base(targetDir)
+	}
+	name, err = configNamePolicy(s.config).normalize(name)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	storage := openStorage(s.config)
+	meta := Metadata{Tags: request.Tags, Description: strings.TrimSpace(request.Description)}
+	if err := storeBookmark(s.config, storage, name, targetDir, meta); err != nil {
+		writeStorageError(w, r, name, err)
+		return
+	}
+
+	bookmark, err := storage.Get(name)
+	if err != nil {
+		writeStorageError(w, r, name, err)
+		return
+	}
+	all, _ := marks.LoadMetadata(s.config.MarksDir)
+	writeJSON(w, http.StatusCreated, s.apiEntry(bookmark, all))
+}
+
+func (s *apiServer) delete(w http.ResponseWriter, r *http.Request) {
+	if !s.write {
+		writeAPIError(w, http.StatusForbidden, "read-only server; start it with 'mark serve --write'")
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := marks.CheckName(name); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	storage := openStorage(s.config)
+	bookmark, err := storage.Get(name)
+	if err == nil {
+		_, err = removeBookmark(s.config, storage, bookmark)
+	}
+	if err != nil {
+		writeStorageError(w, r, name, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiTarget validates a target given to the API: a URL or an existing
+// directory. There is no current directory to fall back on, so relative
+// paths are refused.
+func apiTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if marks.IsURLTarget(target) {
+		return target, validateURLTarget(target)
+	}
+
+	path := marks.ExpandPath(normalizeTargetArg(target))
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("target must be an absolute path or a URL: %q", target)
+	}
+	path = normalizeTarget(path)
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("target directory does not exist: %s", target)
+	}
+	return path, nil
+}
+
+// writeStorageError maps a storage error to its HTTP status
+func writeStorageError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	switch {
+	case errors.Is(err, marks.ErrNotFound), errors.Is(err, marks.ErrNotBookmark):
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("bookmark '%s' does not exist", name))
+	case errors.Is(err, marks.ErrExists):
+		writeAPIError(w, http.StatusConflict, fmt.Sprintf("bookmark '%s' already exists", name))
+	case errors.Is(err, marks.ErrReadOnly):
+		writeAPIError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, marks.ErrInvalidName):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeInternalError(w, r, err)
+	}
+}
+
+// isLoopback reports whether a listen address only accepts local clients
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isLoopbackHost reports whether a request's Host header, with or without
+// a port, names a loopback address
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return isLoopback(net.JoinHostPort(host, "0"))
+}
+
+// serveCommand runs the local HTTP JSON API
+// ('mark serve [--listen <addr>] [--write] [--token <token>]')
+func serveCommand(config Config, flags *ParsedFlags, args []string) {
+	server := &apiServer{config: config, token: os.Getenv("MARK_SERVE_TOKEN")}
+	addr := defaultServeAddr
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--write":
+			server.write = true
+		case "--listen", "--token":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+			if args[i] == "--listen" {
+				addr = args[i+1]
+			} else {
+				server.token = args[i+1]
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown serve option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	// Anyone who can reach the port could read the bookmarks otherwise
+	if !isLoopback(addr) && server.token == "" {
+		fmt.Fprintf(os.Stderr, "Error: Listening on %s (not loopback) requires --token\n", addr)
+		os.Exit(1)
+	}
+
+	// Fail on a bad storage or source_order before accepting requests
+	openLayeredStorage(config)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	mode := "read-only"
+	if server.write {
+		mode = "read-write"
+	}
+	fmt.Printf("Serving bookmarks on http://%s (%s); Ctrl-C stops\n", listener.Addr(), mode)
+
+	httpServer := &http.Server{Handler: server.handler(), ReadHeaderTimeout: 10 * time.Second}
+	if err := httpServer.Serve(listener); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
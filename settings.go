@@ -0,0 +1,261 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// configKeys lists the settings 'mark config' reads and writes. Repeatable
+// keys (true) may appear on several lines of the config file.
+var configKeys = map[string]bool{
+	"marksdir":           false,
+	"create_completion":  false,
+	"completion_chooser": false,
+	"storage":            false,
+	"storage_file":       false,
+	"storage_command":    false,
+	"name_policy":        false,
+	"ssh_path_map":       true,
+	"container_path_map": true,
+	"container_runtime":  false,
+	"workspace":          true,
+	"jump_dir_fallback":  false,
+	"sort":               false,
+	"default_action":     false,
+	"on_broken":          false,
+	"link_style":         false,
+	"trash_days":         false,
+	"max_age":            false,
+	"dynamic_targets":    false,
+	"dynamic_timeout":    false,
+	"pre_jump":           false,
+	"post_jump":          false,
+	"track_visits":       false,
+	"audit_log":          false,
+	"project_marks":      false,
+	"system_marks":       false,
+	"shared_marks":       true,
+	"repair_root":        true,
+	"source_order":       false,
+	"profile":            true,
+}
+
+// configLine splits a config file line into key and value; comments, blank
+// lines and lines without '=' are not settings
+func configLine(line string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(trimmed, "=")
+	return strings.TrimSpace(key), strings.TrimSpace(value), ok
+}
+
+// configValues returns every value set for key, in file order
+func configValues(lines []string, key string) []string {
+	var values []string
+	for _, line := range lines {
+		if k, v, ok := configLine(line); ok && k == key {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// setConfigValue returns lines with key set to value: the first line for the
+// key is rewritten in place and any further ones dropped, so comments and the
+// order of other settings survive; a new key is appended
+func setConfigValue(lines []string, key, value string) []string {
+	var result []string
+	found := false
+	for _, line := range lines {
+		if k, _, ok := configLine(line); ok && k == key {
+			if !found {
+				result = append(result, key+"="+value)
+				found = true
+			}
+			continue
+		}
+		result = append(result, line)
+	}
+	if !found {
+		result = append(result, key+"="+value)
+	}
+	return result
+}
+
+// validateConfigValue checks value for the settings that only accept
+// particular forms, using the same parsers that read them at runtime
+func validateConfigValue(key, value string) error {
+	var err error
+	switch key {
+	case "marksdir":
+		if value == "" {
+			err = fmt.Errorf("marksdir cannot be empty")
+		}
+	case "storage":
+		switch value {
+		case "symlink", "json", "exec":
+		default:
+			err = fmt.Errorf("unknown storage backend: %s (supported: symlink, json, exec)", value)
+		}
+	case "name_policy":
+		_, err = parseNamePolicy(value)
+	case "ssh_path_map", "container_path_map", "workspace":
+		_, err = marks.ParsePathMapping(value)
+	case "sort":
+		_, err = parseSortKeys(value)
+	case "source_order":
+		_, err = parseSourceOrder(value)
+	case "track_visits", "audit_log", "dynamic_targets":
+		if value != "on" && value != "off" {
+			err = fmt.Errorf("invalid %s '%s' (supported: on, off)", key, value)
+		}
+	case "default_action":
+		if value != "create" && value != "jump" {
+			err = fmt.Errorf("unknown default_action '%s' (supported: create, jump)", value)
+		}
+	case "on_broken":
+		if !validOnBroken(value) {
+			err = fmt.Errorf("unknown on_broken mode '%s' (supported: %s)", value, strings.Join(onBrokenModes, ", "))
+		}
+	case "link_style":
+		if !validLinkStyle(value) {
+			err = fmt.Errorf("unknown link_style '%s' (supported: %s)", value, strings.Join(linkStyles, ", "))
+		}
+	case "trash_days":
+		_, err = parseTrashDays(value)
+	case "max_age":
+		_, err = parseMaxAge(value)
+	case "dynamic_timeout":
+		_, err = parseDynamicTimeout(value)
+	case "profile":
+		name, dir, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(dir) == "" {
+			err = fmt.Errorf("invalid profile '%s' (expected <name>=<marksdir>)", value)
+		}
+	}
+	return err
+}
+
+// readConfigLines returns the lines of the config file; a missing file has none
+func readConfigLines(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), nil
+}
+
+// configCommand reads and changes settings without the setup wizard
+// ('mark config get <key>', 'mark config set <key> <value>', 'mark config list')
+func configCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark config get <key> | set <key> <value> | list\n")
+		os.Exit(1)
+	}
+
+	path, err := marks.ConfigFilePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		os.Exit(1)
+	}
+	lines, err := readConfigLines(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		for _, line := range lines {
+			if key, value, ok := configLine(line); ok {
+				fmt.Printf("%s=%s\n", key, value)
+			}
+		}
+
+	case "get":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Error: Usage: mark config get <key>\n")
+			os.Exit(1)
+		}
+		requireConfigKey(args[1])
+		values := configValues(lines, args[1])
+		if len(values) == 0 {
+			// Unset, like 'git config': no output, exit status 1
+			os.Exit(1)
+		}
+		for _, value := range values {
+			fmt.Println(value)
+		}
+
+	case "set":
+		if len(args) != 3 {
+			fmt.Fprintf(os.Stderr, "Error: Usage: mark config set <key> <value>\n")
+			os.Exit(1)
+		}
+		key, value := args[1], strings.TrimSpace(args[2])
+		requireConfigKey(key)
+		if err := validateConfigValue(key, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+
+		lines = setConfigValue(lines, key, value)
+		content := strings.Join(lines, "\n") + "\n"
+		if err := marks.WriteFileBackup(path, []byte(content), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		if configKeys[key] {
+			fmt.Printf("✓ Set %s=%s (replacing any other %s lines)\n", key, value, key)
+		} else {
+			fmt.Printf("✓ Set %s=%s\n", key, value)
+		}
+		if key == "pre_jump" || key == "post_jump" || key == "track_visits" {
+			fmt.Println("  The shell integration picks this up in new shells; run 'mark --alias' to refresh an installed one.")
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown config command: %s (use get, set or list)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// requireConfigKey exits with the list of known settings if key is unknown
+func requireConfigKey(key string) {
+	if _, ok := configKeys[key]; ok {
+		return
+	}
+	var keys []string
+	for k := range configKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(os.Stderr, "Error: Unknown setting '%s'\nKnown settings: %s\n", key, strings.Join(keys, ", "))
+	os.Exit(1)
+}
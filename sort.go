@@ -0,0 +1,116 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sortEntry carries everything the sort keys compare for one bookmark
+type sortEntry struct {
+	name     string
+	target   string
+	pinned   bool
+	usage    usageSummary
+	frecency float64
+}
+
+// sortKeys maps sort key names to comparisons; each returns a negative
+// number when a should be listed before b
+var sortKeys = map[string]func(a, b sortEntry) int{
+	"name":   func(a, b sortEntry) int { return strings.Compare(a.name, b.name) },
+	"target": func(a, b sortEntry) int { return strings.Compare(a.target, b.target) },
+	"pinned": func(a, b sortEntry) int { return compareBool(a.pinned, b.pinned) },
+	// Usage-based keys put the highest value first
+	"frecency": func(a, b sortEntry) int { return cmp.Compare(b.frecency, a.frecency) },
+	"uses":     func(a, b sortEntry) int { return cmp.Compare(b.usage.Count, a.usage.Count) },
+	"recent":   func(a, b sortEntry) int { return b.usage.LastUsed.Compare(a.usage.LastUsed) },
+}
+
+// parseSortKeys splits a comma-separated list such as "pinned,frecency,name"
+// into known sort keys, in order
+func parseSortKeys(value string) ([]string, error) {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if _, ok := sortKeys[key]; !ok {
+			return nil, fmt.Errorf("unknown sort key '%s' (supported: frecency, name, pinned, recent, target, uses)", key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortNeedsUsage reports whether any of keys compares usage history
+func sortNeedsUsage(keys []string) bool {
+	for _, key := range keys {
+		if key == "frecency" || key == "uses" || key == "recent" {
+			return true
+		}
+	}
+	return false
+}
+
+// compareBookmarks applies keys in order, falling back to the name so the
+// result is always deterministic
+func compareBookmarks(a, b sortEntry, keys []string) int {
+	for _, key := range keys {
+		if c := sortKeys[key](a, b); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.name, b.name)
+}
+
+// frecencyScores weights every jump by its age, so bookmarks used often and
+// lately rank highest
+func frecencyScores(events []usageEvent, now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, event := range events {
+		age := now.Sub(event.Time)
+		switch {
+		case age < time.Hour:
+			scores[event.Name] += 4
+		case age < 24*time.Hour:
+			scores[event.Name] += 2
+		case age < 7*24*time.Hour:
+			scores[event.Name] += 0.5
+		default:
+			scores[event.Name] += 0.25
+		}
+	}
+	return scores
+}
+
+// compareBool orders true before false
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// Default system-wide marks directory shared by every user
+const defaultSystemMarksDir = "/etc/mark/marks"
+
+// defaultSourceOrder is the precedence of bookmark sources when source_order
+// is not configured: the user's own bookmarks win over everything else
+var defaultSourceOrder = []string{"personal", "project", "shared", "system"}
+
+// parseSourceOrder reads a comma-separated source_order such as
+// "shared,personal". Sources left out keep their default relative order
+// after the listed ones.
+func parseSourceOrder(value string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, kind := range defaultSourceOrder {
+		known[kind] = true
+	}
+
+	var order []string
+	seen := make(map[string]bool)
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !known[kind] {
+			return nil, fmt.Errorf("unknown source '%s' in source_order (supported: %s)", kind, strings.Join(defaultSourceOrder, ", "))
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			order = append(order, kind)
+		}
+	}
+	for _, kind := range defaultSourceOrder {
+		if !seen[kind] {
+			order = append(order, kind)
+		}
+	}
+	return order, nil
+}
+
+// openLayeredStorage returns the user's storage combined with the read-only
+// sources, for listing and jumping: the nearest project .marks file (unless
+// project_marks=off), every shared_marks directory and the system marks
+// directory (unless system_marks=off), in source_order precedence
+func openLayeredStorage(config Config) Storage {
+	personal := openStorage(config)
+	order, err := parseSourceOrder(config.SourceOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+
+	storage := &marks.LayeredStorage{Storage: personal}
+	for _, kind := range order {
+		switch kind {
+		case "personal":
+			origin := fmt.Sprintf("user marks directory (%s)", config.MarksDir)
+			if _, ok := personal.(*marks.SymlinkStorage); !ok {
+				origin = fmt.Sprintf("user %s storage", storageName(config))
+			}
+			storage.Sources = append(storage.Sources, marks.Source{Storage: hostFiltered(config, personal), Origin: origin})
+		case "project":
+			if project, path := projectSource(config); project != nil {
+				storage.Sources = append(storage.Sources, marks.Source{Storage: project, Shared: true, Origin: fmt.Sprintf("project marks file (%s)", path)})
+			}
+		case "shared":
+			for _, dir := range config.SharedMarks {
+				storage.Sources = append(storage.Sources, marks.Source{Storage: &marks.SharedDirStorage{Dir: dir}, Shared: true, Origin: fmt.Sprintf("shared marks directory (%s)", dir)})
+			}
+		case "system":
+			if dir := systemMarksDir(config); dir != "" && dir != config.MarksDir {
+				storage.Sources = append(storage.Sources, marks.Source{Storage: &marks.SharedDirStorage{Dir: dir}, Shared: true, Origin: fmt.Sprintf("system marks directory (%s)", dir)})
+			}
+		}
+	}
+	debugf("bookmark sources: %d in order %v", len(storage.Sources), order)
+	return storage
+}
+
+// projectSource returns the bookmarks of the nearest project .marks file and
+// its path, or nil when there is none or project_marks=off
+func projectSource(config Config) (Storage, string) {
+	if config.ProjectMarks == "off" {
+		return nil, ""
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, ""
+	}
+	path := findProjectMarksFile(cwd)
+	if path == "" {
+		return nil, ""
+	}
+	debugf("project bookmarks from %s", path)
+
+	project, err := parseProjectMarks(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring project bookmarks: %v\n", err)
+		return nil, ""
+	}
+	return marks.StaticStorage(project), path
+}
+
+// findBookmark looks a bookmark up in every source like -j does and returns
+// it with the source it came from, exiting if it is unavailable
+func findBookmark(config Config, name string) (Bookmark, marks.Source) {
+	bookmark, source, err := openLayeredStorage(config).(*marks.LayeredStorage).Find(name)
+	if err != nil {
+		exitBookmarkError(name, err)
+	}
+	return bookmark, source
+}
+
+// systemMarksDir returns the system-wide marks directory, "" when disabled
+func systemMarksDir(config Config) string {
+	switch config.SystemMarks {
+	case "":
+		return defaultSystemMarksDir
+	case "off":
+		return ""
+	default:
+		return config.SystemMarks
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// statsDays is how many days of jumps 'mark stats' charts
+const statsDays = 30
+
+// bookmarkStats summarizes the bookmarks and their usage for 'mark stats'
+type bookmarkStats struct {
+	Total       int             `json:"total"`
+	Broken      int             `json:"broken"`
+	URLs        int             `json:"urls"`
+	MostUsed    []bookmarkCount `json:"most_used"`
+	LeastUsed   []bookmarkCount `json:"least_used"`
+	JumpsPerDay []int           `json:"jumps_per_day"` // oldest first, ending today
+}
+
+// buildStats counts bookmarks, ranks them by all-time jumps (listing at most
+// top at each end, never-used ones included) and tallies the jumps of the
+// last statsDays days
+func buildStats(bookmarks []Bookmark, broken map[string]bool, events []usageEvent, now time.Time, top int) bookmarkStats {
+	stats := bookmarkStats{
+		Total:       len(bookmarks),
+		Broken:      len(broken),
+		MostUsed:    []bookmarkCount{},
+		LeastUsed:   []bookmarkCount{},
+		JumpsPerDay: make([]int, statsDays),
+	}
+
+	usage := summarizeUsage(events)
+	var counts []bookmarkCount
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) {
+			stats.URLs++
+		}
+		counts = append(counts, bookmarkCount{Name: bookmark.Name, Count: usage[bookmark.Name].Count})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	for _, c := range counts {
+		if len(stats.MostUsed) == top || c.Count == 0 {
+			break
+		}
+		stats.MostUsed = append(stats.MostUsed, c)
+	}
+	for i := len(counts) - 1; i >= 0 && len(stats.LeastUsed) < top; i-- {
+		stats.LeastUsed = append(stats.LeastUsed, counts[i])
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := today.AddDate(0, 0, -(statsDays - 1))
+	for _, event := range events {
+		t := event.Time.In(now.Location())
+		if t.Before(start) || t.After(now) {
+			continue
+		}
+		// Walk calendar days rather than dividing hours, which DST would skew
+		day := statsDays - 1
+		for t.Before(start.AddDate(0, 0, day)) {
+			day--
+		}
+		stats.JumpsPerDay[day]++
+	}
+	return stats
+}
+
+// printStats writes the summary as plain text
+func printStats(stats bookmarkStats, now time.Time) {
+	jumps := 0
+	for _, n := range stats.JumpsPerDay {
+		jumps += n
+	}
+
+	fmt.Printf("Bookmarks:  %d (%d broken, %d URLs)\n", stats.Total, stats.Broken, stats.URLs)
+	fmt.Printf("Jumps:      %d in the last %d days (%.1f per day)\n", jumps, statsDays, float64(jumps)/statsDays)
+
+	if len(stats.MostUsed) > 0 {
+		fmt.Println("\nMost used:")
+		for _, b := range stats.MostUsed {
+			fmt.Printf("  %5d  %s\n", b.Count, b.Name)
+		}
+	}
+	if len(stats.LeastUsed) > 0 {
+		fmt.Println("\nLeast used:")
+		for _, b := range stats.LeastUsed {
+			fmt.Printf("  %5d  %s\n", b.Count, b.Name)
+		}
+	}
+
+	if jumps > 0 {
+		start := now.AddDate(0, 0, -(statsDays - 1))
+		fmt.Printf("\nJumps per day, %s to %s:\n", start.Format("2006-01-02"), now.Format("2006-01-02"))
+		fmt.Printf("  %s\n", sparkline(stats.JumpsPerDay))
+	}
+}
+
+// statsCommand summarizes bookmarks and local usage ('mark stats [--json]')
+func statsCommand(config Config, flags *ParsedFlags, args []string) {
+	asJSON := false
+	for _, arg := range args {
+		if arg != "--json" {
+			fmt.Fprintf(os.Stderr, "Error: Unknown stats option: %s\n", arg)
+			os.Exit(1)
+		}
+		asJSON = true
+	}
+
+	bookmarks, err := openLayeredStorage(config).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+	broken := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) {
+			continue
+		}
+		if _, err := os.Stat(marks.TargetPath(config, bookmark.Target)); err != nil {
+			broken[bookmark.Name] = true
+		}
+	}
+
+	events, err := loadUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	stats := buildStats(bookmarks, broken, events, now, 5)
+	if asJSON {
+		content, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding stats: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+	printStats(stats, now)
+}
@@ -0,0 +1,50 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// Bookmark, Metadata and Storage come from the library; see pkg/marks
+type (
+	Bookmark = marks.Bookmark
+	Metadata = marks.Metadata
+	Storage  = marks.Storage
+)
+
+// storageName returns the configured storage backend, naming the default
+func storageName(config Config) string {
+	if config.Storage == "" {
+		return "symlink"
+	}
+	return config.Storage
+}
+
+// openStorage returns the configured storage backend or exits with an error
+func openStorage(config Config) Storage {
+	storage, err := marks.Open(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	return storage
+}
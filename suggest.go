@@ -0,0 +1,233 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// dirCount is a directory with the number of times it was visited
+type dirCount struct {
+	Path     string
+	Count    int
+	LastSeen time.Time // zero when the source has no times
+}
+
+// zshHistoryPrefix matches the ": <time>:<duration>;" prefix of zsh's
+// extended history format
+var zshHistoryPrefix = regexp.MustCompile(`^: \d+:\d+;`)
+
+// historyFiles returns the shell history files to scan: $HISTFILE plus the
+// default bash and zsh locations
+func historyFiles() []string {
+	homeDir, _ := os.UserHomeDir()
+	candidates := []string{
+		os.Getenv("HISTFILE"),
+		filepath.Join(homeDir, ".bash_history"),
+		filepath.Join(homeDir, ".zsh_history"),
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, file := range candidates {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		files = append(files, file)
+	}
+	return files
+}
+
+// parseCdHistory returns the targets of absolute or ~-relative cd commands in
+// shell history. Relative targets are skipped since the directory they were
+// typed in is unknown.
+func parseCdHistory(content string) []string {
+	homeDir, _ := os.UserHomeDir()
+
+	var targets []string
+	for _, line := range strings.Split(content, "\n") {
+		line = zshHistoryPrefix.ReplaceAllString(line, "")
+		line = strings.ReplaceAll(line, "&&", ";")
+		for _, command := range strings.Split(line, ";") {
+			fields := strings.Fields(command)
+			if len(fields) < 2 || fields[0] != "cd" {
+				continue
+			}
+
+			target := strings.Trim(strings.Join(fields[1:], " "), `'"`)
+			target = strings.ReplaceAll(target, `\ `, " ")
+			if target == "~" || strings.HasPrefix(target, "~/") {
+				target = filepath.Join(homeDir, strings.TrimPrefix(target, "~"))
+			}
+			if !filepath.IsAbs(target) {
+				continue
+			}
+			targets = append(targets, filepath.Clean(target))
+		}
+	}
+	return targets
+}
+
+// rankDirectories counts visits per directory, most visited first
+func rankDirectories(paths []string) []dirCount {
+	counts := make(map[string]dirCount)
+	for _, path := range paths {
+		counts[path] = dirCount{Path: path, Count: counts[path].Count + 1}
+	}
+	return rankCounts(counts)
+}
+
+// rankCounts orders directory counts, most visited first
+func rankCounts(counts map[string]dirCount) []dirCount {
+	var ranked []dirCount
+	for _, count := range counts {
+		ranked = append(ranked, count)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Path < ranked[j].Path
+	})
+	return ranked
+}
+
+// suggestionCandidates keeps the most visited directories that still exist
+// and are not bookmarked yet, up to limit entries
+func suggestionCandidates(ranked []dirCount, bookmarked map[string]bool, limit int) []dirCount {
+	var candidates []dirCount
+	for _, dir := range ranked {
+		if len(candidates) >= limit {
+			break
+		}
+		if bookmarked[dir.Path] {
+			continue
+		}
+		if info, err := os.Stat(dir.Path); err != nil || !info.IsDir() {
+			continue
+		}
+		candidates = append(candidates, dir)
+	}
+	return candidates
+}
+
+// suggestCommand offers to bookmark frequently visited directories, from the
+// track_visits log or, with --from-history, from shell history
+// ('mark suggest [--from-history] [--limit N]')
+func suggestCommand(config Config, flags *ParsedFlags, args []string) {
+	fromHistory := false
+	limit := 10
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--from-history":
+			fromHistory = true
+		case "--limit":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --limit requires a number\n")
+				os.Exit(1)
+			}
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil || value < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --limit must be a positive number\n")
+				os.Exit(1)
+			}
+			limit = value
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown suggest option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+	var ranked []dirCount
+	if fromHistory {
+		var visits []string
+		for _, file := range historyFiles() {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			visits = append(visits, parseCdHistory(string(content))...)
+		}
+		ranked = rankDirectories(visits)
+	} else {
+		visits, err := loadVisits()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		ranked = rankCounts(visits)
+		if len(ranked) == 0 {
+			fmt.Println("No directory visits recorded yet. Turn on tracking with 'mark config set track_visits on'")
+			fmt.Println("and open a new shell, or use 'mark suggest --from-history' to scan shell history.")
+			return
+		}
+	}
+
+	storage := openStorage(config)
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+	bookmarked := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		bookmarked[normalizeTarget(marks.TargetPath(config, bookmark.Target))] = true
+	}
+
+	candidates := suggestionCandidates(ranked, bookmarked, limit)
+	if len(candidates) == 0 {
+		fmt.Println("No suggestions: no frequently visited directories without a bookmark.")
+		return
+	}
+
+	policy := configNamePolicy(config)
+	reader := bufio.NewReader(os.Stdin)
+	var accepted []importEntry
+	for _, dir := range candidates {
+		name := policy.apply(filepath.Base(dir.Path))
+		fmt.Printf("Bookmark %s (%d visits) as '%s'? (y/N/other name): ", dir.Path, dir.Count, name)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(response)
+
+		switch strings.ToLower(response) {
+		case "", "n", "no":
+			continue
+		case "y", "yes":
+		default:
+			name = response
+		}
+		accepted = append(accepted, importEntry{Name: name, Path: dir.Path})
+	}
+
+	if len(accepted) == 0 {
+		fmt.Println("No bookmarks created.")
+		return
+	}
+	importEntries(config, storage, accepted, "", flags.DryRun)
+}
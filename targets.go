@@ -0,0 +1,215 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// gitRoot returns the top-level directory of the git repository containing
+// dir, as 'git rev-parse --show-toplevel' reports it
+func gitRoot(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("git is not installed")
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+	root := strings.TrimSpace(string(output))
+	if root == "" {
+		return "", fmt.Errorf("%s is not inside a git work tree", dir)
+	}
+	return filepath.FromSlash(root), nil
+}
+
+// normalizeTarget returns the canonical absolute form of a target path:
+// cleaned, so trailing slashes, doubled separators and ".." segments never
+// make one directory look like two
+func normalizeTarget(path string) string {
+	if marks.IsURLTarget(path) || marks.IsCommandTarget(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// linkStyles are the values of link_style / --link-style, deciding how new
+// bookmark targets are stored: as absolute paths, relative to the marks
+// directory, or as ~/ paths relative to the home directory
+var linkStyles = []string{"absolute", "relative", "home"}
+
+// validLinkStyle reports whether style is a known link_style value; empty
+// means the default, absolute
+func validLinkStyle(style string) bool {
+	if style == "" {
+		return true
+	}
+	for _, s := range linkStyles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTarget returns the form in which the absolute directory dir is stored
+// under the configured link style. Relative forms keep a marks directory
+// synced between machines valid when the home path differs (/home/bob vs
+// /Users/bob); directories they cannot express stay absolute. Symlinks
+// must stay followable by other programs, so with symlink storage "home"
+// writes a real relative path through the home directory instead of "~/".
+func linkTarget(config Config, dir string) string {
+	if marks.IsURLTarget(dir) || marks.IsEnvTarget(dir) || marks.IsCommandTarget(dir) {
+		return dir
+	}
+	switch config.LinkStyle {
+	case "relative":
+		if rel, err := filepath.Rel(config.MarksDir, dir); err == nil {
+			return rel
+		}
+	case "home":
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return dir
+		}
+		rel, ok := belowDir(homeDir, dir)
+		if !ok {
+			break
+		}
+		if config.Storage == "" || config.Storage == "symlink" {
+			// Only portable when the marks directory is below home too
+			if _, ok := belowDir(homeDir, config.MarksDir); ok {
+				if link, err := filepath.Rel(config.MarksDir, dir); err == nil {
+					return link
+				}
+			}
+			break
+		}
+		if rel == "." {
+			return "~"
+		}
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return dir
+}
+
+// belowDir returns path relative to dir when path is dir or below it
+func belowDir(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// portableTarget returns a stored target of the marks directory dir in a form
+// that means the same outside it: relative targets are made absolute against
+// dir, while absolute, ~/, $VAR, glob, !command and URL targets are kept as
+// written so they still resolve per machine
+func portableTarget(dir, target string) string {
+	if filepath.IsAbs(target) || marks.IsHomeTarget(target) || marks.IsEnvTarget(target) ||
+		marks.IsGlobTarget(target) || marks.IsCommandTarget(target) || marks.IsURLTarget(target) {
+		return target
+	}
+	return filepath.Join(dir, target)
+}
+
+// targetFix is one stored target that 'mark tidy-targets' rewrites
+type targetFix struct {
+	name string
+	from string
+	to   string
+}
+
+// planTargetFixes lists the bookmarks whose stored target is not in clean
+// form. Relative targets stay relative so marks directories synced between
+// machines keep working.
+func planTargetFixes(bookmarks []Bookmark) []targetFix {
+	var fixes []targetFix
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) {
+			continue
+		}
+		if clean := filepath.Clean(bookmark.Target); clean != bookmark.Target {
+			fixes = append(fixes, targetFix{name: bookmark.Name, from: bookmark.Target, to: clean})
+		}
+	}
+	return fixes
+}
+
+// tidyTargetsCommand rewrites stored targets into clean form
+// ('mark tidy-targets'), a one-off migration for bookmarks created before
+// targets were normalized
+func tidyTargetsCommand(config Config, flags *ParsedFlags, args []string) {
+	storage := openStorage(config)
+	bookmarks, err := storage.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	fixes := planTargetFixes(bookmarks)
+	if len(fixes) == 0 {
+		fmt.Println("All bookmark targets are already normalized.")
+		return
+	}
+
+	fmt.Println("Target plan:")
+	for _, f := range fixes {
+		fmt.Printf("  %-20s %s -> %s\n", f.name, f.from, f.to)
+	}
+
+	fmt.Print("Rewrite these targets? (y/N): ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("No targets changed.")
+		return
+	}
+
+	for _, f := range fixes {
+		if err := storage.Delete(f.name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating '%s': %v\n", f.name, err)
+			os.Exit(1)
+		}
+		if err := storage.Create(f.name, f.to); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating '%s': %v\n", f.name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Normalized bookmark '%s' -> %s\n", f.name, f.to)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sparkBlocks are the bar heights used for the per-day sparkline
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// topRow is one bookmark line of the 'mark top' dashboard
+type topRow struct {
+	Name     string
+	Today    int
+	Week     int
+	Daily    [7]int // jumps per day, oldest first, ending today
+	Frecency float64
+}
+
+// buildTopRows ranks bookmarks jumped to in the last seven days by frecency,
+// returning at most limit rows
+func buildTopRows(events []usageEvent, now time.Time, limit int) []topRow {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := today.AddDate(0, 0, -6)
+
+	rows := make(map[string]*topRow)
+	var recent []usageEvent
+	for _, event := range events {
+		t := event.Time.In(now.Location())
+		if t.Before(weekStart) || t.After(now) {
+			continue
+		}
+		recent = append(recent, event)
+
+		row := rows[event.Name]
+		if row == nil {
+			row = &topRow{Name: event.Name}
+			rows[event.Name] = row
+		}
+		// Walk calendar days rather than dividing hours, which DST would skew
+		day := 6
+		for t.Before(weekStart.AddDate(0, 0, day)) {
+			day--
+		}
+		row.Daily[day]++
+		row.Week++
+		if !t.Before(today) {
+			row.Today++
+		}
+	}
+
+	scores := frecencyScores(recent, now)
+	var ranked []topRow
+	for name, row := range rows {
+		row.Frecency = scores[name]
+		ranked = append(ranked, *row)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Frecency != ranked[j].Frecency {
+			return ranked[i].Frecency > ranked[j].Frecency
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// sparkline draws counts as block characters scaled to the largest count;
+// days without jumps are shown as a dot
+func sparkline(counts []int) string {
+	peak := 0
+	for _, c := range counts {
+		peak = max(peak, c)
+	}
+
+	var sb strings.Builder
+	for _, c := range counts {
+		if c == 0 {
+			sb.WriteRune('·')
+			continue
+		}
+		sb.WriteRune(sparkBlocks[(c*len(sparkBlocks)-1)/peak])
+	}
+	return sb.String()
+}
+
+// renderTop formats one frame of the dashboard
+func renderTop(rows []topRow, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "mark top — %s (Ctrl-C to quit)\n\n", now.Format("2006-01-02 15:04:05"))
+	if len(rows) == 0 {
+		sb.WriteString("  No jumps in the last 7 days.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "  %-20s %5s %5s  %s\n", "NAME", "TODAY", "WEEK", "LAST 7 DAYS")
+	for _, row := range rows {
+		fmt.Fprintf(&sb, "  %-20s %5d %5d  %s\n", row.Name, row.Today, row.Week, sparkline(row.Daily[:]))
+	}
+	return sb.String()
+}
+
+// topCommand shows the most-jumped bookmarks of today and this week,
+// redrawn every few seconds ('mark top [--interval N] [--limit N] [--once]')
+func topCommand(config Config, flags *ParsedFlags, args []string) {
+	interval := 2 * time.Second
+	limit := 15
+	once := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--once":
+			once = true
+		case "--interval", "--limit":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a number\n", args[i])
+				os.Exit(1)
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: Invalid %s value '%s'\n", args[i], args[i+1])
+				os.Exit(1)
+			}
+			if args[i] == "--interval" {
+				interval = time.Duration(n) * time.Second
+			} else {
+				limit = n
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown top option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	draw := func() {
+		events, err := loadUsage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		now := time.Now()
+		frame := renderTop(buildTopRows(events, now, limit), now)
+		if once {
+			fmt.Print(frame)
+			return
+		}
+		// Clear the screen and move the cursor home before each frame
+		fmt.Print("\033[H\033[2J" + frame)
+	}
+
+	draw()
+	if once {
+		return
+	}
+
+	// Hide the cursor while redrawing and restore it on Ctrl-C
+	fmt.Print("\033[?25l")
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			draw()
+		case <-interrupt:
+			fmt.Print("\033[?25h\n")
+			return
+		}
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// defaultTrashDays is how long deleted bookmarks stay restorable unless
+// trash_days says otherwise
+const defaultTrashDays = 30
+
+// trashEntry is a deleted bookmark kept for 'mark restore'
+type trashEntry struct {
+	Name     string    `json:"name"`
+	Target   string    `json:"target"` // raw target, as the storage held it
+	Deleted  time.Time `json:"deleted"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// parseTrashDays validates a trash_days value; empty means the default and
+// 0 turns the trash off
+func parseTrashDays(value string) (int, error) {
+	if value == "" {
+		return defaultTrashDays, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid trash_days '%s' (expected a number of days, 0 to disable)", value)
+	}
+	return days, nil
+}
+
+// trashDays returns the configured retention, exiting on an invalid value
+func trashDays(config Config) int {
+	days, err := parseTrashDays(config.TrashDays)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitConfig)
+	}
+	return days
+}
+
+// loadTrash reads the trash from the marks directory. A missing file is an
+// empty trash.
+func loadTrash(marksDir string) ([]trashEntry, error) {
+	path := filepath.Join(marksDir, marks.TrashFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading trash: %w", err)
+	}
+
+	var entries []trashEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing trash: %w%s", err, marks.BackupHint(path))
+	}
+	return entries, nil
+}
+
+// saveTrash writes the trash back to the marks directory
+func saveTrash(marksDir string, entries []trashEntry) error {
+	if err := os.MkdirAll(marksDir, 0755); err != nil {
+		return fmt.Errorf("error creating marks directory: %w", err)
+	}
+	if entries == nil {
+		entries = []trashEntry{}
+	}
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding trash: %w", err)
+	}
+	if err := marks.WriteFileBackup(filepath.Join(marksDir, marks.TrashFile), append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing trash: %w", err)
+	}
+	return nil
+}
+
+// expireTrash drops entries deleted more than days ago
+func expireTrash(entries []trashEntry, now time.Time, days int) []trashEntry {
+	cutoff := now.AddDate(0, 0, -days)
+	var kept []trashEntry
+	for _, entry := range entries {
+		if entry.Deleted.After(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// moveToTrash records a bookmark that is being deleted, expiring old entries
+// on the way
+func moveToTrash(config Config, bookmark Bookmark, meta *Metadata) error {
+	entries, err := loadTrash(config.MarksDir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entries = expireTrash(entries, now, trashDays(config))
+	entries = append(entries, trashEntry{
+		Name:     bookmark.Name,
+		Target:   bookmark.Target,
+		Deleted:  now,
+		Metadata: meta,
+	})
+	return saveTrash(config.MarksDir, entries)
+}
+
+// trashCommand lists or empties the trash ('mark trash list|empty')
+func trashCommand(config Config, flags *ParsedFlags, args []string) {
+	action := "list"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	entries, err := loadTrash(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	days := trashDays(config)
+	entries = expireTrash(entries, time.Now(), days)
+
+	switch action {
+	case "list":
+		if len(entries) == 0 {
+			fmt.Println("Trash is empty.")
+			return
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Deleted.After(entries[j].Deleted)
+		})
+		for _, entry := range entries {
+			expires := entry.Deleted.AddDate(0, 0, days)
+			fmt.Printf("  %-20s -> %s  (deleted %s, kept until %s)\n",
+				entry.Name, entry.Target, entry.Deleted.Format("2006-01-02 15:04"), expires.Format("2006-01-02"))
+		}
+
+	case "empty":
+		if err := saveTrash(config.MarksDir, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Emptied trash (%d bookmarks)\n", len(entries))
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown trash command: %s (use list or empty)\n", action)
+		os.Exit(1)
+	}
+}
+
+// restoreCommand brings back the most recently deleted bookmark with the
+// given name, including its metadata ('mark restore <name>')
+func restoreCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for restore\n")
+		os.Exit(1)
+	}
+	name := args[0]
+
+	entries, err := loadTrash(config.MarksDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	entries = expireTrash(entries, time.Now(), trashDays(config))
+
+	found := -1
+	for i, entry := range entries {
+		if entry.Name == name && (found < 0 || entry.Deleted.After(entries[found].Deleted)) {
+			found = i
+		}
+	}
+	if found < 0 {
+		fmt.Fprintf(os.Stderr, "Error: No deleted bookmark '%s' in the trash\n", name)
+		os.Exit(exitNotFound)
+	}
+	entry := entries[found]
+
+	if err := openStorage(config).Create(entry.Name, entry.Target); err != nil {
+		if errors.Is(err, marks.ErrExists) {
+			fmt.Fprintf(os.Stderr, "Error: Bookmark '%s' already exists. Use 'mark -d %s' to remove it first.\n", name, name)
+			os.Exit(exitConflict)
+		}
+		fmt.Fprintf(os.Stderr, "Error restoring bookmark: %v\n", err)
+		os.Exit(1)
+	}
+	if entry.Metadata != nil {
+		if err := marks.UpdateMetadata(config.MarksDir, entry.Name, entry.Metadata); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	_ = recordChange("create", entry.Name)
+	_ = recordAudit(config, "restore", entry.Name, marks.TargetPath(config, entry.Target))
+
+	entries = append(entries[:found], entries[found+1:]...)
+	if err := saveTrash(config.MarksDir, entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	fmt.Printf("✓ Restored bookmark '%s' -> %s\n", entry.Name, entry.Target)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// treeBookmark is a bookmark placed in the 'mark -l --tree' view
+type treeBookmark struct {
+	label string // bookmark name as displayed (possibly colored)
+	path  string // absolute target path
+}
+
+// pathTree is one directory level of the tree view
+type pathTree struct {
+	children map[string]*pathTree
+	labels   []string // bookmarks whose target is this directory
+}
+
+// buildPathTree files each bookmark under its target's path components,
+// showing the home directory as ~
+func buildPathTree(bookmarks []treeBookmark, home string) *pathTree {
+	root := &pathTree{children: make(map[string]*pathTree)}
+	for _, bm := range bookmarks {
+		node := root
+		for _, part := range treePathParts(bm.path, home) {
+			child, ok := node.children[part]
+			if !ok {
+				child = &pathTree{children: make(map[string]*pathTree)}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.labels = append(node.labels, bm.label)
+	}
+	return root
+}
+
+// treePathParts splits a target into tree levels; the first level is "/",
+// "~", a volume name or a URL's scheme and host
+func treePathParts(path, home string) []string {
+	if marks.IsURLTarget(path) {
+		scheme, rest, _ := strings.Cut(path, "://")
+		parts := strings.Split(strings.Trim(rest, "/"), "/")
+		parts[0] = scheme + "://" + parts[0]
+		return parts
+	}
+
+	path = filepath.ToSlash(filepath.Clean(path))
+	home = filepath.ToSlash(filepath.Clean(home))
+	if home != "" && home != "." && (path == home || strings.HasPrefix(path, home+"/")) {
+		path = "~" + strings.TrimPrefix(path, home)
+	}
+
+	var parts []string
+	for i, part := range strings.Split(path, "/") {
+		if part == "" {
+			if i == 0 {
+				parts = append(parts, "/")
+			}
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// joinTreeSegment appends one path component to a displayed segment
+func joinTreeSegment(segment, part string) string {
+	if segment == "" {
+		return part
+	}
+	if strings.HasSuffix(segment, "/") {
+		return segment + part
+	}
+	return segment + "/" + part
+}
+
+// pathTreeLines renders the tree, merging directories that hold no
+// bookmarks and have a single subdirectory into one line
+func pathTreeLines(root *pathTree) []string {
+	var lines []string
+	var walk func(node *pathTree, prefix string, top bool)
+	walk = func(node *pathTree, prefix string, top bool) {
+		keys := make([]string, 0, len(node.children))
+		for key := range node.children {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for i, key := range keys {
+			child, segment := node.children[key], key
+			for len(child.labels) == 0 && len(child.children) == 1 {
+				for part, next := range child.children {
+					segment = joinTreeSegment(segment, part)
+					child = next
+				}
+			}
+
+			connector, indent := "├── ", "│   "
+			if i == len(keys)-1 {
+				connector, indent = "└── ", "    "
+			}
+			if top {
+				connector, indent = "", ""
+			}
+
+			line := prefix + connector + segment
+			if len(child.labels) > 0 {
+				line += "  [" + strings.Join(child.labels, ", ") + "]"
+			}
+			lines = append(lines, line)
+			walk(child, prefix+indent, false)
+		}
+	}
+	walk(root, "", true)
+	return lines
+}
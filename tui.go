@@ -0,0 +1,590 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// tuiEntry is one bookmark as listed by 'mark tui'
+type tuiEntry struct {
+	Name   string
+	Path   string
+	Tags   []string
+	Broken bool
+	Shared bool
+}
+
+// tuiOps are the changes the dashboard makes, so the key handling can be
+// tested without touching real bookmarks
+type tuiOps interface {
+	load() ([]tuiEntry, error)
+	create(name, target string) error
+	rename(oldName, newName string) error
+	remove(name string) error
+	setTags(name string, tags []string) error
+}
+
+// Keys the dashboard reacts to; keyRune carries a typed character
+const (
+	keyRune = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyEnter
+	keyBackspace
+	keyEscape
+	keyInterrupt
+)
+
+// tuiKey is one decoded key press
+type tuiKey struct {
+	code int
+	r    rune
+}
+
+// parseKeys decodes the bytes of one terminal read into key presses,
+// understanding the common ESC [ sequences for arrows and page keys
+func parseKeys(buf []byte) []tuiKey {
+	var keys []tuiKey
+	for len(buf) > 0 {
+		switch {
+		case buf[0] == 0x1b && len(buf) >= 3 && (buf[1] == '[' || buf[1] == 'O'):
+			switch buf[2] {
+			case 'A':
+				keys = append(keys, tuiKey{code: keyUp})
+			case 'B':
+				keys = append(keys, tuiKey{code: keyDown})
+			case '5', '6':
+				if len(buf) >= 4 && buf[3] == '~' {
+					code := keyPageUp
+					if buf[2] == '6' {
+						code = keyPageDown
+					}
+					keys = append(keys, tuiKey{code: code})
+					buf = buf[1:]
+				}
+			}
+			buf = buf[3:]
+		case buf[0] == 0x1b:
+			keys = append(keys, tuiKey{code: keyEscape})
+			buf = buf[1:]
+		case buf[0] == '\r' || buf[0] == '\n':
+			keys = append(keys, tuiKey{code: keyEnter})
+			buf = buf[1:]
+		case buf[0] == 0x7f || buf[0] == 0x08:
+			keys = append(keys, tuiKey{code: keyBackspace})
+			buf = buf[1:]
+		case buf[0] == 0x03 || buf[0] == 0x04:
+			keys = append(keys, tuiKey{code: keyInterrupt})
+			buf = buf[1:]
+		case buf[0] < 0x20:
+			buf = buf[1:]
+		default:
+			r, size := utf8.DecodeRune(buf)
+			keys = append(keys, tuiKey{code: keyRune, r: r})
+			buf = buf[size:]
+		}
+	}
+	return keys
+}
+
+// tuiPrompt is a line of input the dashboard is asking for
+type tuiPrompt struct {
+	label  string
+	input  string
+	submit func(input string) error
+}
+
+// tuiState is the dashboard: the bookmarks, the search filter, the cursor
+// and any open prompt or confirmation
+type tuiState struct {
+	ops       tuiOps
+	all       []tuiEntry
+	filter    string
+	searching bool
+	cursor    int
+	prompt    *tuiPrompt
+	confirm   string // name awaiting delete confirmation
+	message   string
+	chosen    string // path to print on exit after Enter
+	done      bool
+}
+
+// newTUIState loads the bookmarks into a fresh dashboard
+func newTUIState(ops tuiOps) (*tuiState, error) {
+	s := &tuiState{ops: ops}
+	return s, s.reload()
+}
+
+// reload re-reads the bookmarks after a change, keeping the cursor in range
+func (s *tuiState) reload() error {
+	entries, err := s.ops.load()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	s.all = entries
+	s.clampCursor()
+	return nil
+}
+
+// visible returns the bookmarks matching the search filter by name, path
+// or tag, ignoring case
+func (s *tuiState) visible() []tuiEntry {
+	if s.filter == "" {
+		return s.all
+	}
+	needle := strings.ToLower(s.filter)
+	var matches []tuiEntry
+	for _, entry := range s.all {
+		haystack := strings.ToLower(entry.Name + " " + entry.Path + " " + strings.Join(entry.Tags, " "))
+		if strings.Contains(haystack, needle) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// selected returns the bookmark under the cursor
+func (s *tuiState) selected() (tuiEntry, bool) {
+	entries := s.visible()
+	if s.cursor < 0 || s.cursor >= len(entries) {
+		return tuiEntry{}, false
+	}
+	return entries[s.cursor], true
+}
+
+func (s *tuiState) clampCursor() {
+	if n := len(s.visible()); s.cursor >= n {
+		s.cursor = n - 1
+	}
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+}
+
+// result turns the outcome of a change into the status line and reloads
+func (s *tuiState) result(err error, success string) error {
+	if err != nil {
+		return err
+	}
+	s.message = success
+	return s.reload()
+}
+
+// handleKey applies one key press; page is how many rows a page key moves
+func (s *tuiState) handleKey(key tuiKey, page int) {
+	if key.code == keyInterrupt {
+		s.done = true
+		return
+	}
+
+	switch {
+	case s.confirm != "":
+		name := s.confirm
+		s.confirm = ""
+		if key.code == keyRune && (key.r == 'y' || key.r == 'Y') {
+			if err := s.result(s.ops.remove(name), fmt.Sprintf("Removed '%s'", name)); err != nil {
+				s.message = "Error: " + err.Error()
+			}
+		} else {
+			s.message = fmt.Sprintf("Kept '%s'", name)
+		}
+	case s.prompt != nil:
+		s.handlePromptKey(key)
+	case s.searching:
+		switch key.code {
+		case keyEnter, keyEscape:
+			s.searching = false
+		case keyBackspace:
+			s.filter = dropLastRune(s.filter)
+		case keyRune:
+			s.filter += string(key.r)
+		case keyUp, keyDown:
+			s.searching = false
+			s.handleKey(key, page)
+		}
+		s.clampCursor()
+	default:
+		s.handleBrowseKey(key, page)
+	}
+}
+
+func (s *tuiState) handlePromptKey(key tuiKey) {
+	switch key.code {
+	case keyEscape:
+		s.prompt = nil
+		s.message = "Cancelled"
+	case keyBackspace:
+		s.prompt.input = dropLastRune(s.prompt.input)
+	case keyRune:
+		s.prompt.input += string(key.r)
+	case keyEnter:
+		prompt := s.prompt
+		s.prompt = nil
+		if err := prompt.submit(strings.TrimSpace(prompt.input)); err != nil {
+			s.message = "Error: " + err.Error()
+		}
+	}
+}
+
+func (s *tuiState) handleBrowseKey(key tuiKey, page int) {
+	s.message = ""
+	entry, ok := s.selected()
+
+	switch key.code {
+	case keyUp:
+		s.cursor--
+	case keyDown:
+		s.cursor++
+	case keyPageUp:
+		s.cursor -= page
+	case keyPageDown:
+		s.cursor += page
+	case keyEscape:
+		if s.filter != "" {
+			s.filter = ""
+		} else {
+			s.done = true
+		}
+	case keyEnter:
+		if !ok {
+			break
+		}
+		if entry.Broken {
+			s.message = fmt.Sprintf("'%s' is broken: %s is missing", entry.Name, entry.Path)
+			break
+		}
+		if marks.IsURLTarget(entry.Path) {
+			s.message = fmt.Sprintf("'%s' is a URL; open it with 'mark open %s'", entry.Name, entry.Name)
+			break
+		}
+		s.chosen = entry.Path
+		s.done = true
+	case keyRune:
+		switch key.r {
+		case 'k':
+			s.cursor--
+		case 'j':
+			s.cursor++
+		case 'g':
+			s.cursor = 0
+		case 'G':
+			s.cursor = len(s.visible()) - 1
+		case 'q':
+			s.done = true
+		case '/':
+			s.searching = true
+		case 'n':
+			s.prompt = &tuiPrompt{label: "New bookmark name", submit: func(name string) error {
+				s.prompt = &tuiPrompt{label: "Target directory (empty for the current one)", submit: func(target string) error {
+					return s.result(s.ops.create(name, target), "Created bookmark")
+				}}
+				return nil
+			}}
+		case 'r':
+			if ok && !entry.Shared {
+				s.prompt = &tuiPrompt{label: fmt.Sprintf("Rename '%s' to", entry.Name), input: entry.Name, submit: func(newName string) error {
+					if newName == "" || newName == entry.Name {
+						return nil
+					}
+					return s.result(s.ops.rename(entry.Name, newName), fmt.Sprintf("Renamed '%s' to '%s'", entry.Name, newName))
+				}}
+			}
+		case 'd':
+			if ok && !entry.Shared {
+				s.confirm = entry.Name
+			}
+		case 't':
+			if ok && !entry.Shared {
+				s.prompt = &tuiPrompt{label: fmt.Sprintf("Tags for '%s' (comma-separated)", entry.Name), input: strings.Join(entry.Tags, ","), submit: func(tags string) error {
+					return s.result(s.ops.setTags(entry.Name, parseTags(tags)), fmt.Sprintf("Tagged '%s'", entry.Name))
+				}}
+			}
+		}
+		if ok && entry.Shared && strings.ContainsRune("rdt", key.r) {
+			s.message = fmt.Sprintf("'%s' is a shared bookmark and cannot be changed here", entry.Name)
+		}
+	}
+	s.clampCursor()
+}
+
+// dropLastRune removes the last character of s
+func dropLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
+
+// renderTUI draws the dashboard for a terminal of the given size
+func renderTUI(s *tuiState, width, height int) string {
+	var sb strings.Builder
+	entries := s.visible()
+
+	header := fmt.Sprintf("mark — %d bookmarks", len(s.all))
+	if s.filter != "" || s.searching {
+		header += fmt.Sprintf(", %d matching /%s", len(entries), s.filter)
+	}
+	sb.WriteString(truncateRunes(header, width) + "\r\n\r\n")
+
+	// Keep the cursor row on screen, scrolling by whole rows
+	rows := height - 4
+	if rows < 1 {
+		rows = 1
+	}
+	offset := 0
+	if s.cursor >= rows {
+		offset = s.cursor - rows + 1
+	}
+
+	for i := offset; i < len(entries) && i < offset+rows; i++ {
+		entry := entries[i]
+		line := fmt.Sprintf("  %-20s %s", entry.Name, entry.Path)
+		if len(entry.Tags) > 0 {
+			line += "  [" + strings.Join(entry.Tags, ",") + "]"
+		}
+		if entry.Shared {
+			line += "  (shared)"
+		}
+		line = truncateRunes(line, width)
+		switch {
+		case i == s.cursor:
+			sb.WriteString("\033[7m" + line + "\033[0m")
+		case entry.Broken:
+			sb.WriteString(colorRed + line + colorReset)
+		default:
+			sb.WriteString(line)
+		}
+		sb.WriteString("\r\n")
+	}
+	if len(entries) == 0 {
+		sb.WriteString("  (no bookmarks)\r\n")
+	}
+
+	// Status line at the bottom
+	sb.WriteString(fmt.Sprintf("\033[%d;1H\033[K", height))
+	var status string
+	switch {
+	case s.confirm != "":
+		status = fmt.Sprintf("Delete '%s'? (y/N)", s.confirm)
+	case s.prompt != nil:
+		status = s.prompt.label + ": " + s.prompt.input
+	case s.searching:
+		status = "/" + s.filter
+	case s.message != "":
+		status = s.message
+	default:
+		status = "Enter jump  / search  n new  r rename  d delete  t tags  q quit"
+	}
+	sb.WriteString(truncateRunes(status, width))
+	return sb.String()
+}
+
+// truncateRunes shortens s to at most width characters
+func truncateRunes(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// markTUIOps applies dashboard changes to the real bookmarks
+type markTUIOps struct {
+	config Config
+}
+
+func (o markTUIOps) load() ([]tuiEntry, error) {
+	bookmarks, err := openLayeredStorage(o.config).List()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := marks.LoadMetadata(o.config.MarksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []tuiEntry
+	for _, bookmark := range bookmarks {
+		entry := tuiEntry{Name: bookmark.Name, Path: marks.TargetPath(o.config, bookmark.Target), Shared: bookmark.Shared}
+		if !marks.IsURLTarget(entry.Path) {
+			info, err := os.Stat(entry.Path)
+			entry.Broken = err != nil || !info.IsDir()
+		}
+		if m := meta[bookmark.Name]; m != nil {
+			entry.Tags = m.Tags
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (o markTUIOps) create(name, target string) error {
+	if target == "" {
+		target = "."
+	}
+	if !marks.IsURLTarget(target) && !filepath.IsAbs(marks.ExpandHome(target)) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		target = filepath.Join(cwd, target)
+	}
+	targetDir, err := apiTarget(target)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = filepath.Base(targetDir)
+	}
+	name, err = configNamePolicy(o.config).normalize(name)
+	if err != nil {
+		return err
+	}
+	return storeBookmark(o.config, openStorage(o.config), name, targetDir, Metadata{})
+}
+
+func (o markTUIOps) rename(oldName, newName string) error {
+	return renameBookmark(o.config, openStorage(o.config), oldName, newName)
+}
+
+func (o markTUIOps) remove(name string) error {
+	storage := openStorage(o.config)
+	bookmark, err := storage.Get(name)
+	if err != nil {
+		return err
+	}
+	_, err = removeBookmark(o.config, storage, bookmark)
+	return err
+}
+
+func (o markTUIOps) setTags(name string, tags []string) error {
+	meta, err := marks.LoadMetadata(o.config.MarksDir)
+	if err != nil {
+		return err
+	}
+	m := Metadata{}
+	if existing := meta[name]; existing != nil {
+		m = *existing
+	}
+	m.Tags = tags
+	if marks.IsEmptyMetadata(&m) {
+		return marks.UpdateMetadata(o.config.MarksDir, name, nil)
+	}
+	return marks.UpdateMetadata(o.config.MarksDir, name, &m)
+}
+
+// stty runs stty on the terminal, returning its output
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// terminalSize returns the rows and columns of the terminal, 24x80 when
+// stty cannot tell
+func terminalSize(tty *os.File) (int, int) {
+	out, err := stty(tty, "size")
+	if fields := strings.Fields(out); err == nil && len(fields) == 2 {
+		rows, errRows := strconv.Atoi(fields[0])
+		cols, errCols := strconv.Atoi(fields[1])
+		if errRows == nil && errCols == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// tuiCommand runs the full-screen dashboard ('mark tui'). It draws on the
+// terminal, so stdout only carries the path chosen with Enter, for
+// 'cd "$(mark tui)"'; quitting without a choice exits with status 1.
+func tuiCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: Unknown tui option: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: mark tui needs a terminal: %v\n", err)
+		os.Exit(1)
+	}
+	defer tty.Close()
+
+	state, err := newTUIState(markTUIOps{config: config})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read key by key without echo; Ctrl-C arrives as a key so the
+	// terminal is always restored
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: mark tui needs stty to control the terminal: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := stty(tty, "-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	restore := func() {
+		stty(tty, saved)
+		fmt.Fprint(tty, "\033[?25h\033[?1049l")
+	}
+
+	// Use the alternate screen so the dashboard leaves no trace
+	fmt.Fprint(tty, "\033[?1049h\033[?25l")
+	buf := make([]byte, 64)
+	for !state.done {
+		rows, cols := terminalSize(tty)
+		fmt.Fprint(tty, "\033[H\033[2J"+renderTUI(state, cols, rows))
+
+		n, err := tty.Read(buf)
+		if err != nil {
+			break
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			state.handleKey(key, rows-4)
+			if state.done {
+				break
+			}
+		}
+	}
+	restore()
+
+	if state.chosen == "" {
+		os.Exit(1)
+	}
+	if entry, ok := state.selected(); ok {
+		_ = recordUsage(entry.Name, entry.Path)
+		_ = recordAudit(config, "jump", entry.Name, entry.Path)
+	}
+	fmt.Println(state.chosen)
+}
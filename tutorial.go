@@ -0,0 +1,200 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// tutorialStep is one stage of 'mark tutorial'
+type tutorialStep struct {
+	title   string
+	explain string
+	command string // suggested command, run when the user just presses Enter
+}
+
+var tutorialSteps = []tutorialStep{
+	{
+		title:   "Bookmark the current directory",
+		explain: "You are in ~/projects/demo. 'mark <name>' bookmarks the directory you are in.",
+		command: "mark demo",
+	},
+	{
+		title:   "Bookmark another path",
+		explain: "'mark <name> <path>' bookmarks any directory without going there first.",
+		command: "mark docs ~/docs",
+	},
+	{
+		title:   "List your bookmarks",
+		explain: "'mark -l' shows every bookmark and where it points ('marks' once aliases are set up).",
+		command: "mark -l",
+	},
+	{
+		title:   "Jump to a bookmark",
+		explain: "The 'jump' shell function runs 'mark -j <name>' and changes into the printed directory.",
+		command: "jump docs",
+	},
+	{
+		title:   "Delete a bookmark",
+		explain: "'mark -d <name>' removes the bookmark; the directory itself is left alone.",
+		command: "mark -d demo",
+	},
+}
+
+// tutorialArgs turns a line typed during the tutorial into arguments for
+// mark. An empty line runs the suggested command, and 'jump <name>' becomes
+// the 'mark -j' call made by the shell function. ok is false for anything
+// that is not a mark or jump command.
+func tutorialArgs(line, suggested string) (args []string, jump bool, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		fields = strings.Fields(suggested)
+	}
+
+	switch fields[0] {
+	case "mark":
+		return fields[1:], false, true
+	case "jump":
+		return append([]string{"--dir-fallback", "-j"}, fields[1:]...), true, true
+	}
+	return nil, false, false
+}
+
+// tutorialSandbox creates a throwaway home with its own config, marks
+// directory and a few directories to bookmark, returning the home and the
+// directory the tour starts in
+func tutorialSandbox() (string, string, error) {
+	home, err := os.MkdirTemp("", "mark-tutorial-")
+	if err != nil {
+		return "", "", fmt.Errorf("error creating tutorial sandbox: %w", err)
+	}
+	// Resolve e.g. macOS /var -> /private/var so jump output matches home
+	if resolved, err := filepath.EvalSymlinks(home); err == nil {
+		home = resolved
+	}
+
+	start := filepath.Join(home, "projects", "demo")
+	for _, dir := range []string{start, filepath.Join(home, "docs"), filepath.Join(home, ".marks")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			os.RemoveAll(home)
+			return "", "", fmt.Errorf("error creating tutorial sandbox: %w", err)
+		}
+	}
+
+	config := fmt.Sprintf("marksdir=%s\n", filepath.Join(home, ".marks"))
+	if err := os.WriteFile(filepath.Join(home, ".mark"), []byte(config), 0644); err != nil {
+		os.RemoveAll(home)
+		return "", "", fmt.Errorf("error creating tutorial sandbox: %w", err)
+	}
+	return home, start, nil
+}
+
+// sandboxEnv returns the current environment with HOME and the state
+// directories pointed into the sandbox, so the tour never touches the
+// user's own bookmarks, config or usage history
+func sandboxEnv(home string) []string {
+	var env []string
+	for _, v := range os.Environ() {
+		key, _, _ := strings.Cut(v, "=")
+		switch key {
+		case "HOME", "XDG_STATE_HOME", "XDG_RUNTIME_DIR", "MARK_PROFILE", "MARK_CONFIG", "MARK_DIR":
+			continue
+		}
+		env = append(env, v)
+	}
+	return append(env, "HOME="+home, "XDG_STATE_HOME="+filepath.Join(home, ".local", "state"))
+}
+
+// tutorialCommand walks through creating, listing, jumping to and deleting
+// bookmarks in a sandbox ('mark tutorial'). It runs before config load, so
+// it works before first-time setup.
+func tutorialCommand(config Config, flags *ParsedFlags, args []string) {
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	home, dir, err := tutorialSandbox()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(home)
+	env := sandboxEnv(home)
+
+	fmt.Println("mark tutorial")
+	fmt.Println()
+	fmt.Println("This tour runs in a sandbox; your own bookmarks are not touched.")
+	fmt.Println("Type each command (or just press Enter to run the suggestion); 'q' quits.")
+
+	reader := bufio.NewReader(os.Stdin)
+	for i, step := range tutorialSteps {
+		fmt.Printf("\nStep %d of %d: %s\n", i+1, len(tutorialSteps), step.title)
+		fmt.Printf("  %s\n", step.explain)
+
+		for {
+			fmt.Printf("  Try: %s\n> ", step.command)
+			line, err := reader.ReadString('\n')
+			line = strings.TrimSpace(line)
+			if line == "q" || (err == io.EOF && line == "") {
+				fmt.Println("\nTutorial ended. Run 'mark tutorial' to start again.")
+				return
+			}
+
+			cmdArgs, jump, ok := tutorialArgs(line, step.command)
+			if !ok {
+				fmt.Println("  Only 'mark' and 'jump' commands work in the tutorial.")
+				continue
+			}
+
+			cmd := exec.Command(self, cmdArgs...)
+			cmd.Dir = dir
+			cmd.Env = env
+			cmd.Stderr = os.Stderr
+			var out bytes.Buffer
+			if jump {
+				cmd.Stdout = &out
+			} else {
+				cmd.Stdout = os.Stdout
+			}
+
+			if err := cmd.Run(); err != nil {
+				fmt.Println("  That did not work; try again, or press Enter for the suggestion.")
+				continue
+			}
+			if jump {
+				dir = strings.TrimSpace(out.String())
+				fmt.Printf("cd %s\n", tildePath(dir, home))
+			}
+			break
+		}
+	}
+
+	fmt.Println("\nThat's the tour. The sandbox has been removed.")
+	if !areAliasesAlreadySetup() {
+		fmt.Println("Run 'mark --alias' to add the jump command to your shell.")
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// installedFiles returns the files setup may have written under homeDir
+// (unified RC files and their legacy predecessors) and the completion files
+// 'mark completion install --user' wrote, where they currently exist
+func installedFiles(homeDir string) []string {
+	candidates := []string{
+		bashRCFile,
+		zshRCFile,
+		fishRCFile,
+		".mark.bash",
+		".mark.zsh",
+		filepath.Join(".config", "fish", "completions", "mark.fish"),
+	}
+
+	var found []string
+	for _, name := range candidates {
+		path := filepath.Join(homeDir, name)
+		if _, err := os.Lstat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		path := completionInstallPath(shell, "", false)
+		if _, err := os.Lstat(path); err == nil && !slices.Contains(found, path) {
+			found = append(found, path)
+		}
+	}
+	return found
+}
+
+// sourcingFiles returns the shell startup files under homeDir that source
+// mark's integration, either through the unified source line or a legacy block
+func sourcingFiles(homeDir string) []string {
+	var found []string
+	for _, name := range []string{".bashrc", ".bash_profile", ".profile", ".zshrc", filepath.Join(".config", "fish", "config.fish")} {
+		path := filepath.Join(homeDir, name)
+		if isSourceLinePresent(path) || fileMentions(path, "# mark command") {
+			found = append(found, path)
+		}
+	}
+	return found
+}
+
+// fileMentions reports whether any line of path contains text
+func fileMentions(path, text string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// removableDir reports whether dir is safe to delete wholesale: never the
+// home directory itself or one of its ancestors
+func removableDir(dir, homeDir string) bool {
+	dir = filepath.Clean(dir)
+	if dir == "" || dir == string(os.PathSeparator) || dir == "." {
+		return false
+	}
+	rel, err := filepath.Rel(dir, homeDir)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+// uninstallCommand removes what setup installed: the shell integration files,
+// the lines sourcing them from startup files and ~/.mark with its backup.
+// With --purge, or
+// when confirmed, the marks directory and usage history go too
+// ('mark uninstall [--yes] [--purge]').
+func uninstallCommand(config Config, flags *ParsedFlags, args []string) {
+	purge := false
+	for _, arg := range args {
+		if arg != "--purge" {
+			fmt.Fprintf(os.Stderr, "Error: Unknown uninstall option: %s\n", arg)
+			os.Exit(1)
+		}
+		purge = true
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		os.Exit(1)
+	}
+	configPath, _ := marks.ConfigFilePath()
+
+	// Read the config directly: uninstalling must never trigger setup
+	marksDir := ""
+	if existing, err := marks.ParseConfigFile(configPath); err == nil {
+		marksDir = existing.MarksDir
+	}
+
+	files := installedFiles(homeDir)
+	sourcing := sourcingFiles(homeDir)
+	_, configErr := os.Stat(configPath)
+	backupPath := configPath + marks.BackupSuffix
+	_, backupErr := os.Stat(backupPath)
+	var data []string // bookmarks and usage history, only deleted when purging
+	if marksDir != "" {
+		if _, err := os.Stat(marksDir); err == nil {
+			data = append(data, marksDir)
+		}
+	}
+	if _, err := os.Stat(stateDir()); err == nil {
+		data = append(data, stateDir())
+	}
+	if len(files) == 0 && len(sourcing) == 0 && configErr != nil && backupErr != nil && (!purge || len(data) == 0) {
+		fmt.Println("Nothing to uninstall.")
+		return
+	}
+
+	fmt.Println("This will remove:")
+	for _, path := range files {
+		fmt.Printf("  %s\n", tildePath(path, homeDir))
+	}
+	for _, path := range sourcing {
+		fmt.Printf("  mark lines in %s\n", tildePath(path, homeDir))
+	}
+	if configErr == nil {
+		fmt.Printf("  %s\n", tildePath(configPath, homeDir))
+	}
+	if backupErr == nil {
+		fmt.Printf("  %s\n", tildePath(backupPath, homeDir))
+	}
+	if purge {
+		for _, path := range data {
+			fmt.Printf("  %s\n", tildePath(path, homeDir))
+		}
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	if !flags.Yes {
+		fmt.Print("Continue? (y/N): ")
+		response, _ := reader.ReadString('\n')
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Nothing removed.")
+			return
+		}
+	}
+
+	// Keeping bookmarks is the default; deleting them needs --purge or an
+	// explicit yes (never implied by --yes)
+	if !purge && !flags.Yes && len(data) > 0 {
+		fmt.Print("Also delete your bookmarks and usage history? (y/N): ")
+		response, _ := reader.ReadString('\n')
+		response = strings.ToLower(strings.TrimSpace(response))
+		purge = response == "y" || response == "yes"
+	}
+
+	var removed []string
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		CleanupExistingCompletion(shell)
+	}
+	for _, path := range files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		removed = append(removed, tildePath(path, homeDir))
+	}
+	for _, path := range sourcing {
+		cleanupShellConfigSourceLine(path)
+		removed = append(removed, "mark lines in "+tildePath(path, homeDir))
+	}
+	if configErr == nil {
+		if err := os.Remove(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", configPath, err)
+			os.Exit(1)
+		}
+		removed = append(removed, tildePath(configPath, homeDir))
+	}
+	if backupErr == nil {
+		if err := os.Remove(backupPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", backupPath, err)
+			os.Exit(1)
+		}
+		removed = append(removed, tildePath(backupPath, homeDir))
+	}
+
+	if purge {
+		for _, path := range data {
+			if !removableDir(path, homeDir) {
+				fmt.Fprintf(os.Stderr, "Warning: Not deleting %s (contains your home directory)\n", path)
+				continue
+			}
+			if err := os.RemoveAll(path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", path, err)
+				os.Exit(1)
+			}
+			removed = append(removed, tildePath(path, homeDir))
+		}
+	}
+
+	for _, item := range removed {
+		fmt.Printf("✓ Removed %s\n", item)
+	}
+	if !purge && marksDir != "" {
+		fmt.Printf("Kept your bookmarks in %s\n", tildePath(marksDir, homeDir))
+	}
+	fmt.Println("Restart your shell to drop the aliases and completion from this session.")
+}
@@ -0,0 +1,91 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// validateURLTarget checks that a URL given as a bookmark target has a host
+func validateURLTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid URL: %s", target)
+	}
+	if strings.ContainsAny(target, " \t\n") {
+		return fmt.Errorf("URL contains whitespace: %q", target)
+	}
+	return nil
+}
+
+// openerCommand returns the command line that opens target with the
+// desktop's default application. URLs go to $BROWSER when it is set.
+func openerCommand(goos, target string, getenv func(string) string) []string {
+	if marks.IsURLTarget(target) {
+		// $BROWSER may list several browsers separated by colons
+		browser := strings.Split(getenv("BROWSER"), ":")[0]
+		if fields := strings.Fields(browser); len(fields) > 0 {
+			return append(fields, target)
+		}
+	}
+
+	switch goos {
+	case "darwin":
+		return []string{"open", target}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler", target}
+	default:
+		return []string{"xdg-open", target}
+	}
+}
+
+// openCommand launches a URL bookmark in the browser, or shows a directory
+// bookmark in the file manager ('mark open <name>')
+func openCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Bookmark name required for open\n")
+		os.Exit(1)
+	}
+	storage := openLayeredStorage(config)
+	name, subpath := splitSubpath(storage, args[0])
+	bookmark := lookupBookmark(storage, name)
+
+	target := bookmark.Target
+	if marks.IsURLTarget(target) {
+		if !insideSubpath(subpath) {
+			fmt.Fprintf(os.Stderr, "Error: '%s' leaves bookmark '%s'\n", subpath, name)
+			os.Exit(1)
+		}
+		if subpath != "" {
+			target = strings.TrimSuffix(target, "/") + "/" + subpath
+		}
+	} else {
+		target = resolveJumpTarget(config, args[0], flags.Raw)
+	}
+	_ = recordUsage(name, target)
+
+	opener := openerCommand(runtime.GOOS, target, os.Getenv)
+	runInteractive(exec.Command(opener[0], opener[1:]...))
+}
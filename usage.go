@@ -0,0 +1,594 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// usageEvent is one successful jump recorded in the usage log
+type usageEvent struct {
+	Time time.Time
+	Name string
+	Path string
+}
+
+// changeEvent is one bookmark creation or deletion recorded in the change log
+type changeEvent struct {
+	Time   time.Time
+	Action string // "create" or "delete"
+	Name   string
+}
+
+// jumpEvent is one change of directory recorded in the jump history
+type jumpEvent struct {
+	Time time.Time
+	From string
+	To   string
+}
+
+// usageSummary aggregates the usage log for a single bookmark
+type usageSummary struct {
+	Count    int
+	LastUsed time.Time
+}
+
+// stateDir returns the directory for per-machine state ($XDG_STATE_HOME/mark)
+func stateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "mark")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".local", "state", "mark")
+}
+
+// usageFilePath returns the location of the usage log
+func usageFilePath() string {
+	return filepath.Join(stateDir(), "usage")
+}
+
+// maxUsageLog caps the size of the usage log in bytes; once it grows past
+// this the oldest jumps are dropped until half remains, so the log is
+// rewritten rarely and readers never parse more than this
+const maxUsageLog = 1 << 20
+
+// recordUsage appends a jump to the usage log as "unix-time<TAB>name<TAB>path"
+func recordUsage(name, path string) error {
+	if err := appendLog(usageFilePath(), "usage log", time.Now().Unix(), name, path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(usageFilePath()); err == nil && info.Size() > maxUsageLog {
+		return compactUsage(maxUsageLog / 2)
+	}
+	return nil
+}
+
+// compactUsage rewrites the usage log with the most recent jumps that fit
+// in limit bytes
+func compactUsage(limit int64) error {
+	events, err := loadUsage()
+	if err != nil {
+		return err
+	}
+
+	start := len(events)
+	var size int64
+	for start > 0 {
+		event := events[start-1]
+		size += int64(len(usageLine(event)))
+		if size > limit {
+			break
+		}
+		start--
+	}
+	debugf("compact usage log: dropping %d of %d jumps", start, len(events))
+	return writeUsage(events[start:])
+}
+
+// appendLog appends one line of tab-separated fields to the log at path,
+// creating the state directory when needed; what names the log in errors
+func appendLog(path, what string, fields ...any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %w", err)
+	}
+
+	debugf("append to %s", path)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", what, err)
+	}
+	defer file.Close()
+
+	line := make([]string, len(fields))
+	for i, field := range fields {
+		line[i] = fmt.Sprint(field)
+	}
+	if _, err := fmt.Fprintln(file, strings.Join(line, "\t")); err != nil {
+		return fmt.Errorf("error writing %s: %w", what, err)
+	}
+	return nil
+}
+
+// handoffFilePath returns the file holding the last jump target for
+// 'mark -j --handoff': $XDG_RUNTIME_DIR/mark/last-path, or the state
+// directory when there is no runtime directory
+func handoffFilePath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "mark", "last-path")
+	}
+	return filepath.Join(stateDir(), "last-path")
+}
+
+// writeHandoff replaces the handoff file with path, so other programs can
+// pick up the last jump target without reading terminal output
+func writeHandoff(path string) error {
+	handoffPath := handoffFilePath()
+	if err := os.MkdirAll(filepath.Dir(handoffPath), 0700); err != nil {
+		return fmt.Errorf("error creating handoff directory: %w", err)
+	}
+	if err := marks.WriteFileAtomic(handoffPath, []byte(path+"\n"), 0600); err != nil {
+		return fmt.Errorf("error writing handoff file: %w", err)
+	}
+	return nil
+}
+
+// historyFilePath returns the location of the jump history
+func historyFilePath() string {
+	return filepath.Join(stateDir(), "history")
+}
+
+// recordJump appends a jump from the current directory to path to the jump
+// history as "unix-time<TAB>from<TAB>to"
+func recordJump(path string) error {
+	from, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	return appendLog(historyFilePath(), "jump history", time.Now().Unix(), from, path)
+}
+
+// loadJumpHistory reads the jump history, oldest first. A missing file
+// yields no events.
+func loadJumpHistory() ([]jumpEvent, error) {
+	file, err := os.Open(historyFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading jump history: %w", err)
+	}
+	defer file.Close()
+
+	var events []jumpEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		events = append(events, jumpEvent{Time: time.Unix(seconds, 0), From: parts[1], To: parts[2]})
+	}
+	return events, scanner.Err()
+}
+
+// previousLocation returns where the last jump started, the target of
+// 'mark -j -'. Jumping back records a jump too, so repeating it toggles.
+func previousLocation(events []jumpEvent) (string, bool) {
+	if len(events) == 0 || events[len(events)-1].From == "" {
+		return "", false
+	}
+	return events[len(events)-1].From, true
+}
+
+// historyCommand prints the last N jump destinations, most recent first
+// ('mark history [N]')
+func historyCommand(config Config, flags *ParsedFlags, args []string) {
+	n := 20
+	if len(args) > 0 {
+		value, err := strconv.Atoi(args[0])
+		if err != nil || value < 1 {
+			fmt.Fprintf(os.Stderr, "Error: history count must be a positive number\n")
+			os.Exit(1)
+		}
+		n = value
+	}
+
+	events, err := loadJumpHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(events) == 0 {
+		fmt.Println("No jumps recorded yet. Jump with 'mark -j <name>' or 'jump <name>'")
+		return
+	}
+
+	for i := len(events) - 1; i >= 0 && i >= len(events)-n; i-- {
+		fmt.Printf("  %s  %s\n", formatLastUsed(events[i].Time), events[i].To)
+	}
+}
+
+// visitsFilePath returns the location of the directory visit counts
+func visitsFilePath() string {
+	return filepath.Join(stateDir(), "visits")
+}
+
+// maxVisitDirs caps the visit counts; the directories seen longest ago are
+// dropped first
+const maxVisitDirs = 1000
+
+// recordVisit counts a directory the shell changed into
+func recordVisit(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return nil
+	}
+	dir = filepath.Clean(dir)
+
+	visits, err := loadVisits()
+	if err != nil {
+		return err
+	}
+	visit := visits[dir]
+	visit.Path = dir
+	visit.Count++
+	visit.LastSeen = time.Now().Truncate(time.Second)
+	visits[dir] = visit
+	return writeVisits(visits)
+}
+
+// loadVisits reads the visit counts, one "count<TAB>last-seen<TAB>path"
+// line per directory. Lines of the older one-line-per-visit log
+// ("unix-time<TAB>path") are folded in. A missing file yields no visits.
+func loadVisits() (map[string]dirCount, error) {
+	visits := make(map[string]dirCount)
+	file, err := os.Open(visitsFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return visits, nil
+		}
+		return nil, fmt.Errorf("error reading visit counts: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		count, seen, dir := "1", fields[0], fields[len(fields)-1]
+		if len(fields) == 3 {
+			count, seen = fields[0], fields[1]
+		}
+		n, err := strconv.Atoi(count)
+		unix, err2 := strconv.ParseInt(seen, 10, 64)
+		if len(fields) < 2 || err != nil || err2 != nil || !filepath.IsAbs(dir) {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		visit := visits[dir]
+		visit.Path = dir
+		visit.Count += n
+		if t := time.Unix(unix, 0); t.After(visit.LastSeen) {
+			visit.LastSeen = t
+		}
+		visits[dir] = visit
+	}
+	return visits, scanner.Err()
+}
+
+// writeVisits replaces the visit counts, keeping the maxVisitDirs
+// directories seen most recently
+func writeVisits(visits map[string]dirCount) error {
+	kept := make([]dirCount, 0, len(visits))
+	for _, visit := range visits {
+		kept = append(kept, visit)
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if !kept[i].LastSeen.Equal(kept[j].LastSeen) {
+			return kept[i].LastSeen.After(kept[j].LastSeen)
+		}
+		return kept[i].Path < kept[j].Path
+	})
+	if len(kept) > maxVisitDirs {
+		kept = kept[:maxVisitDirs]
+	}
+
+	var sb strings.Builder
+	for _, visit := range kept {
+		fmt.Fprintf(&sb, "%d\t%d\t%s\n", visit.Count, visit.LastSeen.Unix(), visit.Path)
+	}
+	visitsPath := visitsFilePath()
+	if err := os.MkdirAll(filepath.Dir(visitsPath), 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %w", err)
+	}
+	debugf("write %s", visitsPath)
+	if err := marks.WriteFileAtomic(visitsPath, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("error writing visit counts: %w", err)
+	}
+	return nil
+}
+
+// changesFilePath returns the location of the bookmark change log
+func changesFilePath() string {
+	return filepath.Join(stateDir(), "changes")
+}
+
+// recordChange appends a creation or deletion to the change log as
+// "unix-time<TAB>action<TAB>name"
+func recordChange(action, name string) error {
+	return appendLog(changesFilePath(), "change log", time.Now().Unix(), action, name)
+}
+
+// auditFilePath returns the location of the audit log
+func auditFilePath() string {
+	return filepath.Join(stateDir(), "log")
+}
+
+// auditUser names who ran mark: the invoking user behind sudo, otherwise
+// the login name from the environment
+func auditUser(getenv func(string) string) string {
+	for _, key := range []string{"SUDO_USER", "USER", "USERNAME", "LOGNAME"} {
+		if name := getenv(key); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// recordAudit appends an operation to the audit log when audit_log=on, as
+// "RFC3339-time<TAB>user<TAB>operation<TAB>name<TAB>target". Unlike the
+// other logs it is meant to be read by people, e.g. on shared admin accounts.
+func recordAudit(config Config, operation, name, target string) error {
+	if config.AuditLog != "on" {
+		return nil
+	}
+	return appendLog(auditFilePath(), "audit log", time.Now().Format(time.RFC3339), auditUser(os.Getenv), operation, name, target)
+}
+
+// loadChanges reads every event from the change log, oldest first.
+// A missing log yields no events.
+func loadChanges() ([]changeEvent, error) {
+	file, err := os.Open(changesFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading change log: %w", err)
+	}
+	defer file.Close()
+
+	var events []changeEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		events = append(events, changeEvent{
+			Time:   time.Unix(seconds, 0),
+			Action: parts[1],
+			Name:   parts[2],
+		})
+	}
+	return events, scanner.Err()
+}
+
+// loadUsage reads every event from the usage log, oldest first.
+// A missing log yields no events.
+func loadUsage() ([]usageEvent, error) {
+	file, err := os.Open(usageFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading usage log: %w", err)
+	}
+	defer file.Close()
+
+	var events []usageEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		events = append(events, usageEvent{
+			Time: time.Unix(seconds, 0),
+			Name: parts[1],
+			Path: parts[2],
+		})
+	}
+	return events, scanner.Err()
+}
+
+// summarizeUsage returns the use count and last-used time per bookmark name
+func summarizeUsage(events []usageEvent) map[string]usageSummary {
+	summary := make(map[string]usageSummary)
+	for _, event := range events {
+		s := summary[event.Name]
+		s.Count++
+		if event.Time.After(s.LastUsed) {
+			s.LastUsed = event.Time
+		}
+		summary[event.Name] = s
+	}
+	return summary
+}
+
+// createdTimes returns when each bookmark name was last created according to
+// the change log
+func createdTimes(changes []changeEvent) map[string]time.Time {
+	created := make(map[string]time.Time)
+	for _, change := range changes {
+		if change.Action == "create" && change.Time.After(created[change.Name]) {
+			created[change.Name] = change.Time
+		}
+	}
+	return created
+}
+
+// creationTime returns when a bookmark was created: the time stored in its
+// metadata, else the last creation in the change log, else for personal
+// bookmarks older than both the symlink's own mtime. It is zero when none
+// is known.
+func creationTime(config Config, name string, m *Metadata, logged map[string]time.Time, shared bool) time.Time {
+	if m != nil && !m.Created.IsZero() {
+		return m.Created
+	}
+	if t, ok := logged[name]; ok {
+		return t
+	}
+	if shared {
+		return time.Time{}
+	}
+	if info, err := os.Lstat(filepath.Join(config.MarksDir, name)); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// formatLastUsed renders a last-used time for listings, "-" when never used
+func formatLastUsed(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// formatAge renders how long ago something happened, coarsely: "today",
+// "12 days ago", "5 months ago", "3 years ago"
+func formatAge(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	switch {
+	case days < 1:
+		return "today"
+	case days == 1:
+		return "1 day ago"
+	case days < 60:
+		return fmt.Sprintf("%d days ago", days)
+	case days < 730:
+		return fmt.Sprintf("%d months ago", days/30)
+	default:
+		return fmt.Sprintf("%d years ago", days/365)
+	}
+}
+
+// recentBookmarks returns the latest jump per bookmark, most recent first,
+// limited to n entries
+func recentBookmarks(events []usageEvent, n int) []usageEvent {
+	var recent []usageEvent
+	seen := make(map[string]bool)
+	for i := len(events) - 1; i >= 0 && len(recent) < n; i-- {
+		if seen[events[i].Name] {
+			continue
+		}
+		seen[events[i].Name] = true
+		recent = append(recent, events[i])
+	}
+	return recent
+}
+
+// recentCommand prints the last N bookmarks jumped to ('mark recent [N]')
+func recentCommand(config Config, flags *ParsedFlags, args []string) {
+	n := 10
+	if len(args) > 0 {
+		value, err := strconv.Atoi(args[0])
+		if err != nil || value < 1 {
+			fmt.Fprintf(os.Stderr, "Error: recent count must be a positive number\n")
+			os.Exit(1)
+		}
+		n = value
+	}
+
+	events, err := loadUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	recent := recentBookmarks(events, n)
+	if len(recent) == 0 {
+		fmt.Println("No recent jumps. Jump to a bookmark with 'mark -j <name>'")
+		return
+	}
+
+	for _, event := range recent {
+		fmt.Printf("  %-20s %s  %s\n", event.Name, formatLastUsed(event.Time), event.Path)
+	}
+}
+
+// usageLine formats event as a line of the usage log
+func usageLine(event usageEvent) string {
+	return fmt.Sprintf("%d\t%s\t%s\n", event.Time.Unix(), event.Name, event.Path)
+}
+
+// writeUsage replaces the usage log with events
+func writeUsage(events []usageEvent) error {
+	usagePath := usageFilePath()
+	if err := os.MkdirAll(filepath.Dir(usagePath), 0755); err != nil {
+		return fmt.Errorf("error creating state directory: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, event := range events {
+		sb.WriteString(usageLine(event))
+	}
+	if err := marks.WriteFileAtomic(usagePath, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("error writing usage log: %w", err)
+	}
+	return nil
+}
+
+// renameUsage moves the usage history of oldName over to newName
+func renameUsage(oldName, newName string) error {
+	events, err := loadUsage()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range events {
+		if events[i].Name == oldName {
+			events[i].Name = newName
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeUsage(events)
+}
@@ -0,0 +1,195 @@
+/*
+Copyright (C) 2025  Mark CLI Contributors
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/brockers/mark/pkg/marks"
+)
+
+// coverMatch is a bookmark whose target contains a directory, with the
+// path from the target down to it
+type coverMatch struct {
+	Name   string
+	Target string // resolved target directory
+	Rest   string // relative path below the target, "" for the target itself
+}
+
+// spec returns the match as 'jump' accepts it: name or name/rest
+func (m coverMatch) spec() string {
+	if m.Rest == "" {
+		return m.Name
+	}
+	return m.Name + "/" + filepath.ToSlash(m.Rest)
+}
+
+// bookmarksCovering returns the bookmarks whose target equals or is an
+// ancestor of path, the most specific (deepest target) first. Targets are
+// resolved the same way as path, so symlinked directories still match.
+func bookmarksCovering(config Config, bookmarks []Bookmark, path string) []coverMatch {
+	var matches []coverMatch
+	for _, bookmark := range bookmarks {
+		if marks.IsURLTarget(bookmark.Target) || marks.IsCommandTarget(bookmark.Target) {
+			continue
+		}
+		target := marks.ExpandPath(marks.TargetPath(config, bookmark.Target))
+		rest, err := filepath.Rel(target, path)
+		if err != nil || rest == ".." || strings.HasPrefix(rest, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rest == "." {
+			rest = ""
+		}
+		matches = append(matches, coverMatch{Name: bookmark.Name, Target: target, Rest: rest})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if len(matches[i].Target) != len(matches[j].Target) {
+			return len(matches[i].Target) > len(matches[j].Target)
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// whichCommand prints the bookmarks covering a directory
+// ('mark which [path] [--first] [--long]'), one name[/rest] per line as
+// 'jump' accepts it. It exits with status 2 when no bookmark covers it.
+func whichCommand(config Config, flags *ParsedFlags, args []string) {
+	path := ""
+	first := false
+	for _, arg := range args {
+		switch {
+		case arg == "--first":
+			first = true
+		case strings.HasPrefix(arg, "--"):
+			fmt.Fprintf(os.Stderr, "Error: Unknown which option: %s\n", arg)
+			os.Exit(1)
+		case path == "":
+			path = arg
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Usage: mark which [path] [--first]\n")
+			os.Exit(1)
+		}
+	}
+
+	if path == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
+			os.Exit(1)
+		}
+		path = cwd
+	}
+	path, err := filepath.Abs(marks.ExpandHome(normalizeTargetArg(path)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path = marks.ExpandPath(path)
+
+	bookmarks, err := openLayeredStorage(config).List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading bookmarks: %v\n", err)
+		os.Exit(1)
+	}
+
+	matches := bookmarksCovering(config, bookmarks, path)
+	if len(matches) == 0 {
+		if !first {
+			fmt.Fprintf(os.Stderr, "No bookmark covers %s\n", path)
+		}
+		os.Exit(exitNotFound)
+	}
+	if first {
+		matches = matches[:1]
+	}
+	for _, match := range matches {
+		if flags.Long {
+			fmt.Printf("%-30s -> %s\n", match.spec(), match.Target)
+		} else {
+			fmt.Println(match.spec())
+		}
+	}
+}
+
+// breadcrumb expresses path through the closest covering bookmark as
+// name:rest (or name for the target itself). Outside every bookmark it
+// falls back to the path with the home directory shortened to ~.
+func breadcrumb(matches []coverMatch, path, home string) string {
+	if len(matches) > 0 {
+		match := matches[0]
+		if match.Rest == "" {
+			return match.Name
+		}
+		return match.Name + ":" + filepath.ToSlash(match.Rest)
+	}
+	if home != "" {
+		if path == home {
+			return "~"
+		}
+		if rest, err := filepath.Rel(home, path); err == nil && rest != ".." && !strings.HasPrefix(rest, ".."+string(filepath.Separator)) {
+			return "~/" + filepath.ToSlash(rest)
+		}
+	}
+	return path
+}
+
+// breadcrumbCommand prints the current location through the nearest
+// personal bookmark ('mark breadcrumb [path]') for shell prompts. Shared
+// sources are not read, to keep it cheap. It never fails: errors fall back
+// to the plain path so a prompt is never left empty.
+func breadcrumbCommand(config Config, flags *ParsedFlags, args []string) {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: Usage: mark breadcrumb [path]\n")
+		os.Exit(1)
+	}
+
+	path := ""
+	if len(args) == 1 {
+		path = marks.ExpandHome(normalizeTargetArg(args[0]))
+	} else if cwd, err := os.Getwd(); err == nil {
+		path = cwd
+	} else {
+		path = os.Getenv("PWD")
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = marks.ExpandPath(path)
+
+	home, _ := os.UserHomeDir()
+	if home != "" {
+		home = marks.ExpandPath(home)
+	}
+
+	var matches []coverMatch
+	if storage, err := marks.Open(config); err == nil {
+		if bookmarks, err := storage.List(); err == nil {
+			matches = bookmarksCovering(config, bookmarks, path)
+		} else {
+			debugf("breadcrumb: %v", err)
+		}
+	}
+	fmt.Println(breadcrumb(matches, path, home))
+}